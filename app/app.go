@@ -1,67 +1,168 @@
 package app
 
 import (
+	"context"
 	"net/http"
+	"time"
 
 	"github.com/serhiirubets/rubeticket/config"
 	"github.com/serhiirubets/rubeticket/internal/app/accounts"
+	"github.com/serhiirubets/rubeticket/internal/app/admin/audit"
 	"github.com/serhiirubets/rubeticket/internal/app/admin/bands"
 	"github.com/serhiirubets/rubeticket/internal/app/admin/concerts"
+	"github.com/serhiirubets/rubeticket/internal/app/admin/events"
+	"github.com/serhiirubets/rubeticket/internal/app/admin/files"
+	"github.com/serhiirubets/rubeticket/internal/app/admin/genres"
+	"github.com/serhiirubets/rubeticket/internal/app/admin/imports"
+	"github.com/serhiirubets/rubeticket/internal/app/admin/roles"
 	"github.com/serhiirubets/rubeticket/internal/app/admin/venues"
 	"github.com/serhiirubets/rubeticket/internal/app/auth"
+	"github.com/serhiirubets/rubeticket/internal/app/catalog"
 	"github.com/serhiirubets/rubeticket/internal/app/file"
 	"github.com/serhiirubets/rubeticket/internal/app/fileuploader"
+	"github.com/serhiirubets/rubeticket/internal/app/resumable"
+	"github.com/serhiirubets/rubeticket/internal/app/search"
 	"github.com/serhiirubets/rubeticket/internal/app/uploads"
 	"github.com/serhiirubets/rubeticket/internal/app/users"
+	"github.com/serhiirubets/rubeticket/internal/pkg/background"
 	"github.com/serhiirubets/rubeticket/internal/pkg/db"
 	"github.com/serhiirubets/rubeticket/internal/pkg/filestorage"
+	"github.com/serhiirubets/rubeticket/internal/pkg/health"
 	"github.com/serhiirubets/rubeticket/internal/pkg/log"
+	"github.com/serhiirubets/rubeticket/internal/pkg/metrics"
 	"github.com/serhiirubets/rubeticket/internal/pkg/middleware"
+	"github.com/serhiirubets/rubeticket/internal/pkg/scanner"
+	"github.com/serhiirubets/rubeticket/internal/pkg/signedurl"
 	httpSwagger "github.com/swaggo/http-swagger"
 )
 
-func InitApp(conf *config.Config, logger log.ILogger) (http.Handler, error) {
+const (
+	// How often expired resumable uploads are removed
+	uploadReapInterval = 15 * time.Minute
+	// How often uses of expired one-time links are forgotten
+	signedURLPruneInterval = time.Hour
+)
+
+// InitApp builds the router. checker gets the probes of the database, storage and background workers,
+// the caller turns its readiness off on shutdown. runner runs the background work, the caller stops it
+// on shutdown before closing the database
+func InitApp(conf *config.Config, logger log.ILogger, dbInstance db.IDb, checker *health.Checker, runner *background.Runner) (http.Handler, error) {
 	router := http.NewServeMux()
 	v1Router := http.NewServeMux()
 	v1AdminRouter := http.NewServeMux()
-	dbInstance := db.NewDb(conf)
-	storage := filestorage.NewLocalStorage("uploads")
+	storage := filestorage.NewLocalStorage(conf.Uploads.Dir)
+
+	var fileScanner scanner.Scanner = scanner.NewStubScanner()
+	if conf.Scanner.ClamAVAddress != "" {
+		fileScanner = scanner.NewClamAVScanner(conf.Scanner.ClamAVAddress, time.Duration(conf.Scanner.IdleTimeoutSeconds)*time.Second)
+	}
+
+	sqlDB, err := dbInstance.SqlDB()
+	if err != nil {
+		return nil, err
+	}
+	// A background worker that has not made progress for this long is considered stuck
+	heartbeats := health.NewHeartbeats(5 * time.Minute)
 
 	// Middlewares
-	middlewares := middleware.Chain(middleware.CORS)
+	// Tracing goes before the access log, so its line carries the trace ID
+	chain := []middleware.Middleware{middleware.RequestID}
+	if conf.Tracing.Enabled() {
+		chain = append(chain, middleware.Tracing)
+	}
+	chain = append(chain, middleware.AccessLog(logger), middleware.Metrics, middleware.CORS(conf.CORS))
+	middlewares := middleware.Chain(chain...)
 
 	// Open routes
 	openRoutes := []string{
 		"/auth/login",
 		"/auth/register",
 		"/uploads/{fileName}",
+		"/signed-files/{fileName}",
 	}
-	authMiddleware := middleware.NewAuthMiddleware(conf, logger, openRoutes, "/api/v1")
-	authMiddlewareAdmin := middleware.NewAuthMiddleware(conf, logger, nil, "/admin/v1")
 
 	// Repositories
 	usersRepository := users.NewUserRepository(dbInstance)
 	fileRepository := file.NewRepository(dbInstance)
 	venueRepository := venues.NewVenueRepository(dbInstance)
 	bandRepository := bands.NewBandRepository(dbInstance)
+	genreRepository := genres.NewGenreRepository(dbInstance)
 	concertRepository := concerts.NewConcertRepository(dbInstance)
+	uploadRepository := resumable.NewRepository(dbInstance)
+
+	authMiddleware := middleware.NewAuthMiddleware(conf, logger, usersRepository, openRoutes, "/api/v1")
+	authMiddlewareAdmin := middleware.NewAuthMiddleware(conf, logger, usersRepository, nil, "/admin/v1")
 
 	// Services
+	auditService := audit.NewAuditService(audit.NewAuditRepository(dbInstance))
+	auditRecorder := audit.NewRecorder(auditService)
 	authService := auth.NewAuthService(usersRepository)
-	venueService := venues.NewVenueService(venueRepository)
-	bandService := bands.NewBandService(bandRepository)
-	concertService := concerts.NewConcertService(concertRepository, venueRepository, bandRepository)
+	venueService := venues.NewVenueService(dbInstance, venueRepository, auditRecorder)
+	bandService := bands.NewBandService(dbInstance, bandRepository, genreRepository, auditRecorder)
+	genreService := genres.NewGenreService(dbInstance, genreRepository)
+	concertService := concerts.NewConcertService(dbInstance, concertRepository, venueRepository, bandRepository, auditRecorder, conf.Db.TxMaxRetries)
 
 	// Utils
 	fileUploader := fileuploader.NewFileUploader(&fileuploader.Deps{
 		Logger:         logger,
 		DB:             dbInstance,
-		MaxSizeMB:      10,
-		AllowedTypes:   []string{"image/"},
+		MaxSizeMB:      conf.Uploads.MaxSizeMB,
+		AllowedTypes:   conf.Uploads.AllowedTypes,
 		Storage:        storage,
+		Scanner:        fileScanner,
+		MaxScanSizeMB:  conf.Scanner.MaxScanSizeMB,
 		FileRepository: fileRepository,
 	})
 
+	resumableService := resumable.NewService(&resumable.ServiceDeps{
+		Logger:       logger,
+		Storage:      storage,
+		Repository:   uploadRepository,
+		FileUploader: fileUploader,
+		AllowedTypes: conf.Uploads.ResumableAllowedTypes,
+		MaxSizeMB:    conf.Uploads.ResumableMaxSizeMB,
+		ChunkSizeMB:  conf.Uploads.ResumableChunkSizeMB,
+		Expiration:   time.Duration(conf.Uploads.ResumableExpirationHours) * time.Hour,
+	})
+
+	if err := runner.Every("resumable-reaper", uploadReapInterval, resumableService.ReapExpired); err != nil {
+		return nil, err
+	}
+
+	err = runner.Every("signed-url-pruner", signedURLPruneInterval, func(ctx context.Context) error {
+		_, err := fileRepository.PruneSignedURLUses(ctx, time.Now().Add(-uploads.MaxSignedURLLifetime))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	fileService := files.NewFileService(fileRepository, fileUploader)
+	searchService := search.NewService(search.NewRepository(dbInstance))
+	eventService := events.NewEventService(dbInstance, events.NewEventRepository(dbInstance), auditRecorder)
+	importService := imports.NewImportService(&imports.ServiceDeps{
+		DB:                dbInstance,
+		Logger:            logger,
+		Repository:        imports.NewJobRepository(dbInstance),
+		VenueService:      venueService,
+		VenueRepository:   venueRepository,
+		BandService:       bandService,
+		BandRepository:    bandRepository,
+		ConcertService:    concertService,
+		ConcertRepository: concertRepository,
+		Heartbeats:        heartbeats,
+		Runner:            runner,
+		Audit:             auditRecorder,
+	})
+	// No import runs before boot, the unfinished ones were cut off by a restart
+	failed, err := importService.FailUnfinished(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	if failed > 0 {
+		logger.Warn("Marked interrupted imports as failed", "count", failed)
+	}
+
 	// Handlers
 
 	// Public handlers
@@ -88,6 +189,28 @@ func InitApp(conf *config.Config, logger log.ILogger) (http.Handler, error) {
 		Logger:       logger,
 		Config:       conf,
 		FileUploader: fileUploader,
+		Signer:       signedurl.NewSigner(conf.Auth.URLSigningSecret),
+	})
+
+	resumable.NewResumableHandler(v1Router, &resumable.HandlerDeps{
+		Logger:  logger,
+		Config:  conf,
+		Service: resumableService,
+	})
+
+	search.NewSearchHandler(v1Router, &search.HandlerDeps{
+		Logger:  logger,
+		Config:  conf,
+		Service: searchService,
+	})
+
+	catalog.NewCatalogHandler(v1Router, &catalog.HandlerDeps{
+		Logger:         logger,
+		Config:         conf,
+		EventService:   eventService,
+		ConcertService: concertService,
+		BandService:    bandService,
+		GenreService:   genreService,
 	})
 
 	// Admin handlers
@@ -112,14 +235,64 @@ func InitApp(conf *config.Config, logger log.ILogger) (http.Handler, error) {
 		UserRepository: usersRepository,
 	})
 
+	genres.NewGenreHandler(v1AdminRouter, &genres.GenreHandlerDeps{
+		Config:  conf,
+		Logger:  logger,
+		Service: genreService,
+	})
+
+	events.NewEventHandler(v1AdminRouter, &events.EventHandlerDeps{
+		Config:  conf,
+		Logger:  logger,
+		Service: eventService,
+	})
+
+	roles.NewRoleHandler(v1AdminRouter, &roles.RoleHandlerDeps{
+		Config:  conf,
+		Logger:  logger,
+		Service: roles.NewRoleService(dbInstance, usersRepository, auditRecorder),
+	})
+
+	audit.NewAuditHandler(v1AdminRouter, &audit.AuditHandlerDeps{
+		Config:  conf,
+		Logger:  logger,
+		Service: auditService,
+	})
+
+	imports.NewImportHandler(v1AdminRouter, &imports.ImportHandlerDeps{
+		Config:  conf,
+		Logger:  logger,
+		Service: importService,
+	})
+
+	files.NewFileHandler(v1AdminRouter, &files.FileHandlerDeps{
+		Config:  conf,
+		Logger:  logger,
+		Service: fileService,
+	})
+
 	// Apply middleware
-	v1RouterWithAuth := authMiddleware.Auth(v1Router)
-	adminRouterWithAuth := authMiddlewareAdmin.Auth(v1AdminRouter)
-	adminRouterWithAuthAndAdmin := authMiddlewareAdmin.AdminOnly(adminRouterWithAuth)
+	v1RouterWithAuth := authMiddleware.Auth(middleware.Route("/api/v1", v1Router))
+	// AdminOnly reads the auth data, so it runs after Auth
+	adminRouterWithAuthAndAdmin := authMiddlewareAdmin.Auth(authMiddlewareAdmin.AdminOnly(middleware.Route("/admin/v1", v1AdminRouter)))
+
+	// Metrics
+	if err := metrics.RegisterDB(sqlDB); err != nil {
+		return nil, err
+	}
+
+	// Health
+	checker.AddLiveness("workers", heartbeats.Check)
+	checker.AddReadiness("db", sqlDB.PingContext)
+	checker.AddReadiness("storage", func(context.Context) error {
+		return storage.CheckWritable()
+	})
+	router.Handle("GET /healthz", checker.Liveness())
+	router.Handle("GET /readyz", checker.Readiness())
 
 	// Swagger
 	router.Handle("/swagger/", httpSwagger.Handler(
-		httpSwagger.URL("http://localhost:7777/swagger/doc.json"),
+		httpSwagger.URL(conf.App.SwaggerURL),
 	))
 
 	// Setup routes
@@ -127,5 +300,5 @@ func InitApp(conf *config.Config, logger log.ILogger) (http.Handler, error) {
 	router.Handle("/api/v1/", http.StripPrefix("/api/v1", v1RouterWithAuth))
 	router.Handle("/public/", http.StripPrefix("/public/", http.FileServer(http.Dir("public"))))
 
-	return middlewares(router), nil
+	return middlewares(middleware.Route("", router)), nil
 }
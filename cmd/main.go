@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
@@ -11,9 +12,18 @@ import (
 	"github.com/serhiirubets/rubeticket/app"
 	"github.com/serhiirubets/rubeticket/config"
 	_ "github.com/serhiirubets/rubeticket/docs"
+	"github.com/serhiirubets/rubeticket/internal/pkg/background"
+	"github.com/serhiirubets/rubeticket/internal/pkg/db"
+	"github.com/serhiirubets/rubeticket/internal/pkg/health"
 	"github.com/serhiirubets/rubeticket/internal/pkg/log"
+	"github.com/serhiirubets/rubeticket/internal/pkg/metrics"
+	"github.com/serhiirubets/rubeticket/internal/pkg/middleware"
+	"github.com/serhiirubets/rubeticket/internal/pkg/tracing"
 )
 
+// How long canceled requests are given to return after the shutdown timeout
+const cancelGracePeriod = 2 * time.Second
+
 // @title Concert booking API
 // @version 1.0
 // @description This is a Concert booking application
@@ -22,9 +32,28 @@ import (
 // @BasePath /v1
 // @host localhost:777
 func main() {
-	conf := config.LoadConfig()
+	conf, err := config.LoadConfig(os.Args[1:])
+	if err == nil {
+		err = conf.Validate()
+	}
+	if err != nil {
+		log.NewLogrusLogger("info").Error("Invalid configuration", "error", err.Error())
+		os.Exit(2)
+	}
 	logger := log.NewLogrusLogger(conf.LogLevel)
-	router, initErr := app.InitApp(conf, logger)
+	shutdownTracing, err := tracing.Setup(context.Background(), conf.Tracing)
+	if err != nil {
+		logger.Error("Tracing setup failed", "error", err.Error())
+		os.Exit(1)
+	}
+	dbInstance, err := db.NewDb(conf, logger)
+	if err != nil {
+		logger.Error("Database connection failed", "error", err.Error())
+		os.Exit(1)
+	}
+	checker := health.NewChecker()
+	runner := background.NewRunner(logger)
+	router, initErr := app.InitApp(conf, logger, dbInstance, checker, runner)
 
 	if initErr != nil {
 		logger.Error("Server error: %v\n ", initErr)
@@ -33,9 +62,24 @@ func main() {
 
 	port := conf.App.Port
 
-	app := http.Server{
+	// Every request context derives from requestsCtx, canceling it aborts in-flight DB queries
+	requestsCtx, cancelRequests := context.WithCancel(context.Background())
+	defer cancelRequests()
+	inFlight := middleware.NewInFlight()
+
+	server := http.Server{
 		Addr:    ":" + port,
-		Handler: router,
+		Handler: inFlight.Track(router),
+		BaseContext: func(net.Listener) context.Context {
+			return requestsCtx
+		},
+	}
+
+	metricsRouter := http.NewServeMux()
+	metricsRouter.Handle("GET /metrics", metrics.Handler())
+	metricsServer := http.Server{
+		Addr:    ":" + conf.App.MetricsPort,
+		Handler: metricsRouter,
 	}
 
 	stop := make(chan os.Signal, 1)
@@ -43,20 +87,60 @@ func main() {
 
 	go func() {
 		logger.Info("Server is listening on port ", port)
-		if err := app.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 			logger.Error("Server failed to start", "error", err.Error())
 			os.Exit(1)
 		}
 	}()
 
+	go func() {
+		logger.Info("Metrics are served on port ", conf.App.MetricsPort)
+		if err := metricsServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			logger.Error("Metrics server failed to start", "error", err.Error())
+			os.Exit(1)
+		}
+	}()
+
 	<-stop
 	logger.Info("Shutting down server...")
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	// Readiness fails from now on, requests that are still routed here in the meantime are served
+	checker.SetReady(false)
+	time.Sleep(time.Duration(conf.App.ShutdownDelaySeconds) * time.Second)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(conf.App.ShutdownTimeoutSeconds)*time.Second)
 	defer cancel()
 
-	if err := app.Shutdown(ctx); err != nil {
-		logger.Error("Server shutdown failed", "error", err.Error())
+	if err := server.Shutdown(ctx); err != nil {
+		logger.Warn("Shutdown timeout exceeded, canceling in-flight requests", "error", err.Error())
+		cancelRequests()
+		server.Close()
+
+		graceCtx, graceCancel := context.WithTimeout(context.Background(), cancelGracePeriod)
+		defer graceCancel()
+		if err := inFlight.Wait(graceCtx); err != nil {
+			logger.Error("In-flight requests did not finish", "error", err.Error())
+		}
+	}
+
+	// Scrapes are short, the ones still running when the timeout is hit are dropped
+	if err := metricsServer.Shutdown(ctx); err != nil {
+		metricsServer.Close()
+	}
+
+	// Background work still uses the database, so it is stopped first
+	runnerCtx, runnerCancel := context.WithTimeout(context.Background(), time.Duration(conf.App.ShutdownTimeoutSeconds)*time.Second)
+	defer runnerCancel()
+	if err := runner.Stop(runnerCtx); err != nil {
+		logger.Error("Background work did not finish", "error", err.Error())
+	}
+
+	if err := shutdownTracing(ctx); err != nil {
+		logger.Warn("Flushing traces failed", "error", err.Error())
+	}
+
+	if err := dbInstance.Close(); err != nil {
+		logger.Error("Closing database failed", "error", err.Error())
 		os.Exit(1)
 	}
 
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/serhiirubets/rubeticket/config"
+	"github.com/serhiirubets/rubeticket/internal/pkg/migrate"
+	"github.com/serhiirubets/rubeticket/migrations"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+const migrationsDir = "migrations"
+
+const usage = `Usage: go run ./cmd/migrate <command> [args]
+
+Commands:
+  up [N]        apply N pending migrations, all by default
+  down [N]      roll back N latest migrations, 1 by default
+  status        show applied and pending migrations
+  create NAME   create empty up and down files in migrations/
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Print(usage)
+		os.Exit(2)
+	}
+
+	command := os.Args[1]
+	if command == "create" {
+		if len(os.Args) < 3 {
+			fmt.Print(usage)
+			os.Exit(2)
+		}
+		paths, err := migrate.Create(migrationsDir, os.Args[2])
+		exitOnError(err)
+		for _, path := range paths {
+			fmt.Println("Created", path)
+		}
+		return
+	}
+
+	conf, err := config.LoadConfig(nil)
+	exitOnError(err)
+	exitOnError(conf.ValidateDb())
+	gormDb, err := gorm.Open(postgres.Open(conf.Db.Dsn), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	exitOnError(err)
+	sqlDb, err := gormDb.DB()
+	exitOnError(err)
+	defer sqlDb.Close()
+
+	migrator := migrate.NewMigrator(sqlDb, migrations.FS)
+	ctx := context.Background()
+
+	switch command {
+	case "up":
+		applied, err := migrator.Up(ctx, stepsArg(0))
+		for _, migration := range applied {
+			fmt.Printf("Applied %d_%s\n", migration.Version, migration.Name)
+		}
+		exitOnError(err)
+		if len(applied) == 0 {
+			fmt.Println("No pending migrations")
+		}
+	case "down":
+		reverted, err := migrator.Down(ctx, stepsArg(1))
+		for _, migration := range reverted {
+			fmt.Printf("Rolled back %d_%s\n", migration.Version, migration.Name)
+		}
+		exitOnError(err)
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		exitOnError(err)
+		for _, status := range statuses {
+			state := "pending"
+			switch {
+			case status.Missing:
+				state = "applied, file missing"
+			case status.Drifted:
+				state = "applied, CHANGED since"
+			case status.Applied:
+				state = "applied " + status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%d_%s\t%s\n", status.Version, status.Name, state)
+		}
+	default:
+		fmt.Print(usage)
+		os.Exit(2)
+	}
+}
+
+func stepsArg(def int) int {
+	if len(os.Args) < 3 {
+		return def
+	}
+	steps, err := strconv.Atoi(os.Args[2])
+	if err != nil || steps < 1 {
+		fmt.Println("N must be a positive number")
+		os.Exit(2)
+	}
+	return steps
+}
+
+func exitOnError(err error) {
+	if err != nil {
+		fmt.Println("Error:", err.Error())
+		os.Exit(1)
+	}
+}
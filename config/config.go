@@ -1,37 +1,204 @@
 package config
 
 import (
-	"github.com/joho/godotenv"
-	"github.com/serhiirubets/rubeticket/internal/pkg/convert"
+	"flag"
 	"log"
 	"os"
+
+	"github.com/joho/godotenv"
 )
 
 type DbConfig struct {
-	Dsn                             string
-	MaxOpenConnections              int
-	MaxIdleConnections              int
-	MaxLifetimeConnectionsInMinutes int
+	Dsn                             string `yaml:"dsn" toml:"dsn"`
+	MaxOpenConnections              int    `yaml:"maxOpenConnections" toml:"maxOpenConnections"`
+	MaxIdleConnections              int    `yaml:"maxIdleConnections" toml:"maxIdleConnections"`
+	MaxLifetimeConnectionsInMinutes int    `yaml:"maxLifetimeConnectionsInMinutes" toml:"maxLifetimeConnectionsInMinutes"`
+	// TxIsolationLevel is the default isolation of transactions: "read committed", "repeatable read" or "serializable"
+	TxIsolationLevel string `yaml:"txIsolationLevel" toml:"txIsolationLevel"`
+	TxMaxRetries     int    `yaml:"txMaxRetries" toml:"txMaxRetries"`
+	// QueryTimeoutSeconds limits every query, 0 disables the limit
+	QueryTimeoutSeconds int `yaml:"queryTimeoutSeconds" toml:"queryTimeoutSeconds"`
+	// ConnectTimeoutSeconds is how long startup keeps retrying to connect, 0 tries once
+	ConnectTimeoutSeconds int `yaml:"connectTimeoutSeconds" toml:"connectTimeoutSeconds"`
 }
 
 type AuthConfig struct {
-	Secret string
+	Secret string `yaml:"secret" toml:"secret"`
+	// URLSigningSecret signs public file links, it must differ from Secret
+	URLSigningSecret string `yaml:"urlSigningSecret" toml:"urlSigningSecret"`
 }
 
 type AppConfig struct {
-	Port string
-	Host string
+	Port string `yaml:"port" toml:"port"`
+	Host string `yaml:"host" toml:"host"`
+	// MetricsPort serves /metrics apart from the API, expose it to the metrics scraper only
+	MetricsPort string `yaml:"metricsPort" toml:"metricsPort"`
+	// ShutdownTimeoutSeconds is how long in-flight requests are waited for on shutdown
+	ShutdownTimeoutSeconds int `yaml:"shutdownTimeoutSeconds" toml:"shutdownTimeoutSeconds"`
+	// ShutdownDelaySeconds is how long the server keeps serving after readiness is turned off
+	// on shutdown, so that load balancers stop sending requests first
+	ShutdownDelaySeconds int `yaml:"shutdownDelaySeconds" toml:"shutdownDelaySeconds"`
+	// PublicURL is prepended to generated links, e.g. "https://api.rubeticket.com"
+	PublicURL string `yaml:"publicUrl" toml:"publicUrl"`
+	// SwaggerURL is where the swagger UI loads the API description from
+	SwaggerURL string `yaml:"swaggerUrl" toml:"swaggerUrl"`
+}
+
+type ScannerConfig struct {
+	// ClamAVAddress is a clamd address, e.g. "tcp://localhost:3310". The local stub scanner is used when empty
+	ClamAVAddress string `yaml:"clamavAddress" toml:"clamavAddress"`
+	// IdleTimeoutSeconds is how long a scan may wait on a single read or write to clamd
+	IdleTimeoutSeconds int `yaml:"idleTimeoutSeconds" toml:"idleTimeoutSeconds"`
+	// MaxScanSizeMB should not exceed StreamMaxLength of clamd and must cover the upload limits,
+	// bigger files are quarantined unscanned. 0 scans every file
+	MaxScanSizeMB int64 `yaml:"maxScanSizeMb" toml:"maxScanSizeMb"`
+}
+
+type TracingConfig struct {
+	// Exporter is "otlp" or "stdout", tracing is off when empty
+	Exporter string `yaml:"exporter" toml:"exporter"`
+	// OTLPEndpoint is the host:port of an OTLP/HTTP collector, e.g. "localhost:4318".
+	// The standard OTEL_EXPORTER_OTLP_* variables are used when empty
+	OTLPEndpoint string `yaml:"otlpEndpoint" toml:"otlpEndpoint"`
+	OTLPInsecure bool   `yaml:"otlpInsecure" toml:"otlpInsecure"`
+	ServiceName  string `yaml:"serviceName" toml:"serviceName"`
+	// SampleRatio is the share of new traces that are recorded, from 0 to 1.
+	// Traces started by a caller keep the caller's decision
+	SampleRatio float64 `yaml:"sampleRatio" toml:"sampleRatio"`
+}
+
+func (c TracingConfig) Enabled() bool {
+	return c.Exporter != ""
+}
+
+// AdminCORSRoute is the path prefix of the admin API, it has its own CORS policy by default
+const AdminCORSRoute = "/admin/"
+
+type CORSPolicy struct {
+	// AllowedOrigins are exact origins, e.g. "https://app.rubeticket.com", patterns matching any
+	// subdomain, e.g. "https://*.rubeticket.com", or "*" for any origin without credentials
+	AllowedOrigins []string `yaml:"allowedOrigins" toml:"allowedOrigins"`
+	AllowedMethods []string `yaml:"allowedMethods" toml:"allowedMethods"`
+	AllowedHeaders []string `yaml:"allowedHeaders" toml:"allowedHeaders"`
+	// ExposedHeaders are the response headers that browser scripts may read
+	ExposedHeaders   []string `yaml:"exposedHeaders" toml:"exposedHeaders"`
+	AllowCredentials bool     `yaml:"allowCredentials" toml:"allowCredentials"`
+	// MaxAgeSeconds is how long browsers cache a preflight response, 0 leaves it to the browser
+	MaxAgeSeconds int `yaml:"maxAgeSeconds" toml:"maxAgeSeconds"`
+}
+
+type CORSConfig struct {
+	// Default applies to the paths that no route policy matches
+	Default CORSPolicy `yaml:"default" toml:"default"`
+	// Routes maps a path prefix, e.g. "/admin/", to the policy of the paths under it.
+	// The longest matching prefix wins. A route given in a config file replaces the default one entirely
+	Routes map[string]CORSPolicy `yaml:"routes" toml:"routes"`
+}
+
+type UploadsConfig struct {
+	// Dir keeps uploaded files, relative to the working directory
+	Dir          string   `yaml:"dir" toml:"dir"`
+	MaxSizeMB    int64    `yaml:"maxSizeMb" toml:"maxSizeMb"`
+	AllowedTypes []string `yaml:"allowedTypes" toml:"allowedTypes"`
+	// Resumable uploads are meant for big files, so they have their own limits
+	ResumableMaxSizeMB       int64    `yaml:"resumableMaxSizeMb" toml:"resumableMaxSizeMb"`
+	ResumableChunkSizeMB     int64    `yaml:"resumableChunkSizeMb" toml:"resumableChunkSizeMb"`
+	ResumableExpirationHours int      `yaml:"resumableExpirationHours" toml:"resumableExpirationHours"`
+	ResumableAllowedTypes    []string `yaml:"resumableAllowedTypes" toml:"resumableAllowedTypes"`
 }
 
 type Config struct {
-	Db       DbConfig
-	Auth     AuthConfig
-	LogLevel string
-	Env      string
-	App      AppConfig
+	Db       DbConfig      `yaml:"db" toml:"db"`
+	Auth     AuthConfig    `yaml:"auth" toml:"auth"`
+	LogLevel string        `yaml:"logLevel" toml:"logLevel"`
+	Env      string        `yaml:"env" toml:"env"`
+	App      AppConfig     `yaml:"app" toml:"app"`
+	Scanner  ScannerConfig `yaml:"scanner" toml:"scanner"`
+	Tracing  TracingConfig `yaml:"tracing" toml:"tracing"`
+	CORS     CORSConfig    `yaml:"cors" toml:"cors"`
+	Uploads  UploadsConfig `yaml:"uploads" toml:"uploads"`
+}
+
+var (
+	localOrigins = []string{
+		"http://localhost",
+		"http://localhost:8080",
+		"http://localhost:4200",
+		"http://127.0.0.1",
+		"http://127.0.0.1:8080",
+		"http://127.0.0.1:4200",
+	}
+	corsMethods = []string{"POST", "GET", "DELETE", "HEAD", "PATCH", "PUT"}
+)
+
+// Default is the config before any file, env var or flag is applied
+func Default() *Config {
+	return &Config{
+		Db: DbConfig{
+			MaxOpenConnections:              10,
+			MaxIdleConnections:              10,
+			MaxLifetimeConnectionsInMinutes: 1,
+			TxMaxRetries:                    3,
+			QueryTimeoutSeconds:             5,
+			ConnectTimeoutSeconds:           60,
+		},
+		LogLevel: "info",
+		Scanner: ScannerConfig{
+			IdleTimeoutSeconds: 30,
+			// clamd must accept streams up to ResumableMaxSizeMB, see StreamMaxLength
+			MaxScanSizeMB: 0,
+		},
+		App: AppConfig{
+			Port:                   "7777",
+			MetricsPort:            "9090",
+			ShutdownTimeoutSeconds: 5,
+			SwaggerURL:             "/swagger/doc.json",
+		},
+		Tracing: TracingConfig{
+			ServiceName: "rubeticket",
+			SampleRatio: 1,
+		},
+		CORS: CORSConfig{
+			Default: CORSPolicy{
+				AllowedOrigins: localOrigins,
+				AllowedMethods: corsMethods,
+				AllowedHeaders: []string{
+					"authorization", "content-type", "content-length", "tus-resumable", "upload-length",
+					"upload-offset", "upload-metadata", "upload-checksum", "x-request-id", "traceparent", "tracestate",
+				},
+				ExposedHeaders: []string{
+					"location", "tus-resumable", "upload-offset", "upload-length", "upload-expires", "x-request-id", "x-trace-id",
+				},
+				AllowCredentials: true,
+				MaxAgeSeconds:    86400,
+			},
+			Routes: map[string]CORSPolicy{
+				AdminCORSRoute: {
+					AllowedOrigins:   localOrigins,
+					AllowedMethods:   corsMethods,
+					AllowedHeaders:   []string{"authorization", "content-type", "x-request-id", "traceparent", "tracestate"},
+					ExposedHeaders:   []string{"location", "x-request-id", "x-trace-id"},
+					AllowCredentials: true,
+					MaxAgeSeconds:    600,
+				},
+			},
+		},
+		Uploads: UploadsConfig{
+			Dir:                      "uploads",
+			MaxSizeMB:                10,
+			AllowedTypes:             []string{"image/"},
+			ResumableMaxSizeMB:       2048,
+			ResumableChunkSizeMB:     32,
+			ResumableExpirationHours: 24,
+			ResumableAllowedTypes:    []string{"image/", "video/", "application/pdf", "application/zip"},
+		},
+	}
 }
 
-func LoadConfig() *Config {
+// LoadConfig layers, from lowest to highest priority: defaults, the YAML or TOML file given by
+// -config or CONFIG_FILE, env vars (.env included) and command line flags in args.
+// Secrets can be read from files named by *_FILE env vars. The result is not validated, see Validate
+func LoadConfig(args []string) (*Config, error) {
 	env := os.Getenv("ENV")
 	var err error
 	if env == "test" {
@@ -44,25 +211,64 @@ func LoadConfig() *Config {
 		log.Println("Error loading .env file")
 	}
 
-	maxOpenConnections := convert.StringToInt(os.Getenv("MAX_OPEN_CONNECTIONS"), 10)
-	maxIdleConnections := convert.StringToInt(os.Getenv("MAX_IDLE_CONNECTIONS"), 10)
-	maxLifetimeConnectionsInMinutes := convert.StringToInt(os.Getenv("MAX_LIFE_TIME_CONNECTIONS_IN_MINUTES"), 1)
+	flags := newFlags()
+	if err := flags.set.Parse(args); err != nil {
+		return nil, err
+	}
 
-	return &Config{
-		Db: DbConfig{
-			Dsn:                             os.Getenv("DSN"),
-			MaxOpenConnections:              maxOpenConnections,
-			MaxIdleConnections:              maxIdleConnections,
-			MaxLifetimeConnectionsInMinutes: maxLifetimeConnectionsInMinutes,
-		},
-		Auth: AuthConfig{
-			Secret: os.Getenv("SECRET"),
-		},
-		LogLevel: os.Getenv("LOG_LEVEL"),
-		Env:      os.Getenv("ENV"),
-		App: AppConfig{
-			Port: os.Getenv("PORT"),
-			Host: os.Getenv("HOST"),
-		},
+	conf := Default()
+
+	configFile := os.Getenv("CONFIG_FILE")
+	if *flags.configFile != "" {
+		configFile = *flags.configFile
+	}
+	if configFile != "" {
+		if err := loadFile(conf, configFile); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := loadEnv(conf); err != nil {
+		return nil, err
+	}
+	flags.apply(conf)
+
+	return conf, nil
+}
+
+type flags struct {
+	set        *flag.FlagSet
+	configFile *string
+	port       *string
+	host       *string
+	logLevel   *string
+	env        *string
+}
+
+func newFlags() *flags {
+	set := flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+	return &flags{
+		set:        set,
+		configFile: set.String("config", "", "YAML or TOML config file"),
+		port:       set.String("port", "", "Port to listen on"),
+		host:       set.String("host", "", "Host name"),
+		logLevel:   set.String("log-level", "", "Log level: debug, info, warn or error"),
+		env:        set.String("env", "", "Environment, e.g. dev or prod"),
 	}
 }
+
+// apply overrides conf with the flags given on the command line only
+func (f *flags) apply(conf *Config) {
+	f.set.Visit(func(fl *flag.Flag) {
+		switch fl.Name {
+		case "port":
+			conf.App.Port = *f.port
+		case "host":
+			conf.App.Host = *f.host
+		case "log-level":
+			conf.LogLevel = *f.logLevel
+		case "env":
+			conf.Env = *f.env
+		}
+	})
+}
@@ -0,0 +1,142 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// loadEnv overrides conf with the env vars that are set, an empty var counts as not set
+func loadEnv(conf *Config) error {
+	var errs []error
+	str := func(target *string, name string) {
+		if value := os.Getenv(name); value != "" {
+			*target = value
+		}
+	}
+	num := func(target *int, name string) {
+		if value := os.Getenv(name); value != "" {
+			parsed, err := strconv.Atoi(value)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s must be a whole number, got %q", name, value))
+				return
+			}
+			*target = parsed
+		}
+	}
+	num64 := func(target *int64, name string) {
+		if value := os.Getenv(name); value != "" {
+			parsed, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s must be a whole number, got %q", name, value))
+				return
+			}
+			*target = parsed
+		}
+	}
+	float := func(target *float64, name string) {
+		if value := os.Getenv(name); value != "" {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s must be a number, got %q", name, value))
+				return
+			}
+			*target = parsed
+		}
+	}
+	boolean := func(target *bool, name string) {
+		if value := os.Getenv(name); value != "" {
+			parsed, err := strconv.ParseBool(value)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s must be true or false, got %q", name, value))
+				return
+			}
+			*target = parsed
+		}
+	}
+	// lists are comma separated
+	list := func(target *[]string, name string) {
+		if value := os.Getenv(name); value != "" {
+			*target = splitList(value)
+		}
+	}
+	secret := func(target *string, name string) {
+		str(target, name)
+		if path := os.Getenv(name + "_FILE"); path != "" {
+			content, err := os.ReadFile(path)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s_FILE: %w", name, err))
+				return
+			}
+			*target = strings.TrimRight(string(content), "\r\n")
+		}
+	}
+
+	secret(&conf.Db.Dsn, "DSN")
+	num(&conf.Db.MaxOpenConnections, "MAX_OPEN_CONNECTIONS")
+	num(&conf.Db.MaxIdleConnections, "MAX_IDLE_CONNECTIONS")
+	num(&conf.Db.MaxLifetimeConnectionsInMinutes, "MAX_LIFE_TIME_CONNECTIONS_IN_MINUTES")
+	str(&conf.Db.TxIsolationLevel, "DB_TX_ISOLATION")
+	num(&conf.Db.TxMaxRetries, "DB_TX_MAX_RETRIES")
+	num(&conf.Db.QueryTimeoutSeconds, "DB_QUERY_TIMEOUT_SECONDS")
+	num(&conf.Db.ConnectTimeoutSeconds, "DB_CONNECT_TIMEOUT_SECONDS")
+
+	secret(&conf.Auth.Secret, "SECRET")
+	secret(&conf.Auth.URLSigningSecret, "URL_SIGNING_SECRET")
+
+	str(&conf.LogLevel, "LOG_LEVEL")
+	str(&conf.Env, "ENV")
+
+	str(&conf.App.Port, "PORT")
+	str(&conf.App.Host, "HOST")
+	str(&conf.App.MetricsPort, "METRICS_PORT")
+	str(&conf.App.PublicURL, "PUBLIC_URL")
+	str(&conf.App.SwaggerURL, "SWAGGER_URL")
+	num(&conf.App.ShutdownTimeoutSeconds, "SHUTDOWN_TIMEOUT_SECONDS")
+	num(&conf.App.ShutdownDelaySeconds, "SHUTDOWN_DELAY_SECONDS")
+
+	str(&conf.Scanner.ClamAVAddress, "CLAMAV_ADDRESS")
+	num(&conf.Scanner.IdleTimeoutSeconds, "CLAMAV_IDLE_TIMEOUT_SECONDS")
+	num64(&conf.Scanner.MaxScanSizeMB, "SCAN_MAX_SIZE_MB")
+
+	str(&conf.Tracing.Exporter, "TRACING_EXPORTER")
+	str(&conf.Tracing.OTLPEndpoint, "TRACING_OTLP_ENDPOINT")
+	boolean(&conf.Tracing.OTLPInsecure, "TRACING_OTLP_INSECURE")
+	str(&conf.Tracing.ServiceName, "TRACING_SERVICE_NAME")
+	float(&conf.Tracing.SampleRatio, "TRACING_SAMPLE_RATIO")
+
+	list(&conf.CORS.Default.AllowedOrigins, "CORS_ALLOWED_ORIGINS")
+	list(&conf.CORS.Default.ExposedHeaders, "CORS_EXPOSED_HEADERS")
+	boolean(&conf.CORS.Default.AllowCredentials, "CORS_ALLOW_CREDENTIALS")
+	num(&conf.CORS.Default.MaxAgeSeconds, "CORS_MAX_AGE_SECONDS")
+	if value := os.Getenv("CORS_ADMIN_ALLOWED_ORIGINS"); value != "" {
+		if conf.CORS.Routes == nil {
+			conf.CORS.Routes = map[string]CORSPolicy{}
+		}
+		admin := conf.CORS.Routes[AdminCORSRoute]
+		admin.AllowedOrigins = splitList(value)
+		conf.CORS.Routes[AdminCORSRoute] = admin
+	}
+
+	str(&conf.Uploads.Dir, "UPLOADS_DIR")
+	num64(&conf.Uploads.MaxSizeMB, "UPLOAD_MAX_SIZE_MB")
+	list(&conf.Uploads.AllowedTypes, "UPLOAD_ALLOWED_TYPES")
+	num64(&conf.Uploads.ResumableMaxSizeMB, "RESUMABLE_UPLOAD_MAX_SIZE_MB")
+	num64(&conf.Uploads.ResumableChunkSizeMB, "RESUMABLE_UPLOAD_CHUNK_SIZE_MB")
+	num(&conf.Uploads.ResumableExpirationHours, "RESUMABLE_UPLOAD_EXPIRATION_HOURS")
+	list(&conf.Uploads.ResumableAllowedTypes, "RESUMABLE_UPLOAD_ALLOWED_TYPES")
+
+	return errors.Join(errs...)
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,117 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+var (
+	logLevels       = []string{"trace", "debug", "info", "warn", "warning", "error", "fatal", "panic"}
+	isolationLevels = []string{"", "read uncommitted", "read committed", "repeatable read", "serializable"}
+	exporters       = []string{"", "otlp", "stdout"}
+)
+
+// Validate reports every problem of the config at once, the app should not start with any of them
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	errs = append(errs, c.ValidateDb())
+
+	check(c.Auth.Secret != "", "auth secret is empty, set SECRET or SECRET_FILE")
+	check(c.Auth.URLSigningSecret != "", "url signing secret is empty, set URL_SIGNING_SECRET or URL_SIGNING_SECRET_FILE")
+	check(c.Auth.URLSigningSecret == "" || c.Auth.URLSigningSecret != c.Auth.Secret, "url signing secret must differ from the auth secret")
+
+	port, err := strconv.Atoi(c.App.Port)
+	check(err == nil && port > 0 && port <= 65535, "port must be from 1 to 65535, got %q", c.App.Port)
+	metricsPort, err := strconv.Atoi(c.App.MetricsPort)
+	check(err == nil && metricsPort > 0 && metricsPort <= 65535, "metrics port must be from 1 to 65535, got %q", c.App.MetricsPort)
+	check(c.App.MetricsPort != c.App.Port, "metrics port must differ from the port")
+	check(oneOf(strings.ToLower(c.LogLevel), logLevels), "log level must be one of %s, got %q", strings.Join(logLevels, ", "), c.LogLevel)
+	check(c.App.ShutdownTimeoutSeconds >= 0, "shutdown timeout must not be negative")
+	check(c.App.ShutdownDelaySeconds >= 0, "shutdown delay must not be negative")
+
+	check(c.Scanner.IdleTimeoutSeconds > 0, "clamav idle timeout must be positive")
+	check(c.Scanner.MaxScanSizeMB >= 0, "scan max size must not be negative")
+	check(c.Scanner.MaxScanSizeMB == 0 || c.Scanner.MaxScanSizeMB >= max(c.Uploads.MaxSizeMB, c.Uploads.ResumableMaxSizeMB),
+		"scan max size must be 0 or at least the upload max sizes, bigger uploads could not be scanned")
+
+	check(oneOf(c.Tracing.Exporter, exporters), "tracing exporter must be otlp, stdout or empty, got %q", c.Tracing.Exporter)
+	check(c.Tracing.SampleRatio >= 0 && c.Tracing.SampleRatio <= 1, "tracing sample ratio must be from 0 to 1, got %v", c.Tracing.SampleRatio)
+
+	check(c.Uploads.Dir != "", "uploads dir is empty")
+	check(c.Uploads.MaxSizeMB > 0, "upload max size must be positive")
+	check(c.Uploads.ResumableMaxSizeMB > 0, "resumable upload max size must be positive")
+	check(c.Uploads.ResumableChunkSizeMB > 0, "resumable upload chunk size must be positive")
+	check(c.Uploads.ResumableExpirationHours > 0, "resumable upload expiration must be positive")
+
+	errs = append(errs, validateCORS("default", c.CORS.Default))
+	for prefix, policy := range c.CORS.Routes {
+		check(strings.HasPrefix(prefix, "/"), "cors route %q must start with /", prefix)
+		errs = append(errs, validateCORS(prefix, policy))
+	}
+
+	return errors.Join(errs...)
+}
+
+func validateCORS(name string, policy CORSPolicy) error {
+	var errs []error
+	for _, origin := range policy.AllowedOrigins {
+		if origin == "*" {
+			if policy.AllowCredentials {
+				errs = append(errs, fmt.Errorf("cors %s: origin * cannot be used with credentials, list the origins", name))
+			}
+			continue
+		}
+		if !validOrigin(origin) {
+			errs = append(errs, fmt.Errorf("cors %s: origin must look like https://example.com or https://*.example.com, got %q", name, origin))
+		}
+	}
+	if policy.MaxAgeSeconds < 0 {
+		errs = append(errs, fmt.Errorf("cors %s: max age must not be negative", name))
+	}
+	return errors.Join(errs...)
+}
+
+// validOrigin accepts a scheme, a host and an optional port, the host may start with "*." for any subdomain
+func validOrigin(origin string) bool {
+	origin = strings.Replace(origin, "://*.", "://sub.", 1)
+	if strings.Contains(origin, "*") {
+		return false
+	}
+	u, err := url.Parse(origin)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "" &&
+		u.User == nil && u.Path == "" && u.RawQuery == "" && u.Fragment == ""
+}
+
+// ValidateDb checks only the database settings, for commands that do not serve the API
+func (c *Config) ValidateDb() error {
+	var errs []error
+	if c.Db.Dsn == "" {
+		errs = append(errs, errors.New("database DSN is empty, set DSN or DSN_FILE"))
+	}
+	isolation := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(c.Db.TxIsolationLevel), "_", " "))
+	if !oneOf(isolation, isolationLevels) {
+		errs = append(errs, fmt.Errorf("transaction isolation must be read committed, repeatable read or serializable, got %q", c.Db.TxIsolationLevel))
+	}
+	if c.Db.QueryTimeoutSeconds < 0 || c.Db.ConnectTimeoutSeconds < 0 || c.Db.TxMaxRetries < 0 {
+		errs = append(errs, errors.New("database timeouts and retries must not be negative"))
+	}
+	return errors.Join(errs...)
+}
+
+func oneOf(value string, allowed []string) bool {
+	for _, a := range allowed {
+		if value == a {
+			return true
+		}
+	}
+	return false
+}
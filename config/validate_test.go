@@ -0,0 +1,116 @@
+package config_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/serhiirubets/rubeticket/config"
+)
+
+// validConfig returns the default config with the settings that have no default
+func validConfig() *config.Config {
+	conf := config.Default()
+	conf.Db.Dsn = "host=localhost"
+	conf.Auth.Secret = "secret"
+	conf.Auth.URLSigningSecret = "url signing secret"
+	return conf
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		change  func(conf *config.Config)
+		wantErr string
+	}{
+		{name: "defaults", change: func(conf *config.Config) {}},
+		{
+			name: "any origin without credentials",
+			change: func(conf *config.Config) {
+				conf.CORS.Default.AllowedOrigins = []string{"*"}
+				conf.CORS.Default.AllowCredentials = false
+			},
+		},
+		{
+			name: "any origin with credentials",
+			change: func(conf *config.Config) {
+				conf.CORS.Default.AllowedOrigins = []string{"*"}
+				conf.CORS.Default.AllowCredentials = true
+			},
+			wantErr: "cors default: origin * cannot be used with credentials",
+		},
+		{
+			name: "any origin with credentials on a route",
+			change: func(conf *config.Config) {
+				policy := conf.CORS.Routes[config.AdminCORSRoute]
+				policy.AllowedOrigins = []string{"https://app.example.com", "*"}
+				conf.CORS.Routes[config.AdminCORSRoute] = policy
+			},
+			wantErr: "cors /admin/: origin * cannot be used with credentials",
+		},
+		{
+			name: "subdomain pattern",
+			change: func(conf *config.Config) {
+				conf.CORS.Default.AllowedOrigins = []string{"https://*.example.com:8443"}
+			},
+		},
+		{
+			name: "star inside a host",
+			change: func(conf *config.Config) {
+				conf.CORS.Default.AllowedOrigins = []string{"https://app*.example.com"}
+			},
+			wantErr: "origin must look like",
+		},
+		{
+			name: "origin with a path",
+			change: func(conf *config.Config) {
+				conf.CORS.Default.AllowedOrigins = []string{"https://example.com/app"}
+			},
+			wantErr: "origin must look like",
+		},
+		{
+			name: "route without a leading slash",
+			change: func(conf *config.Config) {
+				conf.CORS.Routes["admin/"] = conf.CORS.Default
+			},
+			wantErr: `cors route "admin/" must start with /`,
+		},
+		{
+			name: "scan max size covering the uploads",
+			change: func(conf *config.Config) {
+				conf.Scanner.MaxScanSizeMB = conf.Uploads.ResumableMaxSizeMB
+			},
+		},
+		{
+			name: "scan max size below the resumable upload max size",
+			change: func(conf *config.Config) {
+				conf.Scanner.MaxScanSizeMB = 25
+			},
+			wantErr: "scan max size must be 0 or at least the upload max sizes",
+		},
+		{
+			name: "metrics on the API port",
+			change: func(conf *config.Config) {
+				conf.App.MetricsPort = conf.App.Port
+			},
+			wantErr: "metrics port must differ from the port",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf := validConfig()
+			tt.change(conf)
+
+			err := conf.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Validate() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Validate() error = %v, want one containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
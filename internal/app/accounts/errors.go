@@ -0,0 +1,9 @@
+package accounts
+
+import "github.com/serhiirubets/rubeticket/internal/pkg/apperr"
+
+var (
+	ErrInvalidCredentials = apperr.New(apperr.Unauthorized, "invalid_credentials", "invalid credentials")
+	ErrNothingToUpdate    = apperr.New(apperr.Invalid, "nothing_to_update", "no fields to update")
+	ErrInvalidForm        = apperr.New(apperr.Invalid, "invalid_form", "invalid multipart form")
+)
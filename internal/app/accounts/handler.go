@@ -1,6 +1,7 @@
 package accounts
 
 import (
+	"errors"
 	"mime/multipart"
 	"net/http"
 
@@ -8,6 +9,7 @@ import (
 	"github.com/serhiirubets/rubeticket/internal/app/file"
 	"github.com/serhiirubets/rubeticket/internal/app/fileuploader"
 	"github.com/serhiirubets/rubeticket/internal/app/users"
+	"github.com/serhiirubets/rubeticket/internal/pkg/apperr"
 	"github.com/serhiirubets/rubeticket/internal/pkg/log"
 	"github.com/serhiirubets/rubeticket/internal/pkg/middleware"
 	"github.com/serhiirubets/rubeticket/internal/pkg/req"
@@ -49,23 +51,23 @@ func NewAccountHandler(router *http.ServeMux, deps *AccountHandlerDeps) {
 // @Security ApiKeyAuth
 // @Produce json
 // @Success 200 {object} GetAccountResponse "Success"
-// @Failure 401 {object} string "Not authorized"
+// @Failure 401 {object} res.Problem "Not authorized"
 // @Router /v1/account [get]
 func (handler *AccountHandler) GetAccount() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		authData, _ := middleware.GetAuthData(r)
 
-		user, userErr := handler.UserRepository.GetByEmail(authData.Email)
+		user, userErr := handler.UserRepository.GetByEmail(r.Context(), authData.Email)
 
 		if userErr != nil {
-			handler.Logger.Error("Error getting user by email", userErr.Error())
-			res.Json(w, "Invalid credentials", http.StatusUnauthorized)
+			handler.Logger.WithContext(r.Context()).Error("Error getting user by email", userErr.Error())
+			res.Error(w, r, ErrInvalidCredentials)
 			return
 		}
 
 		var photo file.File
-		photoErr := handler.FileUploader.DB.
-			Where("user_id = ? AND purpose = ?", authData.UserID, "profile").
+		photoErr := handler.FileUploader.DB.WithContext(r.Context()).
+			Where("user_id = ? AND purpose = ? AND status = ?", authData.UserID, "profile", file.Available).
 			Last(&photo).Error
 
 		photoUrl := ""
@@ -96,9 +98,9 @@ func (handler *AccountHandler) GetAccount() http.HandlerFunc {
 // @Produce json
 // @Param body body UpdateAccountRequestPatch true "Fields to update"
 // @Success 200 {object} UpdateAccountResponse "Success"
-// @Failure 400 {object} string "Invalid request body"
-// @Failure 401 {object} string "Not authorized"
-// @Failure 500 {object} string "Internal server error"
+// @Failure 400 {object} res.Problem "Invalid request body"
+// @Failure 401 {object} res.Problem "Not authorized"
+// @Failure 500 {object} res.Problem "Internal server error"
 // @Router /v1/account [patch]
 func (handler *AccountHandler) UpdateAccountPatch() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -110,10 +112,10 @@ func (handler *AccountHandler) UpdateAccountPatch() http.HandlerFunc {
 			return
 		}
 
-		user, err := handler.UserRepository.GetByEmail(authData.Email)
+		user, err := handler.UserRepository.GetByEmail(r.Context(), authData.Email)
 		if err != nil {
-			handler.Logger.Error("Error getting user by email", err.Error())
-			res.Json(w, "Invalid credentials", http.StatusUnauthorized)
+			handler.Logger.WithContext(r.Context()).Error("Error getting user by email", err.Error())
+			res.Error(w, r, ErrInvalidCredentials)
 			return
 		}
 
@@ -135,13 +137,13 @@ func (handler *AccountHandler) UpdateAccountPatch() http.HandlerFunc {
 		}
 
 		if len(updates) == 0 {
-			res.Json(w, "No fields to update", http.StatusBadRequest)
+			res.Error(w, r, ErrNothingToUpdate)
 			return
 		}
 
-		if err := handler.UserRepository.Update(user, updates); err != nil {
-			handler.Logger.Error("Failed to update user", err.Error())
-			res.Json(w, "Internal server error", http.StatusInternalServerError)
+		if err := handler.UserRepository.Update(r.Context(), user, updates); err != nil {
+			handler.Logger.WithContext(r.Context()).Error("Failed to update user", err.Error())
+			res.Error(w, r, err)
 			return
 		}
 
@@ -166,9 +168,9 @@ func (handler *AccountHandler) UpdateAccountPatch() http.HandlerFunc {
 // @Produce json
 // @Param body body UpdateAccountRequestPut true "Full account details to update"
 // @Success 200 {object} UpdateAccountResponse "Success"
-// @Failure 400 {object} string "Invalid request body"
-// @Failure 401 {object} string "Not authorized"
-// @Failure 500 {object} string "Internal server error"
+// @Failure 400 {object} res.Problem "Invalid request body"
+// @Failure 401 {object} res.Problem "Not authorized"
+// @Failure 500 {object} res.Problem "Internal server error"
 // @Router /api/v1/account [put]
 func (handler *AccountHandler) UpdateAccountPut() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -180,10 +182,10 @@ func (handler *AccountHandler) UpdateAccountPut() http.HandlerFunc {
 			return
 		}
 
-		user, errUser := handler.UserRepository.GetByEmail(authData.Email)
+		user, errUser := handler.UserRepository.GetByEmail(r.Context(), authData.Email)
 		if errUser != nil {
-			handler.Logger.Error("Error getting user by email", errUser.Error())
-			res.Json(w, "Invalid credentials", http.StatusUnauthorized)
+			handler.Logger.WithContext(r.Context()).Error("Error getting user by email", errUser.Error())
+			res.Error(w, r, ErrInvalidCredentials)
 			return
 		}
 
@@ -192,9 +194,9 @@ func (handler *AccountHandler) UpdateAccountPut() http.HandlerFunc {
 		user.Gender = body.Gender
 		user.Birthday = body.Birthday
 
-		if err := handler.UserRepository.DB.Save(&user).Error; err != nil {
-			handler.Logger.Error("Failed to update user", err.Error())
-			res.Json(w, "Internal server error", http.StatusInternalServerError)
+		if err := handler.UserRepository.Save(r.Context(), user); err != nil {
+			handler.Logger.WithContext(r.Context()).Error("Failed to update user", err.Error())
+			res.Error(w, r, err)
 			return
 		}
 
@@ -218,9 +220,12 @@ func (handler *AccountHandler) UpdateAccountPut() http.HandlerFunc {
 // @Produce json
 // @Param photo formData file true "Photo file to upload"
 // @Success 200 {object} map[string]string "Success"
-// @Failure 400 {object} string "Bad request"
-// @Failure 401 {object} string "Not authorized"
-// @Failure 500 {object} string "Internal server error"
+// @Failure 400 {object} res.Problem "Bad request"
+// @Failure 401 {object} res.Problem "Not authorized"
+// @Failure 413 {object} res.Problem "File is too large"
+// @Failure 415 {object} res.Problem "Invalid file type"
+// @Failure 422 {object} res.Problem "File is quarantined"
+// @Failure 500 {object} res.Problem "Internal server error"
 // @Router /api/v1/account/photo [post]
 func (handler *AccountHandler) UploadPhoto() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -228,27 +233,35 @@ func (handler *AccountHandler) UploadPhoto() http.HandlerFunc {
 
 		r.Body = http.MaxBytesReader(w, r.Body, handler.FileUploader.MaxSizeMB<<20)
 		if err := r.ParseMultipartForm(handler.FileUploader.MaxSizeMB << 20); err != nil {
-			handler.Logger.Error("Failed to parse multipart form", "error", err.Error())
-			res.Json(w, "Bad request: "+err.Error(), http.StatusBadRequest)
+			handler.Logger.WithContext(r.Context()).Error("Failed to parse multipart form", "error", err.Error())
+			// a body over the limit is answered with 413
+			var tooLarge *http.MaxBytesError
+			if !errors.As(err, &tooLarge) {
+				err = ErrInvalidForm.Wrap(err)
+			}
+			res.Error(w, r, err)
 			return
 		}
 
 		photo, header, err := r.FormFile("photo")
 		if err != nil {
-			handler.Logger.Error("Failed to get file from form", "error", err.Error())
-			res.Json(w, "Bad request: "+err.Error(), http.StatusBadRequest)
+			handler.Logger.WithContext(r.Context()).Error("Failed to get file from form", "error", err.Error())
+			res.Error(w, r, ErrInvalidForm.WithMessage("photo is missing").Wrap(err))
 			return
 		}
 		defer func(photo multipart.File) {
 			err := photo.Close()
 			if err != nil {
-				handler.Logger.Error("Failed to close file", "error", err.Error())
+				handler.Logger.WithContext(r.Context()).Error("Failed to close file", "error", err.Error())
 			}
 		}(photo)
 
-		fileModel, err := handler.FileUploader.UploadFile(photo, header, authData.UserID, "profile")
+		fileModel, err := handler.FileUploader.UploadFile(r.Context(), photo, header, authData.UserID, "profile")
 		if err != nil {
-			res.Json(w, err.Error(), http.StatusBadRequest)
+			if apperr.IsInternal(err) {
+				handler.Logger.WithContext(r.Context()).Error("Failed to upload photo", "error", err.Error())
+			}
+			res.Error(w, r, err)
 			return
 		}
 
@@ -0,0 +1,57 @@
+package audit
+
+import (
+	"encoding/json"
+	"reflect"
+)
+
+// ignoredFields change on every write and only add noise to the log
+var ignoredFields = map[string]bool{
+	"updatedAt": true,
+}
+
+// Diff compares the JSON form of two states field by field. before is nil for created
+// entities and after is nil for removed ones
+func Diff(before, after any) (map[string]Change, error) {
+	from, err := toFields(before)
+	if err != nil {
+		return nil, err
+	}
+	to, err := toFields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := make(map[string]Change)
+	for name, value := range from {
+		if ignoredFields[name] {
+			continue
+		}
+		if !reflect.DeepEqual(value, to[name]) {
+			changes[name] = Change{From: value, To: to[name]}
+		}
+	}
+	for name, value := range to {
+		if _, seen := from[name]; seen || ignoredFields[name] {
+			continue
+		}
+		changes[name] = Change{To: value}
+	}
+	return changes, nil
+}
+
+func toFields(state any) (map[string]any, error) {
+	fields := map[string]any{}
+	if state == nil || (reflect.ValueOf(state).Kind() == reflect.Pointer && reflect.ValueOf(state).IsNil()) {
+		return fields, nil
+	}
+
+	data, err := json.Marshal(state)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
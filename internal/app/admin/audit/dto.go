@@ -0,0 +1,44 @@
+package audit
+
+import (
+	"time"
+
+	"github.com/serhiirubets/rubeticket/internal/pkg/query"
+)
+
+// @Description Audit log entry
+type EntryResponse struct {
+	ID         uint              `json:"id"`
+	CreatedAt  time.Time         `json:"createdAt"`
+	ActorID    *uint             `json:"actorId"`
+	ActorEmail string            `json:"actorEmail"`
+	ActorRole  string            `json:"actorRole"`
+	Action     Action            `json:"action"`
+	Entity     string            `json:"entity"`
+	EntityID   *uint             `json:"entityId"`
+	Changes    map[string]Change `json:"changes"`
+	IP         string            `json:"ip"`
+	UserAgent  string            `json:"userAgent"`
+}
+
+// @Description List audit log response
+type ListEntriesResponse struct {
+	Items []EntryResponse `json:"items"`
+	query.PageMeta
+}
+
+func ToEntryResponse(entry *Entry) *EntryResponse {
+	return &EntryResponse{
+		ID:         entry.ID,
+		CreatedAt:  entry.CreatedAt,
+		ActorID:    entry.ActorID,
+		ActorEmail: entry.ActorEmail,
+		ActorRole:  entry.ActorRole,
+		Action:     entry.Action,
+		Entity:     entry.Entity,
+		EntityID:   entry.EntityID,
+		Changes:    entry.Changes,
+		IP:         entry.IP,
+		UserAgent:  entry.UserAgent,
+	}
+}
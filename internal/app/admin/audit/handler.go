@@ -0,0 +1,114 @@
+package audit
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/serhiirubets/rubeticket/config"
+	"github.com/serhiirubets/rubeticket/internal/pkg/apperr"
+	"github.com/serhiirubets/rubeticket/internal/pkg/log"
+	"github.com/serhiirubets/rubeticket/internal/pkg/query"
+	"github.com/serhiirubets/rubeticket/internal/pkg/res"
+)
+
+type AuditHandlerDeps struct {
+	Config  *config.Config
+	Logger  log.ILogger
+	Service *AuditService
+}
+
+type AuditHandler struct {
+	Config  *config.Config
+	Logger  log.ILogger
+	Service *AuditService
+}
+
+func NewAuditHandler(router *http.ServeMux, deps *AuditHandlerDeps) {
+	handler := AuditHandler{
+		Config:  deps.Config,
+		Logger:  deps.Logger,
+		Service: deps.Service,
+	}
+
+	router.HandleFunc("GET /audit", handler.List())
+	router.HandleFunc("GET /audit/export", handler.Export())
+}
+
+// List godoc
+// @Summary List audit log
+// @Description Get a paginated list of admin and security actions, newest first
+// @Tags Admin/Audit
+// @Produce json
+// @Param page query int false "Page number (default: 1)"
+// @Param pageSize query int false "Page size (default: 10, max: 100)"
+// @Param sort query string false "Comma separated sort fields, prefix with - for descending"
+// @Param cursor query string false "nextCursor from the previous page, replaces page"
+// @Param q query string false "Actor email search"
+// @Param actorId query int false "Actor user ID"
+// @Param action query string false "create, update, delete, restore, purge or role_change"
+// @Param entity query string false "concert, venue, band, user or import"
+// @Param entityId query int false "Entity ID"
+// @Param createdAt[gte] query string false "From time, RFC 3339"
+// @Param createdAt[lt] query string false "Until time, RFC 3339"
+// @Success 200 {object} ListEntriesResponse
+// @Failure 400 {object} res.Problem "Unsupported filter or sort field"
+// @Failure 401 {object} res.Problem "Unauthorized"
+// @Failure 403 {object} res.Problem "Forbidden"
+// @Router /admin/v1/audit [get]
+func (h *AuditHandler) List() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		spec, err := query.ParseSpec(r.URL.Query(), AuditListSchema)
+		if err != nil {
+			res.Error(w, r, err)
+			return
+		}
+
+		entries, err := h.Service.List(r.Context(), spec)
+		if err != nil {
+			if apperr.IsInternal(err) {
+				h.Logger.WithContext(r.Context()).Error("Failed to list audit log", "error", err.Error())
+			}
+			res.Error(w, r, err)
+			return
+		}
+
+		res.Json(w, entries, http.StatusOK)
+	}
+}
+
+// Export godoc
+// @Summary Export audit log as CSV
+// @Description Download all entries matching the filters as CSV, oldest first. Takes the same filters as the list
+// @Tags Admin/Audit
+// @Produce text/csv
+// @Param q query string false "Actor email search"
+// @Param actorId query int false "Actor user ID"
+// @Param action query string false "create, update, delete, restore, purge or role_change"
+// @Param entity query string false "concert, venue, band, user or import"
+// @Param entityId query int false "Entity ID"
+// @Param createdAt[gte] query string false "From time, RFC 3339"
+// @Param createdAt[lt] query string false "Until time, RFC 3339"
+// @Success 200 {file} file "CSV file"
+// @Failure 400 {object} res.Problem "Unsupported filter"
+// @Failure 401 {object} res.Problem "Unauthorized"
+// @Failure 403 {object} res.Problem "Forbidden"
+// @Router /admin/v1/audit/export [get]
+func (h *AuditHandler) Export() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		spec, err := query.ParseSpec(r.URL.Query(), AuditListSchema)
+		if err != nil {
+			res.Error(w, r, err)
+			return
+		}
+
+		fileName := fmt.Sprintf("audit-%s.csv", time.Now().UTC().Format("20060102-150405"))
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fileName))
+
+		// rows are streamed, so a failure after the first batch can only be logged
+		if err := h.Service.ExportCSV(r.Context(), spec, w); err != nil {
+			h.Logger.WithContext(r.Context()).Error("Failed to export audit log", "error", err.Error())
+		}
+	}
+}
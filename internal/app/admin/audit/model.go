@@ -0,0 +1,47 @@
+package audit
+
+import "time"
+
+type Action string
+
+const (
+	ActionCreate     Action = "create"
+	ActionUpdate     Action = "update"
+	ActionDelete     Action = "delete"
+	ActionRestore    Action = "restore"
+	ActionPurge      Action = "purge"
+	ActionRoleChange Action = "role_change"
+)
+
+const (
+	EntityConcert = "concert"
+	EntityVenue   = "venue"
+	EntityBand    = "band"
+	EntityUser    = "user"
+	EntityImport  = "import"
+)
+
+// Change is the value of a field before and after an action, nil when the entity did not exist
+type Change struct {
+	From any `json:"from"`
+	To   any `json:"to"`
+}
+
+// Entry is one row of the append-only audit log
+type Entry struct {
+	ID         uint              `gorm:"primaryKey"`
+	CreatedAt  time.Time         `gorm:"not null"`
+	ActorID    *uint             `gorm:"index:idx_audit_actor_id"`
+	ActorEmail string            `gorm:"type:varchar(255)"`
+	ActorRole  string            `gorm:"type:varchar(20)"`
+	Action     Action            `gorm:"type:varchar(20);not null"`
+	Entity     string            `gorm:"type:varchar(30);not null;index:idx_audit_entity,priority:1"`
+	EntityID   *uint             `gorm:"index:idx_audit_entity,priority:2"`
+	Changes    map[string]Change `gorm:"type:jsonb;serializer:json"`
+	IP         string            `gorm:"type:varchar(45)"`
+	UserAgent  string            `gorm:"type:varchar(255)"`
+}
+
+func (Entry) TableName() string {
+	return "audit_log"
+}
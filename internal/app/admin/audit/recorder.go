@@ -0,0 +1,103 @@
+package audit
+
+import (
+	"context"
+	"net"
+	"net/http"
+
+	"github.com/serhiirubets/rubeticket/internal/pkg/db"
+	"github.com/serhiirubets/rubeticket/internal/pkg/middleware"
+)
+
+const maxUserAgent = 255
+
+// Recorder writes audit entries. Services record in the transaction of the change, so an entry
+// is saved exactly when the change is. A nil Recorder records nothing
+type Recorder struct {
+	service *AuditService
+}
+
+func NewRecorder(service *AuditService) *Recorder {
+	return &Recorder{
+		service: service,
+	}
+}
+
+// actor is who makes changes in a context, see WithActor
+type actor struct {
+	id        *uint
+	email     string
+	role      string
+	ip        string
+	userAgent string
+}
+
+type actorKey struct{}
+
+// WithActor returns the request context carrying the authenticated user and client of r,
+// pass it to services that record changes
+func WithActor(r *http.Request) context.Context {
+	a := actor{
+		ip:        clientIP(r),
+		userAgent: truncate(r.UserAgent(), maxUserAgent),
+	}
+	if authData, err := middleware.GetAuthData(r); err == nil {
+		a.id = &authData.UserID
+		a.email = authData.Email
+		a.role = string(authData.Role)
+	}
+	return context.WithValue(r.Context(), actorKey{}, a)
+}
+
+// Record writes an action on an entity by the actor of ctx in tx, before is nil for
+// created entities and after is nil for removed ones
+func (rec *Recorder) Record(ctx context.Context, tx db.IDb, action Action, entity string, entityID uint, before, after any) error {
+	if rec == nil {
+		return nil
+	}
+
+	entry, err := newEntry(ctx, action, entity, entityID, before, after)
+	if err != nil {
+		return err
+	}
+	return rec.service.WithTx(tx).Record(ctx, entry)
+}
+
+func newEntry(ctx context.Context, action Action, entity string, entityID uint, before, after any) (*Entry, error) {
+	changes, err := Diff(before, after)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := &Entry{
+		Action:   action,
+		Entity:   entity,
+		EntityID: &entityID,
+		Changes:  changes,
+	}
+	if a, ok := ctx.Value(actorKey{}).(actor); ok {
+		entry.ActorID = a.id
+		entry.ActorEmail = a.email
+		entry.ActorRole = a.role
+		entry.IP = a.ip
+		entry.UserAgent = a.userAgent
+	}
+	return entry, nil
+}
+
+// clientIP is the address of the direct peer, forwarding headers are not trusted
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func truncate(value string, max int) string {
+	runes := []rune(value)
+	if len(runes) <= max {
+		return value
+	}
+	return string(runes[:max])
+}
@@ -0,0 +1,78 @@
+package audit
+
+import (
+	"context"
+
+	"github.com/serhiirubets/rubeticket/internal/pkg/db"
+	"github.com/serhiirubets/rubeticket/internal/pkg/query"
+	"gorm.io/gorm"
+)
+
+// exportBatchSize is how many entries are read at once while exporting
+const exportBatchSize = 500
+
+// AuditListSchema lists fields the audit log can be filtered and sorted by, newest first by default
+var AuditListSchema = query.Schema{
+	Filters: map[string]query.Field{
+		"actorId":   {Column: "actor_id", Ops: []query.Operator{query.Eq, query.In}, Type: query.Int},
+		"action":    {Column: "action", Ops: []query.Operator{query.Eq, query.In}},
+		"entity":    {Column: "entity", Ops: []query.Operator{query.Eq, query.In}},
+		"entityId":  {Column: "entity_id", Type: query.Int},
+		"ip":        {Column: "ip"},
+		"createdAt": {Column: "created_at", Ops: []query.Operator{query.Gte, query.Lt}, Type: query.Time},
+	},
+	Sort: map[string]string{
+		"id":        "id",
+		"createdAt": "created_at",
+	},
+	Search:      []string{"actor_email"},
+	DefaultSort: "-id",
+}
+
+type IAuditRepository interface {
+	Create(ctx context.Context, entry *Entry) error
+	List(ctx context.Context, spec *query.Spec) ([]Entry, query.PageMeta, error)
+	Export(ctx context.Context, spec *query.Spec, fn func(entries []Entry) error) error
+	WithTx(tx db.IDb) IAuditRepository
+}
+
+type AuditRepository struct {
+	Db db.IDb
+}
+
+func NewAuditRepository(Db db.IDb) IAuditRepository {
+	return &AuditRepository{Db: Db}
+}
+
+// WithTx returns a copy of the repository that runs queries in tx
+func (r *AuditRepository) WithTx(tx db.IDb) IAuditRepository {
+	return &AuditRepository{Db: tx}
+}
+
+func (r *AuditRepository) Create(ctx context.Context, entry *Entry) error {
+	return r.Db.WithContext(ctx).Create(entry).Error
+}
+
+// List returns one page of entries matching spec, by offset or by cursor
+func (r *AuditRepository) List(ctx context.Context, spec *query.Spec) ([]Entry, query.PageMeta, error) {
+	var entries []Entry
+	var total int64
+
+	filtered := spec.Where(r.Db.WithContext(ctx).Model(&Entry{}), AuditListSchema).Session(&gorm.Session{})
+	if err := filtered.Count(&total).Error; err != nil {
+		return nil, query.PageMeta{}, err
+	}
+	if err := spec.Paginate(filtered).Find(&entries).Error; err != nil {
+		return nil, query.PageMeta{}, err
+	}
+	return query.NewPage(filtered, spec, entries, total)
+}
+
+// Export passes all entries matching spec filters to fn in batches, oldest first. Pagination is ignored
+func (r *AuditRepository) Export(ctx context.Context, spec *query.Spec, fn func(entries []Entry) error) error {
+	var batch []Entry
+	return spec.Where(r.Db.WithContext(ctx).Model(&Entry{}), AuditListSchema).
+		FindInBatches(&batch, exportBatchSize, func(tx *gorm.DB, _ int) error {
+			return fn(batch)
+		}).Error
+}
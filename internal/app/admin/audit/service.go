@@ -0,0 +1,106 @@
+package audit
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/serhiirubets/rubeticket/internal/pkg/db"
+	"github.com/serhiirubets/rubeticket/internal/pkg/query"
+	"github.com/serhiirubets/rubeticket/internal/pkg/tracing"
+)
+
+var csvHeader = []string{"id", "created_at", "actor_id", "actor_email", "actor_role", "action", "entity", "entity_id", "ip", "user_agent", "changes"}
+
+type AuditService struct {
+	repository IAuditRepository
+}
+
+func NewAuditService(repository IAuditRepository) *AuditService {
+	return &AuditService{repository: repository}
+}
+
+// WithTx returns a copy of the service that writes entries in tx
+func (s *AuditService) WithTx(tx db.IDb) *AuditService {
+	return &AuditService{repository: s.repository.WithTx(tx)}
+}
+
+func (s *AuditService) Record(ctx context.Context, entry *Entry) error {
+	ctx, span := tracing.Start(ctx, "AuditService.Record")
+	defer span.End()
+
+	return s.repository.Create(ctx, entry)
+}
+
+func (s *AuditService) List(ctx context.Context, spec *query.Spec) (*ListEntriesResponse, error) {
+	ctx, span := tracing.Start(ctx, "AuditService.List")
+	defer span.End()
+
+	entries, meta, err := s.repository.List(ctx, spec)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &ListEntriesResponse{
+		Items:    make([]EntryResponse, len(entries)),
+		PageMeta: meta,
+	}
+	for i, entry := range entries {
+		response.Items[i] = *ToEntryResponse(&entry)
+	}
+	return response, nil
+}
+
+// ExportCSV writes all entries matching spec filters as CSV, changes are kept as a JSON column
+func (s *AuditService) ExportCSV(ctx context.Context, spec *query.Spec, out io.Writer) error {
+	ctx, span := tracing.Start(ctx, "AuditService.ExportCSV")
+	defer span.End()
+
+	writer := csv.NewWriter(out)
+	if err := writer.Write(csvHeader); err != nil {
+		return err
+	}
+
+	err := s.repository.Export(ctx, spec, func(entries []Entry) error {
+		for _, entry := range entries {
+			changes, err := json.Marshal(entry.Changes)
+			if err != nil {
+				return err
+			}
+			record := []string{
+				strconv.FormatUint(uint64(entry.ID), 10),
+				entry.CreatedAt.UTC().Format(time.RFC3339),
+				optionalID(entry.ActorID),
+				entry.ActorEmail,
+				entry.ActorRole,
+				string(entry.Action),
+				entry.Entity,
+				optionalID(entry.EntityID),
+				entry.IP,
+				entry.UserAgent,
+				string(changes),
+			}
+			if err := writer.Write(record); err != nil {
+				return err
+			}
+		}
+		writer.Flush()
+		return writer.Error()
+	})
+	if err != nil {
+		return err
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+func optionalID(id *uint) string {
+	if id == nil {
+		return ""
+	}
+	return strconv.FormatUint(uint64(*id), 10)
+}
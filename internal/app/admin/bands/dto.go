@@ -1,45 +1,120 @@
 package bands
 
-import "time"
+import (
+	"time"
+
+	"github.com/serhiirubets/rubeticket/internal/app/admin/genres"
+	"github.com/serhiirubets/rubeticket/internal/pkg/query"
+)
 
 // @Description Band response model
 type BandResponse struct {
-	ID          uint      `json:"id"`
-	Name        string    `json:"name"`
-	Description string    `json:"description"`
-	Genre       string    `json:"genre"`
-	CreatedAt   time.Time `json:"createdAt"`
-	UpdatedAt   time.Time `json:"updatedAt"`
+	ID          uint                   `json:"id"`
+	ExternalID  *string                `json:"externalId,omitempty"`
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	Genre       string                 `json:"genre"`
+	Country     string                 `json:"country"`
+	Genres      []genres.GenreResponse `json:"genres,omitempty"`
+	Members     []BandMemberResponse   `json:"members,omitempty"`
+	Links       []BandLinkResponse     `json:"links,omitempty"`
+	Version     uint                   `json:"version"`
+	CreatedAt   time.Time              `json:"createdAt"`
+	UpdatedAt   time.Time              `json:"updatedAt"`
+	DeletedAt   *time.Time             `json:"deletedAt,omitempty"`
+}
+
+// @Description Band member response model
+type BandMemberResponse struct {
+	Name       string `json:"name"`
+	Role       string `json:"role"`
+	JoinedYear *int   `json:"joinedYear"`
+	LeftYear   *int   `json:"leftYear"`
 }
 
-// @Description Create band request
+// @Description Band link response model
+type BandLinkResponse struct {
+	Kind string `json:"kind"`
+	URL  string `json:"url"`
+}
+
+// @Description Band member, listed in the given order
+type BandMemberRequest struct {
+	Name       string `json:"name" validate:"required,max=100"`
+	Role       string `json:"role" validate:"max=100"`
+	JoinedYear *int   `json:"joinedYear" validate:"omitempty,min=1900,max=2100"`
+	LeftYear   *int   `json:"leftYear" validate:"omitempty,min=1900,max=2100"`
+}
+
+// @Description Band link
+type BandLinkRequest struct {
+	Kind string `json:"kind" validate:"required,oneof=website spotify bandcamp youtube instagram facebook tiktok apple_music"`
+	URL  string `json:"url" validate:"required,url,max=255"`
+}
+
+// @Description Create band request. The first of genreIds is the primary genre
 type CreateBandRequest struct {
-	Name        string `json:"name" validate:"required,max=255"`
-	Description string `json:"description"`
-	Genre       string `json:"genre" validate:"max=100"`
+	ExternalID  string              `json:"externalId" validate:"max=100"`
+	Name        string              `json:"name" validate:"required,max=255"`
+	Description string              `json:"description"`
+	GenreIDs    []uint              `json:"genreIds"`
+	Country     string              `json:"country" validate:"omitempty,iso3166_1_alpha2"`
+	Members     []BandMemberRequest `json:"members" validate:"omitempty,dive"`
+	Links       []BandLinkRequest   `json:"links" validate:"omitempty,dive"`
 }
 
-// @Description Update band request
+// @Description Update band request. Omitted lists are left as is, empty lists clear them. The first of genreIds is the primary genre
 type UpdateBandRequest struct {
-	Name        *string `json:"name" validate:"omitempty,max=255"`
-	Description *string `json:"description"`
-	Genre       *string `json:"genre" validate:"omitempty,max=100"`
+	ExternalID  *string             `json:"externalId" validate:"omitempty,max=100"`
+	Name        *string             `json:"name" validate:"omitempty,max=255"`
+	Description *string             `json:"description"`
+	GenreIDs    []uint              `json:"genreIds"`
+	Country     *string             `json:"country" validate:"omitempty,iso3166_1_alpha2"`
+	Members     []BandMemberRequest `json:"members" validate:"omitempty,dive"`
+	Links       []BandLinkRequest   `json:"links" validate:"omitempty,dive"`
 }
 
 // @Description List bands response
 type ListBandsResponse struct {
 	Items []BandResponse `json:"items"`
+	query.PageMeta
 }
 
 func ToBandResponse(band *Band) *BandResponse {
-	return &BandResponse{
+	response := &BandResponse{
 		ID:          band.Model.ID,
+		ExternalID:  band.ExternalID,
 		Name:        band.Name,
 		Description: band.Description,
 		Genre:       band.Genre,
+		Country:     band.Country,
+		Version:     band.Version,
 		CreatedAt:   band.CreatedAt,
 		UpdatedAt:   band.UpdatedAt,
 	}
+	if band.DeletedAt.Valid {
+		response.DeletedAt = &band.DeletedAt.Time
+	}
+
+	if len(band.Genres) > 0 {
+		response.Genres = genres.ToGenreResponses(band.Genres)
+	}
+	for _, member := range band.Members {
+		response.Members = append(response.Members, BandMemberResponse{
+			Name:       member.Name,
+			Role:       member.Role,
+			JoinedYear: member.JoinedYear,
+			LeftYear:   member.LeftYear,
+		})
+	}
+	for _, link := range band.Links {
+		response.Links = append(response.Links, BandLinkResponse{
+			Kind: link.Kind,
+			URL:  link.URL,
+		})
+	}
+
+	return response
 }
 
 func ToBandResponses(bands []Band) []BandResponse {
@@ -49,3 +124,25 @@ func ToBandResponses(bands []Band) []BandResponse {
 	}
 	return responses
 }
+
+func toBandMembers(requests []BandMemberRequest) []BandMember {
+	members := make([]BandMember, len(requests))
+	for i, request := range requests {
+		members[i] = BandMember{
+			Name:       request.Name,
+			Role:       request.Role,
+			JoinedYear: request.JoinedYear,
+			LeftYear:   request.LeftYear,
+			Position:   i + 1,
+		}
+	}
+	return members
+}
+
+func toBandLinks(requests []BandLinkRequest) []BandLink {
+	links := make([]BandLink, len(requests))
+	for i, request := range requests {
+		links[i] = BandLink{Kind: request.Kind, URL: request.URL}
+	}
+	return links
+}
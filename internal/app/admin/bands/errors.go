@@ -0,0 +1,14 @@
+package bands
+
+import "github.com/serhiirubets/rubeticket/internal/pkg/apperr"
+
+var (
+	ErrInvalidID       = apperr.New(apperr.Invalid, "invalid_band_id", "invalid band ID")
+	ErrBandNotFound    = apperr.New(apperr.NotFound, "band_not_found", "band not found")
+	ErrBandNotInTrash  = apperr.New(apperr.NotFound, "band_not_in_trash", "band not found in trash")
+	ErrGenreNotFound   = apperr.New(apperr.Invalid, "genre_not_found", "genre not found")
+	ErrMemberYears     = apperr.New(apperr.Invalid, "member_years", "member left year is before joined year")
+	ErrBandInUse       = apperr.New(apperr.Conflict, "band_in_use", "band is in concert lineups, use cascade=true to take it out of them")
+	ErrVersionConflict = apperr.New(apperr.PreconditionFailed, "version_conflict", "band was changed by someone else")
+	ErrExternalIDTaken = apperr.New(apperr.Conflict, "external_id_taken", "external ID is used by another band")
+)
@@ -5,8 +5,12 @@ import (
 	"strconv"
 
 	"github.com/serhiirubets/rubeticket/config"
+	"github.com/serhiirubets/rubeticket/internal/app/admin/audit"
 	"github.com/serhiirubets/rubeticket/internal/app/users"
+	"github.com/serhiirubets/rubeticket/internal/pkg/apperr"
+	"github.com/serhiirubets/rubeticket/internal/pkg/etag"
 	"github.com/serhiirubets/rubeticket/internal/pkg/log"
+	"github.com/serhiirubets/rubeticket/internal/pkg/query"
 	"github.com/serhiirubets/rubeticket/internal/pkg/req"
 	"github.com/serhiirubets/rubeticket/internal/pkg/res"
 )
@@ -38,6 +42,9 @@ func NewBandHandler(router *http.ServeMux, deps *BandHandlerDeps) {
 	router.HandleFunc("DELETE /bands/{id}", handler.Delete())
 	router.HandleFunc("GET /bands/{id}", handler.GetByID())
 	router.HandleFunc("GET /bands", handler.List())
+	router.HandleFunc("GET /bands/trash", handler.ListTrash())
+	router.HandleFunc("POST /bands/trash/{id}/restore", handler.Restore())
+	router.HandleFunc("DELETE /bands/trash/{id}", handler.Purge())
 }
 
 // Create godoc
@@ -48,9 +55,10 @@ func NewBandHandler(router *http.ServeMux, deps *BandHandlerDeps) {
 // @Produce json
 // @Param request body CreateBandRequest true "Band details"
 // @Success 201 {object} BandResponse
-// @Failure 400 {string} string "Bad request"
-// @Failure 401 {string} string "Unauthorized"
-// @Failure 403 {string} string "Forbidden"
+// @Failure 400 {object} res.Problem "Bad request"
+// @Failure 401 {object} res.Problem "Unauthorized"
+// @Failure 403 {object} res.Problem "Forbidden"
+// @Failure 409 {object} res.Problem "External ID is taken"
 // @Router /admin/v1/bands [post]
 func (h *BandHandler) Create() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -59,20 +67,17 @@ func (h *BandHandler) Create() http.HandlerFunc {
 			return
 		}
 
-		band, err := h.Service.Create(payload)
+		band, err := h.Service.Create(audit.WithActor(r), payload)
 		if err != nil {
-			h.Logger.Error("Failed to create band", "error", err.Error())
-			res.Json(w, "Failed to create band", http.StatusInternalServerError)
+			if apperr.IsInternal(err) {
+				h.Logger.WithContext(r.Context()).Error("Failed to create band", "error", err.Error())
+			}
+			res.Error(w, r, err)
 			return
 		}
 
-		response := BandResponse{
-			ID:          band.ID,
-			Name:        band.Name,
-			Description: band.Description,
-		}
-
-		res.Json(w, response, http.StatusCreated)
+		etag.Set(w, band.Version)
+		res.Json(w, band, http.StatusCreated)
 	}
 }
 
@@ -83,70 +88,94 @@ func (h *BandHandler) Create() http.HandlerFunc {
 // @Accept json
 // @Produce json
 // @Param id path int true "Band ID"
+// @Param If-Match header string true "ETag from the last read, * to skip the check"
 // @Param request body UpdateBandRequest true "Band details to update"
 // @Success 200 {object} BandResponse
-// @Failure 400 {string} string "Bad request"
-// @Failure 401 {string} string "Unauthorized"
-// @Failure 403 {string} string "Forbidden"
-// @Failure 404 {string} string "Not found"
+// @Header 200 {string} ETag "Version of the band"
+// @Failure 400 {object} res.Problem "Bad request"
+// @Failure 401 {object} res.Problem "Unauthorized"
+// @Failure 403 {object} res.Problem "Forbidden"
+// @Failure 404 {object} res.Problem "Not found"
+// @Failure 409 {object} res.Problem "External ID is taken"
+// @Failure 412 {object} res.Problem "Band was changed since it was read"
+// @Failure 428 {object} res.Problem "If-Match is missing"
 // @Router /admin/v1/bands/{id} [put]
 func (h *BandHandler) Update() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		id, err := strconv.ParseUint(r.PathValue("id"), 10, 32)
 		if err != nil {
-			res.Json(w, "Invalid band ID", http.StatusBadRequest)
+			res.Error(w, r, ErrInvalidID)
 			return
 		}
 
-		payload, err := req.HandleBody[UpdateBandRequest](&w, r)
+		version, err := etag.IfMatch(r)
 		if err != nil {
+			res.Error(w, r, err)
 			return
 		}
 
-		band, err := h.Service.Update(uint(id), payload)
+		payload, err := req.HandleBody[UpdateBandRequest](&w, r)
 		if err != nil {
-			if err.Error() == "band not found" {
-				res.Json(w, "Band not found", http.StatusNotFound)
-				return
-			}
-			h.Logger.Error("Failed to update band", "error", err.Error())
-			res.Json(w, "Failed to update band", http.StatusInternalServerError)
 			return
 		}
 
-		response := BandResponse{
-			ID:          band.ID,
-			Name:        band.Name,
-			Description: band.Description,
+		band, err := h.Service.Update(audit.WithActor(r), uint(id), version, payload)
+		if err != nil {
+			if apperr.IsInternal(err) {
+				h.Logger.WithContext(r.Context()).Error("Failed to update band", "error", err.Error())
+			}
+			res.Error(w, r, err)
+			return
 		}
 
-		res.Json(w, response, http.StatusOK)
+		etag.Set(w, band.Version)
+		res.Json(w, band, http.StatusOK)
 	}
 }
 
 // Delete godoc
 // @Summary Delete a band
-// @Description Delete an existing band
+// @Description Move a band to trash. A band billed on concerts is kept unless cascade is set, then it is taken out of their lineups
 // @Tags Admin/Bands
 // @Produce json
 // @Param id path int true "Band ID"
+// @Param If-Match header string true "ETag from the last read, * to skip the check"
+// @Param cascade query bool false "Take the band out of concert lineups"
 // @Success 204 "No Content"
-// @Failure 400 {string} string "Bad request"
-// @Failure 401 {string} string "Unauthorized"
-// @Failure 403 {string} string "Forbidden"
+// @Failure 400 {object} res.Problem "Bad request"
+// @Failure 401 {object} res.Problem "Unauthorized"
+// @Failure 403 {object} res.Problem "Forbidden"
+// @Failure 404 {object} res.Problem "Not found"
+// @Failure 409 {object} res.Problem "Band is in concert lineups"
+// @Failure 412 {object} res.Problem "Band was changed since it was read"
+// @Failure 428 {object} res.Problem "If-Match is missing"
 // @Router /admin/v1/bands/{id} [delete]
 func (h *BandHandler) Delete() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		id, err := strconv.ParseUint(r.PathValue("id"), 10, 32)
 		if err != nil {
-			res.Json(w, "Invalid band ID", http.StatusBadRequest)
+			res.Error(w, r, ErrInvalidID)
+			return
+		}
+
+		cascade, err := req.BoolParam(r, "cascade")
+		if err != nil {
+			res.Error(w, r, err)
+			return
+		}
+
+		version, err := etag.IfMatch(r)
+		if err != nil {
+			res.Error(w, r, err)
 			return
 		}
 
-		err = h.Service.Delete(uint(id))
+		err = h.Service.Delete(audit.WithActor(r), uint(id), version, cascade)
 		if err != nil {
-			h.Logger.Error("Failed to delete band", "error", err.Error())
-			res.Json(w, "Failed to delete band", http.StatusInternalServerError)
+			if apperr.IsInternal(err) {
+				h.Logger.WithContext(r.Context()).Error("Failed to delete band", "error", err.Error())
+			}
+			res.Error(w, r, err)
 			return
 		}
 
@@ -161,32 +190,28 @@ func (h *BandHandler) Delete() http.HandlerFunc {
 // @Produce json
 // @Param id path int true "Band ID"
 // @Success 200 {object} BandResponse
-// @Failure 400 {string} string "Bad request"
-// @Failure 401 {string} string "Unauthorized"
-// @Failure 403 {string} string "Forbidden"
-// @Failure 404 {string} string "Not found"
+// @Header 200 {string} ETag "Version of the band, send it as If-Match to update or delete"
+// @Failure 400 {object} res.Problem "Bad request"
+// @Failure 401 {object} res.Problem "Unauthorized"
+// @Failure 403 {object} res.Problem "Forbidden"
+// @Failure 404 {object} res.Problem "Not found"
 // @Router /admin/v1/bands/{id} [get]
 func (h *BandHandler) GetByID() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		id, err := strconv.ParseUint(r.PathValue("id"), 10, 32)
 		if err != nil {
-			res.Json(w, "Invalid band ID", http.StatusBadRequest)
+			res.Error(w, r, ErrInvalidID)
 			return
 		}
 
-		band, err := h.Service.GetByID(uint(id))
+		band, err := h.Service.GetByID(r.Context(), uint(id))
 		if err != nil {
-			res.Json(w, "Band not found", http.StatusNotFound)
+			res.Error(w, r, err)
 			return
 		}
 
-		response := BandResponse{
-			ID:          band.ID,
-			Name:        band.Name,
-			Description: band.Description,
-		}
-
-		res.Json(w, response, http.StatusOK)
+		etag.Set(w, band.Version)
+		res.Json(w, band, http.StatusOK)
 	}
 }
 
@@ -197,34 +222,145 @@ func (h *BandHandler) GetByID() http.HandlerFunc {
 // @Produce json
 // @Param page query int false "Page number (default: 1)"
 // @Param pageSize query int false "Page size (default: 10, max: 100)"
+// @Param sort query string false "Comma separated sort fields, prefix with - for descending"
+// @Param q query string false "Search term"
+// @Param cursor query string false "nextCursor from the previous page, replaces page"
+// @Param genreId query int false "Genre ID, subgenres included"
+// @Param country query string false "Country of origin, ISO 3166-1 alpha-2"
+// @Param include query string false "Comma separated: genres, members, links"
 // @Success 200 {object} ListBandsResponse
-// @Failure 401 {string} string "Unauthorized"
-// @Failure 403 {string} string "Forbidden"
+// @Failure 400 {object} res.Problem "Unsupported filter or sort field"
+// @Failure 401 {object} res.Problem "Unauthorized"
+// @Failure 403 {object} res.Problem "Forbidden"
 // @Router /admin/v1/bands [get]
 func (h *BandHandler) List() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
-		pageSize, _ := strconv.Atoi(r.URL.Query().Get("pageSize"))
+		spec, err := query.ParseSpec(r.URL.Query(), BandListSchema)
+		if err != nil {
+			res.Error(w, r, err)
+			return
+		}
+
+		bands, err := h.Service.List(r.Context(), spec)
+		if err != nil {
+			if apperr.IsInternal(err) {
+				h.Logger.WithContext(r.Context()).Error("Failed to list bands", "error", err.Error())
+			}
+			res.Error(w, r, err)
+			return
+		}
+
+		res.Json(w, bands, http.StatusOK)
+	}
+}
+
+// ListTrash godoc
+// @Summary List deleted bands
+// @Description Get a paginated list of bands in trash
+// @Tags Admin/Bands
+// @Produce json
+// @Param page query int false "Page number (default: 1)"
+// @Param pageSize query int false "Page size (default: 10, max: 100)"
+// @Param sort query string false "Comma separated sort fields, prefix with - for descending, deletedAt is supported"
+// @Param q query string false "Search term"
+// @Param cursor query string false "nextCursor from the previous page, replaces page"
+// @Success 200 {object} ListBandsResponse
+// @Failure 400 {object} res.Problem "Unsupported filter or sort field"
+// @Failure 401 {object} res.Problem "Unauthorized"
+// @Failure 403 {object} res.Problem "Forbidden"
+// @Router /admin/v1/bands/trash [get]
+func (h *BandHandler) ListTrash() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		spec, err := query.ParseSpec(r.URL.Query(), BandTrashSchema)
+		if err != nil {
+			res.Error(w, r, err)
+			return
+		}
+
+		bands, err := h.Service.ListDeleted(r.Context(), spec)
+		if err != nil {
+			if apperr.IsInternal(err) {
+				h.Logger.WithContext(r.Context()).Error("Failed to list deleted bands", "error", err.Error())
+			}
+			res.Error(w, r, err)
+			return
+		}
+
+		res.Json(w, bands, http.StatusOK)
+	}
+}
+
+// Restore godoc
+// @Summary Restore a deleted band
+// @Description Bring a band back from trash. Lineups it was taken out of on delete are not restored
+// @Tags Admin/Bands
+// @Produce json
+// @Param id path int true "Band ID"
+// @Success 200 {object} BandResponse
+// @Failure 400 {object} res.Problem "Bad request"
+// @Failure 401 {object} res.Problem "Unauthorized"
+// @Failure 403 {object} res.Problem "Forbidden"
+// @Failure 404 {object} res.Problem "Not found in trash"
+// @Router /admin/v1/bands/trash/{id}/restore [post]
+func (h *BandHandler) Restore() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseUint(r.PathValue("id"), 10, 32)
+		if err != nil {
+			res.Error(w, r, ErrInvalidID)
+			return
+		}
 
-		bands, err := h.Service.List(page, pageSize)
+		band, err := h.Service.Restore(audit.WithActor(r), uint(id))
 		if err != nil {
-			h.Logger.Error("Failed to list bands", "error", err.Error())
-			res.Json(w, "Failed to list bands", http.StatusInternalServerError)
+			if apperr.IsInternal(err) {
+				h.Logger.WithContext(r.Context()).Error("Failed to restore band", "error", err.Error())
+			}
+			res.Error(w, r, err)
 			return
 		}
 
-		response := ListBandsResponse{
-			Items: make([]BandResponse, len(bands)),
+		etag.Set(w, band.Version)
+		res.Json(w, band, http.StatusOK)
+	}
+}
+
+// Purge godoc
+// @Summary Permanently delete a band
+// @Description Remove a band from trash for good. Lineups, of deleted concerts too, block it unless cascade is set
+// @Tags Admin/Bands
+// @Produce json
+// @Param id path int true "Band ID"
+// @Param cascade query bool false "Take the band out of all concert lineups"
+// @Success 204 "No Content"
+// @Failure 400 {object} res.Problem "Bad request"
+// @Failure 401 {object} res.Problem "Unauthorized"
+// @Failure 403 {object} res.Problem "Forbidden"
+// @Failure 404 {object} res.Problem "Not found in trash"
+// @Failure 409 {object} res.Problem "Band is in concert lineups"
+// @Router /admin/v1/bands/trash/{id} [delete]
+func (h *BandHandler) Purge() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseUint(r.PathValue("id"), 10, 32)
+		if err != nil {
+			res.Error(w, r, ErrInvalidID)
+			return
 		}
 
-		for i, band := range bands {
-			response.Items[i] = BandResponse{
-				ID:          band.ID,
-				Name:        band.Name,
-				Description: band.Description,
+		cascade, err := req.BoolParam(r, "cascade")
+		if err != nil {
+			res.Error(w, r, err)
+			return
+		}
+
+		err = h.Service.Purge(audit.WithActor(r), uint(id), cascade)
+		if err != nil {
+			if apperr.IsInternal(err) {
+				h.Logger.WithContext(r.Context()).Error("Failed to purge band", "error", err.Error())
 			}
+			res.Error(w, r, err)
+			return
 		}
 
-		res.Json(w, response, http.StatusOK)
+		w.WriteHeader(http.StatusNoContent)
 	}
 }
@@ -1,18 +1,53 @@
 package bands
 
 import (
+	"github.com/serhiirubets/rubeticket/internal/app/admin/genres"
 	"gorm.io/gorm"
 )
 
+const (
+	LinkWebsite    = "website"
+	LinkSpotify    = "spotify"
+	LinkBandcamp   = "bandcamp"
+	LinkYoutube    = "youtube"
+	LinkInstagram  = "instagram"
+	LinkFacebook   = "facebook"
+	LinkTiktok     = "tiktok"
+	LinkAppleMusic = "apple_music"
+)
+
 // @Description Band model
 type Band struct {
 	*gorm.Model
 	Name        string `json:"name" gorm:"type:varchar(255);not null;index:idx_band_name"`
 	Description string `json:"description" gorm:"type:text"`
-	Genre       string `json:"genre" gorm:"type:varchar(100);index:idx_band_genre"`
+	// Genre is the name of the primary genre, kept for search and simple filtering
+	Genre   string         `json:"genre" gorm:"type:varchar(100);index:idx_band_genre"`
+	Country string         `json:"country" gorm:"type:varchar(2);index:idx_band_country"`
+	Genres  []genres.Genre `json:"genres" gorm:"many2many:band_genres"`
+	Members []BandMember   `json:"members" gorm:"foreignKey:BandID"`
+	Links   []BandLink     `json:"links" gorm:"foreignKey:BandID"`
+	// ExternalID is the key of the band in the system it was imported from
+	ExternalID *string `json:"externalId" gorm:"type:varchar(100);uniqueIndex:idx_band_external_id"`
+	// Version is bumped on every write and sent as the ETag
+	Version uint `json:"version" gorm:"not null;default:1"`
+}
+
+// @Description Band member, current when LeftYear is empty
+type BandMember struct {
+	ID         uint   `json:"id" gorm:"primaryKey"`
+	BandID     uint   `json:"bandId" gorm:"not null;index:idx_band_member_band_id"`
+	Name       string `json:"name" gorm:"type:varchar(100);not null"`
+	Role       string `json:"role" gorm:"type:varchar(100)"`
+	JoinedYear *int   `json:"joinedYear"`
+	LeftYear   *int   `json:"leftYear"`
+	Position   int    `json:"position" gorm:"not null;default:0"`
 }
 
-type ConcertBands struct {
-	ConcertID uint `json:"concertId" gorm:"primaryKey;index:idx_concert_bands"`
-	BandID    uint `json:"bandId" gorm:"primaryKey;index:idx_concert_bands"`
+// @Description Social or streaming link of a band
+type BandLink struct {
+	ID     uint   `json:"id" gorm:"primaryKey"`
+	BandID uint   `json:"bandId" gorm:"not null;index:idx_band_link_band_id"`
+	Kind   string `json:"kind" gorm:"type:varchar(20);not null"`
+	URL    string `json:"url" gorm:"type:varchar(255);not null"`
 }
@@ -1,15 +1,64 @@
 package bands
 
 import (
+	"context"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/serhiirubets/rubeticket/internal/app/admin/genres"
 	"github.com/serhiirubets/rubeticket/internal/pkg/db"
+	"github.com/serhiirubets/rubeticket/internal/pkg/query"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// exportBatchSize is how many bands are read at once while exporting
+const exportBatchSize = 500
+
+// BandListSchema lists fields bands can be filtered, sorted and searched by
+var BandListSchema = query.Schema{
+	Filters: map[string]query.Field{
+		"name":    {Column: "name", Ops: []query.Operator{query.Eq, query.Like}},
+		"genre":   {Column: "genre", Ops: []query.Operator{query.Eq, query.In}},
+		"genreId": {Condition: genres.BandsInGenre, Type: query.Int},
+		"country": {Column: "country", Ops: []query.Operator{query.Eq, query.In}},
+	},
+	Sort: map[string]string{
+		"id":        "id",
+		"name":      "name",
+		"genre":     "genre",
+		"createdAt": "created_at",
+	},
+	Search:      []string{"name", "description"},
+	DefaultSort: "id",
+	Includes: map[string]string{
+		"genres":  "Genres",
+		"members": "Members",
+		"links":   "Links",
+	},
+}
+
+// BandTrashSchema is BandListSchema for deleted bands, also sortable by deletedAt
+var BandTrashSchema = BandListSchema.WithSort("deletedAt", "deleted_at")
+
 type IBandRepository interface {
-	Create(band *Band) (*Band, error)
-	Update(band *Band) error
-	Delete(id uint) error
-	GetByID(id uint) (*Band, error)
-	List(page, pageSize int) ([]Band, error)
+	Create(ctx context.Context, band *Band) (*Band, error)
+	Update(ctx context.Context, band *Band) error
+	Delete(ctx context.Context, id uint, version uint, cascade bool) error
+	GetByID(ctx context.Context, id uint) (*Band, error)
+	GetByExternalID(ctx context.Context, externalID string) (*Band, error)
+	GetByIDs(ctx context.Context, ids []uint) ([]Band, error)
+	GetByIDsForShare(ctx context.Context, ids []uint) ([]Band, error)
+	LockForUpdate(ctx context.Context, id uint) error
+	List(ctx context.Context, spec *query.Spec) ([]Band, query.PageMeta, error)
+	Export(ctx context.Context, spec *query.Spec, fn func(bands []Band) error) error
+	CountConcerts(ctx context.Context, id uint, withTrashed bool) (int64, error)
+	GetDeleted(ctx context.Context, id uint) (*Band, error)
+	ListDeleted(ctx context.Context, spec *query.Spec) ([]Band, query.PageMeta, error)
+	Restore(ctx context.Context, id uint) error
+	Purge(ctx context.Context, id uint, cascade bool) error
+	WithTx(tx db.IDb) IBandRepository
 }
 
 type BandRepository struct {
@@ -20,34 +69,294 @@ func NewBandRepository(Db db.IDb) IBandRepository {
 	return &BandRepository{Db: Db}
 }
 
-func (r *BandRepository) Create(band *Band) (*Band, error) {
-	if err := r.Db.Create(band).Error; err != nil {
+// WithTx returns a copy of the repository that runs queries in tx
+func (r *BandRepository) WithTx(tx db.IDb) IBandRepository {
+	return &BandRepository{Db: tx}
+}
+
+func (r *BandRepository) Create(ctx context.Context, band *Band) (*Band, error) {
+	if err := r.Db.WithContext(ctx).Omit(clause.Associations).Create(band).Error; err != nil {
+		return nil, err
+	}
+	if err := r.saveProfile(ctx, band); err != nil {
 		return nil, err
 	}
 	return band, nil
 }
 
-func (r *BandRepository) Update(band *Band) error {
-	return r.Db.Save(band).Error
+// Update saves the band when nobody changed it since it was read and replaces its genres, members and links.
+// Call it in a transaction to keep them in sync
+func (r *BandRepository) Update(ctx context.Context, band *Band) error {
+	conn := r.Db.WithContext(ctx)
+	updated, err := db.SaveVersioned(conn, band, &band.Version)
+	if err != nil {
+		return err
+	}
+	if !updated {
+		return ErrVersionConflict
+	}
+	if err := conn.Exec("DELETE FROM band_genres WHERE band_id = ?", band.ID).Error; err != nil {
+		return err
+	}
+	if err := conn.Where("band_id = ?", band.ID).Delete(&BandMember{}).Error; err != nil {
+		return err
+	}
+	if err := conn.Where("band_id = ?", band.ID).Delete(&BandLink{}).Error; err != nil {
+		return err
+	}
+	return r.saveProfile(ctx, band)
+}
+
+// saveProfile inserts genre links, members and links of a saved band
+func (r *BandRepository) saveProfile(ctx context.Context, band *Band) error {
+	conn := r.Db.WithContext(ctx)
+	if len(band.Genres) > 0 {
+		rows := make([]map[string]interface{}, len(band.Genres))
+		for i, genre := range band.Genres {
+			rows[i] = map[string]interface{}{"band_id": band.ID, "genre_id": genre.ID, "position": i}
+		}
+		if err := conn.Table("band_genres").Create(&rows).Error; err != nil {
+			return err
+		}
+	}
+	if len(band.Members) > 0 {
+		for i := range band.Members {
+			band.Members[i].ID = 0
+			band.Members[i].BandID = band.ID
+		}
+		if err := conn.Create(&band.Members).Error; err != nil {
+			return err
+		}
+	}
+	if len(band.Links) > 0 {
+		for i := range band.Links {
+			band.Links[i].ID = 0
+			band.Links[i].BandID = band.ID
+		}
+		if err := conn.Create(&band.Links).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// bumpConcerts follows a "removed" CTE of concert_bands rows. Concerts whose lineup changed
+// get a new version, so that their ETags do not match anymore
+const bumpConcerts = `UPDATE concerts SET version = version + 1, updated_at = now()
+			WHERE id IN (SELECT concert_id FROM removed)`
+
+// Delete soft deletes the band, with cascade it is first taken out of lineups of concerts
+// that are not deleted. Only the given version is deleted. Call it in a transaction
+func (r *BandRepository) Delete(ctx context.Context, id uint, version uint, cascade bool) error {
+	conn := r.Db.WithContext(ctx)
+
+	result := conn.Model(&Band{}).
+		Where("id = ? AND version = ?", id, version).
+		Updates(map[string]interface{}{"deleted_at": time.Now(), "version": gorm.Expr("version + 1")})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrVersionConflict
+	}
+
+	if cascade {
+		return conn.Exec(`WITH removed AS (
+				DELETE FROM concert_bands
+				WHERE band_id = ? AND concert_id IN (SELECT id FROM concerts WHERE deleted_at IS NULL)
+				RETURNING concert_id
+			)
+			`+bumpConcerts, id).Error
+	}
+	return nil
+}
+
+func (r *BandRepository) GetByID(ctx context.Context, id uint) (*Band, error) {
+	var band Band
+	err := r.Db.WithContext(ctx).
+		Preload("Genres").
+		Preload("Members", func(db *gorm.DB) *gorm.DB { return db.Order("position") }).
+		Preload("Links").
+		First(&band, id).Error
+	if err != nil {
+		return nil, err
+	}
+	bands := []Band{band}
+	if err := r.orderGenres(ctx, bands); err != nil {
+		return nil, err
+	}
+	return &bands[0], nil
+}
+
+// orderGenres puts the genres of the bands in the order they were saved in, the primary genre first.
+// Preloading cannot order by the join table
+func (r *BandRepository) orderGenres(ctx context.Context, bands []Band) error {
+	ids := make([]uint, len(bands))
+	for i, band := range bands {
+		ids[i] = band.ID
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	var links []struct {
+		BandID  uint
+		GenreID uint
+	}
+	err := r.Db.WithContext(ctx).Table("band_genres").Select("band_id", "genre_id").
+		Where("band_id IN ?", ids).Order("position").Find(&links).Error
+	if err != nil {
+		return err
+	}
+
+	positions := make(map[[2]uint]int, len(links))
+	for i, link := range links {
+		positions[[2]uint{link.BandID, link.GenreID}] = i
+	}
+	for _, band := range bands {
+		sort.SliceStable(band.Genres, func(i, j int) bool {
+			return positions[[2]uint{band.ID, band.Genres[i].ID}] < positions[[2]uint{band.ID, band.Genres[j].ID}]
+		})
+	}
+	return nil
 }
 
-func (r *BandRepository) Delete(id uint) error {
-	return r.Db.Delete(&Band{}, id).Error
+// Export passes all bands with genres, members and links matching spec filters to fn in batches, by id. Pagination is ignored
+func (r *BandRepository) Export(ctx context.Context, spec *query.Spec, fn func(bands []Band) error) error {
+	var batch []Band
+	return spec.Where(r.Db.WithContext(ctx).Model(&Band{}), BandListSchema).
+		Preload("Genres").
+		Preload("Members", func(db *gorm.DB) *gorm.DB { return db.Order("position") }).
+		Preload("Links").
+		FindInBatches(&batch, exportBatchSize, func(tx *gorm.DB, _ int) error {
+			if err := r.orderGenres(ctx, batch); err != nil {
+				return err
+			}
+			return fn(batch)
+		}).Error
 }
 
-func (r *BandRepository) GetByID(id uint) (*Band, error) {
+// GetByExternalID finds the band with the external ID, deleted ones included
+func (r *BandRepository) GetByExternalID(ctx context.Context, externalID string) (*Band, error) {
 	var band Band
-	if err := r.Db.First(&band, id).Error; err != nil {
+	if err := r.Db.WithContext(ctx).Unscoped().Where("external_id = ?", externalID).First(&band).Error; err != nil {
 		return nil, err
 	}
 	return &band, nil
 }
 
-func (r *BandRepository) List(page, pageSize int) ([]Band, error) {
+// List returns one page of bands matching spec, by offset or by cursor. Genres, members and links are loaded only when included
+func (r *BandRepository) List(ctx context.Context, spec *query.Spec) ([]Band, query.PageMeta, error) {
 	var bands []Band
-	offset := (page - 1) * pageSize
-	if err := r.Db.Offset(offset).Limit(pageSize).Find(&bands).Error; err != nil {
+	var total int64
+
+	filtered := spec.Where(r.Db.WithContext(ctx).Model(&Band{}), BandListSchema).Session(&gorm.Session{})
+	if err := filtered.Count(&total).Error; err != nil {
+		return nil, query.PageMeta{}, err
+	}
+	if err := spec.Preload(spec.Paginate(filtered)).Find(&bands).Error; err != nil {
+		return nil, query.PageMeta{}, err
+	}
+	if slices.Contains(spec.Include, "Genres") {
+		if err := r.orderGenres(ctx, bands); err != nil {
+			return nil, query.PageMeta{}, err
+		}
+	}
+	return query.NewPage(filtered, spec, bands, total)
+}
+
+// GetByIDs loads bands in one query, missing ids are skipped
+func (r *BandRepository) GetByIDs(ctx context.Context, ids []uint) ([]Band, error) {
+	var bands []Band
+	if len(ids) == 0 {
+		return bands, nil
+	}
+	if err := r.Db.WithContext(ctx).Where("id IN ?", ids).Find(&bands).Error; err != nil {
 		return nil, err
 	}
 	return bands, nil
 }
+
+// GetByIDsForShare is GetByIDs that keeps the bands from being deleted until the transaction ends
+func (r *BandRepository) GetByIDsForShare(ctx context.Context, ids []uint) ([]Band, error) {
+	var bands []Band
+	if len(ids) == 0 {
+		return bands, nil
+	}
+	err := r.Db.WithContext(ctx).Clauses(clause.Locking{Strength: "SHARE"}).
+		Where("id IN ?", ids).Order("id").Find(&bands).Error
+	if err != nil {
+		return nil, err
+	}
+	return bands, nil
+}
+
+// LockForUpdate waits for transactions that read the band for share, e.g. ones adding it to lineups,
+// so that checks made afterwards see their changes. Call it in a transaction
+func (r *BandRepository) LockForUpdate(ctx context.Context, id uint) error {
+	return r.Db.WithContext(ctx).Exec(`SELECT id FROM bands WHERE id = ? FOR UPDATE`, id).Error
+}
+
+// CountConcerts counts concerts the band is billed on, withTrashed includes deleted concerts
+func (r *BandRepository) CountConcerts(ctx context.Context, id uint, withTrashed bool) (int64, error) {
+	q := r.Db.WithContext(ctx).Table("concert_bands cb").Where("cb.band_id = ?", id)
+	if !withTrashed {
+		q = q.Joins("JOIN concerts c ON c.id = cb.concert_id AND c.deleted_at IS NULL")
+	}
+
+	var count int64
+	if err := q.Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (r *BandRepository) GetDeleted(ctx context.Context, id uint) (*Band, error) {
+	var band Band
+	if err := query.Trashed(r.Db.WithContext(ctx)).First(&band, id).Error; err != nil {
+		return nil, err
+	}
+	return &band, nil
+}
+
+// ListDeleted returns one page of deleted bands matching spec
+func (r *BandRepository) ListDeleted(ctx context.Context, spec *query.Spec) ([]Band, query.PageMeta, error) {
+	var bands []Band
+	var total int64
+
+	filtered := spec.Where(query.Trashed(r.Db.WithContext(ctx).Model(&Band{})), BandTrashSchema).Session(&gorm.Session{})
+	if err := filtered.Count(&total).Error; err != nil {
+		return nil, query.PageMeta{}, err
+	}
+	if err := spec.Preload(spec.Paginate(filtered)).Find(&bands).Error; err != nil {
+		return nil, query.PageMeta{}, err
+	}
+	if slices.Contains(spec.Include, "Genres") {
+		if err := r.orderGenres(ctx, bands); err != nil {
+			return nil, query.PageMeta{}, err
+		}
+	}
+	return query.NewPage(filtered, spec, bands, total)
+}
+
+func (r *BandRepository) Restore(ctx context.Context, id uint) error {
+	return r.Db.WithContext(ctx).Unscoped().Model(&Band{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{"deleted_at": nil, "version": gorm.Expr("version + 1")}).Error
+}
+
+// Purge removes the band permanently, with cascade it is taken out of all lineups first.
+// Genres, members and links are removed by foreign keys. Call it in a transaction
+func (r *BandRepository) Purge(ctx context.Context, id uint, cascade bool) error {
+	conn := r.Db.WithContext(ctx)
+	if cascade {
+		err := conn.Exec(`WITH removed AS (
+				DELETE FROM concert_bands WHERE band_id = ? RETURNING concert_id
+			)
+			`+bumpConcerts, id).Error
+		if err != nil {
+			return err
+		}
+	}
+	return conn.Unscoped().Delete(&Band{}, id).Error
+}
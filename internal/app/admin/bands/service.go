@@ -1,59 +1,326 @@
 package bands
 
-import "errors"
+import (
+	"context"
+
+	"github.com/serhiirubets/rubeticket/internal/app/admin/audit"
+	"github.com/serhiirubets/rubeticket/internal/app/admin/genres"
+	"github.com/serhiirubets/rubeticket/internal/pkg/convert"
+	"github.com/serhiirubets/rubeticket/internal/pkg/db"
+	"github.com/serhiirubets/rubeticket/internal/pkg/etag"
+	"github.com/serhiirubets/rubeticket/internal/pkg/query"
+	"github.com/serhiirubets/rubeticket/internal/pkg/tracing"
+)
 
 type BandService struct {
+	db         db.IDb
 	repository IBandRepository
+	genreRepo  genres.IGenreRepository
+	audit      *audit.Recorder
 }
 
-func NewBandService(repository IBandRepository) *BandService {
-	return &BandService{repository: repository}
+func NewBandService(db db.IDb, repository IBandRepository, genreRepo genres.IGenreRepository, auditRecorder *audit.Recorder) *BandService {
+	return &BandService{
+		db:         db,
+		repository: repository,
+		genreRepo:  genreRepo,
+		audit:      auditRecorder,
+	}
+}
+
+// WithTx returns a copy of the service that runs in tx, its own transactions become savepoints
+func (s *BandService) WithTx(tx db.IDb) *BandService {
+	return &BandService{
+		db:         tx,
+		repository: s.repository.WithTx(tx),
+		genreRepo:  s.genreRepo.WithTx(tx),
+		audit:      s.audit,
+	}
 }
 
-func (s *BandService) Create(payload *CreateBandRequest) (*Band, error) {
+func (s *BandService) Create(ctx context.Context, payload *CreateBandRequest) (*BandResponse, error) {
+	ctx, span := tracing.Start(ctx, "BandService.Create")
+	defer span.End()
+
+	if err := checkMembers(payload.Members); err != nil {
+		return nil, err
+	}
+
 	band := &Band{
+		ExternalID:  convert.OptionalString(payload.ExternalID),
 		Name:        payload.Name,
 		Description: payload.Description,
+		Country:     payload.Country,
+		Members:     toBandMembers(payload.Members),
+		Links:       toBandLinks(payload.Links),
 	}
 
-	return s.repository.Create(band)
+	err := s.db.WithTx(ctx, func(tx db.IDb) error {
+		if payload.ExternalID != "" {
+			if _, err := s.repository.WithTx(tx).GetByExternalID(ctx, payload.ExternalID); err == nil {
+				return ErrExternalIDTaken
+			}
+		}
+		if err := s.setGenres(ctx, tx, band, payload.GenreIDs); err != nil {
+			return err
+		}
+
+		var err error
+		band, err = s.repository.WithTx(tx).Create(ctx, band)
+		if err != nil {
+			return err
+		}
+		return s.audit.Record(ctx, tx, audit.ActionCreate, audit.EntityBand, band.ID, nil, ToBandResponse(band))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return ToBandResponse(band), nil
 }
 
-func (s *BandService) Update(id uint, payload *UpdateBandRequest) (*Band, error) {
-	band, err := s.repository.GetByID(id)
+// Update changes the band if it still has the expected version, etag.Any skips the check
+func (s *BandService) Update(ctx context.Context, id uint, version uint, payload *UpdateBandRequest) (*BandResponse, error) {
+	ctx, span := tracing.Start(ctx, "BandService.Update")
+	defer span.End()
+
+	if err := checkMembers(payload.Members); err != nil {
+		return nil, err
+	}
+
+	var band *Band
+
+	err := s.db.WithTx(ctx, func(tx db.IDb) error {
+		repository := s.repository.WithTx(tx)
+
+		var err error
+		band, err = repository.GetByID(ctx, id)
+		if err != nil {
+			return ErrBandNotFound
+		}
+		if !etag.Matches(version, band.Version) {
+			return ErrVersionConflict
+		}
+		before := ToBandResponse(band)
+
+		if payload.ExternalID != nil {
+			if *payload.ExternalID != "" {
+				if existing, err := repository.GetByExternalID(ctx, *payload.ExternalID); err == nil && existing.ID != id {
+					return ErrExternalIDTaken
+				}
+			}
+			band.ExternalID = convert.OptionalString(*payload.ExternalID)
+		}
+		if payload.Name != nil {
+			band.Name = *payload.Name
+		}
+		if payload.Description != nil {
+			band.Description = *payload.Description
+		}
+		if payload.Country != nil {
+			band.Country = *payload.Country
+		}
+		if payload.GenreIDs != nil {
+			if err := s.setGenres(ctx, tx, band, payload.GenreIDs); err != nil {
+				return err
+			}
+		}
+		if payload.Members != nil {
+			band.Members = toBandMembers(payload.Members)
+		}
+		if payload.Links != nil {
+			band.Links = toBandLinks(payload.Links)
+		}
+
+		if err := repository.Update(ctx, band); err != nil {
+			return err
+		}
+		return s.audit.Record(ctx, tx, audit.ActionUpdate, audit.EntityBand, id, before, ToBandResponse(band))
+	})
 	if err != nil {
-		return nil, errors.New("band not found")
+		return nil, err
 	}
 
-	if payload.Name != nil {
-		band.Name = *payload.Name
+	return ToBandResponse(band), nil
+}
+
+// Delete moves the band to trash if it still has the expected version. A band billed on concerts
+// is kept unless cascade is set, then it is taken out of their lineups
+func (s *BandService) Delete(ctx context.Context, id uint, version uint, cascade bool) error {
+	ctx, span := tracing.Start(ctx, "BandService.Delete")
+	defer span.End()
+
+	return s.db.WithTx(ctx, func(tx db.IDb) error {
+		repository := s.repository.WithTx(tx)
+
+		// Concerts being saved hold the band for share, the lock waits for them so that they are counted
+		if err := repository.LockForUpdate(ctx, id); err != nil {
+			return err
+		}
+		band, err := repository.GetByID(ctx, id)
+		if err != nil {
+			return ErrBandNotFound
+		}
+		if !etag.Matches(version, band.Version) {
+			return ErrVersionConflict
+		}
+		if err := checkConcerts(ctx, repository, id, false, cascade); err != nil {
+			return err
+		}
+		if err := repository.Delete(ctx, id, band.Version, cascade); err != nil {
+			return err
+		}
+		return s.audit.Record(ctx, tx, audit.ActionDelete, audit.EntityBand, id, ToBandResponse(band), nil)
+	})
+}
+
+func (s *BandService) GetByID(ctx context.Context, id uint) (*BandResponse, error) {
+	ctx, span := tracing.Start(ctx, "BandService.GetByID")
+	defer span.End()
+
+	band, err := s.repository.GetByID(ctx, id)
+	if err != nil {
+		return nil, ErrBandNotFound
 	}
-	if payload.Description != nil {
-		band.Description = *payload.Description
+
+	return ToBandResponse(band), nil
+}
+
+func (s *BandService) List(ctx context.Context, spec *query.Spec) (*ListBandsResponse, error) {
+	ctx, span := tracing.Start(ctx, "BandService.List")
+	defer span.End()
+
+	bands, meta, err := s.repository.List(ctx, spec)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ListBandsResponse{
+		Items:    ToBandResponses(bands),
+		PageMeta: meta,
+	}, nil
+}
+
+func (s *BandService) ListDeleted(ctx context.Context, spec *query.Spec) (*ListBandsResponse, error) {
+	ctx, span := tracing.Start(ctx, "BandService.ListDeleted")
+	defer span.End()
+
+	bands, meta, err := s.repository.ListDeleted(ctx, spec)
+	if err != nil {
+		return nil, err
 	}
 
-	err = s.repository.Update(band)
+	return &ListBandsResponse{
+		Items:    ToBandResponses(bands),
+		PageMeta: meta,
+	}, nil
+}
+
+// Restore brings the band back from trash, lineups it was taken out of are not restored
+func (s *BandService) Restore(ctx context.Context, id uint) (*BandResponse, error) {
+	ctx, span := tracing.Start(ctx, "BandService.Restore")
+	defer span.End()
+
+	var band *Band
+
+	err := s.db.WithTx(ctx, func(tx db.IDb) error {
+		repository := s.repository.WithTx(tx)
+
+		if _, err := repository.GetDeleted(ctx, id); err != nil {
+			return ErrBandNotInTrash
+		}
+		if err := repository.Restore(ctx, id); err != nil {
+			return err
+		}
+
+		var err error
+		band, err = repository.GetByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		return s.audit.Record(ctx, tx, audit.ActionRestore, audit.EntityBand, id, nil, ToBandResponse(band))
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	return band, nil
+	return ToBandResponse(band), nil
+}
+
+// Purge permanently removes a band from trash. Lineups, of deleted concerts too, block it unless cascade is set
+func (s *BandService) Purge(ctx context.Context, id uint, cascade bool) error {
+	ctx, span := tracing.Start(ctx, "BandService.Purge")
+	defer span.End()
+
+	return s.db.WithTx(ctx, func(tx db.IDb) error {
+		repository := s.repository.WithTx(tx)
+
+		if _, err := repository.GetDeleted(ctx, id); err != nil {
+			return ErrBandNotInTrash
+		}
+		if err := checkConcerts(ctx, repository, id, true, cascade); err != nil {
+			return err
+		}
+		if err := repository.Purge(ctx, id, cascade); err != nil {
+			return err
+		}
+		return s.audit.Record(ctx, tx, audit.ActionPurge, audit.EntityBand, id, nil, nil)
+	})
 }
 
-func (s *BandService) Delete(id uint) error {
-	return s.repository.Delete(id)
+// setGenres replaces band genres keeping the given order, the first one becomes the primary genre
+func (s *BandService) setGenres(ctx context.Context, tx db.IDb, band *Band, ids []uint) error {
+	found, err := s.genreRepo.WithTx(tx).GetByIDs(ctx, ids)
+	if err != nil {
+		return err
+	}
+
+	byID := make(map[uint]genres.Genre, len(found))
+	for _, genre := range found {
+		byID[genre.ID] = genre
+	}
+
+	band.Genres = make([]genres.Genre, 0, len(ids))
+	seen := make(map[uint]bool, len(ids))
+	for _, id := range ids {
+		genre, ok := byID[id]
+		if !ok {
+			return ErrGenreNotFound
+		}
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		band.Genres = append(band.Genres, genre)
+	}
+
+	band.Genre = ""
+	if len(band.Genres) > 0 {
+		band.Genre = band.Genres[0].Name
+	}
+	return nil
 }
 
-func (s *BandService) GetByID(id uint) (*Band, error) {
-	return s.repository.GetByID(id)
+func checkMembers(members []BandMemberRequest) error {
+	for _, member := range members {
+		if member.JoinedYear != nil && member.LeftYear != nil && *member.LeftYear < *member.JoinedYear {
+			return ErrMemberYears
+		}
+	}
+	return nil
 }
 
-func (s *BandService) List(page, pageSize int) ([]Band, error) {
-	if page < 1 {
-		page = 1
+// checkConcerts fails with ErrBandInUse when the band is billed on concerts and is not to be taken out of them
+func checkConcerts(ctx context.Context, repository IBandRepository, id uint, withTrashed bool, cascade bool) error {
+	if cascade {
+		return nil
+	}
+	count, err := repository.CountConcerts(ctx, id, withTrashed)
+	if err != nil {
+		return err
 	}
-	if pageSize < 1 || pageSize > 100 {
-		pageSize = 10
+	if count > 0 {
+		return ErrBandInUse
 	}
-	return s.repository.List(page, pageSize)
+	return nil
 }
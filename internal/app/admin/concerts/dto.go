@@ -1,47 +1,162 @@
 package concerts
 
 import (
+	"sort"
 	"time"
 
 	"github.com/serhiirubets/rubeticket/internal/app/admin/bands"
 	"github.com/serhiirubets/rubeticket/internal/app/admin/venues"
+	"github.com/serhiirubets/rubeticket/internal/pkg/query"
 )
 
 // @Description Concert response model
 type ConcertResponse struct {
-	ID          uint                 `json:"id"`
-	Title       string               `json:"title"`
-	Description string               `json:"description"`
-	PosterURL   string               `json:"posterUrl"`
-	Date        time.Time            `json:"date"`
-	VenueID     uint                 `json:"venueId"`
-	Venue       venues.VenueResponse `json:"venue"`
-	Bands       []bands.BandResponse `json:"bands"`
-	CreatedAt   time.Time            `json:"createdAt"`
-	UpdatedAt   time.Time            `json:"updatedAt"`
-}
-
-// @Description Create concert request
+	ID          uint                  `json:"id"`
+	ExternalID  *string               `json:"externalId,omitempty"`
+	Title       string                `json:"title"`
+	Description string                `json:"description"`
+	PosterURL   string                `json:"posterUrl"`
+	Date        time.Time             `json:"date"`
+	DoorsAt     *time.Time            `json:"doorsAt,omitempty"`
+	CurfewAt    *time.Time            `json:"curfewAt,omitempty"`
+	TimeZone    string                `json:"timeZone,omitempty"`
+	EventID     *uint                 `json:"eventId,omitempty"`
+	VenueID     uint                  `json:"venueId"`
+	Venue       *venues.VenueResponse `json:"venue,omitempty"`
+	Bands       []bands.BandResponse  `json:"bands,omitempty"`
+	Lineup      []LineupEntryResponse `json:"lineup,omitempty"`
+	Version     uint                  `json:"version"`
+	CreatedAt   time.Time             `json:"createdAt"`
+	UpdatedAt   time.Time             `json:"updatedAt"`
+	DeletedAt   *time.Time            `json:"deletedAt,omitempty"`
+}
+
+// @Description Lineup entry response model
+type LineupEntryResponse struct {
+	BandID   uint                `json:"bandId"`
+	Band     *bands.BandResponse `json:"band,omitempty"`
+	Position int                 `json:"position"`
+	Role     string              `json:"role"`
+	SetStart *time.Time          `json:"setStart,omitempty"`
+	SetEnd   *time.Time          `json:"setEnd,omitempty"`
+}
+
+// @Description Lineup entry, entries are billed in the order they are sent
+type LineupEntryRequest struct {
+	BandID   uint       `json:"bandId" validate:"required"`
+	Role     string     `json:"role" validate:"omitempty,oneof=headliner support"`
+	SetStart *time.Time `json:"setStart"`
+	SetEnd   *time.Time `json:"setEnd"`
+}
+
+// @Description Create concert request. Date is the show time, pass either bandIds (first one headlines) or lineup
 type CreateConcertRequest struct {
-	Title       string    `json:"title" validate:"required,max=100"`
-	Description string    `json:"description" validate:"max=300"`
-	PosterURL   string    `json:"posterUrl" validate:"max=100"`
-	Date        time.Time `json:"date" validate:"required"`
-	VenueID     uint      `json:"venueId" validate:"required"`
-	BandIDs     []uint    `json:"bandIds" validate:"required,min=1"`
+	ExternalID  string               `json:"externalId" validate:"max=100"`
+	Title       string               `json:"title" validate:"required,max=100"`
+	Description string               `json:"description" validate:"max=300"`
+	PosterURL   string               `json:"posterUrl" validate:"max=100"`
+	Date        time.Time            `json:"date" validate:"required"`
+	DoorsAt     *time.Time           `json:"doorsAt"`
+	CurfewAt    *time.Time           `json:"curfewAt"`
+	VenueID     uint                 `json:"venueId" validate:"required"`
+	BandIDs     []uint               `json:"bandIds" validate:"required_without=Lineup,omitempty,min=1"`
+	Lineup      []LineupEntryRequest `json:"lineup" validate:"required_without=BandIDs,omitempty,min=1,dive"`
 }
 
-// @Description Update concert request
+// @Description Update concert request. Omitted fields are kept, clearDoorsAt and clearCurfewAt remove the times
 type UpdateConcertRequest struct {
-	Title       *string    `json:"title" validate:"omitempty,max=100"`
-	Description *string    `json:"description" validate:"omitempty,max=300"`
-	PosterURL   *string    `json:"posterUrl" validate:"omitempty,max=100"`
-	Date        *time.Time `json:"date"`
-	VenueID     *uint      `json:"venueId"`
-	BandIDs     []uint     `json:"bandIds" validate:"omitempty,min=1"`
+	ExternalID    *string              `json:"externalId" validate:"omitempty,max=100"`
+	Title         *string              `json:"title" validate:"omitempty,max=100"`
+	Description   *string              `json:"description" validate:"omitempty,max=300"`
+	PosterURL     *string              `json:"posterUrl" validate:"omitempty,max=100"`
+	Date          *time.Time           `json:"date"`
+	DoorsAt       *time.Time           `json:"doorsAt" validate:"excluded_with=ClearDoorsAt"`
+	ClearDoorsAt  bool                 `json:"clearDoorsAt"`
+	CurfewAt      *time.Time           `json:"curfewAt" validate:"excluded_with=ClearCurfewAt"`
+	ClearCurfewAt bool                 `json:"clearCurfewAt"`
+	VenueID       *uint                `json:"venueId"`
+	BandIDs       []uint               `json:"bandIds" validate:"omitempty,min=1"`
+	Lineup        []LineupEntryRequest `json:"lineup" validate:"omitempty,min=1,dive"`
+}
+
+// @Description Concert near the requested point, distance is to the venue
+type NearbyConcertResponse struct {
+	ConcertResponse
+	DistanceKm float64 `json:"distanceKm"`
 }
 
 // @Description List concerts response
 type ListConcertsResponse struct {
 	Items []ConcertResponse `json:"items"`
+	query.PageMeta
+}
+
+// ToConcertResponse converts from Concert to ConcertResponse, relations are left out when not loaded.
+// When the venue is loaded times are shown in its time zone
+func ToConcertResponse(concert *Concert) *ConcertResponse {
+	loc := time.UTC
+	response := &ConcertResponse{
+		ID:          concert.Model.ID,
+		ExternalID:  concert.ExternalID,
+		Title:       concert.Title,
+		Description: concert.Description,
+		PosterURL:   concert.PosterURL,
+		EventID:     concert.EventID,
+		VenueID:     concert.VenueID,
+		Version:     concert.Version,
+		CreatedAt:   concert.Model.CreatedAt,
+		UpdatedAt:   concert.Model.UpdatedAt,
+	}
+	if concert.DeletedAt.Valid {
+		response.DeletedAt = &concert.DeletedAt.Time
+	}
+	if concert.Venue.Model != nil {
+		loc = concert.Venue.Location()
+		response.Venue = venues.ToVenueResponse(&concert.Venue)
+		response.TimeZone = loc.String()
+	}
+
+	response.Date = concert.Date.In(loc)
+	response.DoorsAt = inLocation(concert.DoorsAt, loc)
+	response.CurfewAt = inLocation(concert.CurfewAt, loc)
+
+	if concert.Bands != nil {
+		response.Bands = bands.ToBandResponses(concert.Bands)
+	}
+	if concert.Lineup != nil {
+		lineup := append([]ConcertBands{}, concert.Lineup...)
+		sort.SliceStable(lineup, func(i, j int) bool { return lineup[i].Position < lineup[j].Position })
+
+		response.Lineup = make([]LineupEntryResponse, len(lineup))
+		for i, entry := range lineup {
+			response.Lineup[i] = LineupEntryResponse{
+				BandID:   entry.BandID,
+				Position: entry.Position,
+				Role:     entry.Role,
+				SetStart: inLocation(entry.SetStart, loc),
+				SetEnd:   inLocation(entry.SetEnd, loc),
+			}
+			if entry.Band.Model != nil {
+				response.Lineup[i].Band = bands.ToBandResponse(&entry.Band)
+			}
+		}
+
+		if concert.Bands == nil {
+			response.Bands = make([]bands.BandResponse, 0, len(lineup))
+			for _, entry := range response.Lineup {
+				if entry.Band != nil {
+					response.Bands = append(response.Bands, *entry.Band)
+				}
+			}
+		}
+	}
+	return response
+}
+
+func inLocation(t *time.Time, loc *time.Location) *time.Time {
+	if t == nil {
+		return nil
+	}
+	local := t.In(loc)
+	return &local
 }
@@ -0,0 +1,20 @@
+package concerts
+
+import "github.com/serhiirubets/rubeticket/internal/pkg/apperr"
+
+var (
+	ErrInvalidID         = apperr.New(apperr.Invalid, "invalid_concert_id", "invalid concert ID")
+	ErrConcertNotFound   = apperr.New(apperr.NotFound, "concert_not_found", "concert not found")
+	ErrConcertNotInTrash = apperr.New(apperr.NotFound, "concert_not_in_trash", "concert not found in trash")
+	ErrVenueNotFound     = apperr.New(apperr.Invalid, "venue_not_found", "venue not found")
+	ErrBandNotFound      = apperr.New(apperr.Invalid, "band_not_found", "band not found")
+	ErrDuplicateBand     = apperr.New(apperr.Invalid, "duplicate_band", "band is listed more than once")
+	ErrDoorsAfterShow    = apperr.New(apperr.Invalid, "doors_after_show", "doors must open before the show starts")
+	ErrCurfewBeforeShow  = apperr.New(apperr.Invalid, "curfew_before_show", "curfew must be after the show starts")
+	ErrInvalidSetTime    = apperr.New(apperr.Invalid, "invalid_set_time", "set must end after it starts")
+	ErrSetOutsideConcert = apperr.New(apperr.Invalid, "set_outside_concert", "set must be between doors and curfew")
+	ErrVenueDoubleBooked = apperr.New(apperr.Conflict, "venue_double_booked", "venue is already booked at this time")
+	ErrVenueInTrash      = apperr.New(apperr.Conflict, "venue_in_trash", "venue of the concert is deleted")
+	ErrVersionConflict   = apperr.New(apperr.PreconditionFailed, "version_conflict", "concert was changed by someone else")
+	ErrExternalIDTaken   = apperr.New(apperr.Conflict, "external_id_taken", "external ID is used by another concert")
+)
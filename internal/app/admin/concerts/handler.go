@@ -5,8 +5,12 @@ import (
 	"strconv"
 
 	"github.com/serhiirubets/rubeticket/config"
+	"github.com/serhiirubets/rubeticket/internal/app/admin/audit"
 	"github.com/serhiirubets/rubeticket/internal/app/users"
+	"github.com/serhiirubets/rubeticket/internal/pkg/apperr"
+	"github.com/serhiirubets/rubeticket/internal/pkg/etag"
 	"github.com/serhiirubets/rubeticket/internal/pkg/log"
+	"github.com/serhiirubets/rubeticket/internal/pkg/query"
 	"github.com/serhiirubets/rubeticket/internal/pkg/req"
 	"github.com/serhiirubets/rubeticket/internal/pkg/res"
 )
@@ -38,6 +42,9 @@ func NewConcertHandler(router *http.ServeMux, deps *ConcertHandlerDeps) {
 	router.HandleFunc("DELETE /admin/concerts/{id}", handler.Delete())
 	router.HandleFunc("GET /admin/concerts/{id}", handler.GetByID())
 	router.HandleFunc("GET /admin/concerts", handler.List())
+	router.HandleFunc("GET /admin/concerts/trash", handler.ListTrash())
+	router.HandleFunc("POST /admin/concerts/trash/{id}/restore", handler.Restore())
+	router.HandleFunc("DELETE /admin/concerts/trash/{id}", handler.Purge())
 }
 
 // Create godoc
@@ -48,9 +55,10 @@ func NewConcertHandler(router *http.ServeMux, deps *ConcertHandlerDeps) {
 // @Produce json
 // @Param request body CreateConcertRequest true "Concert details"
 // @Success 201 {object} ConcertResponse
-// @Failure 400 {string} string "Bad request"
-// @Failure 401 {string} string "Unauthorized"
-// @Failure 403 {string} string "Forbidden"
+// @Failure 400 {object} res.Problem "Bad request"
+// @Failure 401 {object} res.Problem "Unauthorized"
+// @Failure 403 {object} res.Problem "Forbidden"
+// @Failure 409 {object} res.Problem "Venue is already booked at this time or external ID is taken"
 // @Router /admin/v1/concerts [post]
 func (h *ConcertHandler) Create() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -59,13 +67,16 @@ func (h *ConcertHandler) Create() http.HandlerFunc {
 			return
 		}
 
-		concert, err := h.Service.Create(payload)
+		concert, err := h.Service.Create(audit.WithActor(r), payload)
 		if err != nil {
-			h.Logger.Error("Failed to create concert", "error", err.Error())
-			res.Json(w, err.Error(), http.StatusBadRequest)
+			if apperr.IsInternal(err) {
+				h.Logger.WithContext(r.Context()).Error("Failed to create concert", "error", err.Error())
+			}
+			res.Error(w, r, err)
 			return
 		}
 
+		etag.Set(w, concert.Version)
 		res.Json(w, concert, http.StatusCreated)
 	}
 }
@@ -77,18 +88,29 @@ func (h *ConcertHandler) Create() http.HandlerFunc {
 // @Accept json
 // @Produce json
 // @Param id path int true "Concert ID"
+// @Param If-Match header string true "ETag from the last read, * to skip the check"
 // @Param request body UpdateConcertRequest true "Concert details"
 // @Success 200 {object} ConcertResponse
-// @Failure 400 {string} string "Bad request"
-// @Failure 401 {string} string "Unauthorized"
-// @Failure 403 {string} string "Forbidden"
-// @Failure 404 {string} string "Not found"
+// @Header 200 {string} ETag "Version of the concert"
+// @Failure 400 {object} res.Problem "Bad request"
+// @Failure 401 {object} res.Problem "Unauthorized"
+// @Failure 403 {object} res.Problem "Forbidden"
+// @Failure 404 {object} res.Problem "Not found"
+// @Failure 409 {object} res.Problem "Venue is already booked at this time or external ID is taken"
+// @Failure 412 {object} res.Problem "Concert was changed since it was read"
+// @Failure 428 {object} res.Problem "If-Match is missing"
 // @Router /admin/v1/concerts/{id} [put]
 func (h *ConcertHandler) Update() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		id, err := strconv.ParseUint(r.PathValue("id"), 10, 32)
 		if err != nil {
-			res.Json(w, "Invalid concert ID", http.StatusBadRequest)
+			res.Error(w, r, ErrInvalidID)
+			return
+		}
+
+		version, err := etag.IfMatch(r)
+		if err != nil {
+			res.Error(w, r, err)
 			return
 		}
 
@@ -97,44 +119,55 @@ func (h *ConcertHandler) Update() http.HandlerFunc {
 			return
 		}
 
-		concert, err := h.Service.Update(uint(id), payload)
+		concert, err := h.Service.Update(audit.WithActor(r), uint(id), version, payload)
 		if err != nil {
-			if err.Error() == "concert not found" {
-				res.Json(w, "Concert not found", http.StatusNotFound)
-				return
+			if apperr.IsInternal(err) {
+				h.Logger.WithContext(r.Context()).Error("Failed to update concert", "error", err.Error())
 			}
-			h.Logger.Error("Failed to update concert", "error", err.Error())
-			res.Json(w, err.Error(), http.StatusBadRequest)
+			res.Error(w, r, err)
 			return
 		}
 
+		etag.Set(w, concert.Version)
 		res.Json(w, concert, http.StatusOK)
 	}
 }
 
 // Delete godoc
 // @Summary Delete a concert
-// @Description Delete an existing concert
+// @Description Move a concert to trash
 // @Tags Admin/Concerts
 // @Produce json
 // @Param id path int true "Concert ID"
+// @Param If-Match header string true "ETag from the last read, * to skip the check"
 // @Success 204 "No Content"
-// @Failure 400 {string} string "Bad request"
-// @Failure 401 {string} string "Unauthorized"
-// @Failure 403 {string} string "Forbidden"
+// @Failure 400 {object} res.Problem "Bad request"
+// @Failure 401 {object} res.Problem "Unauthorized"
+// @Failure 403 {object} res.Problem "Forbidden"
+// @Failure 404 {object} res.Problem "Not found"
+// @Failure 412 {object} res.Problem "Concert was changed since it was read"
+// @Failure 428 {object} res.Problem "If-Match is missing"
 // @Router /admin/v1/concerts/{id} [delete]
 func (h *ConcertHandler) Delete() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		id, err := strconv.ParseUint(r.PathValue("id"), 10, 32)
 		if err != nil {
-			res.Json(w, "Invalid concert ID", http.StatusBadRequest)
+			res.Error(w, r, ErrInvalidID)
+			return
+		}
+
+		version, err := etag.IfMatch(r)
+		if err != nil {
+			res.Error(w, r, err)
 			return
 		}
 
-		err = h.Service.Delete(uint(id))
+		err = h.Service.Delete(audit.WithActor(r), uint(id), version)
 		if err != nil {
-			h.Logger.Error("Failed to delete concert", "error", err.Error())
-			res.Json(w, "Failed to delete concert", http.StatusInternalServerError)
+			if apperr.IsInternal(err) {
+				h.Logger.WithContext(r.Context()).Error("Failed to delete concert", "error", err.Error())
+			}
+			res.Error(w, r, err)
 			return
 		}
 
@@ -149,25 +182,27 @@ func (h *ConcertHandler) Delete() http.HandlerFunc {
 // @Produce json
 // @Param id path int true "Concert ID"
 // @Success 200 {object} ConcertResponse
-// @Failure 400 {string} string "Bad request"
-// @Failure 401 {string} string "Unauthorized"
-// @Failure 403 {string} string "Forbidden"
-// @Failure 404 {string} string "Not found"
+// @Header 200 {string} ETag "Version of the concert, send it as If-Match to update or delete"
+// @Failure 400 {object} res.Problem "Bad request"
+// @Failure 401 {object} res.Problem "Unauthorized"
+// @Failure 403 {object} res.Problem "Forbidden"
+// @Failure 404 {object} res.Problem "Not found"
 // @Router /admin/v1/concerts/{id} [get]
 func (h *ConcertHandler) GetByID() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		id, err := strconv.ParseUint(r.PathValue("id"), 10, 32)
 		if err != nil {
-			res.Json(w, "Invalid concert ID", http.StatusBadRequest)
+			res.Error(w, r, ErrInvalidID)
 			return
 		}
 
-		concert, err := h.Service.GetByID(uint(id))
+		concert, err := h.Service.GetByID(r.Context(), uint(id))
 		if err != nil {
-			res.Json(w, "Concert not found", http.StatusNotFound)
+			res.Error(w, r, err)
 			return
 		}
 
+		etag.Set(w, concert.Version)
 		res.Json(w, concert, http.StatusOK)
 	}
 }
@@ -179,22 +214,137 @@ func (h *ConcertHandler) GetByID() http.HandlerFunc {
 // @Produce json
 // @Param page query int false "Page number (default: 1)"
 // @Param pageSize query int false "Page size (default: 10, max: 100)"
+// @Param sort query string false "Comma separated sort fields, prefix with - for descending"
+// @Param q query string false "Search term"
+// @Param cursor query string false "nextCursor from the previous page, replaces page"
+// @Param include query string false "Relations to load: venue,bands"
 // @Success 200 {object} ListConcertsResponse
-// @Failure 401 {string} string "Unauthorized"
-// @Failure 403 {string} string "Forbidden"
+// @Failure 400 {object} res.Problem "Unsupported filter or sort field"
+// @Failure 401 {object} res.Problem "Unauthorized"
+// @Failure 403 {object} res.Problem "Forbidden"
 // @Router /admin/v1/concerts [get]
 func (h *ConcertHandler) List() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
-		pageSize, _ := strconv.Atoi(r.URL.Query().Get("pageSize"))
+		spec, err := query.ParseSpec(r.URL.Query(), ConcertListSchema)
+		if err != nil {
+			res.Error(w, r, err)
+			return
+		}
+
+		concerts, err := h.Service.List(r.Context(), spec)
+		if err != nil {
+			if apperr.IsInternal(err) {
+				h.Logger.WithContext(r.Context()).Error("Failed to list concerts", "error", err.Error())
+			}
+			res.Error(w, r, err)
+			return
+		}
+
+		res.Json(w, concerts, http.StatusOK)
+	}
+}
+
+// ListTrash godoc
+// @Summary List deleted concerts
+// @Description Get a paginated list of concerts in trash
+// @Tags Admin/Concerts
+// @Produce json
+// @Param page query int false "Page number (default: 1)"
+// @Param pageSize query int false "Page size (default: 10, max: 100)"
+// @Param sort query string false "Comma separated sort fields, prefix with - for descending, deletedAt is supported"
+// @Param q query string false "Search term"
+// @Param cursor query string false "nextCursor from the previous page, replaces page"
+// @Param include query string false "Comma separated: venue, bands, lineup"
+// @Success 200 {object} ListConcertsResponse
+// @Failure 400 {object} res.Problem "Unsupported filter or sort field"
+// @Failure 401 {object} res.Problem "Unauthorized"
+// @Failure 403 {object} res.Problem "Forbidden"
+// @Router /admin/v1/concerts/trash [get]
+func (h *ConcertHandler) ListTrash() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		spec, err := query.ParseSpec(r.URL.Query(), ConcertTrashSchema)
+		if err != nil {
+			res.Error(w, r, err)
+			return
+		}
 
-		concerts, err := h.Service.List(page, pageSize)
+		concerts, err := h.Service.ListDeleted(r.Context(), spec)
 		if err != nil {
-			h.Logger.Error("Failed to list concerts", "error", err.Error())
-			res.Json(w, "Failed to list concerts", http.StatusInternalServerError)
+			if apperr.IsInternal(err) {
+				h.Logger.WithContext(r.Context()).Error("Failed to list deleted concerts", "error", err.Error())
+			}
+			res.Error(w, r, err)
 			return
 		}
 
 		res.Json(w, concerts, http.StatusOK)
 	}
 }
+
+// Restore godoc
+// @Summary Restore a deleted concert
+// @Description Bring a concert back from trash. Its venue must not be deleted and must be free at that time
+// @Tags Admin/Concerts
+// @Produce json
+// @Param id path int true "Concert ID"
+// @Success 200 {object} ConcertResponse
+// @Failure 400 {object} res.Problem "Bad request"
+// @Failure 401 {object} res.Problem "Unauthorized"
+// @Failure 403 {object} res.Problem "Forbidden"
+// @Failure 404 {object} res.Problem "Not found in trash"
+// @Failure 409 {object} res.Problem "Venue is deleted or booked"
+// @Router /admin/v1/concerts/trash/{id}/restore [post]
+func (h *ConcertHandler) Restore() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseUint(r.PathValue("id"), 10, 32)
+		if err != nil {
+			res.Error(w, r, ErrInvalidID)
+			return
+		}
+
+		concert, err := h.Service.Restore(audit.WithActor(r), uint(id))
+		if err != nil {
+			if apperr.IsInternal(err) {
+				h.Logger.WithContext(r.Context()).Error("Failed to restore concert", "error", err.Error())
+			}
+			res.Error(w, r, err)
+			return
+		}
+
+		etag.Set(w, concert.Version)
+		res.Json(w, concert, http.StatusOK)
+	}
+}
+
+// Purge godoc
+// @Summary Permanently delete a concert
+// @Description Remove a concert from trash for good together with its lineup
+// @Tags Admin/Concerts
+// @Produce json
+// @Param id path int true "Concert ID"
+// @Success 204 "No Content"
+// @Failure 400 {object} res.Problem "Bad request"
+// @Failure 401 {object} res.Problem "Unauthorized"
+// @Failure 403 {object} res.Problem "Forbidden"
+// @Failure 404 {object} res.Problem "Not found in trash"
+// @Router /admin/v1/concerts/trash/{id} [delete]
+func (h *ConcertHandler) Purge() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseUint(r.PathValue("id"), 10, 32)
+		if err != nil {
+			res.Error(w, r, ErrInvalidID)
+			return
+		}
+
+		err = h.Service.Purge(audit.WithActor(r), uint(id))
+		if err != nil {
+			if apperr.IsInternal(err) {
+				h.Logger.WithContext(r.Context()).Error("Failed to purge concert", "error", err.Error())
+			}
+			res.Error(w, r, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
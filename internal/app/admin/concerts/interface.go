@@ -1,10 +1,19 @@
 package concerts
 
+import (
+	"context"
+
+	"github.com/serhiirubets/rubeticket/internal/pkg/query"
+)
+
 // IConcertService определяет интерфейс для сервиса концертов.
 type IConcertService interface {
-	Create(request *CreateConcertRequest) (*ConcertResponse, error)
-	Update(id uint, request *UpdateConcertRequest) (*ConcertResponse, error)
-	Delete(id uint) error
-	GetByID(id uint) (*ConcertResponse, error)
-	List(page, pageSize int) (*ListConcertsResponse, error)
+	Create(ctx context.Context, request *CreateConcertRequest) (*ConcertResponse, error)
+	Update(ctx context.Context, id uint, version uint, request *UpdateConcertRequest) (*ConcertResponse, error)
+	Delete(ctx context.Context, id uint, version uint) error
+	GetByID(ctx context.Context, id uint) (*ConcertResponse, error)
+	List(ctx context.Context, spec *query.Spec) (*ListConcertsResponse, error)
+	ListDeleted(ctx context.Context, spec *query.Spec) (*ListConcertsResponse, error)
+	Restore(ctx context.Context, id uint) (*ConcertResponse, error)
+	Purge(ctx context.Context, id uint) error
 }
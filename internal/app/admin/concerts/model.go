@@ -8,16 +8,48 @@ import (
 	"gorm.io/gorm"
 )
 
+const (
+	RoleHeadliner = "headliner"
+	RoleSupport   = "support"
+
+	// DefaultDuration is how long a concert occupies the venue when it has no curfew
+	DefaultDuration = 4 * time.Hour
+)
+
 // @Description Concert model
 type Concert struct {
 	*gorm.Model
-	Title       string       `json:"title" gorm:"type:varchar(100);not null"`
-	Description string       `json:"description" gorm:"type:varchar(300)"`
-	PosterURL   string       `json:"posterUrl" gorm:"type:varchar(100)"`
-	Date        time.Time    `json:"date" gorm:"not null;index:idx_concert_date"`
-	VenueID     uint         `json:"venueId" gorm:"not null;index:idx_concert_venue_id"`
-	Venue       venues.Venue `json:"venue"`
-	Bands       []bands.Band `json:"bands" gorm:"many2many:concert_bands;"`
+	Title       string `json:"title" gorm:"type:varchar(100);not null"`
+	Description string `json:"description" gorm:"type:varchar(300)"`
+	PosterURL   string `json:"posterUrl" gorm:"type:varchar(100)"`
+	// Date is when the show starts
+	Date     time.Time  `json:"date" gorm:"not null;index:idx_concert_date"`
+	DoorsAt  *time.Time `json:"doorsAt"`
+	CurfewAt *time.Time `json:"curfewAt"`
+	// EventID is the festival or tour the concert is part of
+	EventID *uint        `json:"eventId" gorm:"index:idx_concert_event_id"`
+	VenueID uint         `json:"venueId" gorm:"not null;index:idx_concert_venue_id"`
+	Venue   venues.Venue `json:"venue"`
+	Bands   []bands.Band `json:"bands" gorm:"many2many:concert_bands;"`
+	// Lineup is the same join table with billing and set times
+	Lineup []ConcertBands `json:"lineup" gorm:"foreignKey:ConcertID"`
+	// ExternalID is the key of the concert in the system it was imported from
+	ExternalID *string `json:"externalId" gorm:"type:varchar(100);uniqueIndex:idx_concert_external_id"`
+	// Version is bumped on every write and sent as the ETag
+	Version uint `json:"version" gorm:"not null;default:1"`
+}
+
+// Occupies returns the time the venue is taken by the concert, from doors to curfew
+func (c *Concert) Occupies() (time.Time, time.Time) {
+	start := c.Date
+	if c.DoorsAt != nil {
+		start = *c.DoorsAt
+	}
+	end := c.Date.Add(DefaultDuration)
+	if c.CurfewAt != nil {
+		end = *c.CurfewAt
+	}
+	return start, end
 }
 
 // ConcertBands many to many relation model
@@ -25,4 +57,10 @@ type Concert struct {
 type ConcertBands struct {
 	ConcertID uint `json:"concertId" gorm:"primaryKey;index:idx_concert_bands"`
 	BandID    uint `json:"bandId" gorm:"primaryKey;index:idx_concert_bands"`
+	// Position is the billing order starting from 1
+	Position int        `json:"position" gorm:"not null;default:0"`
+	Role     string     `json:"role" gorm:"type:varchar(20);not null;default:support"`
+	SetStart *time.Time `json:"setStart"`
+	SetEnd   *time.Time `json:"setEnd"`
+	Band     bands.Band `json:"band" gorm:"foreignKey:BandID"`
 }
@@ -1,17 +1,82 @@
 package concerts
 
 import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
 	"github.com/serhiirubets/rubeticket/internal/pkg/db"
+	"github.com/serhiirubets/rubeticket/internal/pkg/query"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// exportBatchSize is how many concerts are read at once while exporting
+const exportBatchSize = 500
+
+// ConcertListSchema lists fields concerts can be filtered, sorted and searched by
+var ConcertListSchema = query.Schema{
+	Filters: map[string]query.Field{
+		"title":   {Column: "title", Ops: []query.Operator{query.Eq, query.Like}},
+		"venueId": {Column: "venue_id", Ops: []query.Operator{query.Eq, query.In}, Type: query.Int},
+		"date":    {Column: "date", Ops: []query.Operator{query.Eq, query.Gt, query.Gte, query.Lt, query.Lte}, Type: query.Time},
+	},
+	Sort: map[string]string{
+		"id":        "id",
+		"title":     "title",
+		"date":      "date",
+		"createdAt": "created_at",
+	},
+	Search:      []string{"title", "description"},
+	DefaultSort: "id",
+	Includes: map[string]string{
+		"venue":  "Venue",
+		"bands":  "Bands",
+		"lineup": "Lineup.Band",
+	},
+}
+
+// ConcertTrashSchema is ConcertListSchema for deleted concerts, also sortable by deletedAt
+var ConcertTrashSchema = ConcertListSchema.WithSort("deletedAt", "deleted_at")
+
 type IConcertRepository interface {
-	Create(concert *Concert) (*Concert, error)
-	Update(concert *Concert) error
-	Delete(id uint) error
-	GetByID(id uint) (*Concert, error)
-	List(page, pageSize int) ([]Concert, error)
+	Create(ctx context.Context, concert *Concert) (*Concert, error)
+	Update(ctx context.Context, concert *Concert) error
+	Delete(ctx context.Context, id uint, version uint) error
+	GetByExternalID(ctx context.Context, externalID string) (*Concert, error)
+	GetByID(ctx context.Context, id uint) (*Concert, error)
+	List(ctx context.Context, spec *query.Spec) ([]Concert, query.PageMeta, error)
+	Export(ctx context.Context, spec *query.Spec, fn func(concerts []Concert) error) error
+	HasOverlap(ctx context.Context, concert *Concert) (bool, error)
+	ListNear(ctx context.Context, near *NearQuery) ([]ConcertDistance, error)
+	ListByBand(ctx context.Context, bandID uint, upcoming bool, limit int) ([]Concert, error)
+	GetDeleted(ctx context.Context, id uint) (*Concert, error)
+	ListDeleted(ctx context.Context, spec *query.Spec) ([]Concert, query.PageMeta, error)
+	Restore(ctx context.Context, id uint) error
+	Purge(ctx context.Context, id uint) error
+	WithTx(tx db.IDb) IConcertRepository
 }
 
+// NearQuery selects upcoming concerts within RadiusKm of a point
+type NearQuery struct {
+	Latitude  float64
+	Longitude float64
+	RadiusKm  float64
+	Limit     int
+}
+
+type ConcertDistance struct {
+	Concert    Concert
+	DistanceKm float64
+}
+
+// earthRadiusKm and kmPerDegree are used for the haversine distance and the bounding box
+const (
+	earthRadiusKm = 6371.0
+	kmPerDegree   = 111.045
+)
+
 type ConcertRepository struct {
 	Db db.IDb
 }
@@ -20,34 +85,243 @@ func NewConcertRepository(Db db.IDb) IConcertRepository {
 	return &ConcertRepository{Db: Db}
 }
 
-func (r *ConcertRepository) Create(concert *Concert) (*Concert, error) {
-	if err := r.Db.Create(concert).Error; err != nil {
+// WithTx returns a copy of the repository that runs queries in tx
+func (r *ConcertRepository) WithTx(tx db.IDb) IConcertRepository {
+	return &ConcertRepository{Db: tx}
+}
+
+// Create inserts the concert and its lineup, call it in a transaction to keep both in sync
+func (r *ConcertRepository) Create(ctx context.Context, concert *Concert) (*Concert, error) {
+	if err := r.Db.WithContext(ctx).Omit(clause.Associations).Create(concert).Error; err != nil {
+		return nil, err
+	}
+	if err := r.saveLineup(ctx, concert); err != nil {
 		return nil, err
 	}
 	return concert, nil
 }
 
-func (r *ConcertRepository) Update(concert *Concert) error {
-	return r.Db.Save(concert).Error
+// Update saves the concert when nobody changed it since it was read and replaces its lineup.
+// Call it in a transaction
+func (r *ConcertRepository) Update(ctx context.Context, concert *Concert) error {
+	updated, err := db.SaveVersioned(r.Db.WithContext(ctx), concert, &concert.Version)
+	if err != nil {
+		return err
+	}
+	if !updated {
+		return ErrVersionConflict
+	}
+	if err := r.Db.WithContext(ctx).Where("concert_id = ?", concert.ID).Delete(&ConcertBands{}).Error; err != nil {
+		return err
+	}
+	return r.saveLineup(ctx, concert)
 }
 
-func (r *ConcertRepository) Delete(id uint) error {
-	return r.Db.Delete(&Concert{}, id).Error
+func (r *ConcertRepository) saveLineup(ctx context.Context, concert *Concert) error {
+	if len(concert.Lineup) == 0 {
+		return nil
+	}
+	for i := range concert.Lineup {
+		concert.Lineup[i].ConcertID = concert.ID
+	}
+	return r.Db.WithContext(ctx).Omit("Band").Create(&concert.Lineup).Error
 }
 
-func (r *ConcertRepository) GetByID(id uint) (*Concert, error) {
+// HasOverlap reports whether another concert at the same venue overlaps the doors to curfew window
+func (r *ConcertRepository) HasOverlap(ctx context.Context, concert *Concert) (bool, error) {
+	start, end := concert.Occupies()
+
+	q := r.Db.WithContext(ctx).Model(&Concert{}).
+		Where("venue_id = ?", concert.VenueID).
+		Where("coalesce(doors_at, date) < ?", end).
+		Where("coalesce(curfew_at, date + ?::interval) > ?", fmt.Sprintf("%d seconds", int(DefaultDuration.Seconds())), start)
+	if concert.Model != nil && concert.ID != 0 {
+		q = q.Where("id <> ?", concert.ID)
+	}
+
+	var count int64
+	if err := q.Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// Delete soft deletes the concert if it still has the given version
+func (r *ConcertRepository) Delete(ctx context.Context, id uint, version uint) error {
+	result := r.Db.WithContext(ctx).Model(&Concert{}).
+		Where("id = ? AND version = ?", id, version).
+		Updates(map[string]interface{}{"deleted_at": time.Now(), "version": gorm.Expr("version + 1")})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrVersionConflict
+	}
+	return nil
+}
+
+// GetByID loads the concert with its venue and lineup
+func (r *ConcertRepository) GetByID(ctx context.Context, id uint) (*Concert, error) {
+	var concert Concert
+	if err := r.Db.WithContext(ctx).Preload("Venue").Preload("Lineup.Band").First(&concert, id).Error; err != nil {
+		return nil, err
+	}
+	return &concert, nil
+}
+
+// Export passes all concerts with venue and lineup matching spec filters to fn in batches, by id. Pagination is ignored
+func (r *ConcertRepository) Export(ctx context.Context, spec *query.Spec, fn func(concerts []Concert) error) error {
+	var batch []Concert
+	return spec.Where(r.Db.WithContext(ctx).Model(&Concert{}), ConcertListSchema).
+		Preload("Venue").
+		Preload("Lineup.Band").
+		FindInBatches(&batch, exportBatchSize, func(tx *gorm.DB, _ int) error {
+			return fn(batch)
+		}).Error
+}
+
+// GetByExternalID finds the concert with the external ID, deleted ones included
+func (r *ConcertRepository) GetByExternalID(ctx context.Context, externalID string) (*Concert, error) {
 	var concert Concert
-	if err := r.Db.First(&concert, id).Error; err != nil {
+	if err := r.Db.WithContext(ctx).Unscoped().Where("external_id = ?", externalID).First(&concert).Error; err != nil {
 		return nil, err
 	}
 	return &concert, nil
 }
 
-func (r *ConcertRepository) List(page, pageSize int) ([]Concert, error) {
+// List returns one page of concerts matching spec, by offset or by cursor. Venue and bands are loaded only when included
+func (r *ConcertRepository) List(ctx context.Context, spec *query.Spec) ([]Concert, query.PageMeta, error) {
 	var concerts []Concert
-	offset := (page - 1) * pageSize
-	if err := r.Db.Offset(offset).Limit(pageSize).Find(&concerts).Error; err != nil {
+	var total int64
+
+	filtered := spec.Where(r.Db.WithContext(ctx).Model(&Concert{}), ConcertListSchema).Session(&gorm.Session{})
+	if err := filtered.Count(&total).Error; err != nil {
+		return nil, query.PageMeta{}, err
+	}
+	if err := spec.Preload(spec.Paginate(filtered)).Find(&concerts).Error; err != nil {
+		return nil, query.PageMeta{}, err
+	}
+	return query.NewPage(filtered, spec, concerts, total)
+}
+
+// ListNear returns upcoming concerts by venue distance, nearest first. A bounding box on
+// venue coordinates narrows rows before the haversine distance is computed
+func (r *ConcertRepository) ListNear(ctx context.Context, near *NearQuery) ([]ConcertDistance, error) {
+	distance := `? * 2 * asin(least(1, sqrt(
+		power(sin(radians(v.latitude - ?) / 2), 2) +
+		cos(radians(?)) * cos(radians(v.latitude)) * power(sin(radians(v.longitude - ?) / 2), 2))))`
+
+	latDelta := near.RadiusKm / kmPerDegree
+	q := r.Db.WithContext(ctx).
+		Table("concerts c").
+		Select("c.id, "+distance+" AS distance_km", earthRadiusKm, near.Latitude, near.Latitude, near.Longitude).
+		Joins("JOIN venues v ON v.id = c.venue_id AND v.deleted_at IS NULL").
+		Where("c.deleted_at IS NULL AND c.date >= ?", time.Now()).
+		Where("v.latitude BETWEEN ? AND ?", near.Latitude-latDelta, near.Latitude+latDelta)
+
+	// the longitude box is skipped near the poles and the antimeridian, where it would not be a simple range
+	if cos := math.Cos(near.Latitude * math.Pi / 180); cos > 0.01 {
+		lngDelta := near.RadiusKm / (kmPerDegree * cos)
+		if near.Longitude-lngDelta >= -180 && near.Longitude+lngDelta <= 180 {
+			q = q.Where("v.longitude BETWEEN ? AND ?", near.Longitude-lngDelta, near.Longitude+lngDelta)
+		}
+	}
+
+	var rows []struct {
+		ID         uint
+		DistanceKm float64
+	}
+	err := r.Db.WithContext(ctx).
+		Table("(?) AS nearby", q).
+		Where("distance_km <= ?", near.RadiusKm).
+		Order("distance_km").
+		Limit(near.Limit).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return []ConcertDistance{}, nil
+	}
+
+	ids := make([]uint, len(rows))
+	for i, row := range rows {
+		ids[i] = row.ID
+	}
+
+	var found []Concert
+	if err := r.Db.WithContext(ctx).Preload("Venue").Preload("Lineup.Band").Where("id IN ?", ids).Find(&found).Error; err != nil {
+		return nil, err
+	}
+	byID := make(map[uint]Concert, len(found))
+	for _, concert := range found {
+		byID[concert.ID] = concert
+	}
+
+	result := make([]ConcertDistance, 0, len(rows))
+	for _, row := range rows {
+		if concert, ok := byID[row.ID]; ok {
+			result = append(result, ConcertDistance{Concert: concert, DistanceKm: row.DistanceKm})
+		}
+	}
+	return result, nil
+}
+
+// ListByBand returns concerts the band plays, upcoming ones soonest first and past ones latest first
+func (r *ConcertRepository) ListByBand(ctx context.Context, bandID uint, upcoming bool, limit int) ([]Concert, error) {
+	q := r.Db.WithContext(ctx).
+		Preload("Venue").
+		Preload("Lineup.Band").
+		Where("id IN (SELECT concert_id FROM concert_bands WHERE band_id = ?)", bandID)
+	if upcoming {
+		q = q.Where("date >= ?", time.Now()).Order("date")
+	} else {
+		q = q.Where("date < ?", time.Now()).Order("date DESC")
+	}
+
+	var concerts []Concert
+	if err := q.Limit(limit).Find(&concerts).Error; err != nil {
 		return nil, err
 	}
 	return concerts, nil
 }
+
+// GetDeleted loads a deleted concert with its lineup, the venue is loaded even when deleted too
+func (r *ConcertRepository) GetDeleted(ctx context.Context, id uint) (*Concert, error) {
+	var concert Concert
+	if err := query.Trashed(r.Db.WithContext(ctx)).Preload("Venue").Preload("Lineup.Band").First(&concert, id).Error; err != nil {
+		return nil, err
+	}
+	return &concert, nil
+}
+
+// ListDeleted returns one page of deleted concerts matching spec
+func (r *ConcertRepository) ListDeleted(ctx context.Context, spec *query.Spec) ([]Concert, query.PageMeta, error) {
+	var concerts []Concert
+	var total int64
+
+	filtered := spec.Where(query.Trashed(r.Db.WithContext(ctx).Model(&Concert{})), ConcertTrashSchema).Session(&gorm.Session{})
+	if err := filtered.Count(&total).Error; err != nil {
+		return nil, query.PageMeta{}, err
+	}
+	if err := spec.Preload(spec.Paginate(filtered)).Find(&concerts).Error; err != nil {
+		return nil, query.PageMeta{}, err
+	}
+	return query.NewPage(filtered, spec, concerts, total)
+}
+
+func (r *ConcertRepository) Restore(ctx context.Context, id uint) error {
+	return r.Db.WithContext(ctx).Unscoped().Model(&Concert{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{"deleted_at": nil, "version": gorm.Expr("version + 1")}).Error
+}
+
+// Purge removes the concert and its lineup permanently, pass entries go with it by foreign key.
+// Call it in a transaction
+func (r *ConcertRepository) Purge(ctx context.Context, id uint) error {
+	conn := r.Db.WithContext(ctx)
+	if err := conn.Where("concert_id = ?", id).Delete(&ConcertBands{}).Error; err != nil {
+		return err
+	}
+	return conn.Unscoped().Delete(&Concert{}, id).Error
+}
@@ -1,214 +1,471 @@
 package concerts
 
 import (
-	"errors"
+	"context"
+	"database/sql"
+	"math"
 
+	"github.com/serhiirubets/rubeticket/internal/app/admin/audit"
 	"github.com/serhiirubets/rubeticket/internal/app/admin/bands"
 	"github.com/serhiirubets/rubeticket/internal/app/admin/venues"
+	"github.com/serhiirubets/rubeticket/internal/pkg/convert"
+	"github.com/serhiirubets/rubeticket/internal/pkg/db"
+	"github.com/serhiirubets/rubeticket/internal/pkg/etag"
+	"github.com/serhiirubets/rubeticket/internal/pkg/query"
+	"github.com/serhiirubets/rubeticket/internal/pkg/tracing"
 )
 
 type ConcertService struct {
+	db         db.IDb
 	repository IConcertRepository
 	venueRepo  venues.IVenueRepository
 	bandRepo   bands.IBandRepository
+	audit      *audit.Recorder
+	// txMaxRetries is how many times a booking is retried after a serialization failure
+	txMaxRetries int
 }
 
-func NewConcertService(repository IConcertRepository, venueRepo venues.IVenueRepository, bandRepo bands.IBandRepository) *ConcertService {
+func NewConcertService(db db.IDb, repository IConcertRepository, venueRepo venues.IVenueRepository, bandRepo bands.IBandRepository, auditRecorder *audit.Recorder, txMaxRetries int) *ConcertService {
 	return &ConcertService{
-		repository: repository,
-		venueRepo:  venueRepo,
-		bandRepo:   bandRepo,
+		db:           db,
+		repository:   repository,
+		venueRepo:    venueRepo,
+		bandRepo:     bandRepo,
+		audit:        auditRecorder,
+		txMaxRetries: txMaxRetries,
 	}
 }
 
-func (s *ConcertService) Create(payload *CreateConcertRequest) (*ConcertResponse, error) {
-	// check if venue exists
-	venue, err := s.venueRepo.GetByID(payload.VenueID)
-	if err != nil {
-		return nil, errors.New("venue not found")
+// WithTx returns a copy of the service that runs in tx, its own transactions become savepoints
+func (s *ConcertService) WithTx(tx db.IDb) *ConcertService {
+	return &ConcertService{
+		db:           tx,
+		repository:   s.repository.WithTx(tx),
+		venueRepo:    s.venueRepo.WithTx(tx),
+		bandRepo:     s.bandRepo.WithTx(tx),
+		audit:        s.audit,
+		txMaxRetries: s.txMaxRetries,
 	}
+}
+
+func (s *ConcertService) Create(ctx context.Context, payload *CreateConcertRequest) (*ConcertResponse, error) {
+	ctx, span := tracing.Start(ctx, "ConcertService.Create")
+	defer span.End()
+
+	var createdConcert *Concert
 
-	// check if all bands exist
-	var bandsList []bands.Band
-	for _, bandID := range payload.BandIDs {
-		band, err := s.bandRepo.GetByID(bandID)
+	// venue and bands are read for share, so they cannot be deleted until the concert is saved
+	err := s.bookingTx(ctx, func(tx db.IDb) error {
+		if payload.ExternalID != "" {
+			if _, err := s.repository.WithTx(tx).GetByExternalID(ctx, payload.ExternalID); err == nil {
+				return ErrExternalIDTaken
+			}
+		}
+
+		venue, err := s.venueRepo.WithTx(tx).GetByIDForShare(ctx, payload.VenueID)
 		if err != nil {
-			return nil, errors.New("band not found")
+			return ErrVenueNotFound
 		}
-		bandsList = append(bandsList, *band)
-	}
 
-	concert := &Concert{
-		Title:       payload.Title,
-		Description: payload.Description,
-		PosterURL:   payload.PosterURL,
-		Date:        payload.Date,
-		VenueID:     payload.VenueID,
-		Venue:       *venue,
-		Bands:       bandsList,
-	}
+		entries := payload.Lineup
+		if entries == nil {
+			entries = lineupFromBandIDs(payload.BandIDs)
+		}
+		lineup, err := s.buildLineup(ctx, tx, entries)
+		if err != nil {
+			return err
+		}
+
+		concert := &Concert{
+			ExternalID:  convert.OptionalString(payload.ExternalID),
+			Title:       payload.Title,
+			Description: payload.Description,
+			PosterURL:   payload.PosterURL,
+			Date:        payload.Date,
+			DoorsAt:     payload.DoorsAt,
+			CurfewAt:    payload.CurfewAt,
+			VenueID:     payload.VenueID,
+			Venue:       *venue,
+			Lineup:      lineup,
+		}
+
+		if err := s.checkSchedule(ctx, tx, concert); err != nil {
+			return err
+		}
 
-	createdConcert, err := s.repository.Create(concert)
+		createdConcert, err = s.repository.WithTx(tx).Create(ctx, concert)
+		if err != nil {
+			return err
+		}
+		return s.audit.Record(ctx, tx, audit.ActionCreate, audit.EntityConcert, createdConcert.ID, nil, ToConcertResponse(createdConcert))
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	bandResponses := make([]bands.BandResponse, len(createdConcert.Bands))
-	for i, band := range createdConcert.Bands {
-		bandResponse := bands.ToBandResponse(&band)
-		bandResponses[i] = *bandResponse
-	}
+	return ToConcertResponse(createdConcert), nil
+}
+
+// Update changes the concert if it still has the expected version, etag.Any skips the check
+func (s *ConcertService) Update(ctx context.Context, id uint, version uint, payload *UpdateConcertRequest) (*ConcertResponse, error) {
+	ctx, span := tracing.Start(ctx, "ConcertService.Update")
+	defer span.End()
 
-	response := &ConcertResponse{
-		ID:          createdConcert.Model.ID,
-		Title:       createdConcert.Title,
-		Description: createdConcert.Description,
-		PosterURL:   createdConcert.PosterURL,
-		Date:        createdConcert.Date,
-		VenueID:     createdConcert.VenueID,
-		Venue:       *venues.ToVenueResponse(&createdConcert.Venue),
-		Bands:       bandResponses,
-		CreatedAt:   createdConcert.Model.CreatedAt,
-		UpdatedAt:   createdConcert.Model.UpdatedAt,
+	var concert *Concert
+
+	err := s.bookingTx(ctx, func(tx db.IDb) error {
+		repository := s.repository.WithTx(tx)
+
+		var err error
+		concert, err = repository.GetByID(ctx, id)
+		if err != nil {
+			return ErrConcertNotFound
+		}
+		if !etag.Matches(version, concert.Version) {
+			return ErrVersionConflict
+		}
+		before := ToConcertResponse(concert)
+
+		if payload.ExternalID != nil {
+			if *payload.ExternalID != "" {
+				if existing, err := repository.GetByExternalID(ctx, *payload.ExternalID); err == nil && existing.ID != id {
+					return ErrExternalIDTaken
+				}
+			}
+			concert.ExternalID = convert.OptionalString(*payload.ExternalID)
+		}
+		if payload.Title != nil {
+			concert.Title = *payload.Title
+		}
+		if payload.Description != nil {
+			concert.Description = *payload.Description
+		}
+		if payload.PosterURL != nil {
+			concert.PosterURL = *payload.PosterURL
+		}
+		if payload.Date != nil {
+			concert.Date = *payload.Date
+		}
+		if payload.DoorsAt != nil {
+			concert.DoorsAt = payload.DoorsAt
+		} else if payload.ClearDoorsAt {
+			concert.DoorsAt = nil
+		}
+		if payload.CurfewAt != nil {
+			concert.CurfewAt = payload.CurfewAt
+		} else if payload.ClearCurfewAt {
+			concert.CurfewAt = nil
+		}
+		if payload.VenueID != nil {
+			venue, err := s.venueRepo.WithTx(tx).GetByIDForShare(ctx, *payload.VenueID)
+			if err != nil {
+				return ErrVenueNotFound
+			}
+			concert.VenueID = *payload.VenueID
+			concert.Venue = *venue
+		}
+
+		entries := payload.Lineup
+		if entries == nil && payload.BandIDs != nil {
+			entries = lineupFromBandIDs(payload.BandIDs)
+		}
+		if entries != nil {
+			lineup, err := s.buildLineup(ctx, tx, entries)
+			if err != nil {
+				return err
+			}
+			concert.Lineup = lineup
+		}
+
+		if err := s.checkSchedule(ctx, tx, concert); err != nil {
+			return err
+		}
+
+		if err := repository.Update(ctx, concert); err != nil {
+			return err
+		}
+		return s.audit.Record(ctx, tx, audit.ActionUpdate, audit.EntityConcert, id, before, ToConcertResponse(concert))
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	return response, nil
+	return ToConcertResponse(concert), nil
 }
 
-func (s *ConcertService) Update(id uint, payload *UpdateConcertRequest) (*ConcertResponse, error) {
-	concert, err := s.repository.GetByID(id)
+// Delete moves the concert to trash if it still has the expected version
+func (s *ConcertService) Delete(ctx context.Context, id uint, version uint) error {
+	ctx, span := tracing.Start(ctx, "ConcertService.Delete")
+	defer span.End()
+
+	return s.db.WithTx(ctx, func(tx db.IDb) error {
+		repository := s.repository.WithTx(tx)
+
+		concert, err := repository.GetByID(ctx, id)
+		if err != nil {
+			return ErrConcertNotFound
+		}
+		if !etag.Matches(version, concert.Version) {
+			return ErrVersionConflict
+		}
+		if err := repository.Delete(ctx, id, concert.Version); err != nil {
+			return err
+		}
+		return s.audit.Record(ctx, tx, audit.ActionDelete, audit.EntityConcert, id, ToConcertResponse(concert), nil)
+	})
+}
+
+func (s *ConcertService) ListDeleted(ctx context.Context, spec *query.Spec) (*ListConcertsResponse, error) {
+	ctx, span := tracing.Start(ctx, "ConcertService.ListDeleted")
+	defer span.End()
+
+	concerts, meta, err := s.repository.ListDeleted(ctx, spec)
 	if err != nil {
-		return nil, errors.New("concert not found")
+		return nil, err
 	}
 
-	if payload.Title != nil {
-		concert.Title = *payload.Title
-	}
-	if payload.Description != nil {
-		concert.Description = *payload.Description
-	}
-	if payload.PosterURL != nil {
-		concert.PosterURL = *payload.PosterURL
+	response := &ListConcertsResponse{
+		Items:    make([]ConcertResponse, len(concerts)),
+		PageMeta: meta,
 	}
-	if payload.Date != nil {
-		concert.Date = *payload.Date
+
+	for i, concert := range concerts {
+		response.Items[i] = *ToConcertResponse(&concert)
 	}
-	if payload.VenueID != nil {
-		venue, err := s.venueRepo.GetByID(*payload.VenueID)
+
+	return response, nil
+}
+
+// Restore brings the concert back from trash. Its venue must not be deleted and must still be free at that time
+func (s *ConcertService) Restore(ctx context.Context, id uint) (*ConcertResponse, error) {
+	ctx, span := tracing.Start(ctx, "ConcertService.Restore")
+	defer span.End()
+
+	var concert *Concert
+
+	err := s.bookingTx(ctx, func(tx db.IDb) error {
+		repository := s.repository.WithTx(tx)
+
+		var err error
+		concert, err = repository.GetDeleted(ctx, id)
 		if err != nil {
-			return nil, errors.New("venue not found")
+			return ErrConcertNotInTrash
+		}
+		if _, err := s.venueRepo.WithTx(tx).GetByIDForShare(ctx, concert.VenueID); err != nil {
+			return ErrVenueInTrash
+		}
+		if err := s.checkSchedule(ctx, tx, concert); err != nil {
+			return err
+		}
+		if err := repository.Restore(ctx, id); err != nil {
+			return err
 		}
-		concert.VenueID = *payload.VenueID
-		concert.Venue = *venue
+
+		concert, err = repository.GetByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		return s.audit.Record(ctx, tx, audit.ActionRestore, audit.EntityConcert, id, nil, ToConcertResponse(concert))
+	})
+	if err != nil {
+		return nil, err
 	}
-	if payload.BandIDs != nil {
-		var bandsList []bands.Band
-		for _, bandID := range payload.BandIDs {
-			band, err := s.bandRepo.GetByID(bandID)
-			if err != nil {
-				return nil, errors.New("band not found")
-			}
-			bandsList = append(bandsList, *band)
+
+	return ToConcertResponse(concert), nil
+}
+
+// Purge permanently removes a concert from trash
+func (s *ConcertService) Purge(ctx context.Context, id uint) error {
+	ctx, span := tracing.Start(ctx, "ConcertService.Purge")
+	defer span.End()
+
+	return s.db.WithTx(ctx, func(tx db.IDb) error {
+		repository := s.repository.WithTx(tx)
+
+		if _, err := repository.GetDeleted(ctx, id); err != nil {
+			return ErrConcertNotInTrash
 		}
-		concert.Bands = bandsList
+		if err := repository.Purge(ctx, id); err != nil {
+			return err
+		}
+		return s.audit.Record(ctx, tx, audit.ActionPurge, audit.EntityConcert, id, nil, nil)
+	})
+}
+
+func (s *ConcertService) GetByID(ctx context.Context, id uint) (*ConcertResponse, error) {
+	ctx, span := tracing.Start(ctx, "ConcertService.GetByID")
+	defer span.End()
+
+	concert, err := s.repository.GetByID(ctx, id)
+	if err != nil {
+		return nil, ErrConcertNotFound
 	}
 
-	err = s.repository.Update(concert)
+	return ToConcertResponse(concert), nil
+}
+
+func (s *ConcertService) List(ctx context.Context, spec *query.Spec) (*ListConcertsResponse, error) {
+	ctx, span := tracing.Start(ctx, "ConcertService.List")
+	defer span.End()
+
+	concerts, meta, err := s.repository.List(ctx, spec)
 	if err != nil {
 		return nil, err
 	}
 
-	bandResponses := make([]bands.BandResponse, len(concert.Bands))
-	for i, band := range concert.Bands {
-		bandResponse := bands.ToBandResponse(&band)
-		bandResponses[i] = *bandResponse
+	response := &ListConcertsResponse{
+		Items:    make([]ConcertResponse, len(concerts)),
+		PageMeta: meta,
 	}
 
-	response := &ConcertResponse{
-		ID:          concert.Model.ID,
-		Title:       concert.Title,
-		Description: concert.Description,
-		PosterURL:   concert.PosterURL,
-		Date:        concert.Date,
-		VenueID:     concert.VenueID,
-		Venue:       *venues.ToVenueResponse(&concert.Venue),
-		Bands:       bandResponses,
-		CreatedAt:   concert.Model.CreatedAt,
-		UpdatedAt:   concert.Model.UpdatedAt,
+	for i, concert := range concerts {
+		response.Items[i] = *ToConcertResponse(&concert)
 	}
 
 	return response, nil
 }
 
-func (s *ConcertService) Delete(id uint) error {
-	return s.repository.Delete(id)
-}
+// Near returns upcoming concerts around a point ordered by distance
+func (s *ConcertService) Near(ctx context.Context, near *NearQuery) ([]NearbyConcertResponse, error) {
+	ctx, span := tracing.Start(ctx, "ConcertService.Near")
+	defer span.End()
 
-func (s *ConcertService) GetByID(id uint) (*ConcertResponse, error) {
-	concert, err := s.repository.GetByID(id)
+	found, err := s.repository.ListNear(ctx, near)
 	if err != nil {
 		return nil, err
 	}
 
-	bandResponses := make([]bands.BandResponse, len(concert.Bands))
-	for i, band := range concert.Bands {
-		bandResponse := bands.ToBandResponse(&band)
-		bandResponses[i] = *bandResponse
+	response := make([]NearbyConcertResponse, len(found))
+	for i, item := range found {
+		response[i] = NearbyConcertResponse{
+			ConcertResponse: *ToConcertResponse(&item.Concert),
+			DistanceKm:      math.Round(item.DistanceKm*100) / 100,
+		}
+	}
+	return response, nil
+}
+
+// ByBand returns upcoming and past concerts of a band, at most limit of each
+func (s *ConcertService) ByBand(ctx context.Context, bandID uint, limit int) (upcoming []ConcertResponse, past []ConcertResponse, err error) {
+	ctx, span := tracing.Start(ctx, "ConcertService.ByBand")
+	defer span.End()
+
+	next, err := s.repository.ListByBand(ctx, bandID, true, limit)
+	if err != nil {
+		return nil, nil, err
+	}
+	previous, err := s.repository.ListByBand(ctx, bandID, false, limit)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	response := &ConcertResponse{
-		ID:          concert.Model.ID,
-		Title:       concert.Title,
-		Description: concert.Description,
-		PosterURL:   concert.PosterURL,
-		Date:        concert.Date,
-		VenueID:     concert.VenueID,
-		Venue:       *venues.ToVenueResponse(&concert.Venue),
-		Bands:       bandResponses,
-		CreatedAt:   concert.Model.CreatedAt,
-		UpdatedAt:   concert.Model.UpdatedAt,
+	upcoming = make([]ConcertResponse, len(next))
+	for i, concert := range next {
+		upcoming[i] = *ToConcertResponse(&concert)
+	}
+	past = make([]ConcertResponse, len(previous))
+	for i, concert := range previous {
+		past[i] = *ToConcertResponse(&concert)
 	}
+	return upcoming, past, nil
+}
 
-	return response, nil
+// bookingTx runs fn in a serializable transaction, so two overlapping concerts
+// saved at the same time cannot both pass the double booking check. The loser is retried and sees the winner
+func (s *ConcertService) bookingTx(ctx context.Context, fn func(tx db.IDb) error) error {
+	return s.db.WithTxOptions(ctx, db.TxOptions{Isolation: sql.LevelSerializable, MaxRetries: s.txMaxRetries}, fn)
 }
 
-func (s *ConcertService) List(page, pageSize int) (*ListConcertsResponse, error) {
-	if page < 1 {
-		page = 1
+// checkSchedule validates concert and set times and that the venue is free
+func (s *ConcertService) checkSchedule(ctx context.Context, tx db.IDb, concert *Concert) error {
+	if concert.DoorsAt != nil && concert.DoorsAt.After(concert.Date) {
+		return ErrDoorsAfterShow
 	}
-	if pageSize < 1 || pageSize > 100 {
-		pageSize = 10
+	if concert.CurfewAt != nil && !concert.CurfewAt.After(concert.Date) {
+		return ErrCurfewBeforeShow
+	}
+
+	start, end := concert.Occupies()
+	for _, entry := range concert.Lineup {
+		if entry.SetStart != nil && entry.SetEnd != nil && !entry.SetEnd.After(*entry.SetStart) {
+			return ErrInvalidSetTime
+		}
+		if (entry.SetStart != nil && entry.SetStart.Before(start)) || (entry.SetEnd != nil && entry.SetEnd.After(end)) {
+			return ErrSetOutsideConcert
+		}
 	}
 
-	concerts, err := s.repository.List(page, pageSize)
+	overlaps, err := s.repository.WithTx(tx).HasOverlap(ctx, concert)
+	if err != nil {
+		return err
+	}
+	if overlaps {
+		return ErrVenueDoubleBooked
+	}
+	return nil
+}
+
+// lineupFromBandIDs bills the first band as headliner, duplicates are skipped
+func lineupFromBandIDs(ids []uint) []LineupEntryRequest {
+	entries := make([]LineupEntryRequest, 0, len(ids))
+	seen := make(map[uint]bool, len(ids))
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		entries = append(entries, LineupEntryRequest{BandID: id})
+	}
+	return entries
+}
+
+// buildLineup fetches all bands with one query and keeps the order of entries as billing order.
+// The bands are read for share, so they cannot be deleted until the concert is saved
+func (s *ConcertService) buildLineup(ctx context.Context, tx db.IDb, entries []LineupEntryRequest) ([]ConcertBands, error) {
+	ids := make([]uint, len(entries))
+	for i, entry := range entries {
+		ids[i] = entry.BandID
+	}
+
+	found, err := s.bandRepo.WithTx(tx).GetByIDsForShare(ctx, ids)
 	if err != nil {
 		return nil, err
 	}
 
-	response := &ListConcertsResponse{
-		Items: make([]ConcertResponse, len(concerts)),
+	byID := make(map[uint]bands.Band, len(found))
+	for _, band := range found {
+		byID[band.ID] = band
 	}
 
-	for i, concert := range concerts {
-		bandResponses := make([]bands.BandResponse, len(concert.Bands))
-		for j, band := range concert.Bands {
-			bandResponse := bands.ToBandResponse(&band)
-			bandResponses[j] = *bandResponse
+	lineup := make([]ConcertBands, len(entries))
+	seen := make(map[uint]bool, len(entries))
+	for i, entry := range entries {
+		band, ok := byID[entry.BandID]
+		if !ok {
+			return nil, ErrBandNotFound
+		}
+		if seen[entry.BandID] {
+			return nil, ErrDuplicateBand
 		}
+		seen[entry.BandID] = true
 
-		response.Items[i] = ConcertResponse{
-			ID:          concert.Model.ID,
-			Title:       concert.Title,
-			Description: concert.Description,
-			PosterURL:   concert.PosterURL,
-			Date:        concert.Date,
-			VenueID:     concert.VenueID,
-			Venue:       *venues.ToVenueResponse(&concert.Venue),
-			Bands:       bandResponses,
-			CreatedAt:   concert.Model.CreatedAt,
-			UpdatedAt:   concert.Model.UpdatedAt,
+		role := entry.Role
+		if role == "" {
+			role = RoleSupport
+			if i == 0 {
+				role = RoleHeadliner
+			}
+		}
+
+		lineup[i] = ConcertBands{
+			BandID:   entry.BandID,
+			Position: i + 1,
+			Role:     role,
+			SetStart: entry.SetStart,
+			SetEnd:   entry.SetEnd,
+			Band:     band,
 		}
 	}
 
-	return response, nil
+	return lineup, nil
 }
@@ -0,0 +1,193 @@
+package events
+
+import (
+	"sort"
+	"time"
+
+	"github.com/serhiirubets/rubeticket/internal/app/admin/bands"
+	"github.com/serhiirubets/rubeticket/internal/app/admin/concerts"
+	"github.com/serhiirubets/rubeticket/internal/pkg/query"
+)
+
+// @Description Event response model, startsAt and endsAt span its concerts
+type EventResponse struct {
+	ID          uint           `json:"id"`
+	Kind        string         `json:"kind"`
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	PosterURL   string         `json:"posterUrl"`
+	StartsAt    *time.Time     `json:"startsAt,omitempty"`
+	EndsAt      *time.Time     `json:"endsAt,omitempty"`
+	ConcertIDs  []uint         `json:"concertIds"`
+	Passes      []PassResponse `json:"passes"`
+	CreatedAt   time.Time      `json:"createdAt"`
+	UpdatedAt   time.Time      `json:"updatedAt"`
+}
+
+// @Description Event with aggregated lineup and day by day schedule
+type EventDetailResponse struct {
+	EventResponse
+	Lineup   []LineupBandResponse  `json:"lineup"`
+	Schedule []ScheduleDayResponse `json:"schedule"`
+}
+
+// @Description Band playing the event, headliner if it headlines any of its concerts
+type LineupBandResponse struct {
+	Band       bands.BandResponse `json:"band"`
+	Headliner  bool               `json:"headliner"`
+	ConcertIDs []uint             `json:"concertIds"`
+}
+
+// @Description Concerts of one day, in the venue time zone
+type ScheduleDayResponse struct {
+	Date     string                     `json:"date"`
+	Concerts []concerts.ConcertResponse `json:"concerts"`
+}
+
+// @Description Pass response model, price is in minor units
+type PassResponse struct {
+	ID          uint   `json:"id"`
+	EventID     uint   `json:"eventId"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Price       int64  `json:"price"`
+	Currency    string `json:"currency"`
+	ConcertIDs  []uint `json:"concertIds"`
+}
+
+// @Description Create event request
+type CreateEventRequest struct {
+	Kind        string `json:"kind" validate:"required,oneof=festival tour"`
+	Name        string `json:"name" validate:"required,max=100"`
+	Description string `json:"description" validate:"max=1000"`
+	PosterURL   string `json:"posterUrl" validate:"max=100"`
+	ConcertIDs  []uint `json:"concertIds"`
+}
+
+// @Description Update event request, concertIds replaces the concerts when sent
+type UpdateEventRequest struct {
+	Kind        *string `json:"kind" validate:"omitempty,oneof=festival tour"`
+	Name        *string `json:"name" validate:"omitempty,max=100"`
+	Description *string `json:"description" validate:"omitempty,max=1000"`
+	PosterURL   *string `json:"posterUrl" validate:"omitempty,max=100"`
+	ConcertIDs  []uint  `json:"concertIds"`
+}
+
+// @Description Create pass request, price is in minor units
+type CreatePassRequest struct {
+	Name        string `json:"name" validate:"required,max=100"`
+	Description string `json:"description" validate:"max=300"`
+	Price       int64  `json:"price" validate:"min=0"`
+	Currency    string `json:"currency" validate:"required,iso4217"`
+	ConcertIDs  []uint `json:"concertIds" validate:"required,min=1"`
+}
+
+// @Description Update pass request
+type UpdatePassRequest struct {
+	Name        *string `json:"name" validate:"omitempty,max=100"`
+	Description *string `json:"description" validate:"omitempty,max=300"`
+	Price       *int64  `json:"price" validate:"omitempty,min=0"`
+	Currency    *string `json:"currency" validate:"omitempty,iso4217"`
+	ConcertIDs  []uint  `json:"concertIds" validate:"omitempty,min=1"`
+}
+
+// @Description List events response
+type ListEventsResponse struct {
+	Items []EventResponse `json:"items"`
+	query.PageMeta
+}
+
+// ToPassResponse converts from Pass to PassResponse
+func ToPassResponse(pass *Pass) *PassResponse {
+	concertIDs := make([]uint, len(pass.Concerts))
+	for i, concert := range pass.Concerts {
+		concertIDs[i] = concert.ID
+	}
+	return &PassResponse{
+		ID:          pass.ID,
+		EventID:     pass.EventID,
+		Name:        pass.Name,
+		Description: pass.Description,
+		Price:       pass.Price,
+		Currency:    pass.Currency,
+		ConcertIDs:  concertIDs,
+	}
+}
+
+// ToEventResponse converts from Event to EventResponse
+func ToEventResponse(event *Event) *EventResponse {
+	response := &EventResponse{
+		ID:          event.ID,
+		Kind:        event.Kind,
+		Name:        event.Name,
+		Description: event.Description,
+		PosterURL:   event.PosterURL,
+		ConcertIDs:  make([]uint, len(event.Concerts)),
+		Passes:      make([]PassResponse, len(event.Passes)),
+		CreatedAt:   event.CreatedAt,
+		UpdatedAt:   event.UpdatedAt,
+	}
+
+	for i, concert := range event.Concerts {
+		response.ConcertIDs[i] = concert.ID
+
+		start, end := concert.Occupies()
+		if response.StartsAt == nil || start.Before(*response.StartsAt) {
+			response.StartsAt = &start
+		}
+		if response.EndsAt == nil || end.After(*response.EndsAt) {
+			response.EndsAt = &end
+		}
+	}
+	for i, pass := range event.Passes {
+		response.Passes[i] = *ToPassResponse(&pass)
+	}
+
+	return response
+}
+
+// ToEventDetailResponse adds the lineup of all concerts and the schedule grouped by local day
+func ToEventDetailResponse(event *Event) *EventDetailResponse {
+	response := &EventDetailResponse{
+		EventResponse: *ToEventResponse(event),
+		Lineup:        []LineupBandResponse{},
+		Schedule:      []ScheduleDayResponse{},
+	}
+
+	lineupIndex := make(map[uint]int)
+	for _, concert := range event.Concerts {
+		concertResponse := concerts.ToConcertResponse(&concert)
+
+		day := concertResponse.Date.Format(time.DateOnly)
+		if n := len(response.Schedule); n == 0 || response.Schedule[n-1].Date != day {
+			response.Schedule = append(response.Schedule, ScheduleDayResponse{Date: day})
+		}
+		last := &response.Schedule[len(response.Schedule)-1]
+		last.Concerts = append(last.Concerts, *concertResponse)
+
+		for _, entry := range concert.Lineup {
+			i, ok := lineupIndex[entry.BandID]
+			if !ok {
+				i = len(response.Lineup)
+				lineupIndex[entry.BandID] = i
+				response.Lineup = append(response.Lineup, LineupBandResponse{Band: *bands.ToBandResponse(&entry.Band)})
+			}
+			response.Lineup[i].Headliner = response.Lineup[i].Headliner || entry.Role == concerts.RoleHeadliner
+			response.Lineup[i].ConcertIDs = append(response.Lineup[i].ConcertIDs, concert.ID)
+		}
+	}
+
+	// headliners first, then bands playing more shows, then by name
+	sort.SliceStable(response.Lineup, func(i, j int) bool {
+		a, b := response.Lineup[i], response.Lineup[j]
+		if a.Headliner != b.Headliner {
+			return a.Headliner
+		}
+		if len(a.ConcertIDs) != len(b.ConcertIDs) {
+			return len(a.ConcertIDs) > len(b.ConcertIDs)
+		}
+		return a.Band.Name < b.Band.Name
+	})
+
+	return response
+}
@@ -0,0 +1,14 @@
+package events
+
+import "github.com/serhiirubets/rubeticket/internal/pkg/apperr"
+
+var (
+	ErrInvalidID           = apperr.New(apperr.Invalid, "invalid_event_id", "invalid event ID")
+	ErrInvalidPassID       = apperr.New(apperr.Invalid, "invalid_pass_id", "invalid pass ID")
+	ErrEventNotFound       = apperr.New(apperr.NotFound, "event_not_found", "event not found")
+	ErrPassNotFound        = apperr.New(apperr.NotFound, "pass_not_found", "pass not found")
+	ErrConcertNotFound     = apperr.New(apperr.Invalid, "concert_not_found", "concert not found")
+	ErrConcertInOtherEvent = apperr.New(apperr.Invalid, "concert_in_other_event", "concert belongs to another event")
+	ErrConcertNotInEvent   = apperr.New(apperr.Invalid, "concert_not_in_event", "pass concerts must belong to the event")
+	ErrConcertTaken        = apperr.New(apperr.Conflict, "concert_taken", "concert was added to another event at the same time")
+)
@@ -0,0 +1,341 @@
+package events
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/serhiirubets/rubeticket/config"
+	"github.com/serhiirubets/rubeticket/internal/app/admin/audit"
+	"github.com/serhiirubets/rubeticket/internal/pkg/apperr"
+	"github.com/serhiirubets/rubeticket/internal/pkg/log"
+	"github.com/serhiirubets/rubeticket/internal/pkg/query"
+	"github.com/serhiirubets/rubeticket/internal/pkg/req"
+	"github.com/serhiirubets/rubeticket/internal/pkg/res"
+)
+
+type EventHandlerDeps struct {
+	Config  *config.Config
+	Logger  log.ILogger
+	Service *EventService
+}
+
+type EventHandler struct {
+	Config  *config.Config
+	Logger  log.ILogger
+	Service *EventService
+}
+
+func NewEventHandler(router *http.ServeMux, deps *EventHandlerDeps) {
+	handler := EventHandler{
+		Config:  deps.Config,
+		Logger:  deps.Logger,
+		Service: deps.Service,
+	}
+
+	router.HandleFunc("POST /events", handler.Create())
+	router.HandleFunc("PUT /events/{id}", handler.Update())
+	router.HandleFunc("DELETE /events/{id}", handler.Delete())
+	router.HandleFunc("GET /events/{id}", handler.GetByID())
+	router.HandleFunc("GET /events", handler.List())
+	router.HandleFunc("POST /events/{id}/passes", handler.CreatePass())
+	router.HandleFunc("PUT /events/{id}/passes/{passId}", handler.UpdatePass())
+	router.HandleFunc("DELETE /events/{id}/passes/{passId}", handler.DeletePass())
+}
+
+// Create godoc
+// @Summary Create a festival or tour
+// @Description Create an event and attach existing concerts to it
+// @Tags Admin/Events
+// @Accept json
+// @Produce json
+// @Param request body CreateEventRequest true "Event details"
+// @Success 201 {object} EventDetailResponse
+// @Failure 400 {object} res.Problem "Bad request"
+// @Failure 401 {object} res.Problem "Unauthorized"
+// @Failure 403 {object} res.Problem "Forbidden"
+// @Failure 409 {object} res.Problem "Concert was added to another event"
+// @Router /admin/v1/events [post]
+func (h *EventHandler) Create() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		payload, err := req.HandleBody[CreateEventRequest](&w, r)
+		if err != nil {
+			return
+		}
+
+		event, err := h.Service.Create(audit.WithActor(r), payload)
+		if err != nil {
+			if apperr.IsInternal(err) {
+				h.Logger.WithContext(r.Context()).Error("Failed to create event", "error", err.Error())
+			}
+			res.Error(w, r, err)
+			return
+		}
+
+		res.Json(w, event, http.StatusCreated)
+	}
+}
+
+// Update godoc
+// @Summary Update a festival or tour
+// @Description Update an event, concertIds replaces its concerts when sent
+// @Tags Admin/Events
+// @Accept json
+// @Produce json
+// @Param id path int true "Event ID"
+// @Param request body UpdateEventRequest true "Event details"
+// @Success 200 {object} EventDetailResponse
+// @Failure 400 {object} res.Problem "Bad request"
+// @Failure 401 {object} res.Problem "Unauthorized"
+// @Failure 403 {object} res.Problem "Forbidden"
+// @Failure 404 {object} res.Problem "Not found"
+// @Failure 409 {object} res.Problem "Concert was added to another event"
+// @Router /admin/v1/events/{id} [put]
+func (h *EventHandler) Update() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseUint(r.PathValue("id"), 10, 32)
+		if err != nil {
+			res.Error(w, r, ErrInvalidID)
+			return
+		}
+
+		payload, err := req.HandleBody[UpdateEventRequest](&w, r)
+		if err != nil {
+			return
+		}
+
+		event, err := h.Service.Update(audit.WithActor(r), uint(id), payload)
+		if err != nil {
+			if apperr.IsInternal(err) {
+				h.Logger.WithContext(r.Context()).Error("Failed to update event", "error", err.Error())
+			}
+			res.Error(w, r, err)
+			return
+		}
+
+		res.Json(w, event, http.StatusOK)
+	}
+}
+
+// Delete godoc
+// @Summary Delete a festival or tour
+// @Description Delete an event and its passes, concerts are kept and detached
+// @Tags Admin/Events
+// @Produce json
+// @Param id path int true "Event ID"
+// @Success 204 "No Content"
+// @Failure 400 {object} res.Problem "Bad request"
+// @Failure 401 {object} res.Problem "Unauthorized"
+// @Failure 403 {object} res.Problem "Forbidden"
+// @Router /admin/v1/events/{id} [delete]
+func (h *EventHandler) Delete() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseUint(r.PathValue("id"), 10, 32)
+		if err != nil {
+			res.Error(w, r, ErrInvalidID)
+			return
+		}
+
+		err = h.Service.Delete(audit.WithActor(r), uint(id))
+		if err != nil {
+			if apperr.IsInternal(err) {
+				h.Logger.WithContext(r.Context()).Error("Failed to delete event", "error", err.Error())
+			}
+			res.Error(w, r, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// GetByID godoc
+// @Summary Get a festival or tour by ID
+// @Description Get an event with its passes, aggregated lineup and schedule
+// @Tags Admin/Events
+// @Produce json
+// @Param id path int true "Event ID"
+// @Success 200 {object} EventDetailResponse
+// @Failure 400 {object} res.Problem "Bad request"
+// @Failure 401 {object} res.Problem "Unauthorized"
+// @Failure 403 {object} res.Problem "Forbidden"
+// @Failure 404 {object} res.Problem "Not found"
+// @Router /admin/v1/events/{id} [get]
+func (h *EventHandler) GetByID() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseUint(r.PathValue("id"), 10, 32)
+		if err != nil {
+			res.Error(w, r, ErrInvalidID)
+			return
+		}
+
+		event, err := h.Service.GetByID(r.Context(), uint(id))
+		if err != nil {
+			res.Error(w, r, err)
+			return
+		}
+
+		res.Json(w, event, http.StatusOK)
+	}
+}
+
+// List godoc
+// @Summary List festivals and tours
+// @Description Get a paginated list of events
+// @Tags Admin/Events
+// @Produce json
+// @Param page query int false "Page number (default: 1)"
+// @Param pageSize query int false "Page size (default: 10, max: 100)"
+// @Param sort query string false "Comma separated sort fields, prefix with - for descending"
+// @Param q query string false "Search term"
+// @Param cursor query string false "nextCursor from the previous page, replaces page"
+// @Param kind query string false "festival or tour"
+// @Success 200 {object} ListEventsResponse
+// @Failure 400 {object} res.Problem "Unsupported filter or sort field"
+// @Failure 401 {object} res.Problem "Unauthorized"
+// @Failure 403 {object} res.Problem "Forbidden"
+// @Router /admin/v1/events [get]
+func (h *EventHandler) List() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		spec, err := query.ParseSpec(r.URL.Query(), EventListSchema)
+		if err != nil {
+			res.Error(w, r, err)
+			return
+		}
+
+		events, err := h.Service.List(r.Context(), spec)
+		if err != nil {
+			if apperr.IsInternal(err) {
+				h.Logger.WithContext(r.Context()).Error("Failed to list events", "error", err.Error())
+			}
+			res.Error(w, r, err)
+			return
+		}
+
+		res.Json(w, events, http.StatusOK)
+	}
+}
+
+// CreatePass godoc
+// @Summary Create a pass
+// @Description Create a pass granting entry to several concerts of the event
+// @Tags Admin/Events
+// @Accept json
+// @Produce json
+// @Param id path int true "Event ID"
+// @Param request body CreatePassRequest true "Pass details"
+// @Success 201 {object} PassResponse
+// @Failure 400 {object} res.Problem "Bad request"
+// @Failure 401 {object} res.Problem "Unauthorized"
+// @Failure 403 {object} res.Problem "Forbidden"
+// @Failure 404 {object} res.Problem "Not found"
+// @Router /admin/v1/events/{id}/passes [post]
+func (h *EventHandler) CreatePass() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseUint(r.PathValue("id"), 10, 32)
+		if err != nil {
+			res.Error(w, r, ErrInvalidID)
+			return
+		}
+
+		payload, err := req.HandleBody[CreatePassRequest](&w, r)
+		if err != nil {
+			return
+		}
+
+		pass, err := h.Service.CreatePass(r.Context(), uint(id), payload)
+		if err != nil {
+			if apperr.IsInternal(err) {
+				h.Logger.WithContext(r.Context()).Error("Failed to create pass", "error", err.Error())
+			}
+			res.Error(w, r, err)
+			return
+		}
+
+		res.Json(w, pass, http.StatusCreated)
+	}
+}
+
+// UpdatePass godoc
+// @Summary Update a pass
+// @Description Update a pass, concertIds replaces its concerts when sent
+// @Tags Admin/Events
+// @Accept json
+// @Produce json
+// @Param id path int true "Event ID"
+// @Param passId path int true "Pass ID"
+// @Param request body UpdatePassRequest true "Pass details"
+// @Success 200 {object} PassResponse
+// @Failure 400 {object} res.Problem "Bad request"
+// @Failure 401 {object} res.Problem "Unauthorized"
+// @Failure 403 {object} res.Problem "Forbidden"
+// @Failure 404 {object} res.Problem "Not found"
+// @Router /admin/v1/events/{id}/passes/{passId} [put]
+func (h *EventHandler) UpdatePass() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseUint(r.PathValue("id"), 10, 32)
+		if err != nil {
+			res.Error(w, r, ErrInvalidID)
+			return
+		}
+		passID, err := strconv.ParseUint(r.PathValue("passId"), 10, 32)
+		if err != nil {
+			res.Error(w, r, ErrInvalidPassID)
+			return
+		}
+
+		payload, err := req.HandleBody[UpdatePassRequest](&w, r)
+		if err != nil {
+			return
+		}
+
+		pass, err := h.Service.UpdatePass(r.Context(), uint(id), uint(passID), payload)
+		if err != nil {
+			if apperr.IsInternal(err) {
+				h.Logger.WithContext(r.Context()).Error("Failed to update pass", "error", err.Error())
+			}
+			res.Error(w, r, err)
+			return
+		}
+
+		res.Json(w, pass, http.StatusOK)
+	}
+}
+
+// DeletePass godoc
+// @Summary Delete a pass
+// @Description Delete a pass of the event
+// @Tags Admin/Events
+// @Produce json
+// @Param id path int true "Event ID"
+// @Param passId path int true "Pass ID"
+// @Success 204 "No Content"
+// @Failure 400 {object} res.Problem "Bad request"
+// @Failure 401 {object} res.Problem "Unauthorized"
+// @Failure 403 {object} res.Problem "Forbidden"
+// @Failure 404 {object} res.Problem "Not found"
+// @Router /admin/v1/events/{id}/passes/{passId} [delete]
+func (h *EventHandler) DeletePass() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseUint(r.PathValue("id"), 10, 32)
+		if err != nil {
+			res.Error(w, r, ErrInvalidID)
+			return
+		}
+		passID, err := strconv.ParseUint(r.PathValue("passId"), 10, 32)
+		if err != nil {
+			res.Error(w, r, ErrInvalidPassID)
+			return
+		}
+
+		err = h.Service.DeletePass(r.Context(), uint(id), uint(passID))
+		if err != nil {
+			if apperr.IsInternal(err) {
+				h.Logger.WithContext(r.Context()).Error("Failed to delete pass", "error", err.Error())
+			}
+			res.Error(w, r, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
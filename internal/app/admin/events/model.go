@@ -0,0 +1,34 @@
+package events
+
+import (
+	"github.com/serhiirubets/rubeticket/internal/app/admin/concerts"
+	"gorm.io/gorm"
+)
+
+const (
+	KindFestival = "festival"
+	KindTour     = "tour"
+)
+
+// @Description Festival or tour grouping several concerts
+type Event struct {
+	*gorm.Model
+	Kind        string             `json:"kind" gorm:"type:varchar(20);not null;index:idx_event_kind"`
+	Name        string             `json:"name" gorm:"type:varchar(100);not null"`
+	Description string             `json:"description" gorm:"type:varchar(1000)"`
+	PosterURL   string             `json:"posterUrl" gorm:"type:varchar(100)"`
+	Concerts    []concerts.Concert `json:"concerts" gorm:"foreignKey:EventID"`
+	Passes      []Pass             `json:"passes" gorm:"foreignKey:EventID"`
+}
+
+// @Description Pass granting entry to several concerts of an event
+type Pass struct {
+	*gorm.Model
+	EventID     uint   `json:"eventId" gorm:"not null;index:idx_pass_event_id"`
+	Name        string `json:"name" gorm:"type:varchar(100);not null"`
+	Description string `json:"description" gorm:"type:varchar(300)"`
+	// Price is in minor units, e.g. cents
+	Price    int64              `json:"price" gorm:"not null"`
+	Currency string             `json:"currency" gorm:"type:varchar(3);not null"`
+	Concerts []concerts.Concert `json:"concerts" gorm:"many2many:pass_concerts;"`
+}
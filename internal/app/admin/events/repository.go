@@ -0,0 +1,224 @@
+package events
+
+import (
+	"context"
+
+	"github.com/serhiirubets/rubeticket/internal/app/admin/concerts"
+	"github.com/serhiirubets/rubeticket/internal/pkg/db"
+	"github.com/serhiirubets/rubeticket/internal/pkg/query"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// EventListSchema lists fields events can be filtered, sorted and searched by
+var EventListSchema = query.Schema{
+	Filters: map[string]query.Field{
+		"kind": {Column: "kind", Ops: []query.Operator{query.Eq, query.In}},
+		"name": {Column: "name", Ops: []query.Operator{query.Eq, query.Like}},
+	},
+	Sort: map[string]string{
+		"id":        "id",
+		"name":      "name",
+		"createdAt": "created_at",
+	},
+	Search:      []string{"name", "description"},
+	DefaultSort: "id",
+}
+
+type IEventRepository interface {
+	Create(ctx context.Context, event *Event) (*Event, error)
+	Update(ctx context.Context, event *Event) error
+	Delete(ctx context.Context, id uint) ([]uint, error)
+	GetByID(ctx context.Context, id uint) (*Event, error)
+	List(ctx context.Context, spec *query.Spec) ([]Event, query.PageMeta, error)
+	GetConcerts(ctx context.Context, ids []uint) ([]concerts.Concert, error)
+	SetConcerts(ctx context.Context, eventID uint, concertIDs []uint) (detached []uint, attached []uint, err error)
+	GetPass(ctx context.Context, eventID, passID uint) (*Pass, error)
+	SavePass(ctx context.Context, pass *Pass) error
+	DeletePass(ctx context.Context, pass *Pass) error
+	WithTx(tx db.IDb) IEventRepository
+}
+
+type EventRepository struct {
+	Db db.IDb
+}
+
+func NewEventRepository(Db db.IDb) IEventRepository {
+	return &EventRepository{Db: Db}
+}
+
+// WithTx returns a copy of the repository that runs queries in tx
+func (r *EventRepository) WithTx(tx db.IDb) IEventRepository {
+	return &EventRepository{Db: tx}
+}
+
+func (r *EventRepository) Create(ctx context.Context, event *Event) (*Event, error) {
+	if err := r.Db.WithContext(ctx).Omit(clause.Associations).Create(event).Error; err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+func (r *EventRepository) Update(ctx context.Context, event *Event) error {
+	return r.Db.WithContext(ctx).Omit(clause.Associations).Save(event).Error
+}
+
+// Delete detaches the concerts and removes the event with its passes, returns the IDs of the detached concerts.
+// Call it in a transaction
+func (r *EventRepository) Delete(ctx context.Context, id uint) ([]uint, error) {
+	conn := r.Db.WithContext(ctx)
+
+	var detached []uint
+	err := conn.Raw(`UPDATE concerts SET event_id = NULL, version = version + 1, updated_at = now()
+		WHERE event_id = ? AND deleted_at IS NULL RETURNING id`, id).Scan(&detached).Error
+	if err != nil {
+		return nil, err
+	}
+	if err := conn.Where("event_id = ?", id).Delete(&Pass{}).Error; err != nil {
+		return nil, err
+	}
+	if err := conn.Delete(&Event{}, id).Error; err != nil {
+		return nil, err
+	}
+	return detached, nil
+}
+
+// GetByID loads the event with concerts in date order, their venues and lineups, and passes
+func (r *EventRepository) GetByID(ctx context.Context, id uint) (*Event, error) {
+	var event Event
+	err := r.Db.WithContext(ctx).
+		Preload("Concerts", func(db *gorm.DB) *gorm.DB { return db.Order("date") }).
+		Preload("Concerts.Venue").
+		Preload("Concerts.Lineup.Band").
+		Preload("Passes").
+		Preload("Passes.Concerts").
+		First(&event, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+// List returns one page of events with concert dates and passes, by offset or by cursor
+func (r *EventRepository) List(ctx context.Context, spec *query.Spec) ([]Event, query.PageMeta, error) {
+	var events []Event
+	var total int64
+
+	filtered := spec.Where(r.Db.WithContext(ctx).Model(&Event{}), EventListSchema).Session(&gorm.Session{})
+	if err := filtered.Count(&total).Error; err != nil {
+		return nil, query.PageMeta{}, err
+	}
+
+	err := spec.Paginate(filtered).
+		Preload("Concerts", func(db *gorm.DB) *gorm.DB {
+			return db.Select("id", "event_id", "date", "doors_at", "curfew_at").Order("date")
+		}).
+		Preload("Passes").
+		Preload("Passes.Concerts", func(db *gorm.DB) *gorm.DB { return db.Select("concerts.id") }).
+		Find(&events).Error
+	if err != nil {
+		return nil, query.PageMeta{}, err
+	}
+	return query.NewPage(filtered, spec, events, total)
+}
+
+// GetConcerts loads concerts by ids, missing ids are skipped
+func (r *EventRepository) GetConcerts(ctx context.Context, ids []uint) ([]concerts.Concert, error) {
+	var found []concerts.Concert
+	if len(ids) == 0 {
+		return found, nil
+	}
+	if err := r.Db.WithContext(ctx).Where("id IN ?", ids).Find(&found).Error; err != nil {
+		return nil, err
+	}
+	return found, nil
+}
+
+// SetConcerts makes concertIDs the concerts of the event and returns the IDs of the concerts that left
+// and joined it. Moved concerts get a new version, concerts that leave are removed from its passes.
+// Fails with ErrConcertTaken when a concert belongs to another event, call it in a transaction
+func (r *EventRepository) SetConcerts(ctx context.Context, eventID uint, concertIDs []uint) ([]uint, []uint, error) {
+	conn := r.Db.WithContext(ctx)
+
+	var detached []uint
+	detach := `UPDATE concerts SET event_id = NULL, version = version + 1, updated_at = now()
+		WHERE event_id = ? AND deleted_at IS NULL`
+	var err error
+	if len(concertIDs) > 0 {
+		err = conn.Raw(detach+" AND id NOT IN ? RETURNING id", eventID, concertIDs).Scan(&detached).Error
+	} else {
+		err = conn.Raw(detach+" RETURNING id", eventID).Scan(&detached).Error
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var attached []uint
+	if len(concertIDs) > 0 {
+		unique := make(map[uint]bool, len(concertIDs))
+		for _, id := range concertIDs {
+			unique[id] = true
+		}
+
+		// the check before is not enough, another event may have taken a concert since it was read
+		err := conn.Raw(`UPDATE concerts SET event_id = ?, version = version + 1, updated_at = now()
+			WHERE id IN ? AND event_id IS NULL AND deleted_at IS NULL RETURNING id`, eventID, concertIDs).Scan(&attached).Error
+		if err != nil {
+			return nil, nil, err
+		}
+		var kept int64
+		err = conn.Model(&concerts.Concert{}).Where("id IN ? AND event_id = ?", concertIDs, eventID).Count(&kept).Error
+		if err != nil {
+			return nil, nil, err
+		}
+		if kept != int64(len(unique)) {
+			return nil, nil, ErrConcertTaken
+		}
+	}
+
+	err = conn.Exec(`
+		DELETE FROM pass_concerts pc
+		USING passes p
+		WHERE pc.pass_id = p.id AND p.event_id = ?
+			AND pc.concert_id NOT IN (SELECT id FROM concerts WHERE event_id = ?)`, eventID, eventID).Error
+	if err != nil {
+		return nil, nil, err
+	}
+	return detached, attached, nil
+}
+
+func (r *EventRepository) GetPass(ctx context.Context, eventID, passID uint) (*Pass, error) {
+	var pass Pass
+	err := r.Db.WithContext(ctx).
+		Preload("Concerts").
+		Where("event_id = ?", eventID).
+		First(&pass, passID).Error
+	if err != nil {
+		return nil, err
+	}
+	return &pass, nil
+}
+
+// SavePass creates or updates the pass and replaces its concerts, call it in a transaction
+func (r *EventRepository) SavePass(ctx context.Context, pass *Pass) error {
+	conn := r.Db.WithContext(ctx)
+	if err := conn.Omit(clause.Associations).Save(pass).Error; err != nil {
+		return err
+	}
+	if err := conn.Exec("DELETE FROM pass_concerts WHERE pass_id = ?", pass.ID).Error; err != nil {
+		return err
+	}
+	if len(pass.Concerts) == 0 {
+		return nil
+	}
+
+	rows := make([]map[string]interface{}, len(pass.Concerts))
+	for i, concert := range pass.Concerts {
+		rows[i] = map[string]interface{}{"pass_id": pass.ID, "concert_id": concert.ID}
+	}
+	return conn.Table("pass_concerts").Create(&rows).Error
+}
+
+func (r *EventRepository) DeletePass(ctx context.Context, pass *Pass) error {
+	return r.Db.WithContext(ctx).Delete(pass).Error
+}
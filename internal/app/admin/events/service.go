@@ -0,0 +1,319 @@
+package events
+
+import (
+	"context"
+
+	"github.com/serhiirubets/rubeticket/internal/app/admin/audit"
+	"github.com/serhiirubets/rubeticket/internal/app/admin/concerts"
+	"github.com/serhiirubets/rubeticket/internal/pkg/db"
+	"github.com/serhiirubets/rubeticket/internal/pkg/query"
+	"github.com/serhiirubets/rubeticket/internal/pkg/tracing"
+)
+
+type EventService struct {
+	db         db.IDb
+	repository IEventRepository
+	audit      *audit.Recorder
+}
+
+func NewEventService(db db.IDb, repository IEventRepository, auditRecorder *audit.Recorder) *EventService {
+	return &EventService{
+		db:         db,
+		repository: repository,
+		audit:      auditRecorder,
+	}
+}
+
+// concertEvent is the part of a concert an event changes, it is what the audit log records for moved concerts
+type concertEvent struct {
+	EventID *uint `json:"eventId"`
+}
+
+func (s *EventService) Create(ctx context.Context, payload *CreateEventRequest) (*EventDetailResponse, error) {
+	ctx, span := tracing.Start(ctx, "EventService.Create")
+	defer span.End()
+
+	var event *Event
+
+	err := s.db.WithTx(ctx, func(tx db.IDb) error {
+		repository := s.repository.WithTx(tx)
+
+		created, err := repository.Create(ctx, &Event{
+			Kind:        payload.Kind,
+			Name:        payload.Name,
+			Description: payload.Description,
+			PosterURL:   payload.PosterURL,
+		})
+		if err != nil {
+			return err
+		}
+
+		if err := s.setConcerts(ctx, tx, repository, created.ID, payload.ConcertIDs); err != nil {
+			return err
+		}
+
+		event, err = repository.GetByID(ctx, created.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return ToEventDetailResponse(event), nil
+}
+
+func (s *EventService) Update(ctx context.Context, id uint, payload *UpdateEventRequest) (*EventDetailResponse, error) {
+	ctx, span := tracing.Start(ctx, "EventService.Update")
+	defer span.End()
+
+	var event *Event
+
+	err := s.db.WithTx(ctx, func(tx db.IDb) error {
+		repository := s.repository.WithTx(tx)
+
+		var err error
+		event, err = repository.GetByID(ctx, id)
+		if err != nil {
+			return ErrEventNotFound
+		}
+
+		if payload.Kind != nil {
+			event.Kind = *payload.Kind
+		}
+		if payload.Name != nil {
+			event.Name = *payload.Name
+		}
+		if payload.Description != nil {
+			event.Description = *payload.Description
+		}
+		if payload.PosterURL != nil {
+			event.PosterURL = *payload.PosterURL
+		}
+
+		if err := repository.Update(ctx, event); err != nil {
+			return err
+		}
+
+		if payload.ConcertIDs != nil {
+			if err := s.setConcerts(ctx, tx, repository, id, payload.ConcertIDs); err != nil {
+				return err
+			}
+			event, err = repository.GetByID(ctx, id)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return ToEventDetailResponse(event), nil
+}
+
+func (s *EventService) Delete(ctx context.Context, id uint) error {
+	ctx, span := tracing.Start(ctx, "EventService.Delete")
+	defer span.End()
+
+	return s.db.WithTx(ctx, func(tx db.IDb) error {
+		detached, err := s.repository.WithTx(tx).Delete(ctx, id)
+		if err != nil {
+			return err
+		}
+		return s.recordMoves(ctx, tx, id, detached, nil)
+	})
+}
+
+func (s *EventService) GetByID(ctx context.Context, id uint) (*EventDetailResponse, error) {
+	ctx, span := tracing.Start(ctx, "EventService.GetByID")
+	defer span.End()
+
+	event, err := s.repository.GetByID(ctx, id)
+	if err != nil {
+		return nil, ErrEventNotFound
+	}
+	return ToEventDetailResponse(event), nil
+}
+
+func (s *EventService) List(ctx context.Context, spec *query.Spec) (*ListEventsResponse, error) {
+	ctx, span := tracing.Start(ctx, "EventService.List")
+	defer span.End()
+
+	events, meta, err := s.repository.List(ctx, spec)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &ListEventsResponse{
+		Items:    make([]EventResponse, len(events)),
+		PageMeta: meta,
+	}
+	for i, event := range events {
+		response.Items[i] = *ToEventResponse(&event)
+	}
+
+	return response, nil
+}
+
+func (s *EventService) CreatePass(ctx context.Context, eventID uint, payload *CreatePassRequest) (*PassResponse, error) {
+	ctx, span := tracing.Start(ctx, "EventService.CreatePass")
+	defer span.End()
+
+	pass := &Pass{
+		EventID:     eventID,
+		Name:        payload.Name,
+		Description: payload.Description,
+		Price:       payload.Price,
+		Currency:    payload.Currency,
+	}
+
+	err := s.db.WithTx(ctx, func(tx db.IDb) error {
+		repository := s.repository.WithTx(tx)
+
+		if _, err := repository.GetByID(ctx, eventID); err != nil {
+			return ErrEventNotFound
+		}
+
+		concertsList, err := s.eventConcerts(ctx, repository, eventID, payload.ConcertIDs)
+		if err != nil {
+			return err
+		}
+		pass.Concerts = concertsList
+
+		return repository.SavePass(ctx, pass)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return ToPassResponse(pass), nil
+}
+
+func (s *EventService) UpdatePass(ctx context.Context, eventID, passID uint, payload *UpdatePassRequest) (*PassResponse, error) {
+	ctx, span := tracing.Start(ctx, "EventService.UpdatePass")
+	defer span.End()
+
+	var pass *Pass
+
+	err := s.db.WithTx(ctx, func(tx db.IDb) error {
+		repository := s.repository.WithTx(tx)
+
+		var err error
+		pass, err = repository.GetPass(ctx, eventID, passID)
+		if err != nil {
+			return ErrPassNotFound
+		}
+
+		if payload.Name != nil {
+			pass.Name = *payload.Name
+		}
+		if payload.Description != nil {
+			pass.Description = *payload.Description
+		}
+		if payload.Price != nil {
+			pass.Price = *payload.Price
+		}
+		if payload.Currency != nil {
+			pass.Currency = *payload.Currency
+		}
+		if payload.ConcertIDs != nil {
+			concertsList, err := s.eventConcerts(ctx, repository, eventID, payload.ConcertIDs)
+			if err != nil {
+				return err
+			}
+			pass.Concerts = concertsList
+		}
+
+		return repository.SavePass(ctx, pass)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return ToPassResponse(pass), nil
+}
+
+func (s *EventService) DeletePass(ctx context.Context, eventID, passID uint) error {
+	ctx, span := tracing.Start(ctx, "EventService.DeletePass")
+	defer span.End()
+
+	pass, err := s.repository.GetPass(ctx, eventID, passID)
+	if err != nil {
+		return ErrPassNotFound
+	}
+	return s.repository.DeletePass(ctx, pass)
+}
+
+// setConcerts checks that concerts exist and are not part of another event before attaching them
+func (s *EventService) setConcerts(ctx context.Context, tx db.IDb, repository IEventRepository, eventID uint, ids []uint) error {
+	found, err := repository.GetConcerts(ctx, ids)
+	if err != nil {
+		return err
+	}
+
+	byID := make(map[uint]concerts.Concert, len(found))
+	for _, concert := range found {
+		byID[concert.ID] = concert
+	}
+	for _, id := range ids {
+		concert, ok := byID[id]
+		if !ok {
+			return ErrConcertNotFound
+		}
+		if concert.EventID != nil && *concert.EventID != eventID {
+			return ErrConcertInOtherEvent
+		}
+	}
+
+	detached, attached, err := repository.SetConcerts(ctx, eventID, ids)
+	if err != nil {
+		return err
+	}
+	return s.recordMoves(ctx, tx, eventID, detached, attached)
+}
+
+// recordMoves audits concerts that left or joined the event
+func (s *EventService) recordMoves(ctx context.Context, tx db.IDb, eventID uint, detached, attached []uint) error {
+	for _, id := range detached {
+		err := s.audit.Record(ctx, tx, audit.ActionUpdate, audit.EntityConcert, id, concertEvent{EventID: &eventID}, concertEvent{})
+		if err != nil {
+			return err
+		}
+	}
+	for _, id := range attached {
+		err := s.audit.Record(ctx, tx, audit.ActionUpdate, audit.EntityConcert, id, concertEvent{}, concertEvent{EventID: &eventID})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// eventConcerts loads concerts for a pass, all of them must belong to the event
+func (s *EventService) eventConcerts(ctx context.Context, repository IEventRepository, eventID uint, ids []uint) ([]concerts.Concert, error) {
+	found, err := repository.GetConcerts(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[uint]concerts.Concert, len(found))
+	for _, concert := range found {
+		byID[concert.ID] = concert
+	}
+
+	concertsList := make([]concerts.Concert, 0, len(ids))
+	seen := make(map[uint]bool, len(ids))
+	for _, id := range ids {
+		concert, ok := byID[id]
+		if !ok {
+			return nil, ErrConcertNotFound
+		}
+		if concert.EventID == nil || *concert.EventID != eventID {
+			return nil, ErrConcertNotInEvent
+		}
+		if !seen[id] {
+			seen[id] = true
+			concertsList = append(concertsList, concert)
+		}
+	}
+
+	return concertsList, nil
+}
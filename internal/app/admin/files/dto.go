@@ -0,0 +1,39 @@
+package files
+
+import (
+	"time"
+
+	"github.com/serhiirubets/rubeticket/internal/app/file"
+)
+
+// @Description File response model
+type FileResponse struct {
+	ID         uint        `json:"id"`
+	UUID       string      `json:"uuid"`
+	UserID     uint        `json:"userId"`
+	FilePath   string      `json:"filePath"`
+	Purpose    string      `json:"purpose"`
+	Status     file.Status `json:"status"`
+	ScanResult string      `json:"scanResult"`
+	ScannedAt  *time.Time  `json:"scannedAt"`
+	CreatedAt  time.Time   `json:"createdAt"`
+}
+
+// @Description List files response
+type ListFilesResponse struct {
+	Items []FileResponse `json:"items"`
+}
+
+func ToFileResponse(f *file.File) *FileResponse {
+	return &FileResponse{
+		ID:         f.ID,
+		UUID:       f.UUID,
+		UserID:     f.UserID,
+		FilePath:   f.FilePath,
+		Purpose:    f.Purpose,
+		Status:     f.Status,
+		ScanResult: f.ScanResult,
+		ScannedAt:  f.ScannedAt,
+		CreatedAt:  f.CreatedAt,
+	}
+}
@@ -0,0 +1,9 @@
+package files
+
+import "github.com/serhiirubets/rubeticket/internal/pkg/apperr"
+
+var (
+	ErrInvalidID          = apperr.New(apperr.Invalid, "invalid_file_id", "invalid file ID")
+	ErrFileNotFound       = apperr.New(apperr.NotFound, "file_not_found", "file not found")
+	ErrFileNotQuarantined = apperr.New(apperr.NotFound, "file_not_quarantined", "file is not quarantined")
+)
@@ -0,0 +1,206 @@
+package files
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/serhiirubets/rubeticket/config"
+	"github.com/serhiirubets/rubeticket/internal/pkg/apperr"
+	"github.com/serhiirubets/rubeticket/internal/pkg/log"
+	"github.com/serhiirubets/rubeticket/internal/pkg/res"
+)
+
+type FileHandlerDeps struct {
+	Config  *config.Config
+	Logger  log.ILogger
+	Service *FileService
+}
+
+type FileHandler struct {
+	Config  *config.Config
+	Logger  log.ILogger
+	Service *FileService
+}
+
+func NewFileHandler(router *http.ServeMux, deps *FileHandlerDeps) {
+	handler := FileHandler{
+		Config:  deps.Config,
+		Logger:  deps.Logger,
+		Service: deps.Service,
+	}
+
+	router.HandleFunc("GET /files/quarantined", handler.ListQuarantined())
+	router.HandleFunc("GET /files/quarantined/{id}/content", handler.Download())
+	router.HandleFunc("POST /files/quarantined/{id}/release", handler.Release())
+	router.HandleFunc("POST /files/quarantined/{id}/rescan", handler.Rescan())
+	router.HandleFunc("DELETE /files/quarantined/{id}", handler.Delete())
+}
+
+// ListQuarantined godoc
+// @Summary List quarantined files
+// @Description Get a paginated list of files that failed the malware scan
+// @Tags Admin/Files
+// @Produce json
+// @Param page query int false "Page number (default: 1)"
+// @Param pageSize query int false "Page size (default: 10, max: 100)"
+// @Success 200 {object} ListFilesResponse
+// @Failure 401 {object} res.Problem "Unauthorized"
+// @Failure 403 {object} res.Problem "Forbidden"
+// @Router /admin/v1/files/quarantined [get]
+func (h *FileHandler) ListQuarantined() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		pageSize, _ := strconv.Atoi(r.URL.Query().Get("pageSize"))
+
+		files, err := h.Service.ListQuarantined(r.Context(), page, pageSize)
+		if err != nil {
+			if apperr.IsInternal(err) {
+				h.Logger.WithContext(r.Context()).Error("Failed to list quarantined files", "error", err.Error())
+			}
+			res.Error(w, r, err)
+			return
+		}
+
+		res.Json(w, files, http.StatusOK)
+	}
+}
+
+// Download godoc
+// @Summary Download a quarantined file
+// @Description Download the content of a quarantined file for review. The file is always sent as an attachment
+// @Tags Admin/Files
+// @Produce application/octet-stream
+// @Param id path int true "File ID"
+// @Success 200 {file} file "File content"
+// @Failure 400 {object} res.Problem "Bad request"
+// @Failure 401 {object} res.Problem "Unauthorized"
+// @Failure 403 {object} res.Problem "Forbidden"
+// @Failure 404 {object} res.Problem "Not found"
+// @Router /admin/v1/files/quarantined/{id}/content [get]
+func (h *FileHandler) Download() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseUint(r.PathValue("id"), 10, 32)
+		if err != nil {
+			res.Error(w, r, ErrInvalidID)
+			return
+		}
+
+		f, err := h.Service.GetQuarantined(r.Context(), uint(id))
+		if err != nil {
+			res.Error(w, r, err)
+			return
+		}
+
+		content, err := h.Service.Open(f)
+		if err != nil {
+			h.Logger.WithContext(r.Context()).Warn("Quarantined file not found in storage", "uuid", f.UUID, "error", err.Error())
+			res.Error(w, r, ErrFileNotFound)
+			return
+		}
+		defer content.Close()
+
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Content-Disposition", `attachment; filename="`+f.FilePath+`.quarantined"`)
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		if _, err := io.Copy(w, content); err != nil {
+			h.Logger.WithContext(r.Context()).Error("Failed to send quarantined file", "uuid", f.UUID, "error", err.Error())
+		}
+	}
+}
+
+// Release godoc
+// @Summary Release a quarantined file
+// @Description Mark a quarantined file as available after review
+// @Tags Admin/Files
+// @Produce json
+// @Param id path int true "File ID"
+// @Success 200 {object} FileResponse
+// @Failure 400 {object} res.Problem "Bad request"
+// @Failure 401 {object} res.Problem "Unauthorized"
+// @Failure 403 {object} res.Problem "Forbidden"
+// @Failure 404 {object} res.Problem "Not found"
+// @Router /admin/v1/files/quarantined/{id}/release [post]
+func (h *FileHandler) Release() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseUint(r.PathValue("id"), 10, 32)
+		if err != nil {
+			res.Error(w, r, ErrInvalidID)
+			return
+		}
+
+		f, err := h.Service.Release(r.Context(), uint(id))
+		if err != nil {
+			h.writeError(w, r, "Failed to release file", err)
+			return
+		}
+
+		res.Json(w, f, http.StatusOK)
+	}
+}
+
+// Rescan godoc
+// @Summary Rescan a quarantined file
+// @Description Scan a quarantined file again, e.g. after scanner signatures were updated. Clean files become available
+// @Tags Admin/Files
+// @Produce json
+// @Param id path int true "File ID"
+// @Success 200 {object} FileResponse
+// @Failure 400 {object} res.Problem "Bad request"
+// @Failure 401 {object} res.Problem "Unauthorized"
+// @Failure 403 {object} res.Problem "Forbidden"
+// @Failure 404 {object} res.Problem "Not found"
+// @Router /admin/v1/files/quarantined/{id}/rescan [post]
+func (h *FileHandler) Rescan() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseUint(r.PathValue("id"), 10, 32)
+		if err != nil {
+			res.Error(w, r, ErrInvalidID)
+			return
+		}
+
+		f, err := h.Service.Rescan(r.Context(), uint(id))
+		if err != nil {
+			h.writeError(w, r, "Failed to rescan file", err)
+			return
+		}
+
+		res.Json(w, f, http.StatusOK)
+	}
+}
+
+// Delete godoc
+// @Summary Delete a quarantined file
+// @Description Remove a quarantined file from storage
+// @Tags Admin/Files
+// @Produce json
+// @Param id path int true "File ID"
+// @Success 204 "No Content"
+// @Failure 400 {object} res.Problem "Bad request"
+// @Failure 401 {object} res.Problem "Unauthorized"
+// @Failure 403 {object} res.Problem "Forbidden"
+// @Failure 404 {object} res.Problem "Not found"
+// @Router /admin/v1/files/quarantined/{id} [delete]
+func (h *FileHandler) Delete() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseUint(r.PathValue("id"), 10, 32)
+		if err != nil {
+			res.Error(w, r, ErrInvalidID)
+			return
+		}
+
+		if err := h.Service.Delete(r.Context(), uint(id)); err != nil {
+			h.writeError(w, r, "Failed to delete file", err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func (h *FileHandler) writeError(w http.ResponseWriter, r *http.Request, message string, err error) {
+	if apperr.IsInternal(err) {
+		h.Logger.WithContext(r.Context()).Error(message, "error", err.Error())
+	}
+	res.Error(w, r, err)
+}
@@ -0,0 +1,115 @@
+package files
+
+import (
+	"context"
+	"io"
+	"strconv"
+
+	"github.com/serhiirubets/rubeticket/internal/app/file"
+	"github.com/serhiirubets/rubeticket/internal/app/fileuploader"
+	"github.com/serhiirubets/rubeticket/internal/pkg/tracing"
+)
+
+type FileService struct {
+	repository   file.IFileRepository
+	fileUploader *fileuploader.FileUploader
+}
+
+func NewFileService(repository file.IFileRepository, fileUploader *fileuploader.FileUploader) *FileService {
+	return &FileService{repository: repository, fileUploader: fileUploader}
+}
+
+func (s *FileService) ListQuarantined(ctx context.Context, page, pageSize int) (*ListFilesResponse, error) {
+	ctx, span := tracing.Start(ctx, "FileService.ListQuarantined")
+	defer span.End()
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 10
+	}
+
+	quarantined, err := s.repository.ListByStatus(ctx, file.Quarantined, page, pageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &ListFilesResponse{
+		Items: make([]FileResponse, len(quarantined)),
+	}
+	for i, f := range quarantined {
+		response.Items[i] = *ToFileResponse(&f)
+	}
+
+	return response, nil
+}
+
+func (s *FileService) GetQuarantined(ctx context.Context, id uint) (*file.File, error) {
+	ctx, span := tracing.Start(ctx, "FileService.GetQuarantined")
+	defer span.End()
+
+	f, err := s.repository.GetById(ctx, strconv.FormatUint(uint64(id), 10))
+	if err != nil {
+		return nil, ErrFileNotFound
+	}
+	if f.Status != file.Quarantined {
+		return nil, ErrFileNotQuarantined
+	}
+	return f, nil
+}
+
+func (s *FileService) Open(f *file.File) (io.ReadCloser, error) {
+	return s.fileUploader.Storage.Open(f.FilePath)
+}
+
+// Release marks a quarantined file as available, e.g. after a false positive
+func (s *FileService) Release(ctx context.Context, id uint) (*FileResponse, error) {
+	ctx, span := tracing.Start(ctx, "FileService.Release")
+	defer span.End()
+
+	f, err := s.GetQuarantined(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repository.Update(ctx, f, map[string]interface{}{"status": file.Available}); err != nil {
+		return nil, err
+	}
+	f.Status = file.Available
+
+	return ToFileResponse(f), nil
+}
+
+func (s *FileService) Rescan(ctx context.Context, id uint) (*FileResponse, error) {
+	ctx, span := tracing.Start(ctx, "FileService.Rescan")
+	defer span.End()
+
+	f, err := s.GetQuarantined(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.fileUploader.Scan(ctx, f); err != nil {
+		return nil, err
+	}
+
+	return ToFileResponse(f), nil
+}
+
+// Delete removes a quarantined file from storage and its metadata
+func (s *FileService) Delete(ctx context.Context, id uint) error {
+	ctx, span := tracing.Start(ctx, "FileService.Delete")
+	defer span.End()
+
+	f, err := s.GetQuarantined(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if err := s.fileUploader.Storage.Remove(f.FilePath); err != nil {
+		s.fileUploader.Logger.Warn("Failed to remove quarantined file", "uuid", f.UUID, "error", err.Error())
+	}
+
+	return s.repository.Delete(ctx, f.ID)
+}
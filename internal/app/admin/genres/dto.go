@@ -0,0 +1,74 @@
+package genres
+
+// @Description Genre response model
+type GenreResponse struct {
+	ID       uint   `json:"id"`
+	Name     string `json:"name"`
+	Slug     string `json:"slug"`
+	ParentID *uint  `json:"parentId"`
+}
+
+// @Description Genre with its subgenres
+type GenreTreeResponse struct {
+	GenreResponse
+	Children []GenreTreeResponse `json:"children"`
+}
+
+// @Description Create genre request
+type CreateGenreRequest struct {
+	Name     string `json:"name" validate:"required,max=100"`
+	ParentID *uint  `json:"parentId"`
+}
+
+// @Description Update genre request, parentId 0 makes it a top level genre
+type UpdateGenreRequest struct {
+	Name     *string `json:"name" validate:"omitempty,max=100"`
+	ParentID *uint   `json:"parentId"`
+}
+
+func ToGenreResponse(genre *Genre) *GenreResponse {
+	return &GenreResponse{
+		ID:       genre.ID,
+		Name:     genre.Name,
+		Slug:     genre.Slug,
+		ParentID: genre.ParentID,
+	}
+}
+
+func ToGenreResponses(genres []Genre) []GenreResponse {
+	responses := make([]GenreResponse, len(genres))
+	for i, genre := range genres {
+		responses[i] = *ToGenreResponse(&genre)
+	}
+	return responses
+}
+
+// ToGenreTree nests genres under their parents, genres whose parent is missing become roots
+func ToGenreTree(genres []Genre) []GenreTreeResponse {
+	known := make(map[uint]bool, len(genres))
+	children := make(map[uint][]Genre)
+	var roots []Genre
+	for _, genre := range genres {
+		known[genre.ID] = true
+	}
+	for _, genre := range genres {
+		if genre.ParentID != nil && known[*genre.ParentID] {
+			children[*genre.ParentID] = append(children[*genre.ParentID], genre)
+		} else {
+			roots = append(roots, genre)
+		}
+	}
+
+	var build func(level []Genre) []GenreTreeResponse
+	build = func(level []Genre) []GenreTreeResponse {
+		nodes := make([]GenreTreeResponse, len(level))
+		for i, genre := range level {
+			nodes[i] = GenreTreeResponse{
+				GenreResponse: *ToGenreResponse(&genre),
+				Children:      build(children[genre.ID]),
+			}
+		}
+		return nodes
+	}
+	return build(roots)
+}
@@ -0,0 +1,11 @@
+package genres
+
+import "github.com/serhiirubets/rubeticket/internal/pkg/apperr"
+
+var (
+	ErrInvalidID      = apperr.New(apperr.Invalid, "invalid_genre_id", "invalid genre ID")
+	ErrGenreNotFound  = apperr.New(apperr.NotFound, "genre_not_found", "genre not found")
+	ErrParentNotFound = apperr.New(apperr.Invalid, "parent_genre_not_found", "parent genre not found")
+	ErrGenreCycle     = apperr.New(apperr.Invalid, "genre_cycle", "genre cannot be its own ancestor")
+	ErrSlugTaken      = apperr.New(apperr.Conflict, "genre_exists", "genre with this name already exists")
+)
@@ -0,0 +1,197 @@
+package genres
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/serhiirubets/rubeticket/config"
+	"github.com/serhiirubets/rubeticket/internal/pkg/apperr"
+	"github.com/serhiirubets/rubeticket/internal/pkg/log"
+	"github.com/serhiirubets/rubeticket/internal/pkg/req"
+	"github.com/serhiirubets/rubeticket/internal/pkg/res"
+)
+
+type GenreHandlerDeps struct {
+	Config  *config.Config
+	Logger  log.ILogger
+	Service *GenreService
+}
+
+type GenreHandler struct {
+	Config  *config.Config
+	Logger  log.ILogger
+	Service *GenreService
+}
+
+func NewGenreHandler(router *http.ServeMux, deps *GenreHandlerDeps) {
+	handler := GenreHandler{
+		Config:  deps.Config,
+		Logger:  deps.Logger,
+		Service: deps.Service,
+	}
+
+	router.HandleFunc("POST /genres", handler.Create())
+	router.HandleFunc("PUT /genres/{id}", handler.Update())
+	router.HandleFunc("DELETE /genres/{id}", handler.Delete())
+	router.HandleFunc("GET /genres/{id}", handler.GetByID())
+	router.HandleFunc("GET /genres", handler.Tree())
+}
+
+// Create godoc
+// @Summary Create a genre
+// @Description Create a genre, parentId makes it a subgenre
+// @Tags Admin/Genres
+// @Accept json
+// @Produce json
+// @Param request body CreateGenreRequest true "Genre details"
+// @Success 201 {object} GenreResponse
+// @Failure 400 {object} res.Problem "Bad request"
+// @Failure 401 {object} res.Problem "Unauthorized"
+// @Failure 403 {object} res.Problem "Forbidden"
+// @Failure 409 {object} res.Problem "Genre already exists"
+// @Router /admin/v1/genres [post]
+func (h *GenreHandler) Create() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		payload, err := req.HandleBody[CreateGenreRequest](&w, r)
+		if err != nil {
+			return
+		}
+
+		genre, err := h.Service.Create(r.Context(), payload)
+		if err != nil {
+			if apperr.IsInternal(err) {
+				h.Logger.WithContext(r.Context()).Error("Failed to create genre", "error", err.Error())
+			}
+			res.Error(w, r, err)
+			return
+		}
+
+		res.Json(w, genre, http.StatusCreated)
+	}
+}
+
+// Update godoc
+// @Summary Update a genre
+// @Description Rename a genre or move it under another parent, parentId 0 moves it to the top level
+// @Tags Admin/Genres
+// @Accept json
+// @Produce json
+// @Param id path int true "Genre ID"
+// @Param request body UpdateGenreRequest true "Genre details"
+// @Success 200 {object} GenreResponse
+// @Failure 400 {object} res.Problem "Bad request"
+// @Failure 401 {object} res.Problem "Unauthorized"
+// @Failure 403 {object} res.Problem "Forbidden"
+// @Failure 404 {object} res.Problem "Not found"
+// @Failure 409 {object} res.Problem "Genre already exists"
+// @Router /admin/v1/genres/{id} [put]
+func (h *GenreHandler) Update() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseUint(r.PathValue("id"), 10, 32)
+		if err != nil {
+			res.Error(w, r, ErrInvalidID)
+			return
+		}
+
+		payload, err := req.HandleBody[UpdateGenreRequest](&w, r)
+		if err != nil {
+			return
+		}
+
+		genre, err := h.Service.Update(r.Context(), uint(id), payload)
+		if err != nil {
+			if apperr.IsInternal(err) {
+				h.Logger.WithContext(r.Context()).Error("Failed to update genre", "error", err.Error())
+			}
+			res.Error(w, r, err)
+			return
+		}
+
+		res.Json(w, genre, http.StatusOK)
+	}
+}
+
+// Delete godoc
+// @Summary Delete a genre
+// @Description Delete a genre, its subgenres move up to its parent
+// @Tags Admin/Genres
+// @Produce json
+// @Param id path int true "Genre ID"
+// @Success 204 "No Content"
+// @Failure 400 {object} res.Problem "Bad request"
+// @Failure 401 {object} res.Problem "Unauthorized"
+// @Failure 403 {object} res.Problem "Forbidden"
+// @Router /admin/v1/genres/{id} [delete]
+func (h *GenreHandler) Delete() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseUint(r.PathValue("id"), 10, 32)
+		if err != nil {
+			res.Error(w, r, ErrInvalidID)
+			return
+		}
+
+		if err := h.Service.Delete(r.Context(), uint(id)); err != nil {
+			if apperr.IsInternal(err) {
+				h.Logger.WithContext(r.Context()).Error("Failed to delete genre", "error", err.Error())
+			}
+			res.Error(w, r, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// GetByID godoc
+// @Summary Get a genre by ID
+// @Description Get details of a specific genre
+// @Tags Admin/Genres
+// @Produce json
+// @Param id path int true "Genre ID"
+// @Success 200 {object} GenreResponse
+// @Failure 400 {object} res.Problem "Bad request"
+// @Failure 401 {object} res.Problem "Unauthorized"
+// @Failure 403 {object} res.Problem "Forbidden"
+// @Failure 404 {object} res.Problem "Not found"
+// @Router /admin/v1/genres/{id} [get]
+func (h *GenreHandler) GetByID() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseUint(r.PathValue("id"), 10, 32)
+		if err != nil {
+			res.Error(w, r, ErrInvalidID)
+			return
+		}
+
+		genre, err := h.Service.GetByID(r.Context(), uint(id))
+		if err != nil {
+			res.Error(w, r, err)
+			return
+		}
+
+		res.Json(w, genre, http.StatusOK)
+	}
+}
+
+// Tree godoc
+// @Summary List genres
+// @Description Get all genres nested under their parents
+// @Tags Admin/Genres
+// @Produce json
+// @Success 200 {array} GenreTreeResponse
+// @Failure 401 {object} res.Problem "Unauthorized"
+// @Failure 403 {object} res.Problem "Forbidden"
+// @Router /admin/v1/genres [get]
+func (h *GenreHandler) Tree() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tree, err := h.Service.Tree(r.Context())
+		if err != nil {
+			if apperr.IsInternal(err) {
+				h.Logger.WithContext(r.Context()).Error("Failed to list genres", "error", err.Error())
+			}
+			res.Error(w, r, err)
+			return
+		}
+
+		res.Json(w, tree, http.StatusOK)
+	}
+}
@@ -0,0 +1,32 @@
+package genres
+
+import (
+	"strings"
+	"unicode"
+
+	"gorm.io/gorm"
+)
+
+// @Description Genre, subgenres point to their parent
+type Genre struct {
+	*gorm.Model
+	Name     string `json:"name" gorm:"type:varchar(100);not null"`
+	Slug     string `json:"slug" gorm:"type:varchar(100);not null;uniqueIndex:idx_genre_slug,where:deleted_at IS NULL"`
+	ParentID *uint  `json:"parentId" gorm:"index:idx_genre_parent_id"`
+}
+
+// Slugify lowercases the name and replaces everything except letters and digits with dashes
+func Slugify(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(strings.TrimSpace(name)) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+			dash = false
+		} else if !dash && b.Len() > 0 {
+			b.WriteRune('-')
+			dash = true
+		}
+	}
+	return strings.TrimSuffix(b.String(), "-")
+}
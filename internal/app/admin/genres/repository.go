@@ -0,0 +1,138 @@
+package genres
+
+import (
+	"context"
+
+	"github.com/serhiirubets/rubeticket/internal/pkg/db"
+)
+
+// descendantsCTE selects the genre with the given id and all its subgenres
+const descendantsCTE = `WITH RECURSIVE tree AS (
+	SELECT id FROM genres WHERE id = ? AND deleted_at IS NULL
+	UNION
+	SELECT g.id FROM genres g JOIN tree t ON g.parent_id = t.id WHERE g.deleted_at IS NULL
+) SELECT id FROM tree`
+
+// BandsInGenre is a band list condition matching bands of a genre or any of its subgenres
+const BandsInGenre = "id IN (SELECT bg.band_id FROM band_genres bg WHERE bg.genre_id IN (" + descendantsCTE + "))"
+
+type IGenreRepository interface {
+	Create(ctx context.Context, genre *Genre) (*Genre, error)
+	Update(ctx context.Context, genre *Genre) error
+	RenamePrimary(ctx context.Context, id uint, oldName, newName string) error
+	Delete(ctx context.Context, id uint) error
+	GetByID(ctx context.Context, id uint) (*Genre, error)
+	GetBySlug(ctx context.Context, slug string) (*Genre, error)
+	GetByIDs(ctx context.Context, ids []uint) ([]Genre, error)
+	List(ctx context.Context) ([]Genre, error)
+	DescendantIDs(ctx context.Context, id uint) ([]uint, error)
+	WithTx(tx db.IDb) IGenreRepository
+}
+
+type GenreRepository struct {
+	Db db.IDb
+}
+
+func NewGenreRepository(Db db.IDb) IGenreRepository {
+	return &GenreRepository{Db: Db}
+}
+
+// WithTx returns a copy of the repository that runs queries in tx
+func (r *GenreRepository) WithTx(tx db.IDb) IGenreRepository {
+	return &GenreRepository{Db: tx}
+}
+
+func (r *GenreRepository) Create(ctx context.Context, genre *Genre) (*Genre, error) {
+	if err := r.Db.WithContext(ctx).Create(genre).Error; err != nil {
+		return nil, err
+	}
+	return genre, nil
+}
+
+func (r *GenreRepository) Update(ctx context.Context, genre *Genre) error {
+	return r.Db.WithContext(ctx).Save(genre).Error
+}
+
+// RenamePrimary renames the primary genre of bands that have the genre as primary.
+// Call it in the transaction that renames the genre
+func (r *GenreRepository) RenamePrimary(ctx context.Context, id uint, oldName, newName string) error {
+	return r.Db.WithContext(ctx).Exec(`
+		UPDATE bands SET genre = ?, version = version + 1, updated_at = now()
+		WHERE genre = ? AND id IN (SELECT band_id FROM band_genres WHERE genre_id = ?)`,
+		newName, oldName, id).Error
+}
+
+// Delete removes the genre, its subgenres move up to its parent.
+// Bands that had it as primary genre get another of their genres or none. Call it in a transaction
+func (r *GenreRepository) Delete(ctx context.Context, id uint) error {
+	genre, err := r.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	conn := r.Db.WithContext(ctx)
+	if err := conn.Model(&Genre{}).Where("parent_id = ?", id).Update("parent_id", genre.ParentID).Error; err != nil {
+		return err
+	}
+	err = conn.Exec(`
+		UPDATE bands b SET version = b.version + 1, updated_at = now(), genre = coalesce((
+			SELECT g.name FROM band_genres bg
+			JOIN genres g ON g.id = bg.genre_id AND g.deleted_at IS NULL
+			WHERE bg.band_id = b.id AND bg.genre_id <> ?
+			ORDER BY bg.position
+			LIMIT 1
+		), '')
+		WHERE b.genre = ? AND b.id IN (SELECT band_id FROM band_genres WHERE genre_id = ?)`,
+		id, genre.Name, id).Error
+	if err != nil {
+		return err
+	}
+	if err := conn.Exec("DELETE FROM band_genres WHERE genre_id = ?", id).Error; err != nil {
+		return err
+	}
+	return conn.Delete(&Genre{}, id).Error
+}
+
+func (r *GenreRepository) GetByID(ctx context.Context, id uint) (*Genre, error) {
+	var genre Genre
+	if err := r.Db.WithContext(ctx).First(&genre, id).Error; err != nil {
+		return nil, err
+	}
+	return &genre, nil
+}
+
+func (r *GenreRepository) GetBySlug(ctx context.Context, slug string) (*Genre, error) {
+	var genre Genre
+	if err := r.Db.WithContext(ctx).Where("slug = ?", slug).First(&genre).Error; err != nil {
+		return nil, err
+	}
+	return &genre, nil
+}
+
+// GetByIDs loads genres in one query, missing ids are skipped
+func (r *GenreRepository) GetByIDs(ctx context.Context, ids []uint) ([]Genre, error) {
+	var genres []Genre
+	if len(ids) == 0 {
+		return genres, nil
+	}
+	if err := r.Db.WithContext(ctx).Where("id IN ?", ids).Find(&genres).Error; err != nil {
+		return nil, err
+	}
+	return genres, nil
+}
+
+func (r *GenreRepository) List(ctx context.Context) ([]Genre, error) {
+	var genres []Genre
+	if err := r.Db.WithContext(ctx).Order("name").Find(&genres).Error; err != nil {
+		return nil, err
+	}
+	return genres, nil
+}
+
+// DescendantIDs returns the id of the genre and of all genres below it
+func (r *GenreRepository) DescendantIDs(ctx context.Context, id uint) ([]uint, error) {
+	var ids []uint
+	if err := r.Db.WithContext(ctx).Raw(descendantsCTE, id).Scan(&ids).Error; err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
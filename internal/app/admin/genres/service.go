@@ -0,0 +1,137 @@
+package genres
+
+import (
+	"context"
+	"slices"
+
+	"github.com/serhiirubets/rubeticket/internal/pkg/db"
+	"github.com/serhiirubets/rubeticket/internal/pkg/tracing"
+)
+
+type GenreService struct {
+	db         db.IDb
+	repository IGenreRepository
+}
+
+func NewGenreService(db db.IDb, repository IGenreRepository) *GenreService {
+	return &GenreService{
+		db:         db,
+		repository: repository,
+	}
+}
+
+func (s *GenreService) Create(ctx context.Context, payload *CreateGenreRequest) (*GenreResponse, error) {
+	ctx, span := tracing.Start(ctx, "GenreService.Create")
+	defer span.End()
+
+	genre := &Genre{
+		Name: payload.Name,
+		Slug: Slugify(payload.Name),
+	}
+	if _, err := s.repository.GetBySlug(ctx, genre.Slug); err == nil {
+		return nil, ErrSlugTaken
+	}
+
+	if payload.ParentID != nil {
+		if _, err := s.repository.GetByID(ctx, *payload.ParentID); err != nil {
+			return nil, ErrParentNotFound
+		}
+		genre.ParentID = payload.ParentID
+	}
+
+	created, err := s.repository.Create(ctx, genre)
+	if err != nil {
+		return nil, err
+	}
+	return ToGenreResponse(created), nil
+}
+
+func (s *GenreService) Update(ctx context.Context, id uint, payload *UpdateGenreRequest) (*GenreResponse, error) {
+	ctx, span := tracing.Start(ctx, "GenreService.Update")
+	defer span.End()
+
+	var genre *Genre
+
+	err := s.db.WithTx(ctx, func(tx db.IDb) error {
+		repository := s.repository.WithTx(tx)
+
+		var err error
+		genre, err = repository.GetByID(ctx, id)
+		if err != nil {
+			return ErrGenreNotFound
+		}
+
+		oldName := genre.Name
+		if payload.Name != nil {
+			slug := Slugify(*payload.Name)
+			if existing, err := repository.GetBySlug(ctx, slug); err == nil && existing.ID != id {
+				return ErrSlugTaken
+			}
+			genre.Name = *payload.Name
+			genre.Slug = slug
+		}
+
+		if payload.ParentID != nil {
+			if *payload.ParentID == 0 {
+				genre.ParentID = nil
+			} else {
+				if _, err := repository.GetByID(ctx, *payload.ParentID); err != nil {
+					return ErrParentNotFound
+				}
+				descendants, err := repository.DescendantIDs(ctx, id)
+				if err != nil {
+					return err
+				}
+				if slices.Contains(descendants, *payload.ParentID) {
+					return ErrGenreCycle
+				}
+				genre.ParentID = payload.ParentID
+			}
+		}
+
+		if err := repository.Update(ctx, genre); err != nil {
+			return err
+		}
+		if genre.Name != oldName {
+			return repository.RenamePrimary(ctx, id, oldName, genre.Name)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return ToGenreResponse(genre), nil
+}
+
+func (s *GenreService) Delete(ctx context.Context, id uint) error {
+	ctx, span := tracing.Start(ctx, "GenreService.Delete")
+	defer span.End()
+
+	return s.db.WithTx(ctx, func(tx db.IDb) error {
+		return s.repository.WithTx(tx).Delete(ctx, id)
+	})
+}
+
+func (s *GenreService) GetByID(ctx context.Context, id uint) (*GenreResponse, error) {
+	ctx, span := tracing.Start(ctx, "GenreService.GetByID")
+	defer span.End()
+
+	genre, err := s.repository.GetByID(ctx, id)
+	if err != nil {
+		return nil, ErrGenreNotFound
+	}
+	return ToGenreResponse(genre), nil
+}
+
+// Tree returns all genres nested under their parents
+func (s *GenreService) Tree(ctx context.Context) ([]GenreTreeResponse, error) {
+	ctx, span := tracing.Start(ctx, "GenreService.Tree")
+	defer span.End()
+
+	genres, err := s.repository.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return ToGenreTree(genres), nil
+}
@@ -0,0 +1,381 @@
+package imports
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/serhiirubets/rubeticket/internal/pkg/req"
+)
+
+// listSeparator splits CSV cells of simple lists like genreIds, other lists and objects are JSON in the cell
+const listSeparator = ";"
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// column is a field of a row type, the CSV header and JSON key are its json name
+type column struct {
+	name  string
+	index []int
+}
+
+// row is one decoded row of an import file, value points at a row struct
+type row struct {
+	number int
+	value  any
+	errors []RowError
+}
+
+// columnsOf lists the fields of a row struct in order, embedded structs are flattened
+func columnsOf(t reflect.Type) []column {
+	var columns []column
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			for _, embedded := range columnsOf(field.Type) {
+				columns = append(columns, column{name: embedded.name, index: append([]int{i}, embedded.index...)})
+			}
+			continue
+		}
+		name := jsonName(field)
+		if !field.IsExported() || name == "" {
+			continue
+		}
+		columns = append(columns, column{name: name, index: []int{i}})
+	}
+	return columns
+}
+
+func jsonName(field reflect.StructField) string {
+	name := strings.Split(field.Tag.Get("json"), ",")[0]
+	if name == "-" {
+		return ""
+	}
+	return name
+}
+
+// decodeRows reads all rows of an import file. A broken file is an error, a value that does not fit
+// its field is a row error and the rest of the file is still read
+func decodeRows(format Format, data []byte, newRow func() any) ([]row, error) {
+	switch format {
+	case FormatCSV:
+		return decodeCSV(data, newRow)
+	case FormatJSON:
+		return decodeJSON(data, newRow)
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+}
+
+func decodeJSON(data []byte, newRow func() any) ([]row, error) {
+	var items []json.RawMessage
+	if err := json.Unmarshal(data, &items); err != nil {
+		return nil, ErrInvalidFile.WithMessage("expected a JSON array of objects").Wrap(err)
+	}
+
+	rows := make([]row, len(items))
+	for i, item := range items {
+		rows[i] = row{number: i + 1, value: newRow()}
+
+		decoder := json.NewDecoder(bytes.NewReader(item))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(rows[i].value); err != nil {
+			rowError := RowError{Row: i + 1, Code: ErrInvalidFile.Code, Message: err.Error()}
+			var typeErr *json.UnmarshalTypeError
+			if errors.As(err, &typeErr) {
+				rowError.Field = typeErr.Field
+				rowError.Code = "type"
+				rowError.Message = "must be " + typeErr.Type.String()
+			}
+			rows[i].errors = append(rows[i].errors, rowError)
+		}
+	}
+	return rows, nil
+}
+
+func decodeCSV(data []byte, newRow func() any) ([]row, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, ErrEmptyImport
+	}
+	if err != nil {
+		return nil, ErrInvalidFile.WithMessage(err.Error()).Wrap(err)
+	}
+	// spreadsheets often save CSV with a byte order mark
+	header[0] = strings.TrimPrefix(header[0], "\ufeff")
+
+	byName := make(map[string]column)
+	for _, c := range columnsOf(reflect.TypeOf(newRow()).Elem()) {
+		byName[c.name] = c
+	}
+	columns := make([]column, len(header))
+	for i, name := range header {
+		c, ok := byName[strings.TrimSpace(name)]
+		if !ok {
+			return nil, ErrInvalidFile.WithMessage(fmt.Sprintf("unknown column %q", name))
+		}
+		columns[i] = c
+	}
+
+	var rows []row
+	for number := 1; ; number++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, ErrInvalidFile.WithMessage(err.Error()).Wrap(err)
+		}
+
+		decoded := row{number: number, value: newRow()}
+		target := reflect.ValueOf(decoded.value).Elem()
+		for i, cell := range record {
+			if err := setCell(target.FieldByIndex(columns[i].index), cell); err != nil {
+				decoded.errors = append(decoded.errors, RowError{Row: number, Field: columns[i].name, Code: "type", Message: err.Error()})
+			}
+		}
+		rows = append(rows, decoded)
+	}
+	return rows, nil
+}
+
+// setCell parses a CSV cell into a field, an empty cell leaves the zero value
+func setCell(v reflect.Value, cell string) error {
+	cell = strings.TrimSpace(cell)
+	if cell == "" {
+		v.Set(reflect.Zero(v.Type()))
+		return nil
+	}
+
+	if v.Type() == timeType {
+		t, err := time.Parse(time.RFC3339, cell)
+		if err != nil {
+			return errors.New("must be an RFC 3339 time")
+		}
+		v.Set(reflect.ValueOf(t))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer:
+		elem := reflect.New(v.Type().Elem())
+		if err := setCell(elem.Elem(), cell); err != nil {
+			return err
+		}
+		v.Set(elem)
+	case reflect.String:
+		v.SetString(cell)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(cell, 10, v.Type().Bits())
+		if err != nil {
+			return errors.New("must be a whole number")
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(cell, 10, v.Type().Bits())
+		if err != nil {
+			return errors.New("must be a positive whole number")
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(cell, v.Type().Bits())
+		if err != nil {
+			return errors.New("must be a number")
+		}
+		v.SetFloat(n)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(cell)
+		if err != nil {
+			return errors.New("must be true or false")
+		}
+		v.SetBool(b)
+	case reflect.Slice:
+		if isScalar(v.Type().Elem()) {
+			parts := strings.Split(cell, listSeparator)
+			list := reflect.MakeSlice(v.Type(), len(parts), len(parts))
+			for i, part := range parts {
+				if err := setCell(list.Index(i), part); err != nil {
+					return err
+				}
+			}
+			v.Set(list)
+			return nil
+		}
+		fallthrough
+	default:
+		if err := json.Unmarshal([]byte(cell), v.Addr().Interface()); err != nil {
+			return fmt.Errorf("must be JSON: %s", err.Error())
+		}
+	}
+	return nil
+}
+
+// formatCell is the reverse of setCell
+func formatCell(v reflect.Value) (string, error) {
+	if v.Type() == timeType {
+		t := v.Interface().(time.Time)
+		if t.IsZero() {
+			return "", nil
+		}
+		return t.UTC().Format(time.RFC3339), nil
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			return "", nil
+		}
+		return formatCell(v.Elem())
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, v.Type().Bits()), nil
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+	case reflect.Slice:
+		if v.Len() == 0 {
+			return "", nil
+		}
+		if isScalar(v.Type().Elem()) {
+			parts := make([]string, v.Len())
+			for i := range parts {
+				part, err := formatCell(v.Index(i))
+				if err != nil {
+					return "", err
+				}
+				parts[i] = part
+			}
+			return strings.Join(parts, listSeparator), nil
+		}
+	}
+
+	data, err := json.Marshal(v.Interface())
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+func isScalar(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// rowWriter writes export rows in the format they are imported in
+type rowWriter interface {
+	Write(value any) error
+	Close() error
+}
+
+func newRowWriter(format Format, out io.Writer, rowType reflect.Type) (rowWriter, error) {
+	switch format {
+	case FormatCSV:
+		columns := columnsOf(rowType)
+		header := make([]string, len(columns))
+		for i, c := range columns {
+			header[i] = c.name
+		}
+		writer := csv.NewWriter(out)
+		if err := writer.Write(header); err != nil {
+			return nil, err
+		}
+		return &csvRowWriter{writer: writer, columns: columns}, nil
+	case FormatJSON:
+		return &jsonRowWriter{out: out}, nil
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+}
+
+type csvRowWriter struct {
+	writer  *csv.Writer
+	columns []column
+}
+
+func (w *csvRowWriter) Write(value any) error {
+	source := reflect.ValueOf(value).Elem()
+	record := make([]string, len(w.columns))
+	for i, c := range w.columns {
+		cell, err := formatCell(source.FieldByIndex(c.index))
+		if err != nil {
+			return err
+		}
+		record[i] = cell
+	}
+	return w.writer.Write(record)
+}
+
+func (w *csvRowWriter) Close() error {
+	w.writer.Flush()
+	return w.writer.Error()
+}
+
+// jsonRowWriter streams rows as one JSON array
+type jsonRowWriter struct {
+	out   io.Writer
+	count int
+}
+
+func (w *jsonRowWriter) Write(value any) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	separator := ",\n"
+	if w.count == 0 {
+		separator = "[\n"
+	}
+	w.count++
+	if _, err := io.WriteString(w.out, separator); err != nil {
+		return err
+	}
+	_, err = w.out.Write(data)
+	return err
+}
+
+func (w *jsonRowWriter) Close() error {
+	end := "\n]\n"
+	if w.count == 0 {
+		end = "[]\n"
+	}
+	_, err := io.WriteString(w.out, end)
+	return err
+}
+
+// validateRow checks the validate tags of a row, problems are reported by the json path of the field
+func validateRow(number int, value any) []RowError {
+	err := req.IsValid(value)
+	if err == nil {
+		return nil
+	}
+
+	fields := req.ValidationFields(err)
+	if fields == nil {
+		return []RowError{{Row: number, Code: req.ErrValidation.Code, Message: err.Error()}}
+	}
+
+	rowErrors := make([]RowError, len(fields))
+	for i, field := range fields {
+		rowErrors[i] = RowError{Row: number, Field: field.Field, Code: field.Code, Message: field.Message}
+	}
+	return rowErrors
+}
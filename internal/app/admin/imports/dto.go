@@ -0,0 +1,44 @@
+package imports
+
+import "time"
+
+// @Description Import job with its report. Counts are final once status is done, a dry run reports what would be saved
+type JobResponse struct {
+	ID         uint       `json:"id"`
+	Entity     string     `json:"entity"`
+	Format     Format     `json:"format"`
+	DryRun     bool       `json:"dryRun"`
+	Status     Status     `json:"status"`
+	Total      int        `json:"total"`
+	Created    int        `json:"created"`
+	Updated    int        `json:"updated"`
+	Failed     int        `json:"failed"`
+	Errors     []RowError `json:"errors"`
+	Error      string     `json:"error,omitempty"`
+	CreatedBy  *uint      `json:"createdBy,omitempty"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	FinishedAt *time.Time `json:"finishedAt,omitempty"`
+}
+
+func ToJobResponse(job *Job) *JobResponse {
+	response := &JobResponse{
+		ID:         job.ID,
+		Entity:     job.Entity,
+		Format:     job.Format,
+		DryRun:     job.DryRun,
+		Status:     job.Status,
+		Total:      job.Total,
+		Created:    job.Created,
+		Updated:    job.Updated,
+		Failed:     job.Failed,
+		Errors:     job.Errors,
+		Error:      job.Error,
+		CreatedBy:  job.CreatedBy,
+		CreatedAt:  job.CreatedAt,
+		FinishedAt: job.FinishedAt,
+	}
+	if response.Errors == nil {
+		response.Errors = []RowError{}
+	}
+	return response
+}
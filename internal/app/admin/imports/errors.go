@@ -0,0 +1,17 @@
+package imports
+
+import "github.com/serhiirubets/rubeticket/internal/pkg/apperr"
+
+var (
+	ErrInvalidID         = apperr.New(apperr.Invalid, "invalid_import_id", "invalid import ID")
+	ErrJobNotFound       = apperr.New(apperr.NotFound, "import_not_found", "import not found")
+	ErrJobNotSaved       = apperr.New(apperr.Internal, "import_not_started", "failed to start import")
+	ErrInvalidFile       = apperr.New(apperr.Invalid, "invalid_file", "file cannot be read")
+	ErrUnsupportedFormat = apperr.New(apperr.Invalid, "unsupported_format", "format must be csv or json")
+	ErrEmptyImport       = apperr.New(apperr.Invalid, "empty_import", "import has no rows")
+	ErrTooManyRows       = apperr.New(apperr.Invalid, "too_many_rows", "import has too many rows")
+	ErrFileTooLarge      = apperr.New(apperr.TooLarge, "file_too_large", "file is too large")
+	ErrNoKey             = apperr.New(apperr.Invalid, "missing_key", "id or externalId is required")
+	ErrInTrash           = apperr.New(apperr.Conflict, "in_trash", "is in trash, restore it before importing")
+	ErrReferenceNotFound = apperr.New(apperr.Invalid, "reference_not_found", "referenced entity not found")
+)
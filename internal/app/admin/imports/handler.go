@@ -0,0 +1,307 @@
+package imports
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/serhiirubets/rubeticket/config"
+	"github.com/serhiirubets/rubeticket/internal/app/admin/audit"
+	"github.com/serhiirubets/rubeticket/internal/pkg/apperr"
+	"github.com/serhiirubets/rubeticket/internal/pkg/log"
+	"github.com/serhiirubets/rubeticket/internal/pkg/middleware"
+	"github.com/serhiirubets/rubeticket/internal/pkg/query"
+	"github.com/serhiirubets/rubeticket/internal/pkg/req"
+	"github.com/serhiirubets/rubeticket/internal/pkg/res"
+)
+
+type ImportHandlerDeps struct {
+	Config  *config.Config
+	Logger  log.ILogger
+	Service *ImportService
+}
+
+type ImportHandler struct {
+	Config  *config.Config
+	Logger  log.ILogger
+	Service *ImportService
+}
+
+func NewImportHandler(router *http.ServeMux, deps *ImportHandlerDeps) {
+	handler := ImportHandler{
+		Config:  deps.Config,
+		Logger:  deps.Logger,
+		Service: deps.Service,
+	}
+
+	router.HandleFunc("POST /admin/venues/import", handler.ImportVenues())
+	router.HandleFunc("GET /admin/venues/export", handler.ExportVenues())
+	router.HandleFunc("POST /bands/import", handler.ImportBands())
+	router.HandleFunc("GET /bands/export", handler.ExportBands())
+	router.HandleFunc("POST /admin/concerts/import", handler.ImportConcerts())
+	router.HandleFunc("GET /admin/concerts/export", handler.ExportConcerts())
+	router.HandleFunc("GET /imports/{id}", handler.GetJob())
+}
+
+// ImportVenues godoc
+// @Summary Import venues
+// @Description Create or update venues from a CSV file with a header row or a JSON array of VenueRow.
+// @Description Rows are matched by id, then by externalId. Valid rows are saved even if other rows fail.
+// @Description Files over 500 rows or with async=true are imported in the background, poll the returned job
+// @Tags Admin/Imports
+// @Accept text/csv,json
+// @Produce json
+// @Param format query string false "csv or json, taken from Content-Type when empty"
+// @Param dryRun query bool false "Only validate and report, nothing is saved"
+// @Param async query bool false "Import in the background"
+// @Param request body []VenueRow true "Rows"
+// @Success 200 {object} JobResponse "Finished import with its report"
+// @Success 202 {object} JobResponse "Import started in the background"
+// @Failure 400 {object} res.Problem "File cannot be read"
+// @Failure 401 {object} res.Problem "Unauthorized"
+// @Failure 403 {object} res.Problem "Forbidden"
+// @Failure 413 {object} res.Problem "File is too large"
+// @Router /admin/v1/venues/import [post]
+func (h *ImportHandler) ImportVenues() http.HandlerFunc {
+	return h.importFile(audit.EntityVenue)
+}
+
+// ExportVenues godoc
+// @Summary Export venues
+// @Description Download venues matching the filters in the import format, by id. Takes the same filters as the list
+// @Tags Admin/Imports
+// @Produce text/csv,json
+// @Param format query string false "csv (default) or json"
+// @Param q query string false "Search"
+// @Success 200 {array} VenueRow
+// @Failure 400 {object} res.Problem "Unsupported format or filter"
+// @Failure 401 {object} res.Problem "Unauthorized"
+// @Failure 403 {object} res.Problem "Forbidden"
+// @Router /admin/v1/venues/export [get]
+func (h *ImportHandler) ExportVenues() http.HandlerFunc {
+	return h.export(audit.EntityVenue)
+}
+
+// ImportBands godoc
+// @Summary Import bands
+// @Description Create or update bands from a CSV file with a header row or a JSON array of BandRow.
+// @Description Rows are matched by id, then by externalId. Valid rows are saved even if other rows fail.
+// @Description Files over 500 rows or with async=true are imported in the background, poll the returned job
+// @Tags Admin/Imports
+// @Accept text/csv,json
+// @Produce json
+// @Param format query string false "csv or json, taken from Content-Type when empty"
+// @Param dryRun query bool false "Only validate and report, nothing is saved"
+// @Param async query bool false "Import in the background"
+// @Param request body []BandRow true "Rows"
+// @Success 200 {object} JobResponse "Finished import with its report"
+// @Success 202 {object} JobResponse "Import started in the background"
+// @Failure 400 {object} res.Problem "File cannot be read"
+// @Failure 401 {object} res.Problem "Unauthorized"
+// @Failure 403 {object} res.Problem "Forbidden"
+// @Failure 413 {object} res.Problem "File is too large"
+// @Router /admin/v1/bands/import [post]
+func (h *ImportHandler) ImportBands() http.HandlerFunc {
+	return h.importFile(audit.EntityBand)
+}
+
+// ExportBands godoc
+// @Summary Export bands
+// @Description Download bands matching the filters in the import format, by id. Takes the same filters as the list
+// @Tags Admin/Imports
+// @Produce text/csv,json
+// @Param format query string false "csv (default) or json"
+// @Param q query string false "Search"
+// @Param genreId query int false "Genre ID, subgenres included"
+// @Param country query string false "Country code"
+// @Success 200 {array} BandRow
+// @Failure 400 {object} res.Problem "Unsupported format or filter"
+// @Failure 401 {object} res.Problem "Unauthorized"
+// @Failure 403 {object} res.Problem "Forbidden"
+// @Router /admin/v1/bands/export [get]
+func (h *ImportHandler) ExportBands() http.HandlerFunc {
+	return h.export(audit.EntityBand)
+}
+
+// ImportConcerts godoc
+// @Summary Import concerts
+// @Description Create or update concerts from a CSV file with a header row or a JSON array of ConcertRow.
+// @Description Rows are matched by id, then by externalId. Venues and bands can be referenced by their externalId.
+// @Description Valid rows are saved even if other rows fail.
+// @Description Files over 500 rows or with async=true are imported in the background, poll the returned job
+// @Tags Admin/Imports
+// @Accept text/csv,json
+// @Produce json
+// @Param format query string false "csv or json, taken from Content-Type when empty"
+// @Param dryRun query bool false "Only validate and report, nothing is saved"
+// @Param async query bool false "Import in the background"
+// @Param request body []ConcertRow true "Rows"
+// @Success 200 {object} JobResponse "Finished import with its report"
+// @Success 202 {object} JobResponse "Import started in the background"
+// @Failure 400 {object} res.Problem "File cannot be read"
+// @Failure 401 {object} res.Problem "Unauthorized"
+// @Failure 403 {object} res.Problem "Forbidden"
+// @Failure 413 {object} res.Problem "File is too large"
+// @Router /admin/v1/concerts/import [post]
+func (h *ImportHandler) ImportConcerts() http.HandlerFunc {
+	return h.importFile(audit.EntityConcert)
+}
+
+// ExportConcerts godoc
+// @Summary Export concerts
+// @Description Download concerts matching the filters in the import format, by id. Takes the same filters as the list
+// @Tags Admin/Imports
+// @Produce text/csv,json
+// @Param format query string false "csv (default) or json"
+// @Param q query string false "Search"
+// @Param venueId query int false "Venue ID"
+// @Param date[gte] query string false "From date, RFC 3339"
+// @Param date[lt] query string false "Until date, RFC 3339"
+// @Success 200 {array} ConcertRow
+// @Failure 400 {object} res.Problem "Unsupported format or filter"
+// @Failure 401 {object} res.Problem "Unauthorized"
+// @Failure 403 {object} res.Problem "Forbidden"
+// @Router /admin/v1/concerts/export [get]
+func (h *ImportHandler) ExportConcerts() http.HandlerFunc {
+	return h.export(audit.EntityConcert)
+}
+
+// GetJob godoc
+// @Summary Get an import
+// @Description Get the status and report of an import
+// @Tags Admin/Imports
+// @Produce json
+// @Param id path int true "Import ID"
+// @Success 200 {object} JobResponse
+// @Failure 400 {object} res.Problem "Bad request"
+// @Failure 401 {object} res.Problem "Unauthorized"
+// @Failure 403 {object} res.Problem "Forbidden"
+// @Failure 404 {object} res.Problem "Not found"
+// @Router /admin/v1/imports/{id} [get]
+func (h *ImportHandler) GetJob() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseUint(r.PathValue("id"), 10, 32)
+		if err != nil {
+			res.Error(w, r, ErrInvalidID)
+			return
+		}
+
+		job, err := h.Service.GetJob(r.Context(), uint(id))
+		if err != nil {
+			res.Error(w, r, err)
+			return
+		}
+
+		res.Json(w, job, http.StatusOK)
+	}
+}
+
+func (h *ImportHandler) importFile(entity string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		format, err := parseFormat(r.URL.Query().Get("format"), r.Header.Get("Content-Type"))
+		if err != nil {
+			res.Error(w, r, err)
+			return
+		}
+		dryRun, err := req.BoolParam(r, "dryRun")
+		if err != nil {
+			res.Error(w, r, err)
+			return
+		}
+		async, err := req.BoolParam(r, "async")
+		if err != nil {
+			res.Error(w, r, err)
+			return
+		}
+
+		data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, MaxImportSize))
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				res.Error(w, r, ErrFileTooLarge.WithMessage(fmt.Sprintf("file is larger than %d MB", MaxImportSize>>20)))
+				return
+			}
+			res.Error(w, r, ErrInvalidFile.Wrap(err))
+			return
+		}
+
+		input := &ImportInput{
+			Entity: entity,
+			Format: format,
+			DryRun: dryRun,
+			Async:  async,
+			Data:   data,
+		}
+		if authData, err := middleware.GetAuthData(r); err == nil {
+			input.UserID = &authData.UserID
+		}
+
+		job, err := h.Service.Import(audit.WithActor(r), input)
+		if err != nil {
+			if apperr.IsInternal(err) {
+				h.Logger.WithContext(r.Context()).Error("Failed to import", "entity", entity, "error", err.Error())
+			}
+			res.Error(w, r, err)
+			return
+		}
+
+		if job.Status == StatusPending {
+			w.Header().Set("Location", fmt.Sprintf("/admin/v1/imports/%d", job.ID))
+			res.Json(w, job, http.StatusAccepted)
+			return
+		}
+		res.Json(w, job, http.StatusOK)
+	}
+}
+
+func (h *ImportHandler) export(entity string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		format, err := parseFormat(r.URL.Query().Get("format"), "text/csv")
+		if err != nil {
+			res.Error(w, r, err)
+			return
+		}
+
+		values := r.URL.Query()
+		values.Del("format")
+		spec, err := query.ParseSpec(values, h.Service.Schema(entity))
+		if err != nil {
+			res.Error(w, r, err)
+			return
+		}
+
+		contentType := "text/csv; charset=utf-8"
+		if format == FormatJSON {
+			contentType = "application/json"
+		}
+		fileName := fmt.Sprintf("%ss-%s.%s", entity, time.Now().UTC().Format("20060102-150405"), format)
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fileName))
+
+		// rows are streamed, so a failure after the first batch can only be logged
+		if err := h.Service.Export(r.Context(), entity, format, spec, w); err != nil {
+			h.Logger.WithContext(r.Context()).Error("Failed to export", "entity", entity, "error", err.Error())
+		}
+	}
+}
+
+// parseFormat takes the format query param, or guesses it from the content type when it is empty
+func parseFormat(param string, contentType string) (Format, error) {
+	switch strings.ToLower(param) {
+	case string(FormatCSV):
+		return FormatCSV, nil
+	case string(FormatJSON):
+		return FormatJSON, nil
+	case "":
+		if strings.Contains(contentType, "csv") {
+			return FormatCSV, nil
+		}
+		return FormatJSON, nil
+	default:
+		return "", ErrUnsupportedFormat
+	}
+}
@@ -0,0 +1,273 @@
+package imports
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/serhiirubets/rubeticket/internal/app/admin/audit"
+	"github.com/serhiirubets/rubeticket/internal/app/admin/bands"
+	"github.com/serhiirubets/rubeticket/internal/app/admin/concerts"
+	"github.com/serhiirubets/rubeticket/internal/app/admin/venues"
+	"github.com/serhiirubets/rubeticket/internal/pkg/apperr"
+	"github.com/serhiirubets/rubeticket/internal/pkg/db"
+	"github.com/serhiirubets/rubeticket/internal/pkg/etag"
+	"github.com/serhiirubets/rubeticket/internal/pkg/query"
+	"gorm.io/gorm"
+)
+
+// kind imports and exports one entity type through its service, so imported rows
+// get the same checks as the admin API
+type kind interface {
+	// entity is the name in import jobs and the audit log
+	entity() string
+	newRow() any
+	// save creates or updates the entity of a valid row in tx and reports whether it was created
+	save(ctx context.Context, tx db.IDb, value any) (bool, error)
+	export(ctx context.Context, spec *query.Spec, fn func(rows []any) error) error
+	schema() query.Schema
+}
+
+func (r *VenueRow) externalID() string   { return r.ExternalID }
+func (r *BandRow) externalID() string    { return r.ExternalID }
+func (r *ConcertRow) externalID() string { return r.ExternalID }
+
+type venueKind struct {
+	service    *venues.VenueService
+	repository venues.IVenueRepository
+}
+
+func (k *venueKind) entity() string       { return audit.EntityVenue }
+func (k *venueKind) newRow() any          { return &VenueRow{} }
+func (k *venueKind) schema() query.Schema { return venues.VenueListSchema }
+
+func (k *venueKind) save(ctx context.Context, tx db.IDb, value any) (bool, error) {
+	row := value.(*VenueRow)
+	repository := k.repository.WithTx(tx)
+
+	id := row.ID
+	if id == 0 {
+		found, err := matchExternalID(row.ExternalID, "venue", func() (*gorm.DeletedAt, uint, error) {
+			venue, err := repository.GetByExternalID(ctx, row.ExternalID)
+			if err != nil {
+				return nil, 0, err
+			}
+			return &venue.DeletedAt, venue.ID, nil
+		})
+		if err != nil {
+			return false, err
+		}
+		id = found
+	}
+
+	service := k.service.WithTx(tx)
+	if id == 0 {
+		_, err := service.Create(ctx, &row.CreateVenueRequest)
+		return true, err
+	}
+	_, err := service.Update(ctx, id, etag.Any, toUpdateVenueRequest(row))
+	return false, err
+}
+
+func (k *venueKind) export(ctx context.Context, spec *query.Spec, fn func(rows []any) error) error {
+	return k.repository.Export(ctx, spec, func(found []venues.Venue) error {
+		rows := make([]any, len(found))
+		for i := range found {
+			rows[i] = toVenueRow(&found[i])
+		}
+		return fn(rows)
+	})
+}
+
+type bandKind struct {
+	service    *bands.BandService
+	repository bands.IBandRepository
+}
+
+func (k *bandKind) entity() string       { return audit.EntityBand }
+func (k *bandKind) newRow() any          { return &BandRow{} }
+func (k *bandKind) schema() query.Schema { return bands.BandListSchema }
+
+func (k *bandKind) save(ctx context.Context, tx db.IDb, value any) (bool, error) {
+	row := value.(*BandRow)
+	repository := k.repository.WithTx(tx)
+
+	id := row.ID
+	if id == 0 {
+		found, err := matchExternalID(row.ExternalID, "band", func() (*gorm.DeletedAt, uint, error) {
+			band, err := repository.GetByExternalID(ctx, row.ExternalID)
+			if err != nil {
+				return nil, 0, err
+			}
+			return &band.DeletedAt, band.ID, nil
+		})
+		if err != nil {
+			return false, err
+		}
+		id = found
+	}
+
+	service := k.service.WithTx(tx)
+	if id == 0 {
+		_, err := service.Create(ctx, &row.CreateBandRequest)
+		return true, err
+	}
+	_, err := service.Update(ctx, id, etag.Any, toUpdateBandRequest(row))
+	return false, err
+}
+
+func (k *bandKind) export(ctx context.Context, spec *query.Spec, fn func(rows []any) error) error {
+	return k.repository.Export(ctx, spec, func(found []bands.Band) error {
+		rows := make([]any, len(found))
+		for i := range found {
+			rows[i] = toBandRow(&found[i])
+		}
+		return fn(rows)
+	})
+}
+
+type concertKind struct {
+	service    *concerts.ConcertService
+	repository concerts.IConcertRepository
+	venueRepo  venues.IVenueRepository
+	bandRepo   bands.IBandRepository
+}
+
+func (k *concertKind) entity() string       { return audit.EntityConcert }
+func (k *concertKind) newRow() any          { return &ConcertRow{} }
+func (k *concertKind) schema() query.Schema { return concerts.ConcertListSchema }
+
+func (k *concertKind) save(ctx context.Context, tx db.IDb, value any) (bool, error) {
+	row := value.(*ConcertRow)
+	repository := k.repository.WithTx(tx)
+
+	id := row.ID
+	if id == 0 {
+		found, err := matchExternalID(row.ExternalID, "concert", func() (*gorm.DeletedAt, uint, error) {
+			concert, err := repository.GetByExternalID(ctx, row.ExternalID)
+			if err != nil {
+				return nil, 0, err
+			}
+			return &concert.DeletedAt, concert.ID, nil
+		})
+		if err != nil {
+			return false, err
+		}
+		id = found
+	}
+
+	venueID, err := k.venueID(ctx, tx, row)
+	if err != nil {
+		return false, err
+	}
+	lineup, err := k.lineup(ctx, tx, row.Lineup)
+	if err != nil {
+		return false, err
+	}
+
+	service := k.service.WithTx(tx)
+	if id == 0 {
+		_, err := service.Create(ctx, &concerts.CreateConcertRequest{
+			ExternalID:  row.ExternalID,
+			Title:       row.Title,
+			Description: row.Description,
+			PosterURL:   row.PosterURL,
+			Date:        row.Date,
+			DoorsAt:     row.DoorsAt,
+			CurfewAt:    row.CurfewAt,
+			VenueID:     venueID,
+			Lineup:      lineup,
+		})
+		return true, err
+	}
+	_, err = service.Update(ctx, id, etag.Any, &concerts.UpdateConcertRequest{
+		ExternalID:  &row.ExternalID,
+		Title:       &row.Title,
+		Description: &row.Description,
+		PosterURL:   &row.PosterURL,
+		Date:        &row.Date,
+		DoorsAt:     row.DoorsAt,
+		CurfewAt:    row.CurfewAt,
+		VenueID:     &venueID,
+		Lineup:      lineup,
+	})
+	return false, err
+}
+
+// venueID resolves the venue of a row, an id wins over an external ID
+func (k *concertKind) venueID(ctx context.Context, tx db.IDb, row *ConcertRow) (uint, error) {
+	if row.VenueID != 0 {
+		return row.VenueID, nil
+	}
+	venue, err := k.venueRepo.WithTx(tx).GetByExternalID(ctx, row.VenueExternalID)
+	if err != nil || venue.DeletedAt.Valid {
+		return 0, referenceError("venueExternalId", fmt.Sprintf("venue %q not found", row.VenueExternalID))
+	}
+	return venue.ID, nil
+}
+
+func (k *concertKind) lineup(ctx context.Context, tx db.IDb, rows []LineupRow) ([]concerts.LineupEntryRequest, error) {
+	repository := k.bandRepo.WithTx(tx)
+
+	entries := make([]concerts.LineupEntryRequest, len(rows))
+	for i, entry := range rows {
+		bandID := entry.BandID
+		if bandID == 0 {
+			band, err := repository.GetByExternalID(ctx, entry.BandExternalID)
+			if err != nil || band.DeletedAt.Valid {
+				return nil, referenceError(
+					fmt.Sprintf("lineup[%d].bandExternalId", i),
+					fmt.Sprintf("band %q not found", entry.BandExternalID),
+				)
+			}
+			bandID = band.ID
+		}
+		entries[i] = concerts.LineupEntryRequest{
+			BandID:   bandID,
+			Role:     entry.Role,
+			SetStart: entry.SetStart,
+			SetEnd:   entry.SetEnd,
+		}
+	}
+	return entries, nil
+}
+
+func (k *concertKind) export(ctx context.Context, spec *query.Spec, fn func(rows []any) error) error {
+	return k.repository.Export(ctx, spec, func(found []concerts.Concert) error {
+		rows := make([]any, len(found))
+		for i := range found {
+			rows[i] = toConcertRow(&found[i])
+		}
+		return fn(rows)
+	})
+}
+
+// matchExternalID finds the entity a row without id updates. It returns 0 when the row creates a new one,
+// an entity in trash is not brought back by an import
+func matchExternalID(externalID string, name string, find func() (*gorm.DeletedAt, uint, error)) (uint, error) {
+	if externalID == "" {
+		return 0, ErrNoKey
+	}
+
+	deletedAt, id, err := find()
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	if deletedAt.Valid {
+		message := name + " " + ErrInTrash.Message
+		return 0, ErrInTrash.WithMessage(message).WithFields(apperr.FieldError{Field: "externalId", Code: ErrInTrash.Code, Message: message})
+	}
+	return id, nil
+}
+
+// referenceError is a row error for a venue or band that is referenced by a row but does not exist
+func referenceError(field string, message string) error {
+	return ErrReferenceNotFound.WithMessage(message).WithFields(apperr.FieldError{
+		Field:   field,
+		Code:    ErrReferenceNotFound.Code,
+		Message: message,
+	})
+}
@@ -0,0 +1,53 @@
+package imports
+
+import "time"
+
+type Status string
+
+const (
+	StatusPending Status = "pending"
+	StatusRunning Status = "running"
+	StatusDone    Status = "done"
+	StatusFailed  Status = "failed"
+)
+
+type Format string
+
+const (
+	FormatCSV  Format = "csv"
+	FormatJSON Format = "json"
+)
+
+// RowError is a problem with one row of an import, Row 1 is the first row after the CSV header
+// or the first element of the JSON array. Field is empty when the row as a whole failed
+type RowError struct {
+	Row        int    `json:"row"`
+	ExternalID string `json:"externalId,omitempty"`
+	Field      string `json:"field,omitempty"`
+	Code       string `json:"code"`
+	Message    string `json:"message"`
+}
+
+// Job is one import, kept for its report. Big imports are run in the background and polled by ID
+type Job struct {
+	ID         uint      `gorm:"primaryKey"`
+	CreatedAt  time.Time `gorm:"not null"`
+	UpdatedAt  time.Time `gorm:"not null"`
+	FinishedAt *time.Time
+	Entity     string     `gorm:"type:varchar(20);not null"`
+	Format     Format     `gorm:"type:varchar(10);not null"`
+	DryRun     bool       `gorm:"not null;default:false"`
+	Status     Status     `gorm:"type:varchar(20);not null"`
+	Total      int        `gorm:"not null;default:0"`
+	Created    int        `gorm:"not null;default:0"`
+	Updated    int        `gorm:"not null;default:0"`
+	Failed     int        `gorm:"not null;default:0"`
+	Errors     []RowError `gorm:"type:jsonb;serializer:json"`
+	// Error is why the whole import failed, row problems are in Errors
+	Error     string `gorm:"type:text"`
+	CreatedBy *uint  `gorm:"index:idx_import_job_created_by"`
+}
+
+func (Job) TableName() string {
+	return "import_jobs"
+}
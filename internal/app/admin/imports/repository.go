@@ -0,0 +1,53 @@
+package imports
+
+import (
+	"context"
+	"time"
+
+	"github.com/serhiirubets/rubeticket/internal/pkg/db"
+)
+
+type IJobRepository interface {
+	Create(ctx context.Context, job *Job) error
+	Update(ctx context.Context, job *Job) error
+	GetByID(ctx context.Context, id uint) (*Job, error)
+	FailUnfinished(ctx context.Context, reason string) (int64, error)
+	WithTx(tx db.IDb) IJobRepository
+}
+
+type JobRepository struct {
+	Db db.IDb
+}
+
+func NewJobRepository(Db db.IDb) IJobRepository {
+	return &JobRepository{Db: Db}
+}
+
+// WithTx returns a copy of the repository that runs queries in tx
+func (r *JobRepository) WithTx(tx db.IDb) IJobRepository {
+	return &JobRepository{Db: tx}
+}
+
+func (r *JobRepository) Create(ctx context.Context, job *Job) error {
+	return r.Db.WithContext(ctx).Create(job).Error
+}
+
+func (r *JobRepository) Update(ctx context.Context, job *Job) error {
+	return r.Db.WithContext(ctx).Save(job).Error
+}
+
+func (r *JobRepository) GetByID(ctx context.Context, id uint) (*Job, error) {
+	var job Job
+	if err := r.Db.WithContext(ctx).First(&job, id).Error; err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// FailUnfinished marks pending and running jobs as failed with reason and returns how many there were
+func (r *JobRepository) FailUnfinished(ctx context.Context, reason string) (int64, error) {
+	result := r.Db.WithContext(ctx).Model(&Job{}).
+		Where("status IN ?", []Status{StatusPending, StatusRunning}).
+		Updates(map[string]interface{}{"status": StatusFailed, "error": reason, "finished_at": time.Now()})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,179 @@
+package imports
+
+import (
+	"sort"
+	"time"
+
+	"github.com/serhiirubets/rubeticket/internal/app/admin/bands"
+	"github.com/serhiirubets/rubeticket/internal/app/admin/concerts"
+	"github.com/serhiirubets/rubeticket/internal/app/admin/venues"
+)
+
+// @Description Venue import row. Rows are matched by id, otherwise by externalId, unmatched rows are created
+type VenueRow struct {
+	ID uint `json:"id,omitempty"`
+	venues.CreateVenueRequest
+}
+
+// @Description Band import row. Rows are matched by id, otherwise by externalId, unmatched rows are created.
+// @Description In CSV genreIds are separated by ";", members and links are JSON
+type BandRow struct {
+	ID uint `json:"id,omitempty"`
+	bands.CreateBandRequest
+}
+
+// @Description Concert import row. Rows are matched by id, otherwise by externalId, unmatched rows are created.
+// @Description The venue and lineup bands are referenced by id or by their externalId. In CSV lineup is JSON
+type ConcertRow struct {
+	ID              uint        `json:"id,omitempty"`
+	ExternalID      string      `json:"externalId" validate:"max=100"`
+	Title           string      `json:"title" validate:"required,max=100"`
+	Description     string      `json:"description" validate:"max=300"`
+	PosterURL       string      `json:"posterUrl" validate:"max=100"`
+	Date            time.Time   `json:"date" validate:"required"`
+	DoorsAt         *time.Time  `json:"doorsAt"`
+	CurfewAt        *time.Time  `json:"curfewAt"`
+	VenueID         uint        `json:"venueId" validate:"required_without=VenueExternalID"`
+	VenueExternalID string      `json:"venueExternalId" validate:"max=100"`
+	Lineup          []LineupRow `json:"lineup" validate:"required,min=1,dive"`
+}
+
+// @Description Lineup entry of a concert import row, billed in the given order
+type LineupRow struct {
+	BandID         uint       `json:"bandId,omitempty" validate:"required_without=BandExternalID"`
+	BandExternalID string     `json:"bandExternalId,omitempty" validate:"max=100"`
+	Role           string     `json:"role" validate:"omitempty,oneof=headliner support"`
+	SetStart       *time.Time `json:"setStart"`
+	SetEnd         *time.Time `json:"setEnd"`
+}
+
+func toVenueRow(venue *venues.Venue) *VenueRow {
+	return &VenueRow{
+		ID: venue.ID,
+		CreateVenueRequest: venues.CreateVenueRequest{
+			ExternalID:  valueOf(venue.ExternalID),
+			Name:        venue.Name,
+			Description: venue.Description,
+			Address:     venue.Address,
+			City:        venue.City,
+			Region:      venue.Region,
+			PostalCode:  venue.PostalCode,
+			Country:     venue.Country,
+			Phone:       venue.Phone,
+			Email:       venue.Email,
+			TimeZone:    venue.TimeZone,
+			Capacity:    venue.Capacity,
+			Latitude:    venue.Latitude,
+			Longitude:   venue.Longitude,
+		},
+	}
+}
+
+// toUpdateVenueRequest sets every field of the row, an imported row replaces the venue
+func toUpdateVenueRequest(row *VenueRow) *venues.UpdateVenueRequest {
+	return &venues.UpdateVenueRequest{
+		ExternalID:  &row.ExternalID,
+		Name:        &row.Name,
+		Description: &row.Description,
+		Address:     &row.Address,
+		City:        &row.City,
+		Region:      &row.Region,
+		PostalCode:  &row.PostalCode,
+		Country:     &row.Country,
+		Phone:       &row.Phone,
+		Email:       &row.Email,
+		TimeZone:    &row.TimeZone,
+		Capacity:    row.Capacity,
+		Latitude:    row.Latitude,
+		Longitude:   row.Longitude,
+	}
+}
+
+// toBandRow lists the primary genre first, so importing the row back keeps it primary
+func toBandRow(band *bands.Band) *BandRow {
+	genreIDs := make([]uint, 0, len(band.Genres))
+	for _, genre := range band.Genres {
+		if genre.Name == band.Genre {
+			genreIDs = append([]uint{genre.ID}, genreIDs...)
+		} else {
+			genreIDs = append(genreIDs, genre.ID)
+		}
+	}
+
+	members := make([]bands.BandMemberRequest, len(band.Members))
+	for i, member := range band.Members {
+		members[i] = bands.BandMemberRequest{
+			Name:       member.Name,
+			Role:       member.Role,
+			JoinedYear: member.JoinedYear,
+			LeftYear:   member.LeftYear,
+		}
+	}
+	links := make([]bands.BandLinkRequest, len(band.Links))
+	for i, link := range band.Links {
+		links[i] = bands.BandLinkRequest{Kind: link.Kind, URL: link.URL}
+	}
+
+	return &BandRow{
+		ID: band.ID,
+		CreateBandRequest: bands.CreateBandRequest{
+			ExternalID:  valueOf(band.ExternalID),
+			Name:        band.Name,
+			Description: band.Description,
+			GenreIDs:    genreIDs,
+			Country:     band.Country,
+			Members:     members,
+			Links:       links,
+		},
+	}
+}
+
+// toUpdateBandRequest sets every field of the row. Lists left out of the row are kept as they are
+func toUpdateBandRequest(row *BandRow) *bands.UpdateBandRequest {
+	return &bands.UpdateBandRequest{
+		ExternalID:  &row.ExternalID,
+		Name:        &row.Name,
+		Description: &row.Description,
+		GenreIDs:    row.GenreIDs,
+		Country:     &row.Country,
+		Members:     row.Members,
+		Links:       row.Links,
+	}
+}
+
+// toConcertRow expects the venue and lineup bands to be loaded, their external IDs are exported too
+func toConcertRow(concert *concerts.Concert) *ConcertRow {
+	lineup := append([]concerts.ConcertBands{}, concert.Lineup...)
+	sort.SliceStable(lineup, func(i, j int) bool { return lineup[i].Position < lineup[j].Position })
+
+	row := &ConcertRow{
+		ID:              concert.ID,
+		ExternalID:      valueOf(concert.ExternalID),
+		Title:           concert.Title,
+		Description:     concert.Description,
+		PosterURL:       concert.PosterURL,
+		Date:            concert.Date,
+		DoorsAt:         concert.DoorsAt,
+		CurfewAt:        concert.CurfewAt,
+		VenueID:         concert.VenueID,
+		VenueExternalID: valueOf(concert.Venue.ExternalID),
+		Lineup:          make([]LineupRow, len(lineup)),
+	}
+	for i, entry := range lineup {
+		row.Lineup[i] = LineupRow{
+			BandID:         entry.BandID,
+			BandExternalID: valueOf(entry.Band.ExternalID),
+			Role:           entry.Role,
+			SetStart:       entry.SetStart,
+			SetEnd:         entry.SetEnd,
+		}
+	}
+	return row
+}
+
+func valueOf(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,379 @@
+package imports
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"time"
+
+	"github.com/serhiirubets/rubeticket/internal/app/admin/audit"
+	"github.com/serhiirubets/rubeticket/internal/app/admin/bands"
+	"github.com/serhiirubets/rubeticket/internal/app/admin/concerts"
+	"github.com/serhiirubets/rubeticket/internal/app/admin/venues"
+	"github.com/serhiirubets/rubeticket/internal/pkg/apperr"
+	"github.com/serhiirubets/rubeticket/internal/pkg/background"
+	"github.com/serhiirubets/rubeticket/internal/pkg/db"
+	"github.com/serhiirubets/rubeticket/internal/pkg/health"
+	"github.com/serhiirubets/rubeticket/internal/pkg/log"
+	"github.com/serhiirubets/rubeticket/internal/pkg/query"
+	"github.com/serhiirubets/rubeticket/internal/pkg/tracing"
+)
+
+const (
+	// MaxImportSize limits the size of an uploaded file
+	MaxImportSize = 32 << 20
+	maxImportRows = 50000
+	// asyncRows is from how many rows an import is always run in the background
+	asyncRows = 500
+	// batchRows is how many rows are saved in one transaction
+	batchRows = 500
+	// workers is how many background imports run at once
+	workers = 2
+	// maxReportedErrors keeps reports of broken files small, failed still counts every row
+	maxReportedErrors = 1000
+	importRetries     = 3
+)
+
+// errDryRun rolls back the transaction of a dry run
+var errDryRun = errors.New("dry run")
+
+type ImportInput struct {
+	Entity string
+	Format Format
+	DryRun bool
+	// Async runs the import in the background even when it is small
+	Async  bool
+	Data   []byte
+	UserID *uint
+}
+
+type ServiceDeps struct {
+	DB                db.IDb
+	Logger            log.ILogger
+	Repository        IJobRepository
+	VenueService      *venues.VenueService
+	VenueRepository   venues.IVenueRepository
+	BandService       *bands.BandService
+	BandRepository    bands.IBandRepository
+	ConcertService    *concerts.ConcertService
+	ConcertRepository concerts.IConcertRepository
+	// Heartbeats is told about running imports, a stuck import fails the liveness probe
+	Heartbeats *health.Heartbeats
+	// Runner runs background imports, they are canceled on shutdown
+	Runner *background.Runner
+	Audit  *audit.Recorder
+}
+
+type ImportService struct {
+	db         db.IDb
+	logger     log.ILogger
+	repository IJobRepository
+	kinds      map[string]kind
+	workers    chan struct{}
+	heartbeats *health.Heartbeats
+	runner     *background.Runner
+	audit      *audit.Recorder
+}
+
+func NewImportService(deps *ServiceDeps) *ImportService {
+	kinds := []kind{
+		&venueKind{service: deps.VenueService, repository: deps.VenueRepository},
+		&bandKind{service: deps.BandService, repository: deps.BandRepository},
+		&concertKind{
+			service:    deps.ConcertService,
+			repository: deps.ConcertRepository,
+			venueRepo:  deps.VenueRepository,
+			bandRepo:   deps.BandRepository,
+		},
+	}
+
+	service := &ImportService{
+		db:         deps.DB,
+		logger:     deps.Logger,
+		repository: deps.Repository,
+		kinds:      make(map[string]kind, len(kinds)),
+		workers:    make(chan struct{}, workers),
+		heartbeats: deps.Heartbeats,
+		runner:     deps.Runner,
+		audit:      deps.Audit,
+	}
+	for _, k := range kinds {
+		service.kinds[k.entity()] = k
+	}
+	return service
+}
+
+// Import reads the file and saves its rows, in the background when the file is big or Async is set.
+// A file that cannot be read is an error, problems with single rows end up in the report of the job.
+// Valid rows are saved even when others fail, so a fixed file can be imported again thanks to upserts.
+// Saved rows are audited as changes of the actor of ctx
+func (s *ImportService) Import(ctx context.Context, input *ImportInput) (*JobResponse, error) {
+	ctx, span := tracing.Start(ctx, "ImportService.Import")
+	defer span.End()
+
+	k := s.kinds[input.Entity]
+
+	rows, err := decodeRows(input.Format, input.Data, k.newRow)
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, ErrEmptyImport
+	}
+	if len(rows) > maxImportRows {
+		return nil, ErrTooManyRows
+	}
+
+	job := &Job{
+		Entity:    input.Entity,
+		Format:    input.Format,
+		DryRun:    input.DryRun,
+		Status:    StatusPending,
+		Total:     len(rows),
+		CreatedBy: input.UserID,
+	}
+	err = s.db.WithTx(ctx, func(tx db.IDb) error {
+		if err := s.repository.WithTx(tx).Create(ctx, job); err != nil {
+			return err
+		}
+		if job.DryRun {
+			return nil
+		}
+		return s.audit.Record(ctx, tx, audit.ActionCreate, audit.EntityImport, job.ID, nil, ToJobResponse(job))
+	})
+	if err != nil {
+		return nil, ErrJobNotSaved.Wrap(err)
+	}
+
+	if input.Async || len(rows) > asyncRows {
+		response := ToJobResponse(job)
+		// The job outlives the request, its status is polled by ID. It keeps the values of ctx,
+		// e.g. the actor, and is canceled by the runner on shutdown
+		jobCtx := context.WithoutCancel(ctx)
+		err := s.runner.Go(workerName(job), func(runnerCtx context.Context) {
+			ctx, cancel := context.WithCancel(jobCtx)
+			defer cancel()
+			defer context.AfterFunc(runnerCtx, cancel)()
+			s.runInBackground(ctx, job, k, rows)
+		})
+		if err != nil {
+			s.finish(jobCtx, job, err)
+			return ToJobResponse(job), nil
+		}
+		return response, nil
+	}
+
+	s.run(ctx, job, k, rows)
+	return ToJobResponse(job), nil
+}
+
+func (s *ImportService) GetJob(ctx context.Context, id uint) (*JobResponse, error) {
+	ctx, span := tracing.Start(ctx, "ImportService.GetJob")
+	defer span.End()
+
+	job, err := s.repository.GetByID(ctx, id)
+	if err != nil {
+		return nil, ErrJobNotFound
+	}
+	return ToJobResponse(job), nil
+}
+
+// Export writes all entities matching spec filters in the format they are imported in
+func (s *ImportService) Export(ctx context.Context, entity string, format Format, spec *query.Spec, out io.Writer) error {
+	ctx, span := tracing.Start(ctx, "ImportService.Export")
+	defer span.End()
+
+	k := s.kinds[entity]
+
+	writer, err := newRowWriter(format, out, reflect.TypeOf(k.newRow()).Elem())
+	if err != nil {
+		return err
+	}
+
+	err = k.export(ctx, spec, func(rows []any) error {
+		for _, value := range rows {
+			if err := writer.Write(value); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return writer.Close()
+}
+
+// Schema returns the filters an export of entity takes, the same as its list
+func (s *ImportService) Schema(entity string) query.Schema {
+	return s.kinds[entity].schema()
+}
+
+func (s *ImportService) runInBackground(ctx context.Context, job *Job, k kind, rows []row) {
+	select {
+	case s.workers <- struct{}{}:
+		defer func() { <-s.workers }()
+	case <-ctx.Done():
+		s.finish(ctx, job, ctx.Err())
+		return
+	}
+
+	job.Status = StatusRunning
+	if err := s.repository.Update(ctx, job); err != nil {
+		s.logger.WithContext(ctx).Error("Failed to start import", "job", job.ID, "error", err.Error())
+	}
+	s.run(ctx, job, k, rows)
+}
+
+// run saves the rows and stores the report on the job
+func (s *ImportService) run(ctx context.Context, job *Job, k kind, rows []row) {
+	worker := workerName(job)
+	s.heartbeats.Beat(worker)
+	defer s.heartbeats.Done(worker)
+
+	s.finish(ctx, job, s.importRows(ctx, job, k, rows))
+}
+
+// finish stores the report of the job, also when ctx was canceled by shutdown
+func (s *ImportService) finish(ctx context.Context, job *Job, err error) {
+	ctx = context.WithoutCancel(ctx)
+
+	now := time.Now()
+	job.FinishedAt = &now
+	job.Status = StatusDone
+	if err != nil {
+		s.logger.WithContext(ctx).Error("Import failed", "job", job.ID, "entity", job.Entity, "error", err.Error())
+		job.Status = StatusFailed
+		job.Error = err.Error()
+	}
+
+	if err := s.repository.Update(ctx, job); err != nil {
+		s.logger.WithContext(ctx).Error("Failed to save import report", "job", job.ID, "error", err.Error())
+	}
+}
+
+// FailUnfinished marks jobs left pending or running by a stopped process as failed.
+// Call it at boot, before any import is started
+func (s *ImportService) FailUnfinished(ctx context.Context) (int64, error) {
+	return s.repository.FailUnfinished(ctx, "import was interrupted by a restart")
+}
+
+// importRows saves rows in batches, each batch in its own transaction and each row in its own
+// savepoint, so a failed row is skipped and the rest is kept. Batches are serializable like concert
+// bookings and are run again as a whole after a serialization failure. Batches of a dry run are
+// rolled back, the counts of a failed import cover the batches saved before the failure
+func (s *ImportService) importRows(ctx context.Context, job *Job, k kind, rows []row) error {
+	opts := db.TxOptions{Isolation: sql.LevelSerializable, MaxRetries: importRetries}
+	job.Created, job.Updated, job.Failed, job.Errors = 0, 0, 0, nil
+
+	for start := 0; start < len(rows); start += batchRows {
+		batch := rows[start:min(start+batchRows, len(rows))]
+		err := s.importBatch(ctx, opts, job, k, batch)
+		if err != nil {
+			return err
+		}
+
+		// progress is visible to clients polling the job
+		if start+batchRows < len(rows) {
+			if err := s.repository.Update(ctx, job); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// importBatch adds the results of batch to the counts of job, only when the batch is saved
+func (s *ImportService) importBatch(ctx context.Context, opts db.TxOptions, job *Job, k kind, batch []row) error {
+	created, updated, failed, rowErrors := job.Created, job.Updated, job.Failed, job.Errors
+
+	err := s.db.WithTxOptions(ctx, opts, func(tx db.IDb) error {
+		job.Created, job.Updated, job.Failed, job.Errors = created, updated, failed, rowErrors
+
+		for _, r := range batch {
+			s.heartbeats.Beat(workerName(job))
+			rowErrors := r.errors
+			if len(rowErrors) == 0 {
+				rowErrors = validateRow(r.number, r.value)
+			}
+
+			if len(rowErrors) == 0 {
+				var created bool
+				err := tx.WithTx(ctx, func(rowTx db.IDb) error {
+					var err error
+					created, err = k.save(ctx, rowTx, r.value)
+					return err
+				})
+				if db.IsRetryable(err) {
+					return err
+				}
+				if err != nil {
+					if apperr.IsInternal(err) {
+						s.logger.WithContext(ctx).Error("Failed to import row", "job", job.ID, "row", r.number, "error", err.Error())
+					}
+					rowErrors = []RowError{toRowError(r.number, err)}
+				} else if created {
+					job.Created++
+				} else {
+					job.Updated++
+				}
+			}
+
+			if len(rowErrors) > 0 {
+				job.Failed++
+				job.Errors = addRowErrors(job.Errors, r.value, rowErrors)
+			}
+		}
+
+		if job.DryRun {
+			return errDryRun
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errDryRun) {
+		job.Created, job.Updated, job.Failed, job.Errors = created, updated, failed, rowErrors
+		return err
+	}
+	return nil
+}
+
+func workerName(job *Job) string {
+	return fmt.Sprintf("import %d", job.ID)
+}
+
+// toRowError reports typed errors with their code and field, other errors are not
+// expected from a valid row and are reported as internal without their text, which
+// may carry SQL or constraint names
+func toRowError(number int, err error) RowError {
+	appErr := apperr.As(err)
+	if appErr == nil || appErr.Kind == apperr.Internal {
+		return RowError{Row: number, Code: "internal_error", Message: "internal error"}
+	}
+
+	rowError := RowError{Row: number, Code: appErr.Code, Message: appErr.Message}
+	if len(appErr.Fields) > 0 {
+		rowError.Field = appErr.Fields[0].Field
+		rowError.Message = appErr.Fields[0].Message
+	}
+	return rowError
+}
+
+// addRowErrors appends errors of a row up to maxReportedErrors, marked with its external ID
+func addRowErrors(errs []RowError, value any, rowErrors []RowError) []RowError {
+	externalID := ""
+	if keyed, ok := value.(interface{ externalID() string }); ok {
+		externalID = keyed.externalID()
+	}
+
+	for _, rowError := range rowErrors {
+		if len(errs) >= maxReportedErrors {
+			break
+		}
+		rowError.ExternalID = externalID
+		errs = append(errs, rowError)
+	}
+	return errs
+}
@@ -0,0 +1,8 @@
+package roles
+
+import "github.com/serhiirubets/rubeticket/internal/app/users"
+
+// @Description Change user role request
+type ChangeRoleRequest struct {
+	Role users.Role `json:"role" validate:"required,oneof=user moderator admin"`
+}
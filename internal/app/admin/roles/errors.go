@@ -0,0 +1,9 @@
+package roles
+
+import "github.com/serhiirubets/rubeticket/internal/pkg/apperr"
+
+var (
+	ErrInvalidID     = apperr.New(apperr.Invalid, "invalid_user_id", "invalid user ID")
+	ErrUserNotFound  = apperr.New(apperr.NotFound, "user_not_found", "user not found")
+	ErrOwnRoleChange = apperr.New(apperr.Forbidden, "own_role_change", "admins cannot change their own role")
+)
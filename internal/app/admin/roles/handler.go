@@ -0,0 +1,82 @@
+package roles
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/serhiirubets/rubeticket/config"
+	"github.com/serhiirubets/rubeticket/internal/app/admin/audit"
+	"github.com/serhiirubets/rubeticket/internal/pkg/apperr"
+	"github.com/serhiirubets/rubeticket/internal/pkg/log"
+	"github.com/serhiirubets/rubeticket/internal/pkg/middleware"
+	"github.com/serhiirubets/rubeticket/internal/pkg/req"
+	"github.com/serhiirubets/rubeticket/internal/pkg/res"
+)
+
+type RoleHandlerDeps struct {
+	Config  *config.Config
+	Logger  log.ILogger
+	Service *RoleService
+}
+
+type RoleHandler struct {
+	Config  *config.Config
+	Logger  log.ILogger
+	Service *RoleService
+}
+
+func NewRoleHandler(router *http.ServeMux, deps *RoleHandlerDeps) {
+	handler := RoleHandler{
+		Config:  deps.Config,
+		Logger:  deps.Logger,
+		Service: deps.Service,
+	}
+
+	router.HandleFunc("PUT /users/{id}/role", handler.ChangeRole())
+}
+
+// ChangeRole godoc
+// @Summary Change user role
+// @Description Set the role of a user. Admin access follows it at once, other uses of the role from the next login. Admins cannot change their own role
+// @Tags Admin/Users
+// @Accept json
+// @Produce json
+// @Param id path int true "User ID"
+// @Param request body ChangeRoleRequest true "New role"
+// @Success 200 {object} users.GetUserResponse
+// @Failure 400 {object} res.Problem "Bad request"
+// @Failure 401 {object} res.Problem "Unauthorized"
+// @Failure 403 {object} res.Problem "Forbidden"
+// @Failure 404 {object} res.Problem "Not found"
+// @Router /admin/v1/users/{id}/role [put]
+func (h *RoleHandler) ChangeRole() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseUint(r.PathValue("id"), 10, 32)
+		if err != nil {
+			res.Error(w, r, ErrInvalidID)
+			return
+		}
+
+		payload, err := req.HandleBody[ChangeRoleRequest](&w, r)
+		if err != nil {
+			return
+		}
+
+		authData, err := middleware.GetAuthData(r)
+		if err != nil {
+			res.Error(w, r, err)
+			return
+		}
+
+		user, err := h.Service.ChangeRole(audit.WithActor(r), authData.UserID, uint(id), payload.Role)
+		if err != nil {
+			if apperr.IsInternal(err) {
+				h.Logger.WithContext(r.Context()).Error("Failed to change user role", "error", err.Error())
+			}
+			res.Error(w, r, err)
+			return
+		}
+
+		res.Json(w, user, http.StatusOK)
+	}
+}
@@ -0,0 +1,62 @@
+package roles
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/serhiirubets/rubeticket/internal/app/admin/audit"
+	"github.com/serhiirubets/rubeticket/internal/app/users"
+	"github.com/serhiirubets/rubeticket/internal/pkg/db"
+	"github.com/serhiirubets/rubeticket/internal/pkg/tracing"
+)
+
+type RoleService struct {
+	db             db.IDb
+	userRepository *users.UserRepository
+	audit          *audit.Recorder
+}
+
+func NewRoleService(db db.IDb, userRepository *users.UserRepository, auditRecorder *audit.Recorder) *RoleService {
+	return &RoleService{
+		db:             db,
+		userRepository: userRepository,
+		audit:          auditRecorder,
+	}
+}
+
+// ChangeRole sets the role of a user. Admin routes check the stored role on every request,
+// so admin access is granted or taken away at once, the role in the token is updated on the next login
+func (s *RoleService) ChangeRole(ctx context.Context, actorID uint, userID uint, role users.Role) (*users.GetUserResponse, error) {
+	ctx, span := tracing.Start(ctx, "RoleService.ChangeRole")
+	defer span.End()
+
+	if actorID == userID {
+		return nil, ErrOwnRoleChange
+	}
+
+	var user *users.User
+
+	err := s.db.WithTx(ctx, func(tx db.IDb) error {
+		repository := s.userRepository.WithTx(tx)
+
+		var err error
+		user, err = repository.GetById(ctx, strconv.FormatUint(uint64(userID), 10))
+		if err != nil {
+			return ErrUserNotFound
+		}
+
+		previous := user.Role
+		if err := repository.Update(ctx, user, map[string]interface{}{"role": role}); err != nil {
+			return err
+		}
+		user.Role = role
+
+		return s.audit.Record(ctx, tx, audit.ActionRoleChange, audit.EntityUser, user.ID,
+			map[string]any{"role": previous}, map[string]any{"role": role})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return user.ToResponse(), nil
+}
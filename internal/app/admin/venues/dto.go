@@ -1,54 +1,102 @@
 package venues
 
-import "time"
+import (
+	"time"
+
+	"github.com/serhiirubets/rubeticket/internal/pkg/query"
+)
 
 // @Description Venue response model
 type VenueResponse struct {
-	ID          uint      `json:"id"`
-	Name        string    `json:"name"`
-	Description string    `json:"description"`
-	Address     string    `json:"address"`
-	Phone       string    `json:"phone"`
-	Email       string    `json:"email"`
-	CreatedAt   time.Time `json:"createdAt"`
-	UpdatedAt   time.Time `json:"updatedAt"`
+	ID          uint       `json:"id"`
+	ExternalID  *string    `json:"externalId,omitempty"`
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	Address     string     `json:"address"`
+	City        string     `json:"city"`
+	Region      string     `json:"region"`
+	PostalCode  string     `json:"postalCode"`
+	Country     string     `json:"country"`
+	Phone       string     `json:"phone"`
+	Email       string     `json:"email"`
+	TimeZone    string     `json:"timeZone"`
+	Capacity    *int       `json:"capacity"`
+	Latitude    *float64   `json:"latitude"`
+	Longitude   *float64   `json:"longitude"`
+	Version     uint       `json:"version"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	UpdatedAt   time.Time  `json:"updatedAt"`
+	DeletedAt   *time.Time `json:"deletedAt,omitempty"`
 }
 
 // @Description Create venue request
 type CreateVenueRequest struct {
-	Name        string `json:"name" validate:"required,max=100"`
-	Description string `json:"description" validate:"max=300"`
-	Address     string `json:"address" validate:"required,max=50"`
-	Phone       string `json:"phone" validate:"max=20"`
-	Email       string `json:"email" validate:"max=50,email"`
+	ExternalID  string   `json:"externalId" validate:"max=100"`
+	Name        string   `json:"name" validate:"required,max=100"`
+	Description string   `json:"description" validate:"max=300"`
+	Address     string   `json:"address" validate:"required,max=255"`
+	City        string   `json:"city" validate:"max=100"`
+	Region      string   `json:"region" validate:"max=100"`
+	PostalCode  string   `json:"postalCode" validate:"max=20"`
+	Country     string   `json:"country" validate:"omitempty,iso3166_1_alpha2"`
+	Phone       string   `json:"phone" validate:"max=20"`
+	Email       string   `json:"email" validate:"max=50,email"`
+	TimeZone    string   `json:"timeZone" validate:"omitempty,timezone"`
+	Capacity    *int     `json:"capacity" validate:"omitempty,min=1"`
+	Latitude    *float64 `json:"latitude" validate:"required_with=Longitude,omitempty,min=-90,max=90"`
+	Longitude   *float64 `json:"longitude" validate:"required_with=Latitude,omitempty,min=-180,max=180"`
 }
 
 // @Description Update venue request
 type UpdateVenueRequest struct {
-	Name        *string `json:"name" validate:"omitempty,max=100"`
-	Description *string `json:"description" validate:"omitempty,max=300"`
-	Address     *string `json:"address" validate:"omitempty,max=50"`
-	Phone       *string `json:"phone" validate:"omitempty,max=20"`
-	Email       *string `json:"email" validate:"omitempty,max=50,email"`
+	ExternalID  *string  `json:"externalId" validate:"omitempty,max=100"`
+	Name        *string  `json:"name" validate:"omitempty,max=100"`
+	Description *string  `json:"description" validate:"omitempty,max=300"`
+	Address     *string  `json:"address" validate:"omitempty,max=255"`
+	City        *string  `json:"city" validate:"omitempty,max=100"`
+	Region      *string  `json:"region" validate:"omitempty,max=100"`
+	PostalCode  *string  `json:"postalCode" validate:"omitempty,max=20"`
+	Country     *string  `json:"country" validate:"omitempty,iso3166_1_alpha2"`
+	Phone       *string  `json:"phone" validate:"omitempty,max=20"`
+	Email       *string  `json:"email" validate:"omitempty,max=50,email"`
+	TimeZone    *string  `json:"timeZone" validate:"omitempty,timezone"`
+	Capacity    *int     `json:"capacity" validate:"omitempty,min=1"`
+	Latitude    *float64 `json:"latitude" validate:"required_with=Longitude,omitempty,min=-90,max=90"`
+	Longitude   *float64 `json:"longitude" validate:"required_with=Latitude,omitempty,min=-180,max=180"`
 }
 
 // @Description List venues response
 type ListVenuesResponse struct {
 	Items []VenueResponse `json:"items"`
+	query.PageMeta
 }
 
 // ToVenueResponse converts from Venue to VenueResponse
 func ToVenueResponse(venue *Venue) *VenueResponse {
-	return &VenueResponse{
+	response := &VenueResponse{
 		ID:          venue.Model.ID,
+		ExternalID:  venue.ExternalID,
 		Name:        venue.Name,
 		Description: venue.Description,
 		Address:     venue.Address,
+		City:        venue.City,
+		Region:      venue.Region,
+		PostalCode:  venue.PostalCode,
+		Country:     venue.Country,
 		Phone:       venue.Phone,
 		Email:       venue.Email,
+		TimeZone:    venue.TimeZone,
+		Capacity:    venue.Capacity,
+		Latitude:    venue.Latitude,
+		Longitude:   venue.Longitude,
+		Version:     venue.Version,
 		CreatedAt:   venue.CreatedAt,
 		UpdatedAt:   venue.UpdatedAt,
 	}
+	if venue.DeletedAt.Valid {
+		response.DeletedAt = &venue.DeletedAt.Time
+	}
+	return response
 }
 
 func ToVenuesResponse(venues []Venue) []VenueResponse {
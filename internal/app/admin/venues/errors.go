@@ -0,0 +1,12 @@
+package venues
+
+import "github.com/serhiirubets/rubeticket/internal/pkg/apperr"
+
+var (
+	ErrInvalidID       = apperr.New(apperr.Invalid, "invalid_venue_id", "invalid venue ID")
+	ErrVenueNotFound   = apperr.New(apperr.NotFound, "venue_not_found", "venue not found")
+	ErrVenueNotInTrash = apperr.New(apperr.NotFound, "venue_not_in_trash", "venue not found in trash")
+	ErrVenueInUse      = apperr.New(apperr.Conflict, "venue_in_use", "venue has concerts, use cascade=true to delete them too")
+	ErrVersionConflict = apperr.New(apperr.PreconditionFailed, "version_conflict", "venue was changed by someone else")
+	ErrExternalIDTaken = apperr.New(apperr.Conflict, "external_id_taken", "external ID is used by another venue")
+)
@@ -5,8 +5,12 @@ import (
 	"strconv"
 
 	"github.com/serhiirubets/rubeticket/config"
+	"github.com/serhiirubets/rubeticket/internal/app/admin/audit"
 	"github.com/serhiirubets/rubeticket/internal/app/users"
+	"github.com/serhiirubets/rubeticket/internal/pkg/apperr"
+	"github.com/serhiirubets/rubeticket/internal/pkg/etag"
 	"github.com/serhiirubets/rubeticket/internal/pkg/log"
+	"github.com/serhiirubets/rubeticket/internal/pkg/query"
 	"github.com/serhiirubets/rubeticket/internal/pkg/req"
 	"github.com/serhiirubets/rubeticket/internal/pkg/res"
 )
@@ -38,6 +42,9 @@ func NewVenueHandler(router *http.ServeMux, deps *VenueHandlerDeps) {
 	router.HandleFunc("DELETE /admin/venues/{id}", handler.Delete())
 	router.HandleFunc("GET /admin/venues/{id}", handler.GetByID())
 	router.HandleFunc("GET /admin/venues", handler.List())
+	router.HandleFunc("GET /admin/venues/trash", handler.ListTrash())
+	router.HandleFunc("POST /admin/venues/trash/{id}/restore", handler.Restore())
+	router.HandleFunc("DELETE /admin/venues/trash/{id}", handler.Purge())
 }
 
 // Create godoc
@@ -48,9 +55,10 @@ func NewVenueHandler(router *http.ServeMux, deps *VenueHandlerDeps) {
 // @Produce json
 // @Param request body CreateVenueRequest true "Venue details"
 // @Success 201 {object} VenueResponse
-// @Failure 400 {string} string "Bad request"
-// @Failure 401 {string} string "Unauthorized"
-// @Failure 403 {string} string "Forbidden"
+// @Failure 400 {object} res.Problem "Bad request"
+// @Failure 401 {object} res.Problem "Unauthorized"
+// @Failure 403 {object} res.Problem "Forbidden"
+// @Failure 409 {object} res.Problem "External ID is taken"
 // @Router /admin/v1/venues [post]
 func (h *VenueHandler) Create() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -59,23 +67,17 @@ func (h *VenueHandler) Create() http.HandlerFunc {
 			return
 		}
 
-		venue, err := h.Service.Create(payload)
+		venue, err := h.Service.Create(audit.WithActor(r), payload)
 		if err != nil {
-			h.Logger.Error("Failed to create venue", "error", err.Error())
-			res.Json(w, "Failed to create venue", http.StatusInternalServerError)
+			if apperr.IsInternal(err) {
+				h.Logger.WithContext(r.Context()).Error("Failed to create venue", "error", err.Error())
+			}
+			res.Error(w, r, err)
 			return
 		}
 
-		response := VenueResponse{
-			ID:          venue.ID,
-			Name:        venue.Name,
-			Description: venue.Description,
-			Address:     venue.Address,
-			Phone:       venue.Phone,
-			Email:       venue.Email,
-		}
-
-		res.Json(w, response, http.StatusCreated)
+		etag.Set(w, venue.Version)
+		res.Json(w, venue, http.StatusCreated)
 	}
 }
 
@@ -86,73 +88,94 @@ func (h *VenueHandler) Create() http.HandlerFunc {
 // @Accept json
 // @Produce json
 // @Param id path int true "Venue ID"
+// @Param If-Match header string true "ETag from the last read, * to skip the check"
 // @Param request body UpdateVenueRequest true "Venue details to update"
 // @Success 200 {object} VenueResponse
-// @Failure 400 {string} string "Bad request"
-// @Failure 401 {string} string "Unauthorized"
-// @Failure 403 {string} string "Forbidden"
-// @Failure 404 {string} string "Not found"
+// @Header 200 {string} ETag "Version of the venue"
+// @Failure 400 {object} res.Problem "Bad request"
+// @Failure 401 {object} res.Problem "Unauthorized"
+// @Failure 403 {object} res.Problem "Forbidden"
+// @Failure 404 {object} res.Problem "Not found"
+// @Failure 409 {object} res.Problem "External ID is taken"
+// @Failure 412 {object} res.Problem "Venue was changed since it was read"
+// @Failure 428 {object} res.Problem "If-Match is missing"
 // @Router /admin/v1/venues/{id} [put]
 func (h *VenueHandler) Update() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		id, err := strconv.ParseUint(r.PathValue("id"), 10, 32)
 		if err != nil {
-			res.Json(w, "Invalid venue ID", http.StatusBadRequest)
+			res.Error(w, r, ErrInvalidID)
 			return
 		}
 
-		payload, err := req.HandleBody[UpdateVenueRequest](&w, r)
+		version, err := etag.IfMatch(r)
 		if err != nil {
+			res.Error(w, r, err)
 			return
 		}
 
-		venue, err := h.Service.Update(uint(id), payload)
+		payload, err := req.HandleBody[UpdateVenueRequest](&w, r)
 		if err != nil {
-			if err.Error() == "venue not found" {
-				res.Json(w, "Venue not found", http.StatusNotFound)
-				return
-			}
-			h.Logger.Error("Failed to update venue", "error", err.Error())
-			res.Json(w, "Failed to update venue", http.StatusInternalServerError)
 			return
 		}
 
-		response := VenueResponse{
-			ID:          venue.ID,
-			Name:        venue.Name,
-			Description: venue.Description,
-			Address:     venue.Address,
-			Phone:       venue.Phone,
-			Email:       venue.Email,
+		venue, err := h.Service.Update(audit.WithActor(r), uint(id), version, payload)
+		if err != nil {
+			if apperr.IsInternal(err) {
+				h.Logger.WithContext(r.Context()).Error("Failed to update venue", "error", err.Error())
+			}
+			res.Error(w, r, err)
+			return
 		}
 
-		res.Json(w, response, http.StatusOK)
+		etag.Set(w, venue.Version)
+		res.Json(w, venue, http.StatusOK)
 	}
 }
 
 // Delete godoc
 // @Summary Delete a venue
-// @Description Delete an existing venue
+// @Description Move a venue to trash. A venue with concerts is kept unless cascade is set, then its concerts are moved to trash too
 // @Tags Admin/Venues
 // @Produce json
 // @Param id path int true "Venue ID"
+// @Param If-Match header string true "ETag from the last read, * to skip the check"
+// @Param cascade query bool false "Also delete concerts at the venue"
 // @Success 204 "No Content"
-// @Failure 400 {string} string "Bad request"
-// @Failure 401 {string} string "Unauthorized"
-// @Failure 403 {string} string "Forbidden"
+// @Failure 400 {object} res.Problem "Bad request"
+// @Failure 401 {object} res.Problem "Unauthorized"
+// @Failure 403 {object} res.Problem "Forbidden"
+// @Failure 404 {object} res.Problem "Not found"
+// @Failure 409 {object} res.Problem "Venue has concerts"
+// @Failure 412 {object} res.Problem "Venue was changed since it was read"
+// @Failure 428 {object} res.Problem "If-Match is missing"
 // @Router /admin/v1/venues/{id} [delete]
 func (h *VenueHandler) Delete() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		id, err := strconv.ParseUint(r.PathValue("id"), 10, 32)
 		if err != nil {
-			res.Json(w, "Invalid venue ID", http.StatusBadRequest)
+			res.Error(w, r, ErrInvalidID)
+			return
+		}
+
+		cascade, err := req.BoolParam(r, "cascade")
+		if err != nil {
+			res.Error(w, r, err)
+			return
+		}
+
+		version, err := etag.IfMatch(r)
+		if err != nil {
+			res.Error(w, r, err)
 			return
 		}
 
-		err = h.Service.Delete(uint(id))
+		err = h.Service.Delete(audit.WithActor(r), uint(id), version, cascade)
 		if err != nil {
-			h.Logger.Error("Failed to delete venue", "error", err.Error())
-			res.Json(w, "Failed to delete venue", http.StatusInternalServerError)
+			if apperr.IsInternal(err) {
+				h.Logger.WithContext(r.Context()).Error("Failed to delete venue", "error", err.Error())
+			}
+			res.Error(w, r, err)
 			return
 		}
 
@@ -167,35 +190,28 @@ func (h *VenueHandler) Delete() http.HandlerFunc {
 // @Produce json
 // @Param id path int true "Venue ID"
 // @Success 200 {object} VenueResponse
-// @Failure 400 {string} string "Bad request"
-// @Failure 401 {string} string "Unauthorized"
-// @Failure 403 {string} string "Forbidden"
-// @Failure 404 {string} string "Not found"
+// @Header 200 {string} ETag "Version of the venue, send it as If-Match to update or delete"
+// @Failure 400 {object} res.Problem "Bad request"
+// @Failure 401 {object} res.Problem "Unauthorized"
+// @Failure 403 {object} res.Problem "Forbidden"
+// @Failure 404 {object} res.Problem "Not found"
 // @Router /admin/v1/venues/{id} [get]
 func (h *VenueHandler) GetByID() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		id, err := strconv.ParseUint(r.PathValue("id"), 10, 32)
 		if err != nil {
-			res.Json(w, "Invalid venue ID", http.StatusBadRequest)
+			res.Error(w, r, ErrInvalidID)
 			return
 		}
 
-		venue, err := h.Service.GetByID(uint(id))
+		venue, err := h.Service.GetByID(r.Context(), uint(id))
 		if err != nil {
-			res.Json(w, "Venue not found", http.StatusNotFound)
+			res.Error(w, r, err)
 			return
 		}
 
-		response := VenueResponse{
-			ID:          venue.ID,
-			Name:        venue.Name,
-			Description: venue.Description,
-			Address:     venue.Address,
-			Phone:       venue.Phone,
-			Email:       venue.Email,
-		}
-
-		res.Json(w, response, http.StatusOK)
+		etag.Set(w, venue.Version)
+		res.Json(w, venue, http.StatusOK)
 	}
 }
 
@@ -206,37 +222,142 @@ func (h *VenueHandler) GetByID() http.HandlerFunc {
 // @Produce json
 // @Param page query int false "Page number (default: 1)"
 // @Param pageSize query int false "Page size (default: 10, max: 100)"
+// @Param sort query string false "Comma separated sort fields, prefix with - for descending"
+// @Param q query string false "Search term"
+// @Param cursor query string false "nextCursor from the previous page, replaces page"
 // @Success 200 {object} ListVenuesResponse
-// @Failure 401 {string} string "Unauthorized"
-// @Failure 403 {string} string "Forbidden"
+// @Failure 400 {object} res.Problem "Unsupported filter or sort field"
+// @Failure 401 {object} res.Problem "Unauthorized"
+// @Failure 403 {object} res.Problem "Forbidden"
 // @Router /admin/v1/venues [get]
 func (h *VenueHandler) List() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
-		pageSize, _ := strconv.Atoi(r.URL.Query().Get("pageSize"))
+		spec, err := query.ParseSpec(r.URL.Query(), VenueListSchema)
+		if err != nil {
+			res.Error(w, r, err)
+			return
+		}
+
+		venues, err := h.Service.List(r.Context(), spec)
+		if err != nil {
+			if apperr.IsInternal(err) {
+				h.Logger.WithContext(r.Context()).Error("Failed to list venues", "error", err.Error())
+			}
+			res.Error(w, r, err)
+			return
+		}
+
+		res.Json(w, venues, http.StatusOK)
+	}
+}
+
+// ListTrash godoc
+// @Summary List deleted venues
+// @Description Get a paginated list of venues in trash
+// @Tags Admin/Venues
+// @Produce json
+// @Param page query int false "Page number (default: 1)"
+// @Param pageSize query int false "Page size (default: 10, max: 100)"
+// @Param sort query string false "Comma separated sort fields, prefix with - for descending, deletedAt is supported"
+// @Param q query string false "Search term"
+// @Param cursor query string false "nextCursor from the previous page, replaces page"
+// @Success 200 {object} ListVenuesResponse
+// @Failure 400 {object} res.Problem "Unsupported filter or sort field"
+// @Failure 401 {object} res.Problem "Unauthorized"
+// @Failure 403 {object} res.Problem "Forbidden"
+// @Router /admin/v1/venues/trash [get]
+func (h *VenueHandler) ListTrash() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		spec, err := query.ParseSpec(r.URL.Query(), VenueTrashSchema)
+		if err != nil {
+			res.Error(w, r, err)
+			return
+		}
+
+		venues, err := h.Service.ListDeleted(r.Context(), spec)
+		if err != nil {
+			if apperr.IsInternal(err) {
+				h.Logger.WithContext(r.Context()).Error("Failed to list deleted venues", "error", err.Error())
+			}
+			res.Error(w, r, err)
+			return
+		}
+
+		res.Json(w, venues, http.StatusOK)
+	}
+}
+
+// Restore godoc
+// @Summary Restore a deleted venue
+// @Description Bring a venue back from trash, concerts deleted together with it are restored too
+// @Tags Admin/Venues
+// @Produce json
+// @Param id path int true "Venue ID"
+// @Success 200 {object} VenueResponse
+// @Failure 400 {object} res.Problem "Bad request"
+// @Failure 401 {object} res.Problem "Unauthorized"
+// @Failure 403 {object} res.Problem "Forbidden"
+// @Failure 404 {object} res.Problem "Not found in trash"
+// @Router /admin/v1/venues/trash/{id}/restore [post]
+func (h *VenueHandler) Restore() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseUint(r.PathValue("id"), 10, 32)
+		if err != nil {
+			res.Error(w, r, ErrInvalidID)
+			return
+		}
 
-		venues, err := h.Service.List(page, pageSize)
+		venue, err := h.Service.Restore(audit.WithActor(r), uint(id))
 		if err != nil {
-			h.Logger.Error("Failed to list venues", "error", err.Error())
-			res.Json(w, "Failed to list venues", http.StatusInternalServerError)
+			if apperr.IsInternal(err) {
+				h.Logger.WithContext(r.Context()).Error("Failed to restore venue", "error", err.Error())
+			}
+			res.Error(w, r, err)
 			return
 		}
 
-		response := ListVenuesResponse{
-			Items: make([]VenueResponse, len(venues.Items)),
+		etag.Set(w, venue.Version)
+		res.Json(w, venue, http.StatusOK)
+	}
+}
+
+// Purge godoc
+// @Summary Permanently delete a venue
+// @Description Remove a venue from trash for good. Concerts at the venue, deleted ones included, block it unless cascade is set
+// @Tags Admin/Venues
+// @Produce json
+// @Param id path int true "Venue ID"
+// @Param cascade query bool false "Also permanently delete concerts at the venue"
+// @Success 204 "No Content"
+// @Failure 400 {object} res.Problem "Bad request"
+// @Failure 401 {object} res.Problem "Unauthorized"
+// @Failure 403 {object} res.Problem "Forbidden"
+// @Failure 404 {object} res.Problem "Not found in trash"
+// @Failure 409 {object} res.Problem "Venue has concerts"
+// @Router /admin/v1/venues/trash/{id} [delete]
+func (h *VenueHandler) Purge() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseUint(r.PathValue("id"), 10, 32)
+		if err != nil {
+			res.Error(w, r, ErrInvalidID)
+			return
 		}
 
-		for i, venue := range venues.Items {
-			response.Items[i] = VenueResponse{
-				ID:          venue.ID,
-				Name:        venue.Name,
-				Description: venue.Description,
-				Address:     venue.Address,
-				Phone:       venue.Phone,
-				Email:       venue.Email,
+		cascade, err := req.BoolParam(r, "cascade")
+		if err != nil {
+			res.Error(w, r, err)
+			return
+		}
+
+		err = h.Service.Purge(audit.WithActor(r), uint(id), cascade)
+		if err != nil {
+			if apperr.IsInternal(err) {
+				h.Logger.WithContext(r.Context()).Error("Failed to purge venue", "error", err.Error())
 			}
+			res.Error(w, r, err)
+			return
 		}
 
-		res.Json(w, response, http.StatusOK)
+		w.WriteHeader(http.StatusNoContent)
 	}
 }
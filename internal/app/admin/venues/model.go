@@ -1,6 +1,8 @@
 package venues
 
 import (
+	"time"
+
 	"gorm.io/gorm"
 )
 
@@ -9,7 +11,35 @@ type Venue struct {
 	*gorm.Model
 	Name        string `json:"name" gorm:"type:varchar(100);not null;index:idx_venue_name"`
 	Description string `json:"description" gorm:"type:varchar(300)"`
-	Address     string `json:"address" gorm:"type:varchar(50);not null;index:idx_venue_address"`
-	Phone       string `json:"phone" gorm:"type:varchar(20)"`
-	Email       string `json:"email" gorm:"type:varchar(50)"`
+	// Address is the street line, the rest of the address is kept in separate fields
+	Address    string `json:"address" gorm:"type:varchar(255);not null;index:idx_venue_address"`
+	City       string `json:"city" gorm:"type:varchar(100);index:idx_venue_city"`
+	Region     string `json:"region" gorm:"type:varchar(100)"`
+	PostalCode string `json:"postalCode" gorm:"type:varchar(20)"`
+	// Country is an ISO 3166-1 alpha-2 code
+	Country string `json:"country" gorm:"type:varchar(2);index:idx_venue_country"`
+	Phone   string `json:"phone" gorm:"type:varchar(20)"`
+	Email   string `json:"email" gorm:"type:varchar(50)"`
+	// TimeZone is an IANA name, concert times are shown in it
+	TimeZone string `json:"timeZone" gorm:"type:varchar(64);not null;default:UTC"`
+	// Capacity is the most tickets that can be sold for a concert here, nil when unknown
+	Capacity  *int     `json:"capacity"`
+	Latitude  *float64 `json:"latitude" gorm:"index:idx_venue_location,priority:1"`
+	Longitude *float64 `json:"longitude" gorm:"index:idx_venue_location,priority:2"`
+	// ExternalID is the key of the venue in the system it was imported from
+	ExternalID *string `json:"externalId" gorm:"type:varchar(100);uniqueIndex:idx_venue_external_id"`
+	// Version is bumped on every write and sent as the ETag
+	Version uint `json:"version" gorm:"not null;default:1"`
+}
+
+// Location returns the venue time zone, UTC when it is not set or unknown
+func (v *Venue) Location() *time.Location {
+	if v.TimeZone == "" {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(v.TimeZone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
 }
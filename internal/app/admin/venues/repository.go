@@ -1,15 +1,57 @@
 package venues
 
 import (
+	"context"
+	"time"
+
 	"github.com/serhiirubets/rubeticket/internal/pkg/db"
+	"github.com/serhiirubets/rubeticket/internal/pkg/query"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// exportBatchSize is how many venues are read at once while exporting
+const exportBatchSize = 500
+
+// VenueListSchema lists fields venues can be filtered, sorted and searched by
+var VenueListSchema = query.Schema{
+	Filters: map[string]query.Field{
+		"name":     {Column: "name", Ops: []query.Operator{query.Eq, query.Like}},
+		"address":  {Column: "address", Ops: []query.Operator{query.Eq, query.Like}},
+		"city":     {Column: "city", Ops: []query.Operator{query.Eq, query.Like, query.In}},
+		"country":  {Column: "country", Ops: []query.Operator{query.Eq, query.In}},
+		"capacity": {Column: "capacity", Ops: []query.Operator{query.Gte, query.Lte}, Type: query.Int},
+	},
+	Sort: map[string]string{
+		"id":        "id",
+		"name":      "name",
+		"city":      "city",
+		"capacity":  "capacity",
+		"createdAt": "created_at",
+	},
+	Search:      []string{"name", "description", "address", "city"},
+	DefaultSort: "id",
+}
+
+// VenueTrashSchema is VenueListSchema for deleted venues, also sortable by deletedAt
+var VenueTrashSchema = VenueListSchema.WithSort("deletedAt", "deleted_at")
+
 type IVenueRepository interface {
-	Create(venue *Venue) (*Venue, error)
-	Update(venue *Venue) error
-	Delete(id uint) error
-	GetByID(id uint) (*Venue, error)
-	List(page, pageSize int) ([]Venue, error)
+	Create(ctx context.Context, venue *Venue) (*Venue, error)
+	Update(ctx context.Context, venue *Venue) error
+	Delete(ctx context.Context, id uint, version uint, cascade bool) ([]uint, error)
+	GetByID(ctx context.Context, id uint) (*Venue, error)
+	GetByIDForShare(ctx context.Context, id uint) (*Venue, error)
+	LockForUpdate(ctx context.Context, id uint) error
+	GetByExternalID(ctx context.Context, externalID string) (*Venue, error)
+	List(ctx context.Context, spec *query.Spec) ([]Venue, query.PageMeta, error)
+	Export(ctx context.Context, spec *query.Spec, fn func(venues []Venue) error) error
+	CountConcerts(ctx context.Context, id uint, withTrashed bool) (int64, error)
+	GetDeleted(ctx context.Context, id uint) (*Venue, error)
+	ListDeleted(ctx context.Context, spec *query.Spec) ([]Venue, query.PageMeta, error)
+	Restore(ctx context.Context, id uint) ([]uint, error)
+	Purge(ctx context.Context, id uint, cascade bool) ([]uint, error)
+	WithTx(tx db.IDb) IVenueRepository
 }
 
 type VenueRepository struct {
@@ -20,34 +62,187 @@ func NewVenueRepository(Db db.IDb) IVenueRepository {
 	return &VenueRepository{Db: Db}
 }
 
-func (r *VenueRepository) Create(venue *Venue) (*Venue, error) {
-	if err := r.Db.Create(venue).Error; err != nil {
+// WithTx returns a copy of the repository that runs queries in tx
+func (r *VenueRepository) WithTx(tx db.IDb) IVenueRepository {
+	return &VenueRepository{Db: tx}
+}
+
+func (r *VenueRepository) Create(ctx context.Context, venue *Venue) (*Venue, error) {
+	if err := r.Db.WithContext(ctx).Create(venue).Error; err != nil {
 		return nil, err
 	}
 	return venue, nil
 }
 
-func (r *VenueRepository) Update(venue *Venue) error {
-	return r.Db.Save(venue).Error
+// Update saves the venue when nobody changed it since it was read, the version is bumped
+func (r *VenueRepository) Update(ctx context.Context, venue *Venue) error {
+	updated, err := db.SaveVersioned(r.Db.WithContext(ctx), venue, &venue.Version)
+	if err != nil {
+		return err
+	}
+	if !updated {
+		return ErrVersionConflict
+	}
+	return nil
 }
 
-func (r *VenueRepository) Delete(id uint) error {
-	return r.Db.Delete(&Venue{}, id).Error
+// Delete soft deletes the venue, with cascade its concerts too. Cascaded concerts get the same
+// deleted_at as the venue, so Restore brings back exactly those. Only the given version is deleted.
+// Returns the IDs of the cascaded concerts. Call it in a transaction
+func (r *VenueRepository) Delete(ctx context.Context, id uint, version uint, cascade bool) ([]uint, error) {
+	conn := r.Db.WithContext(ctx)
+	now := time.Now()
+
+	result := conn.Model(&Venue{}).
+		Where("id = ? AND version = ?", id, version).
+		Updates(map[string]interface{}{"deleted_at": now, "version": gorm.Expr("version + 1")})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrVersionConflict
+	}
+
+	var concertIDs []uint
+	if cascade {
+		err := conn.Raw(`UPDATE concerts SET deleted_at = ?, version = version + 1
+			WHERE venue_id = ? AND deleted_at IS NULL RETURNING id`, now, id).Scan(&concertIDs).Error
+		if err != nil {
+			return nil, err
+		}
+	}
+	return concertIDs, nil
 }
 
-func (r *VenueRepository) GetByID(id uint) (*Venue, error) {
+func (r *VenueRepository) GetByID(ctx context.Context, id uint) (*Venue, error) {
 	var venue Venue
-	if err := r.Db.First(&venue, id).Error; err != nil {
+	if err := r.Db.WithContext(ctx).First(&venue, id).Error; err != nil {
 		return nil, err
 	}
 	return &venue, nil
 }
 
-func (r *VenueRepository) List(page, pageSize int) ([]Venue, error) {
+// GetByIDForShare loads the venue and keeps it from being deleted until the transaction ends
+func (r *VenueRepository) GetByIDForShare(ctx context.Context, id uint) (*Venue, error) {
+	var venue Venue
+	if err := r.Db.WithContext(ctx).Clauses(clause.Locking{Strength: "SHARE"}).First(&venue, id).Error; err != nil {
+		return nil, err
+	}
+	return &venue, nil
+}
+
+// LockForUpdate waits for transactions that read the venue for share, e.g. ones adding concerts to it,
+// so that checks made afterwards see their changes. Call it in a transaction
+func (r *VenueRepository) LockForUpdate(ctx context.Context, id uint) error {
+	return r.Db.WithContext(ctx).Exec(`SELECT id FROM venues WHERE id = ? FOR UPDATE`, id).Error
+}
+
+// Export passes all venues matching spec filters to fn in batches, by id. Pagination is ignored
+func (r *VenueRepository) Export(ctx context.Context, spec *query.Spec, fn func(venues []Venue) error) error {
+	var batch []Venue
+	return spec.Where(r.Db.WithContext(ctx).Model(&Venue{}), VenueListSchema).
+		FindInBatches(&batch, exportBatchSize, func(tx *gorm.DB, _ int) error {
+			return fn(batch)
+		}).Error
+}
+
+// GetByExternalID finds the venue with the external ID, deleted ones included
+func (r *VenueRepository) GetByExternalID(ctx context.Context, externalID string) (*Venue, error) {
+	var venue Venue
+	if err := r.Db.WithContext(ctx).Unscoped().Where("external_id = ?", externalID).First(&venue).Error; err != nil {
+		return nil, err
+	}
+	return &venue, nil
+}
+
+// List returns one page of venues matching spec, by offset or by cursor
+func (r *VenueRepository) List(ctx context.Context, spec *query.Spec) ([]Venue, query.PageMeta, error) {
 	var venues []Venue
-	offset := (page - 1) * pageSize
-	if err := r.Db.Offset(offset).Limit(pageSize).Find(&venues).Error; err != nil {
+	var total int64
+
+	filtered := spec.Where(r.Db.WithContext(ctx).Model(&Venue{}), VenueListSchema).Session(&gorm.Session{})
+	if err := filtered.Count(&total).Error; err != nil {
+		return nil, query.PageMeta{}, err
+	}
+	if err := spec.Paginate(filtered).Find(&venues).Error; err != nil {
+		return nil, query.PageMeta{}, err
+	}
+	return query.NewPage(filtered, spec, venues, total)
+}
+
+// CountConcerts counts concerts at the venue, withTrashed includes deleted ones
+func (r *VenueRepository) CountConcerts(ctx context.Context, id uint, withTrashed bool) (int64, error) {
+	q := r.Db.WithContext(ctx).Table("concerts").Where("venue_id = ?", id)
+	if !withTrashed {
+		q = q.Where("deleted_at IS NULL")
+	}
+
+	var count int64
+	if err := q.Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (r *VenueRepository) GetDeleted(ctx context.Context, id uint) (*Venue, error) {
+	var venue Venue
+	if err := query.Trashed(r.Db.WithContext(ctx)).First(&venue, id).Error; err != nil {
+		return nil, err
+	}
+	return &venue, nil
+}
+
+// ListDeleted returns one page of deleted venues matching spec
+func (r *VenueRepository) ListDeleted(ctx context.Context, spec *query.Spec) ([]Venue, query.PageMeta, error) {
+	var venues []Venue
+	var total int64
+
+	filtered := spec.Where(query.Trashed(r.Db.WithContext(ctx).Model(&Venue{})), VenueTrashSchema).Session(&gorm.Session{})
+	if err := filtered.Count(&total).Error; err != nil {
+		return nil, query.PageMeta{}, err
+	}
+	if err := spec.Paginate(filtered).Find(&venues).Error; err != nil {
+		return nil, query.PageMeta{}, err
+	}
+	return query.NewPage(filtered, spec, venues, total)
+}
+
+// Restore undeletes the venue and the concerts deleted with it and returns the IDs of those concerts.
+// Call it in a transaction
+func (r *VenueRepository) Restore(ctx context.Context, id uint) ([]uint, error) {
+	conn := r.Db.WithContext(ctx)
+
+	var concertIDs []uint
+	err := conn.Raw(`UPDATE concerts SET deleted_at = NULL, version = version + 1
+		WHERE venue_id = ? AND deleted_at = (SELECT deleted_at FROM venues WHERE id = ?) RETURNING id`, id, id).Scan(&concertIDs).Error
+	if err != nil {
+		return nil, err
+	}
+	err = conn.Unscoped().Model(&Venue{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{"deleted_at": nil, "version": gorm.Expr("version + 1")}).Error
+	if err != nil {
+		return nil, err
+	}
+	return concertIDs, nil
+}
+
+// Purge removes the venue permanently, with cascade all its concerts too, and returns the IDs
+// of the removed concerts. Call it in a transaction
+func (r *VenueRepository) Purge(ctx context.Context, id uint, cascade bool) ([]uint, error) {
+	conn := r.Db.WithContext(ctx)
+
+	var concertIDs []uint
+	if cascade {
+		if err := conn.Exec("DELETE FROM concert_bands WHERE concert_id IN (SELECT id FROM concerts WHERE venue_id = ?)", id).Error; err != nil {
+			return nil, err
+		}
+		if err := conn.Raw("DELETE FROM concerts WHERE venue_id = ? RETURNING id", id).Scan(&concertIDs).Error; err != nil {
+			return nil, err
+		}
+	}
+	if err := conn.Unscoped().Delete(&Venue{}, id).Error; err != nil {
 		return nil, err
 	}
-	return venues, nil
+	return concertIDs, nil
 }
@@ -0,0 +1,156 @@
+package venues_test
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/serhiirubets/rubeticket/internal/app/admin/bands"
+	"github.com/serhiirubets/rubeticket/internal/app/admin/concerts"
+	"github.com/serhiirubets/rubeticket/internal/app/admin/venues"
+	"github.com/serhiirubets/rubeticket/internal/pkg/query"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// secondPage returns the query of the page after a page of rows[0], listed with params.
+// trashed lists deleted rows
+func secondPage[T any](t *testing.T, schema query.Schema, params string, rows []T, trashed bool) *gorm.Statement {
+	t.Helper()
+	values, err := url.ParseQuery(params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	spec, err := query.ParseSpec(values, schema)
+	if err != nil {
+		t.Fatalf("ParseSpec() error = %v", err)
+	}
+	spec.PageSize = 1
+
+	conn := dryRunDB(t)
+	_, meta, err := query.NewPage(conn, spec, rows, int64(len(rows)))
+	if err != nil {
+		t.Fatalf("NewPage() error = %v", err)
+	}
+
+	values.Set("cursor", meta.NextCursor)
+	spec, err = query.ParseSpec(values, schema)
+	if err != nil {
+		t.Fatalf("ParseSpec() of the next page error = %v", err)
+	}
+
+	var page []T
+	list := conn.Model(new(T))
+	if trashed {
+		list = query.Trashed(list)
+	}
+	return spec.Paginate(spec.Where(list, schema)).Find(&page).Statement
+}
+
+func TestListByCapacity(t *testing.T) {
+	capacity := 300
+
+	tests := []struct {
+		name     string
+		params   string
+		last     venues.Venue
+		wantSQL  string
+		wantVars []interface{}
+	}{
+		{
+			name:     "after a capacity, unknown capacities follow",
+			params:   "sort=capacity",
+			last:     venues.Venue{Model: &gorm.Model{ID: 4}, Capacity: &capacity},
+			wantSQL:  `SELECT * FROM "venues" WHERE ((((capacity > $1 OR capacity IS NULL)) OR (capacity = $2 AND id > $3))) AND "venues"."deleted_at" IS NULL ORDER BY capacity,id LIMIT $4`,
+			wantVars: []interface{}{"300", "300", "4", 11},
+		},
+		{
+			name:     "after an unknown capacity",
+			params:   "sort=capacity",
+			last:     venues.Venue{Model: &gorm.Model{ID: 4}},
+			wantSQL:  `SELECT * FROM "venues" WHERE (((capacity IS NULL AND id > $1))) AND "venues"."deleted_at" IS NULL ORDER BY capacity,id LIMIT $2`,
+			wantVars: []interface{}{"4", 11},
+		},
+		{
+			name:     "descending after an unknown capacity",
+			params:   "sort=-capacity",
+			last:     venues.Venue{Model: &gorm.Model{ID: 4}},
+			wantSQL:  `SELECT * FROM "venues" WHERE (((capacity IS NOT NULL) OR (capacity IS NULL AND id > $1))) AND "venues"."deleted_at" IS NULL ORDER BY capacity DESC,id LIMIT $2`,
+			wantVars: []interface{}{"4", 11},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stmt := secondPage(t, venues.VenueListSchema, tt.params, []venues.Venue{tt.last, {Model: &gorm.Model{ID: 100}}}, false)
+			if got := stmt.SQL.String(); got != tt.wantSQL {
+				t.Errorf("SQL = %s\nwant  %s", got, tt.wantSQL)
+			}
+			if !reflect.DeepEqual(stmt.Vars, tt.wantVars) {
+				t.Errorf("vars = %#v, want %#v", stmt.Vars, tt.wantVars)
+			}
+		})
+	}
+}
+
+func TestListDeletedSecondPage(t *testing.T) {
+	deletedAt := gorm.DeletedAt{Time: time.Date(2025, 4, 1, 12, 30, 0, 0, time.UTC), Valid: true}
+	model := func(id uint) *gorm.Model {
+		return &gorm.Model{ID: id, DeletedAt: deletedAt}
+	}
+
+	tests := []struct {
+		name string
+		stmt func(t *testing.T) *gorm.Statement
+		want string
+	}{
+		{
+			name: "venues",
+			stmt: func(t *testing.T) *gorm.Statement {
+				return secondPage(t, venues.VenueTrashSchema, "sort=-deletedAt", []venues.Venue{{Model: model(4)}, {Model: model(2)}}, true)
+			},
+			want: `SELECT * FROM "venues" WHERE deleted_at IS NOT NULL AND (((deleted_at < $1) OR (deleted_at = $2 AND id > $3))) ORDER BY deleted_at DESC,id LIMIT $4`,
+		},
+		{
+			name: "bands",
+			stmt: func(t *testing.T) *gorm.Statement {
+				return secondPage(t, bands.BandTrashSchema, "sort=-deletedAt", []bands.Band{{Model: model(4)}, {Model: model(2)}}, true)
+			},
+			want: `SELECT * FROM "bands" WHERE deleted_at IS NOT NULL AND (((deleted_at < $1) OR (deleted_at = $2 AND id > $3))) ORDER BY deleted_at DESC,id LIMIT $4`,
+		},
+		{
+			name: "concerts",
+			stmt: func(t *testing.T) *gorm.Statement {
+				return secondPage(t, concerts.ConcertTrashSchema, "sort=-deletedAt", []concerts.Concert{{Model: model(4)}, {Model: model(2)}}, true)
+			},
+			want: `SELECT * FROM "concerts" WHERE deleted_at IS NOT NULL AND (((deleted_at < $1) OR (deleted_at = $2 AND id > $3))) ORDER BY deleted_at DESC,id LIMIT $4`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stmt := tt.stmt(t)
+			if got := stmt.SQL.String(); got != tt.want {
+				t.Errorf("SQL = %s\nwant  %s", got, tt.want)
+			}
+			wantVars := []interface{}{"2025-04-01T12:30:00Z", "2025-04-01T12:30:00Z", "4", query.DefaultPageSize + 1}
+			if !reflect.DeepEqual(stmt.Vars, wantVars) {
+				t.Errorf("vars = %#v, want %#v", stmt.Vars, wantVars)
+			}
+		})
+	}
+}
+
+// dryRunDB builds SQL without connecting to a database
+func dryRunDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	conn, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}), &gorm.Config{
+		DryRun:               true,
+		DisableAutomaticPing: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return conn
+}
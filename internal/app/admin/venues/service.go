@@ -1,133 +1,327 @@
 package venues
 
 import (
-	"errors"
+	"context"
+
+	"github.com/serhiirubets/rubeticket/internal/app/admin/audit"
+	"github.com/serhiirubets/rubeticket/internal/pkg/convert"
+	"github.com/serhiirubets/rubeticket/internal/pkg/db"
+	"github.com/serhiirubets/rubeticket/internal/pkg/etag"
+	"github.com/serhiirubets/rubeticket/internal/pkg/query"
+	"github.com/serhiirubets/rubeticket/internal/pkg/tracing"
 )
 
 type VenueService struct {
+	db         db.IDb
 	repository IVenueRepository
+	audit      *audit.Recorder
+}
+
+func NewVenueService(db db.IDb, repository IVenueRepository, auditRecorder *audit.Recorder) *VenueService {
+	return &VenueService{
+		db:         db,
+		repository: repository,
+		audit:      auditRecorder,
+	}
 }
 
-func NewVenueService(repository IVenueRepository) *VenueService {
-	return &VenueService{repository: repository}
+// WithTx returns a copy of the service that runs in tx, its own transactions become savepoints
+func (s *VenueService) WithTx(tx db.IDb) *VenueService {
+	return &VenueService{
+		db:         tx,
+		repository: s.repository.WithTx(tx),
+		audit:      s.audit,
+	}
 }
 
-func (s *VenueService) Create(payload *CreateVenueRequest) (*VenueResponse, error) {
+func (s *VenueService) Create(ctx context.Context, payload *CreateVenueRequest) (*VenueResponse, error) {
+	ctx, span := tracing.Start(ctx, "VenueService.Create")
+	defer span.End()
+
 	venue := &Venue{
+		ExternalID:  convert.OptionalString(payload.ExternalID),
 		Name:        payload.Name,
 		Description: payload.Description,
 		Address:     payload.Address,
 		Phone:       payload.Phone,
 		Email:       payload.Email,
+		City:        payload.City,
+		Region:      payload.Region,
+		PostalCode:  payload.PostalCode,
+		Country:     payload.Country,
+		TimeZone:    payload.TimeZone,
+		Capacity:    payload.Capacity,
+		Latitude:    payload.Latitude,
+		Longitude:   payload.Longitude,
 	}
+	if venue.TimeZone == "" {
+		venue.TimeZone = "UTC"
+	}
+
+	err := s.db.WithTx(ctx, func(tx db.IDb) error {
+		repository := s.repository.WithTx(tx)
+
+		if payload.ExternalID != "" {
+			if _, err := repository.GetByExternalID(ctx, payload.ExternalID); err == nil {
+				return ErrExternalIDTaken
+			}
+		}
 
-	created, err := s.repository.Create(venue)
+		var err error
+		venue, err = repository.Create(ctx, venue)
+		if err != nil {
+			return err
+		}
+		return s.audit.Record(ctx, tx, audit.ActionCreate, audit.EntityVenue, venue.ID, nil, ToVenueResponse(venue))
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	return &VenueResponse{
-		ID:          created.ID,
-		Name:        created.Name,
-		Description: created.Description,
-		Address:     created.Address,
-		Phone:       created.Phone,
-		Email:       created.Email,
-		CreatedAt:   created.CreatedAt,
-		UpdatedAt:   created.UpdatedAt,
-	}, nil
+	return ToVenueResponse(venue), nil
 }
 
-func (s *VenueService) Update(id uint, payload *UpdateVenueRequest) (*VenueResponse, error) {
-	venue, err := s.repository.GetByID(id)
+// Update changes the venue if it still has the expected version, etag.Any skips the check
+func (s *VenueService) Update(ctx context.Context, id uint, version uint, payload *UpdateVenueRequest) (*VenueResponse, error) {
+	ctx, span := tracing.Start(ctx, "VenueService.Update")
+	defer span.End()
+
+	var venue *Venue
+
+	err := s.db.WithTx(ctx, func(tx db.IDb) error {
+		repository := s.repository.WithTx(tx)
+
+		var err error
+		venue, err = repository.GetByID(ctx, id)
+		if err != nil {
+			return ErrVenueNotFound
+		}
+		if !etag.Matches(version, venue.Version) {
+			return ErrVersionConflict
+		}
+		before := ToVenueResponse(venue)
+
+		if payload.ExternalID != nil {
+			if *payload.ExternalID != "" {
+				if existing, err := repository.GetByExternalID(ctx, *payload.ExternalID); err == nil && existing.ID != id {
+					return ErrExternalIDTaken
+				}
+			}
+			venue.ExternalID = convert.OptionalString(*payload.ExternalID)
+		}
+		if payload.Name != nil {
+			venue.Name = *payload.Name
+		}
+		if payload.Description != nil {
+			venue.Description = *payload.Description
+		}
+		if payload.Address != nil {
+			venue.Address = *payload.Address
+		}
+		if payload.Phone != nil {
+			venue.Phone = *payload.Phone
+		}
+		if payload.Email != nil {
+			venue.Email = *payload.Email
+		}
+		if payload.City != nil {
+			venue.City = *payload.City
+		}
+		if payload.Region != nil {
+			venue.Region = *payload.Region
+		}
+		if payload.PostalCode != nil {
+			venue.PostalCode = *payload.PostalCode
+		}
+		if payload.Country != nil {
+			venue.Country = *payload.Country
+		}
+		if payload.TimeZone != nil {
+			venue.TimeZone = *payload.TimeZone
+		}
+		if payload.Capacity != nil {
+			venue.Capacity = payload.Capacity
+		}
+		if payload.Latitude != nil {
+			venue.Latitude = payload.Latitude
+			venue.Longitude = payload.Longitude
+		}
+
+		if err := repository.Update(ctx, venue); err != nil {
+			return err
+		}
+		return s.audit.Record(ctx, tx, audit.ActionUpdate, audit.EntityVenue, id, before, ToVenueResponse(venue))
+	})
 	if err != nil {
-		return nil, errors.New("venue not found")
+		return nil, err
 	}
 
-	if payload.Name != nil {
-		venue.Name = *payload.Name
-	}
-	if payload.Description != nil {
-		venue.Description = *payload.Description
-	}
-	if payload.Address != nil {
-		venue.Address = *payload.Address
-	}
-	if payload.Phone != nil {
-		venue.Phone = *payload.Phone
-	}
-	if payload.Email != nil {
-		venue.Email = *payload.Email
+	return ToVenueResponse(venue), nil
+}
+
+// Delete moves the venue to trash if it still has the expected version. A venue with concerts
+// is kept unless cascade is set, then its concerts are moved to trash with it
+func (s *VenueService) Delete(ctx context.Context, id uint, version uint, cascade bool) error {
+	ctx, span := tracing.Start(ctx, "VenueService.Delete")
+	defer span.End()
+
+	return s.db.WithTx(ctx, func(tx db.IDb) error {
+		repository := s.repository.WithTx(tx)
+
+		// Concerts being saved hold the venue for share, the lock waits for them so that they are counted
+		if err := repository.LockForUpdate(ctx, id); err != nil {
+			return err
+		}
+		venue, err := repository.GetByID(ctx, id)
+		if err != nil {
+			return ErrVenueNotFound
+		}
+		if !etag.Matches(version, venue.Version) {
+			return ErrVersionConflict
+		}
+		if err := checkConcerts(ctx, repository, id, false, cascade); err != nil {
+			return err
+		}
+		concertIDs, err := repository.Delete(ctx, id, venue.Version, cascade)
+		if err != nil {
+			return err
+		}
+		if err := s.recordConcerts(ctx, tx, audit.ActionDelete, concertIDs); err != nil {
+			return err
+		}
+		return s.audit.Record(ctx, tx, audit.ActionDelete, audit.EntityVenue, id, ToVenueResponse(venue), nil)
+	})
+}
+
+func (s *VenueService) GetByID(ctx context.Context, id uint) (*VenueResponse, error) {
+	ctx, span := tracing.Start(ctx, "VenueService.GetByID")
+	defer span.End()
+
+	venue, err := s.repository.GetByID(ctx, id)
+	if err != nil {
+		return nil, ErrVenueNotFound
 	}
 
-	err = s.repository.Update(venue)
+	return ToVenueResponse(venue), nil
+}
+
+func (s *VenueService) List(ctx context.Context, spec *query.Spec) (*ListVenuesResponse, error) {
+	ctx, span := tracing.Start(ctx, "VenueService.List")
+	defer span.End()
+
+	venues, meta, err := s.repository.List(ctx, spec)
 	if err != nil {
 		return nil, err
 	}
 
-	return &VenueResponse{
-		ID:          venue.ID,
-		Name:        venue.Name,
-		Description: venue.Description,
-		Address:     venue.Address,
-		Phone:       venue.Phone,
-		Email:       venue.Email,
-		CreatedAt:   venue.CreatedAt,
-		UpdatedAt:   venue.UpdatedAt,
-	}, nil
-}
+	response := &ListVenuesResponse{
+		Items:    make([]VenueResponse, len(venues)),
+		PageMeta: meta,
+	}
+
+	for i, venue := range venues {
+		response.Items[i] = *ToVenueResponse(&venue)
+	}
 
-func (s *VenueService) Delete(id uint) error {
-	return s.repository.Delete(id)
+	return response, nil
 }
 
-func (s *VenueService) GetByID(id uint) (*VenueResponse, error) {
-	venue, err := s.repository.GetByID(id)
+func (s *VenueService) ListDeleted(ctx context.Context, spec *query.Spec) (*ListVenuesResponse, error) {
+	ctx, span := tracing.Start(ctx, "VenueService.ListDeleted")
+	defer span.End()
+
+	venues, meta, err := s.repository.ListDeleted(ctx, spec)
 	if err != nil {
 		return nil, err
 	}
 
-	return &VenueResponse{
-		ID:          venue.ID,
-		Name:        venue.Name,
-		Description: venue.Description,
-		Address:     venue.Address,
-		Phone:       venue.Phone,
-		Email:       venue.Email,
-		CreatedAt:   venue.CreatedAt,
-		UpdatedAt:   venue.UpdatedAt,
+	return &ListVenuesResponse{
+		Items:    ToVenuesResponse(venues),
+		PageMeta: meta,
 	}, nil
 }
 
-func (s *VenueService) List(page, pageSize int) (*ListVenuesResponse, error) {
-	if page < 1 {
-		page = 1
-	}
-	if pageSize < 1 || pageSize > 100 {
-		pageSize = 10
-	}
+// Restore brings the venue back from trash together with concerts deleted with it
+func (s *VenueService) Restore(ctx context.Context, id uint) (*VenueResponse, error) {
+	ctx, span := tracing.Start(ctx, "VenueService.Restore")
+	defer span.End()
+
+	var venue *Venue
 
-	venues, err := s.repository.List(page, pageSize)
+	err := s.db.WithTx(ctx, func(tx db.IDb) error {
+		repository := s.repository.WithTx(tx)
+
+		if _, err := repository.GetDeleted(ctx, id); err != nil {
+			return ErrVenueNotInTrash
+		}
+		concertIDs, err := repository.Restore(ctx, id)
+		if err != nil {
+			return err
+		}
+		if err := s.recordConcerts(ctx, tx, audit.ActionRestore, concertIDs); err != nil {
+			return err
+		}
+
+		venue, err = repository.GetByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		return s.audit.Record(ctx, tx, audit.ActionRestore, audit.EntityVenue, id, nil, ToVenueResponse(venue))
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	response := &ListVenuesResponse{
-		Items: make([]VenueResponse, len(venues)),
-	}
+	return ToVenueResponse(venue), nil
+}
 
-	for i, venue := range venues {
-		response.Items[i] = VenueResponse{
-			ID:          venue.ID,
-			Name:        venue.Name,
-			Description: venue.Description,
-			Address:     venue.Address,
-			Phone:       venue.Phone,
-			Email:       venue.Email,
-			CreatedAt:   venue.CreatedAt,
-			UpdatedAt:   venue.UpdatedAt,
+// Purge permanently removes a venue from trash. Concerts, deleted ones included, block it unless cascade is set
+func (s *VenueService) Purge(ctx context.Context, id uint, cascade bool) error {
+	ctx, span := tracing.Start(ctx, "VenueService.Purge")
+	defer span.End()
+
+	return s.db.WithTx(ctx, func(tx db.IDb) error {
+		repository := s.repository.WithTx(tx)
+
+		if _, err := repository.GetDeleted(ctx, id); err != nil {
+			return ErrVenueNotInTrash
+		}
+		if err := checkConcerts(ctx, repository, id, true, cascade); err != nil {
+			return err
+		}
+		concertIDs, err := repository.Purge(ctx, id, cascade)
+		if err != nil {
+			return err
+		}
+		if err := s.recordConcerts(ctx, tx, audit.ActionPurge, concertIDs); err != nil {
+			return err
+		}
+		return s.audit.Record(ctx, tx, audit.ActionPurge, audit.EntityVenue, id, nil, nil)
+	})
+}
+
+// recordConcerts audits the action on concerts it was cascaded to. Only their IDs are recorded,
+// the concert state belongs to the concerts package
+func (s *VenueService) recordConcerts(ctx context.Context, tx db.IDb, action audit.Action, concertIDs []uint) error {
+	for _, concertID := range concertIDs {
+		if err := s.audit.Record(ctx, tx, action, audit.EntityConcert, concertID, nil, nil); err != nil {
+			return err
 		}
 	}
+	return nil
+}
 
-	return response, nil
+// checkConcerts fails with ErrVenueInUse when the venue has concerts and they are not to be cascaded
+func checkConcerts(ctx context.Context, repository IVenueRepository, id uint, withTrashed bool, cascade bool) error {
+	if cascade {
+		return nil
+	}
+	count, err := repository.CountConcerts(ctx, id, withTrashed)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrVenueInUse
+	}
+	return nil
 }
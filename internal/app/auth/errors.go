@@ -1,6 +1,10 @@
 package auth
 
-const (
-	ErrUserExists       = "user already exists"
-	ErrWrongCredentials = "wrong credentials"
+import "github.com/serhiirubets/rubeticket/internal/pkg/apperr"
+
+var (
+	ErrUserExists       = apperr.New(apperr.Conflict, "user_exists", "user already exists")
+	ErrWrongCredentials = apperr.New(apperr.Unauthorized, "wrong_credentials", "wrong credentials")
+	ErrInvalidBirthday  = apperr.New(apperr.Invalid, "invalid_birthday", "birthday must be a date in YYYY-MM-DD format")
+	ErrInvalidGender    = apperr.New(apperr.Invalid, "invalid_gender", "gender must be male or female")
 )
@@ -5,8 +5,10 @@ import (
 
 	"github.com/serhiirubets/rubeticket/config"
 	"github.com/serhiirubets/rubeticket/internal/app/users"
+	"github.com/serhiirubets/rubeticket/internal/pkg/apperr"
 	"github.com/serhiirubets/rubeticket/internal/pkg/jwt"
 	"github.com/serhiirubets/rubeticket/internal/pkg/log"
+	"github.com/serhiirubets/rubeticket/internal/pkg/metrics"
 	"github.com/serhiirubets/rubeticket/internal/pkg/req"
 	"github.com/serhiirubets/rubeticket/internal/pkg/res"
 )
@@ -41,9 +43,9 @@ func NewAuthHandler(router *http.ServeMux, deps *AuthHandlerDeps) {
 // @Produce json
 // @Param request body LoginRequest true "LoginRequest credentials"
 // @Success 200 {object} LoginResponse "Successfully logged in"
-// @Failure 400 {string} string "Bad request"
-// @Failure 401 {string} string "Unauthorized"
-// @Failure 500 {string} string "Internal server error"
+// @Failure 400 {object} res.Problem "Bad request"
+// @Failure 401 {object} res.Problem "Unauthorized"
+// @Failure 500 {object} res.Problem "Internal server error"
 // @Router /api/v1/auth/login [post]
 func (handler *AuthHandler) Login() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -53,20 +55,23 @@ func (handler *AuthHandler) Login() http.HandlerFunc {
 			return
 		}
 
-		loginDto, err := handler.AuthService.Login(body.Email, body.Password)
+		loginDto, err := handler.AuthService.Login(r.Context(), body.Email, body.Password)
 
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusUnauthorized)
+			metrics.Login(false)
+			res.Error(w, r, err)
 			return
 		}
 
 		token, err := jwt.NewJWT(handler.Config.Auth.Secret).Create(&jwt.Payload{Email: loginDto.Email, Id: loginDto.Id, Role: loginDto.Role})
 
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			handler.Logger.WithContext(r.Context()).Error("Creating jwt failed", "error", err.Error())
+			res.Error(w, r, err)
 			return
 		}
 
+		metrics.Login(true)
 		res.SetToken(w, token)
 		res.Json(w, &LoginResponse{Success: true}, http.StatusOK)
 	}
@@ -80,38 +85,39 @@ func (handler *AuthHandler) Login() http.HandlerFunc {
 // @Produce json
 // @Param request body RegisterRequest true "Registration credentials"
 // @Success 200 {object} RegisterResponse "Successfully registered"
-// @Failure 400 {string} string "Bad request"
-// @Failure 401 {string} string "Unauthorized"
-// @Failure 500 {string} string "Internal server error"
+// @Failure 400 {object} res.Problem "Bad request"
+// @Failure 409 {object} res.Problem "User already exists"
+// @Failure 500 {object} res.Problem "Internal server error"
 // @Router /api/v1/auth/register [post]
 func (handler *AuthHandler) Register() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		body, err := req.HandleBody[RegisterRequest](&w, r)
 
 		if err != nil {
-			handler.Logger.Error("Registration: request body parsing failed")
+			handler.Logger.WithContext(r.Context()).Error("Registration: request body parsing failed")
 			return
 		}
 
-		id, registerErr := handler.AuthService.Register(body)
+		id, registerErr := handler.AuthService.Register(r.Context(), body)
 
 		if registerErr != nil {
-			handler.Logger.WithFields(log.WithFields{
-				"user_email": body.Email,
-			}).Error("Registration failed")
-
-			http.Error(w, registerErr.Error(), http.StatusUnauthorized)
+			if apperr.IsInternal(registerErr) {
+				handler.Logger.WithContext(r.Context()).WithFields(log.WithFields{
+					"user_email": body.Email,
+				}).Error("Registration failed", "error", registerErr.Error())
+			}
+			res.Error(w, r, registerErr)
 			return
 		}
 
 		token, jwtErr := jwt.NewJWT(handler.Config.Auth.Secret).Create(&jwt.Payload{Email: body.Email, Id: id, Role: users.UserRole})
 
 		if jwtErr != nil {
-			handler.Logger.WithFields(log.WithFields{
+			handler.Logger.WithContext(r.Context()).WithFields(log.WithFields{
 				"user_email": body.Email,
 			}).Error("Creating jwt failed")
 
-			http.Error(w, jwtErr.Error(), http.StatusInternalServerError)
+			res.Error(w, r, jwtErr)
 			return
 		}
 
@@ -1,11 +1,12 @@
 package auth
 
 import (
-	"errors"
+	"context"
 	"fmt"
 	"time"
 
 	"github.com/serhiirubets/rubeticket/internal/app/users"
+	"github.com/serhiirubets/rubeticket/internal/pkg/tracing"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -17,11 +18,14 @@ func NewAuthService(userRepository users.IUserRepository) *AuthService {
 	return &AuthService{UserRepository: userRepository}
 }
 
-func (service *AuthService) Register(payload *RegisterRequest) (uint, error) {
-	existedUser, _ := service.UserRepository.GetByEmail(payload.Email)
+func (service *AuthService) Register(ctx context.Context, payload *RegisterRequest) (uint, error) {
+	ctx, span := tracing.Start(ctx, "AuthService.Register")
+	defer span.End()
+
+	existedUser, _ := service.UserRepository.GetByEmail(ctx, payload.Email)
 
 	if existedUser != nil {
-		return 0, errors.New(ErrUserExists)
+		return 0, ErrUserExists
 	}
 
 	fromPassword, fromPassErr := bcrypt.GenerateFromPassword([]byte(payload.Password), bcrypt.DefaultCost)
@@ -31,11 +35,11 @@ func (service *AuthService) Register(payload *RegisterRequest) (uint, error) {
 
 	birthday, birthErr := time.Parse("2006-01-02", payload.Birthday)
 	if birthErr != nil {
-		return 0, fmt.Errorf("wrong birthday format: %w", birthErr)
+		return 0, ErrInvalidBirthday.Wrap(birthErr)
 	}
 
 	if payload.Gender != users.Male && payload.Gender != users.Female {
-		return 0, errors.New("wrong gender value, expected male or female, but got: " + string(payload.Gender))
+		return 0, ErrInvalidGender
 	}
 
 	user := &users.User{
@@ -48,22 +52,25 @@ func (service *AuthService) Register(payload *RegisterRequest) (uint, error) {
 		Role:         users.UserRole,
 	}
 
-	createdUser, dbErr := service.UserRepository.Create(user)
+	createdUser, dbErr := service.UserRepository.Create(ctx, user)
 	if dbErr != nil {
 		return 0, fmt.Errorf("creating user error: %w", dbErr)
 	}
 	return createdUser.ID, nil
 }
 
-func (service *AuthService) Login(email, password string) (*LoginResponseDto, error) {
-	existedUser, _ := service.UserRepository.GetByEmail(email)
+func (service *AuthService) Login(ctx context.Context, email, password string) (*LoginResponseDto, error) {
+	ctx, span := tracing.Start(ctx, "AuthService.Login")
+	defer span.End()
+
+	existedUser, _ := service.UserRepository.GetByEmail(ctx, email)
 	if existedUser == nil {
-		return nil, errors.New(ErrWrongCredentials)
+		return nil, ErrWrongCredentials
 	}
 
 	err := bcrypt.CompareHashAndPassword([]byte(existedUser.PasswordHash), []byte(password))
 	if err != nil {
-		return nil, errors.New(ErrWrongCredentials)
+		return nil, ErrWrongCredentials
 	}
 	return &LoginResponseDto{
 		Id:    existedUser.ID,
@@ -0,0 +1,13 @@
+package catalog
+
+import (
+	"github.com/serhiirubets/rubeticket/internal/app/admin/bands"
+	"github.com/serhiirubets/rubeticket/internal/app/admin/concerts"
+)
+
+// @Description Public band page with its upcoming and past concerts
+type BandPageResponse struct {
+	bands.BandResponse
+	Upcoming []concerts.ConcertResponse `json:"upcoming"`
+	Past     []concerts.ConcertResponse `json:"past"`
+}
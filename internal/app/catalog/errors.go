@@ -0,0 +1,8 @@
+package catalog
+
+import "github.com/serhiirubets/rubeticket/internal/pkg/apperr"
+
+var (
+	ErrInvalidLatitude  = apperr.New(apperr.Invalid, "invalid_latitude", "invalid latitude")
+	ErrInvalidLongitude = apperr.New(apperr.Invalid, "invalid_longitude", "invalid longitude")
+)
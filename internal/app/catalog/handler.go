@@ -0,0 +1,246 @@
+package catalog
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/serhiirubets/rubeticket/config"
+	"github.com/serhiirubets/rubeticket/internal/app/admin/bands"
+	"github.com/serhiirubets/rubeticket/internal/app/admin/concerts"
+	"github.com/serhiirubets/rubeticket/internal/app/admin/events"
+	"github.com/serhiirubets/rubeticket/internal/app/admin/genres"
+	"github.com/serhiirubets/rubeticket/internal/pkg/apperr"
+	"github.com/serhiirubets/rubeticket/internal/pkg/log"
+	"github.com/serhiirubets/rubeticket/internal/pkg/query"
+	"github.com/serhiirubets/rubeticket/internal/pkg/res"
+)
+
+const (
+	defaultRadiusKm = 25
+	maxRadiusKm     = 500
+	defaultNearby   = 20
+	maxNearby       = 100
+	// bandPageConcerts is how many upcoming and past concerts a band page lists
+	bandPageConcerts = 20
+)
+
+type HandlerDeps struct {
+	Logger         log.ILogger
+	Config         *config.Config
+	EventService   *events.EventService
+	ConcertService *concerts.ConcertService
+	BandService    *bands.BandService
+	GenreService   *genres.GenreService
+}
+
+type Handler struct {
+	Logger         log.ILogger
+	Config         *config.Config
+	EventService   *events.EventService
+	ConcertService *concerts.ConcertService
+	BandService    *bands.BandService
+	GenreService   *genres.GenreService
+}
+
+// NewCatalogHandler registers read only endpoints for browsing festivals, tours, bands and genres
+func NewCatalogHandler(router *http.ServeMux, deps *HandlerDeps) {
+	handler := &Handler{
+		Logger:         deps.Logger,
+		Config:         deps.Config,
+		EventService:   deps.EventService,
+		ConcertService: deps.ConcertService,
+		BandService:    deps.BandService,
+		GenreService:   deps.GenreService,
+	}
+
+	router.HandleFunc("GET /events", handler.ListEvents())
+	router.HandleFunc("GET /events/{id}", handler.GetEvent())
+	router.HandleFunc("GET /concerts/near", handler.ConcertsNear())
+	router.HandleFunc("GET /bands/{id}", handler.GetBand())
+	router.HandleFunc("GET /genres", handler.ListGenres())
+}
+
+// ListEvents godoc
+// @Summary List festivals and tours
+// @Description Get a paginated list of events with dates and passes
+// @Tags Catalog
+// @Security ApiKeyAuth
+// @Produce json
+// @Param page query int false "Page number (default: 1)"
+// @Param pageSize query int false "Page size (default: 10, max: 100)"
+// @Param q query string false "Search term"
+// @Param kind query string false "festival or tour"
+// @Success 200 {object} events.ListEventsResponse
+// @Failure 400 {object} res.Problem "Unsupported filter or sort field"
+// @Failure 401 {object} res.Problem "Unauthorized"
+// @Router /api/v1/events [get]
+func (handler *Handler) ListEvents() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		spec, err := query.ParseSpec(r.URL.Query(), events.EventListSchema)
+		if err != nil {
+			res.Error(w, r, err)
+			return
+		}
+
+		result, err := handler.EventService.List(r.Context(), spec)
+		if err != nil {
+			handler.Logger.WithContext(r.Context()).Error("Failed to list events", "error", err.Error())
+			res.Error(w, r, err)
+			return
+		}
+
+		res.Json(w, result, http.StatusOK)
+	}
+}
+
+// GetEvent godoc
+// @Summary Get a festival or tour
+// @Description Get an event with passes, the lineup of all its concerts and the schedule by day
+// @Tags Catalog
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path int true "Event ID"
+// @Success 200 {object} events.EventDetailResponse
+// @Failure 400 {object} res.Problem "Bad request"
+// @Failure 401 {object} res.Problem "Unauthorized"
+// @Failure 404 {object} res.Problem "Not found"
+// @Router /api/v1/events/{id} [get]
+func (handler *Handler) GetEvent() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseUint(r.PathValue("id"), 10, 32)
+		if err != nil {
+			res.Error(w, r, events.ErrInvalidID)
+			return
+		}
+
+		event, err := handler.EventService.GetByID(r.Context(), uint(id))
+		if err != nil {
+			if apperr.IsInternal(err) {
+				handler.Logger.WithContext(r.Context()).Error("Failed to get event", "error", err.Error())
+			}
+			res.Error(w, r, err)
+			return
+		}
+
+		res.Json(w, event, http.StatusOK)
+	}
+}
+
+// ConcertsNear godoc
+// @Summary Upcoming concerts near me
+// @Description Get upcoming concerts at venues within a radius of a point, nearest first
+// @Tags Catalog
+// @Security ApiKeyAuth
+// @Produce json
+// @Param lat query number true "Latitude"
+// @Param lng query number true "Longitude"
+// @Param radiusKm query number false "Radius in km (default: 25, max: 500)"
+// @Param limit query int false "Max results (default: 20, max: 100)"
+// @Success 200 {array} concerts.NearbyConcertResponse
+// @Failure 400 {object} res.Problem "Bad request"
+// @Failure 401 {object} res.Problem "Unauthorized"
+// @Router /api/v1/concerts/near [get]
+func (handler *Handler) ConcertsNear() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		params := r.URL.Query()
+
+		lat, err := strconv.ParseFloat(params.Get("lat"), 64)
+		if err != nil || lat < -90 || lat > 90 {
+			res.Error(w, r, ErrInvalidLatitude)
+			return
+		}
+		lng, err := strconv.ParseFloat(params.Get("lng"), 64)
+		if err != nil || lng < -180 || lng > 180 {
+			res.Error(w, r, ErrInvalidLongitude)
+			return
+		}
+
+		near := &concerts.NearQuery{
+			Latitude:  lat,
+			Longitude: lng,
+			RadiusKm:  defaultRadiusKm,
+			Limit:     defaultNearby,
+		}
+		if radius, err := strconv.ParseFloat(params.Get("radiusKm"), 64); err == nil && radius > 0 && radius <= maxRadiusKm {
+			near.RadiusKm = radius
+		}
+		if limit, err := strconv.Atoi(params.Get("limit")); err == nil && limit > 0 && limit <= maxNearby {
+			near.Limit = limit
+		}
+
+		result, err := handler.ConcertService.Near(r.Context(), near)
+		if err != nil {
+			handler.Logger.WithContext(r.Context()).Error("Failed to find concerts near", "error", err.Error())
+			res.Error(w, r, err)
+			return
+		}
+
+		res.Json(w, result, http.StatusOK)
+	}
+}
+
+// GetBand godoc
+// @Summary Get a band page
+// @Description Get a band profile with genres, members, links and its upcoming and past concerts
+// @Tags Catalog
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path int true "Band ID"
+// @Success 200 {object} BandPageResponse
+// @Failure 400 {object} res.Problem "Bad request"
+// @Failure 401 {object} res.Problem "Unauthorized"
+// @Failure 404 {object} res.Problem "Not found"
+// @Router /api/v1/bands/{id} [get]
+func (handler *Handler) GetBand() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseUint(r.PathValue("id"), 10, 32)
+		if err != nil {
+			res.Error(w, r, bands.ErrInvalidID)
+			return
+		}
+
+		band, err := handler.BandService.GetByID(r.Context(), uint(id))
+		if err != nil {
+			if apperr.IsInternal(err) {
+				handler.Logger.WithContext(r.Context()).Error("Failed to get band", "error", err.Error())
+			}
+			res.Error(w, r, err)
+			return
+		}
+
+		upcoming, past, err := handler.ConcertService.ByBand(r.Context(), band.ID, bandPageConcerts)
+		if err != nil {
+			handler.Logger.WithContext(r.Context()).Error("Failed to list band concerts", "error", err.Error())
+			res.Error(w, r, err)
+			return
+		}
+
+		res.Json(w, BandPageResponse{
+			BandResponse: *band,
+			Upcoming:     upcoming,
+			Past:         past,
+		}, http.StatusOK)
+	}
+}
+
+// ListGenres godoc
+// @Summary List genres
+// @Description Get all genres nested under their parents
+// @Tags Catalog
+// @Security ApiKeyAuth
+// @Produce json
+// @Success 200 {array} genres.GenreTreeResponse
+// @Failure 401 {object} res.Problem "Unauthorized"
+// @Router /api/v1/genres [get]
+func (handler *Handler) ListGenres() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tree, err := handler.GenreService.Tree(r.Context())
+		if err != nil {
+			handler.Logger.WithContext(r.Context()).Error("Failed to list genres", "error", err.Error())
+			res.Error(w, r, err)
+			return
+		}
+
+		res.Json(w, tree, http.StatusOK)
+	}
+}
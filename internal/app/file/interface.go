@@ -1,7 +1,19 @@
 package file
 
+import (
+	"context"
+	"time"
+)
+
 type IFileRepository interface {
-	Create(file *File) (*File, error)
-	GetById(id string) (*File, error)
-	CreateWithStorage(file *File) (*File, error)
+	Create(ctx context.Context, file *File) (*File, error)
+	GetById(ctx context.Context, id string) (*File, error)
+	CreateWithStorage(ctx context.Context, file *File) (*File, error)
+	Update(ctx context.Context, file *File, updates map[string]interface{}) error
+	Delete(ctx context.Context, id uint) error
+	ListByStatus(ctx context.Context, status Status, page, pageSize int) ([]File, error)
+	GetAvailableByPath(ctx context.Context, filePath string) (*File, error)
+	UseSignedURL(ctx context.Context, nonce string, fileID uint, client string) (*SignedURLUse, bool, error)
+	GetSignedURLUse(ctx context.Context, nonce string) (*SignedURLUse, error)
+	PruneSignedURLUses(ctx context.Context, before time.Time) (int64, error)
 }
@@ -1,13 +1,36 @@
 package file
 
 import (
+	"time"
+
 	"gorm.io/gorm"
 )
 
+type Status string
+
+const (
+	// Pending files are stored but not scanned yet
+	Pending     Status = "pending"
+	Available   Status = "available"
+	Quarantined Status = "quarantined"
+)
+
 type File struct {
 	*gorm.Model
-	UUID     string `gorm:"unique;not null"`
-	UserID   uint   `gorm:"not null"`
-	FilePath string `gorm:"not null"`
-	Purpose  string
+	UUID       string `gorm:"unique;not null"`
+	UserID     uint   `gorm:"not null"`
+	FilePath   string `gorm:"not null"`
+	Purpose    string
+	Status     Status `gorm:"type:varchar(20);default:'available';index:idx_file_status"`
+	ScanResult string `gorm:"type:text"`
+	ScannedAt  *time.Time
+}
+
+// SignedURLUse records a used one-time signed link
+type SignedURLUse struct {
+	Nonce  string    `gorm:"primaryKey;type:varchar(64)"`
+	FileID uint      `gorm:"not null"`
+	UsedAt time.Time `gorm:"not null"`
+	// Client identifies who used the link first, only they may continue the download
+	Client string `gorm:"type:varchar(64);not null;default:''"`
 }
@@ -1,7 +1,13 @@
 package file
 
 import (
+	"context"
+	"errors"
+	"time"
+
 	"github.com/serhiirubets/rubeticket/internal/pkg/db"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type Repository struct {
@@ -14,26 +20,87 @@ func NewRepository(Db db.IDb) IFileRepository {
 	}
 }
 
-func (repo *Repository) Create(file *File) (*File, error) {
-	createdFile := repo.Db.Create(file)
+func (repo *Repository) Create(ctx context.Context, file *File) (*File, error) {
+	createdFile := repo.Db.WithContext(ctx).Create(file)
 	if createdFile.Error != nil {
 		return nil, createdFile.Error
 	}
 	return file, nil
 }
 
-func (repo *Repository) GetById(id string) (*File, error) {
+func (repo *Repository) GetById(ctx context.Context, id string) (*File, error) {
 	var file File
-	result := repo.Db.First(&file, id)
+	result := repo.Db.WithContext(ctx).First(&file, id)
 	if result.Error != nil {
 		return nil, result.Error
 	}
 	return &file, nil
 }
 
-func (repo *Repository) CreateWithStorage(file *File) (*File, error) {
-	if err := repo.Db.Create(file).Error; err != nil {
+func (repo *Repository) CreateWithStorage(ctx context.Context, file *File) (*File, error) {
+	if err := repo.Db.WithContext(ctx).Create(file).Error; err != nil {
 		return nil, err
 	}
 	return file, nil
 }
+
+func (repo *Repository) Update(ctx context.Context, file *File, updates map[string]interface{}) error {
+	return repo.Db.WithContext(ctx).Model(file).Updates(updates).Error
+}
+
+func (repo *Repository) Delete(ctx context.Context, id uint) error {
+	return repo.Db.WithContext(ctx).Delete(&File{}, id).Error
+}
+
+func (repo *Repository) ListByStatus(ctx context.Context, status Status, page, pageSize int) ([]File, error) {
+	var files []File
+	offset := (page - 1) * pageSize
+	if err := repo.Db.WithContext(ctx).Where("status = ?", status).Order("id desc").Offset(offset).Limit(pageSize).Find(&files).Error; err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+func (repo *Repository) GetAvailableByPath(ctx context.Context, filePath string) (*File, error) {
+	var file File
+	if err := repo.Db.WithContext(ctx).First(&file, "file_path = ? AND status = ?", filePath, Available).Error; err != nil {
+		return nil, err
+	}
+	return &file, nil
+}
+
+// UseSignedURL marks nonce as used by client and returns the first use, first reports whether it is this one
+func (repo *Repository) UseSignedURL(ctx context.Context, nonce string, fileID uint, client string) (*SignedURLUse, bool, error) {
+	use := SignedURLUse{Nonce: nonce, FileID: fileID, UsedAt: time.Now(), Client: client}
+	result := repo.Db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&use)
+	if result.Error != nil {
+		return nil, false, result.Error
+	}
+	if result.RowsAffected == 1 {
+		return &use, true, nil
+	}
+
+	if err := repo.Db.WithContext(ctx).First(&use, "nonce = ?", nonce).Error; err != nil {
+		return nil, false, err
+	}
+	return &use, false, nil
+}
+
+// GetSignedURLUse returns the first use of nonce, or nil when it is unused
+func (repo *Repository) GetSignedURLUse(ctx context.Context, nonce string) (*SignedURLUse, error) {
+	var use SignedURLUse
+	err := repo.Db.WithContext(ctx).First(&use, "nonce = ?", nonce).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &use, nil
+}
+
+// PruneSignedURLUses deletes the uses recorded before the given time and returns how many were deleted
+func (repo *Repository) PruneSignedURLUses(ctx context.Context, before time.Time) (int64, error) {
+	result := repo.Db.WithContext(ctx).Where("used_at < ?", before).Delete(&SignedURLUse{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,8 @@
+package fileuploader
+
+import "github.com/serhiirubets/rubeticket/internal/pkg/apperr"
+
+var (
+	ErrInvalidType     = apperr.New(apperr.UnsupportedMediaType, "invalid_file_type", "invalid file type")
+	ErrFileQuarantined = apperr.New(apperr.Unprocessable, "file_quarantined", "file is quarantined")
+)
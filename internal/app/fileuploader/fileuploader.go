@@ -1,24 +1,30 @@
 package fileuploader
 
 import (
-	"errors"
+	"context"
+	"fmt"
 	"mime/multipart"
-	"os"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/serhiirubets/rubeticket/internal/app/file"
 	"github.com/serhiirubets/rubeticket/internal/pkg/db"
 	"github.com/serhiirubets/rubeticket/internal/pkg/filestorage"
 	"github.com/serhiirubets/rubeticket/internal/pkg/log"
+	"github.com/serhiirubets/rubeticket/internal/pkg/metrics"
+	"github.com/serhiirubets/rubeticket/internal/pkg/scanner"
+	"github.com/serhiirubets/rubeticket/internal/pkg/tracing"
 )
 
 type Deps struct {
 	Logger         log.ILogger
 	DB             db.IDb
 	Storage        filestorage.Storage
+	Scanner        scanner.Scanner
 	AllowedTypes   []string
 	MaxSizeMB      int64
+	MaxScanSizeMB  int64
 	FileRepository file.IFileRepository
 }
 
@@ -26,8 +32,10 @@ type FileUploader struct {
 	Logger         log.ILogger
 	DB             db.IDb
 	Storage        filestorage.Storage
+	Scanner        scanner.Scanner
 	AllowedTypes   []string
 	MaxSizeMB      int64
+	MaxScanSizeMB  int64
 	FileRepository file.IFileRepository
 }
 
@@ -36,13 +44,18 @@ func NewFileUploader(deps *Deps) *FileUploader {
 		Logger:         deps.Logger,
 		DB:             deps.DB,
 		Storage:        deps.Storage,
+		Scanner:        deps.Scanner,
 		AllowedTypes:   deps.AllowedTypes,
 		MaxSizeMB:      deps.MaxSizeMB,
+		MaxScanSizeMB:  deps.MaxScanSizeMB,
 		FileRepository: deps.FileRepository,
 	}
 }
 
-func (f *FileUploader) UploadFile(uploadFile multipart.File, header *multipart.FileHeader, userID uint, purpose string) (*file.File, error) {
+func (f *FileUploader) UploadFile(ctx context.Context, uploadFile multipart.File, header *multipart.FileHeader, userID uint, purpose string) (*file.File, error) {
+	ctx, span := tracing.Start(ctx, "FileUploader.UploadFile")
+	defer span.End()
+
 	contentType := header.Header.Get("Content-Type")
 	allowed := false
 	for _, allowedType := range f.AllowedTypes {
@@ -52,30 +65,121 @@ func (f *FileUploader) UploadFile(uploadFile multipart.File, header *multipart.F
 		}
 	}
 	if !allowed {
-		f.Logger.Warn("Invalid file type attempted", "type", contentType)
-		return nil, errors.New("invalid file type")
+		f.Logger.WithContext(ctx).Warn("Invalid file type attempted", "type", contentType)
+		return nil, ErrInvalidType
 	}
 
 	fileUUID := uuid.New().String()
 	filePath, err := f.Storage.SaveFile(uploadFile, header, fileUUID)
 	if err != nil {
-		f.Logger.Error("Failed to save file to storage", "error", err.Error())
+		f.Logger.WithContext(ctx).Error("Failed to save file to storage", "error", err.Error())
 		return nil, err
 	}
+	metrics.ObserveUpload(metrics.UploadForm, header.Size)
 
-	fileModel := &file.File{
+	return f.Register(ctx, &file.File{
 		UUID:     fileUUID,
 		UserID:   userID,
 		FilePath: filePath,
 		Purpose:  purpose,
-	}
+	})
+}
+
+// Register saves metadata of a file that is already in storage and scans it.
+// The file becomes available only when the scan is clean, otherwise it is quarantined
+// and returned together with an error. When the scan result cannot be saved, the file
+// is removed rather than left pending forever
+func (f *FileUploader) Register(ctx context.Context, fileModel *file.File) (*file.File, error) {
+	ctx, span := tracing.Start(ctx, "FileUploader.Register")
+	defer span.End()
 
-	createdFile, err := f.FileRepository.CreateWithStorage(fileModel)
+	fileModel.Status = file.Pending
+
+	createdFile, err := f.FileRepository.CreateWithStorage(ctx, fileModel)
 	if err != nil {
-		f.Logger.Error("Failed to save file metadata to DB", "error", err.Error())
-		os.Remove(filePath)
+		f.Logger.WithContext(ctx).Error("Failed to save file metadata to DB", "error", err.Error())
+		f.Storage.Remove(fileModel.FilePath)
 		return nil, err
 	}
 
+	if err := f.Scan(ctx, createdFile); err != nil {
+		if deleteErr := f.FileRepository.Delete(ctx, createdFile.ID); deleteErr != nil {
+			f.Logger.WithContext(ctx).Error("Failed to remove unscanned file metadata", "uuid", createdFile.UUID, "error", deleteErr.Error())
+		}
+		f.Storage.Remove(createdFile.FilePath)
+		return nil, err
+	}
+
+	if createdFile.Status == file.Quarantined {
+		return createdFile, ErrFileQuarantined
+	}
+
 	return createdFile, nil
 }
+
+// Scan checks the stored file and updates its status. Files that cannot be scanned, too big ones
+// included, are quarantined until an admin releases them
+func (f *FileUploader) Scan(ctx context.Context, fileModel *file.File) error {
+	ctx, span := tracing.Start(ctx, "FileUploader.Scan")
+	defer span.End()
+
+	status := file.Available
+	scanResult := "clean"
+	scannedAt := time.Now()
+	scannedAtPtr := &scannedAt
+
+	result, skipped, err := f.scanStored(ctx, fileModel.FilePath)
+	switch {
+	case skipped:
+		f.Logger.WithContext(ctx).Warn("File is too big to be scanned, quarantining it", "uuid", fileModel.UUID, "max_scan_size_mb", f.MaxScanSizeMB)
+		status = file.Quarantined
+		scanResult = fmt.Sprintf("not scanned: larger than %d MB", f.MaxScanSizeMB)
+		scannedAtPtr = nil
+	case err != nil:
+		f.Logger.WithContext(ctx).Error("File scan failed", "uuid", fileModel.UUID, "error", err.Error())
+		status = file.Quarantined
+		scanResult = "scan failed: " + err.Error()
+	case !result.Clean:
+		f.Logger.WithContext(ctx).Warn("Malware detected in uploaded file", "uuid", fileModel.UUID, "signature", result.Signature)
+		status = file.Quarantined
+		scanResult = result.Signature
+	}
+
+	err = f.FileRepository.Update(ctx, fileModel, map[string]interface{}{
+		"status":      status,
+		"scan_result": scanResult,
+		"scanned_at":  scannedAtPtr,
+	})
+	if err != nil {
+		f.Logger.WithContext(ctx).Error("Failed to save file scan result", "uuid", fileModel.UUID, "error", err.Error())
+		return err
+	}
+
+	fileModel.Status = status
+	fileModel.ScanResult = scanResult
+	fileModel.ScannedAt = scannedAtPtr
+
+	return nil
+}
+
+// scanStored reports skipped for files above MaxScanSizeMB, the scanner would reject them anyway
+func (f *FileUploader) scanStored(ctx context.Context, filePath string) (*scanner.Result, bool, error) {
+	if f.MaxScanSizeMB > 0 {
+		size, err := f.Storage.Size(filePath)
+		if err != nil {
+			return nil, false, err
+		}
+		if size > f.MaxScanSizeMB<<20 {
+			return nil, true, nil
+		}
+	}
+
+	stored, err := f.Storage.Open(filePath)
+	if err != nil {
+		return nil, false, err
+	}
+	defer stored.Close()
+
+	result, err := f.Scanner.Scan(ctx, stored)
+	return result, false, err
+}
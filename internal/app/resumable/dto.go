@@ -0,0 +1,81 @@
+package resumable
+
+import (
+	"encoding/base64"
+	"strings"
+	"time"
+)
+
+type CreateUploadInput struct {
+	FileName          string
+	ContentType       string
+	Purpose           string
+	Length            int64
+	ChecksumAlgorithm string
+	Checksum          string
+}
+
+type UploadResponse struct {
+	UUID        string    `json:"uuid"`
+	FileName    string    `json:"fileName"`
+	ContentType string    `json:"contentType"`
+	Length      int64     `json:"length"`
+	Offset      int64     `json:"offset"`
+	Status      Status    `json:"status"`
+	ExpiresAt   time.Time `json:"expiresAt"`
+}
+
+func ToUploadResponse(upload *Upload) *UploadResponse {
+	return &UploadResponse{
+		UUID:        upload.UUID,
+		FileName:    upload.FileName,
+		ContentType: upload.ContentType,
+		Length:      upload.Length,
+		Offset:      upload.Offset,
+		Status:      upload.Status,
+		ExpiresAt:   upload.ExpiresAt,
+	}
+}
+
+// parseMetadata parses Upload-Metadata header: comma separated "key base64(value)" pairs
+func parseMetadata(header string) (map[string]string, error) {
+	metadata := make(map[string]string)
+	if header == "" {
+		return metadata, nil
+	}
+
+	for _, pair := range strings.Split(header, ",") {
+		parts := strings.Fields(pair)
+		switch len(parts) {
+		case 1:
+			metadata[parts[0]] = ""
+		case 2:
+			value, err := base64.StdEncoding.DecodeString(parts[1])
+			if err != nil {
+				return nil, ErrInvalidMetadata.WithMessage("invalid Upload-Metadata value for " + parts[0])
+			}
+			metadata[parts[0]] = string(value)
+		default:
+			return nil, ErrInvalidMetadata
+		}
+	}
+
+	return metadata, nil
+}
+
+// parseChecksum parses Upload-Checksum header: "<algorithm> <base64 digest>"
+func parseChecksum(header string) (string, string, error) {
+	if header == "" {
+		return "", "", nil
+	}
+
+	parts := strings.Fields(header)
+	if len(parts) != 2 {
+		return "", "", ErrInvalidChecksum
+	}
+	if _, err := base64.StdEncoding.DecodeString(parts[1]); err != nil {
+		return "", "", ErrInvalidChecksum.WithMessage("invalid Upload-Checksum digest")
+	}
+
+	return strings.ToLower(parts[0]), parts[1], nil
+}
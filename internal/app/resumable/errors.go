@@ -0,0 +1,20 @@
+package resumable
+
+import "github.com/serhiirubets/rubeticket/internal/pkg/apperr"
+
+var (
+	ErrUploadNotFound     = apperr.New(apperr.NotFound, "upload_not_found", "upload not found")
+	ErrUploadExpired      = apperr.New(apperr.Gone, "upload_expired", "upload expired")
+	ErrUploadCompleted    = apperr.New(apperr.Forbidden, "upload_completed", "upload already completed")
+	ErrOffsetMismatch     = apperr.New(apperr.Conflict, "offset_mismatch", "upload offset mismatch")
+	ErrChunkTooLarge      = apperr.New(apperr.TooLarge, "chunk_too_large", "chunk is too large")
+	ErrTooLarge           = apperr.New(apperr.TooLarge, "upload_too_large", "upload exceeds maximum size")
+	ErrInvalidType        = apperr.New(apperr.UnsupportedMediaType, "invalid_file_type", "invalid file type")
+	ErrChecksumAlgorithm  = apperr.New(apperr.Invalid, "unsupported_checksum_algorithm", "unsupported checksum algorithm")
+	ErrChecksumMismatch   = apperr.New(apperr.Invalid, "checksum_mismatch", "checksum mismatch")
+	ErrInvalidUploadInput = apperr.New(apperr.Invalid, "invalid_upload_length", "invalid upload length")
+	ErrInvalidOffset      = apperr.New(apperr.Invalid, "invalid_upload_offset", "invalid Upload-Offset header")
+	ErrInvalidMetadata    = apperr.New(apperr.Invalid, "invalid_upload_metadata", "invalid Upload-Metadata header")
+	ErrInvalidChecksum    = apperr.New(apperr.Invalid, "invalid_upload_checksum", "invalid Upload-Checksum header")
+	ErrInvalidContentType = apperr.New(apperr.UnsupportedMediaType, "invalid_content_type", "Content-Type must be "+offsetContentType)
+)
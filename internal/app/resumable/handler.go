@@ -0,0 +1,268 @@
+package resumable
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/serhiirubets/rubeticket/config"
+	"github.com/serhiirubets/rubeticket/internal/pkg/apperr"
+	"github.com/serhiirubets/rubeticket/internal/pkg/log"
+	"github.com/serhiirubets/rubeticket/internal/pkg/middleware"
+	"github.com/serhiirubets/rubeticket/internal/pkg/res"
+)
+
+const (
+	TusVersion             = "1.0.0"
+	StatusChecksumMismatch = 460
+	offsetContentType      = "application/offset+octet-stream"
+)
+
+type HandlerDeps struct {
+	Logger  log.ILogger
+	Config  *config.Config
+	Service *Service
+}
+
+type Handler struct {
+	Logger  log.ILogger
+	Config  *config.Config
+	Service *Service
+}
+
+func NewResumableHandler(router *http.ServeMux, deps *HandlerDeps) {
+	handler := &Handler{
+		Logger:  deps.Logger,
+		Config:  deps.Config,
+		Service: deps.Service,
+	}
+
+	router.HandleFunc("POST /resumable-uploads", handler.Create())
+	router.HandleFunc("HEAD /resumable-uploads/{id}", handler.Head())
+	router.HandleFunc("GET /resumable-uploads/{id}", handler.Get())
+	router.HandleFunc("PATCH /resumable-uploads/{id}", handler.Patch())
+	router.HandleFunc("DELETE /resumable-uploads/{id}", handler.Delete())
+}
+
+// Create godoc
+// @Summary Create a resumable upload
+// @Description Start a chunked upload (tus protocol). File name, type and purpose are passed base64 encoded in Upload-Metadata (filename, filetype, purpose)
+// @Tags Uploads
+// @Security ApiKeyAuth
+// @Param Upload-Length header int true "Total file size in bytes"
+// @Param Upload-Metadata header string false "Comma separated key and base64 value pairs"
+// @Param Upload-Checksum header string false "Checksum of the whole file, e.g. 'sha256 <base64 digest>'"
+// @Success 201 "Created, Location header contains the upload URL"
+// @Failure 400 {object} res.Problem "Bad request"
+// @Failure 401 {object} res.Problem "Not authorized"
+// @Failure 413 {object} res.Problem "Upload is too large"
+// @Failure 415 {object} res.Problem "Invalid file type"
+// @Router /api/v1/resumable-uploads [post]
+func (handler *Handler) Create() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		authData, err := middleware.GetAuthData(r)
+		if err != nil {
+			res.Error(w, r, err)
+			return
+		}
+
+		w.Header().Set("Tus-Resumable", TusVersion)
+
+		length, err := strconv.ParseInt(r.Header.Get("Upload-Length"), 10, 64)
+		if err != nil {
+			res.Error(w, r, ErrInvalidUploadInput)
+			return
+		}
+
+		metadata, err := parseMetadata(r.Header.Get("Upload-Metadata"))
+		if err != nil {
+			res.Error(w, r, err)
+			return
+		}
+
+		algorithm, checksum, err := parseChecksum(r.Header.Get("Upload-Checksum"))
+		if err != nil {
+			res.Error(w, r, err)
+			return
+		}
+
+		upload, err := handler.Service.Create(r.Context(), authData.UserID, &CreateUploadInput{
+			FileName:          metadata["filename"],
+			ContentType:       metadata["filetype"],
+			Purpose:           metadata["purpose"],
+			Length:            length,
+			ChecksumAlgorithm: algorithm,
+			Checksum:          checksum,
+		})
+		if err != nil {
+			handler.writeError(w, r, err)
+			return
+		}
+
+		w.Header().Set("Location", "/api/v1/resumable-uploads/"+upload.UUID)
+		w.Header().Set("Upload-Offset", "0")
+		w.Header().Set("Upload-Expires", upload.ExpiresAt.UTC().Format(http.TimeFormat))
+		w.WriteHeader(http.StatusCreated)
+	}
+}
+
+// Head godoc
+// @Summary Get resumable upload progress
+// @Description Return the current offset of the upload in Upload-Offset header
+// @Tags Uploads
+// @Security ApiKeyAuth
+// @Param id path string true "Upload UUID"
+// @Success 200 "Upload-Offset and Upload-Length headers"
+// @Failure 401 {object} res.Problem "Not authorized"
+// @Failure 404 {object} res.Problem "Upload not found"
+// @Router /api/v1/resumable-uploads/{id} [head]
+func (handler *Handler) Head() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		authData, err := middleware.GetAuthData(r)
+		if err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		w.Header().Set("Tus-Resumable", TusVersion)
+		w.Header().Set("Cache-Control", "no-store")
+
+		upload, err := handler.Service.Get(r.Context(), authData.UserID, r.PathValue("id"))
+		if err != nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+		w.Header().Set("Upload-Length", strconv.FormatInt(upload.Length, 10))
+		w.Header().Set("Upload-Expires", upload.ExpiresAt.UTC().Format(http.TimeFormat))
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// Get godoc
+// @Summary Get resumable upload
+// @Description Return the state of the upload
+// @Tags Uploads
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path string true "Upload UUID"
+// @Success 200 {object} UploadResponse
+// @Failure 401 {object} res.Problem "Not authorized"
+// @Failure 404 {object} res.Problem "Upload not found"
+// @Router /api/v1/resumable-uploads/{id} [get]
+func (handler *Handler) Get() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		authData, err := middleware.GetAuthData(r)
+		if err != nil {
+			res.Error(w, r, err)
+			return
+		}
+
+		upload, err := handler.Service.Get(r.Context(), authData.UserID, r.PathValue("id"))
+		if err != nil {
+			handler.writeError(w, r, err)
+			return
+		}
+
+		res.Json(w, ToUploadResponse(upload), http.StatusOK)
+	}
+}
+
+// Patch godoc
+// @Summary Upload a chunk
+// @Description Write a chunk of the file starting at Upload-Offset. The upload is finalized and its checksum verified when the last chunk is received
+// @Tags Uploads
+// @Security ApiKeyAuth
+// @Accept application/offset+octet-stream
+// @Param id path string true "Upload UUID"
+// @Param Upload-Offset header int true "Offset of the chunk"
+// @Success 204 "Chunk stored, Upload-Offset header contains the new offset"
+// @Failure 400 {object} res.Problem "Bad request"
+// @Failure 401 {object} res.Problem "Not authorized"
+// @Failure 404 {object} res.Problem "Upload not found"
+// @Failure 409 {object} res.Problem "Offset mismatch"
+// @Failure 410 {object} res.Problem "Upload expired"
+// @Failure 413 {object} res.Problem "Chunk is too large"
+// @Failure 415 {object} res.Problem "Invalid content type"
+// @Failure 422 {object} res.Problem "File is quarantined"
+// @Failure 460 {object} res.Problem "Checksum mismatch"
+// @Router /api/v1/resumable-uploads/{id} [patch]
+func (handler *Handler) Patch() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		authData, err := middleware.GetAuthData(r)
+		if err != nil {
+			res.Error(w, r, err)
+			return
+		}
+
+		w.Header().Set("Tus-Resumable", TusVersion)
+
+		if r.Header.Get("Content-Type") != offsetContentType {
+			res.Error(w, r, ErrInvalidContentType)
+			return
+		}
+
+		offset, err := strconv.ParseInt(r.Header.Get("Upload-Offset"), 10, 64)
+		if err != nil || offset < 0 {
+			res.Error(w, r, ErrInvalidOffset)
+			return
+		}
+
+		upload, err := handler.Service.WriteChunk(r.Context(), authData.UserID, r.PathValue("id"), offset, r.Body)
+		if upload != nil {
+			w.Header().Set("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+		}
+		if err != nil {
+			handler.writeError(w, r, err)
+			return
+		}
+
+		w.Header().Set("Upload-Expires", upload.ExpiresAt.UTC().Format(http.TimeFormat))
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// Delete godoc
+// @Summary Terminate a resumable upload
+// @Description Remove an unfinished upload and its stored chunks
+// @Tags Uploads
+// @Security ApiKeyAuth
+// @Param id path string true "Upload UUID"
+// @Success 204 "No Content"
+// @Failure 401 {object} res.Problem "Not authorized"
+// @Failure 403 {object} res.Problem "Upload already completed"
+// @Failure 404 {object} res.Problem "Upload not found"
+// @Router /api/v1/resumable-uploads/{id} [delete]
+func (handler *Handler) Delete() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		authData, err := middleware.GetAuthData(r)
+		if err != nil {
+			res.Error(w, r, err)
+			return
+		}
+
+		w.Header().Set("Tus-Resumable", TusVersion)
+
+		if err := handler.Service.Terminate(r.Context(), authData.UserID, r.PathValue("id")); err != nil {
+			handler.writeError(w, r, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// writeError answers with the problem of err, a checksum mismatch gets the status defined by the tus checksum extension
+func (handler *Handler) writeError(w http.ResponseWriter, r *http.Request, err error) {
+	if apperr.IsInternal(err) {
+		handler.Logger.WithContext(r.Context()).Error("Resumable upload failed", "error", err.Error())
+	}
+
+	problem := res.ToProblem(err)
+	if errors.Is(err, ErrChecksumMismatch) {
+		problem.Status = StatusChecksumMismatch
+		problem.Title = "Checksum Mismatch"
+	}
+	res.WriteProblem(w, r, problem)
+}
@@ -0,0 +1,15 @@
+package resumable
+
+import (
+	"context"
+	"time"
+)
+
+type IUploadRepository interface {
+	Create(ctx context.Context, upload *Upload) (*Upload, error)
+	GetByUUID(ctx context.Context, uuid string) (*Upload, error)
+	UpdateOffset(ctx context.Context, upload *Upload, newOffset int64) error
+	Update(ctx context.Context, upload *Upload, updates map[string]interface{}) error
+	Delete(ctx context.Context, upload *Upload) error
+	ListExpired(ctx context.Context, now time.Time, limit int) ([]Upload, error)
+}
@@ -0,0 +1,37 @@
+package resumable
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type Status string
+
+const (
+	Pending   Status = "pending"
+	Completed Status = "completed"
+	Failed    Status = "failed"
+)
+
+// Upload keeps the state of a chunked upload between requests
+type Upload struct {
+	*gorm.Model
+	UUID              string    `gorm:"unique;not null"`
+	UserID            uint      `gorm:"not null;index:idx_upload_user_id"`
+	FileName          string    `gorm:"type:varchar(255)"`
+	ContentType       string    `gorm:"type:varchar(100);not null"`
+	Purpose           string    `gorm:"type:varchar(50)"`
+	Length            int64     `gorm:"not null"`
+	Offset            int64     `gorm:"column:upload_offset;not null;default:0"`
+	ChecksumAlgorithm string    `gorm:"type:varchar(10)"`
+	Checksum          string    `gorm:"type:varchar(128)"`
+	Status            Status    `gorm:"type:varchar(20);default:'pending'"`
+	FileID            *uint     `gorm:"index"`
+	ExpiresAt         time.Time `gorm:"not null"`
+}
+
+// PartName is the name of the file in storage while the upload is in progress
+func (u *Upload) PartName() string {
+	return u.UUID + ".part"
+}
@@ -0,0 +1,65 @@
+package resumable
+
+import (
+	"context"
+	"time"
+
+	"github.com/serhiirubets/rubeticket/internal/pkg/db"
+)
+
+type Repository struct {
+	Db db.IDb
+}
+
+func NewRepository(Db db.IDb) IUploadRepository {
+	return &Repository{Db: Db}
+}
+
+func (repo *Repository) Create(ctx context.Context, upload *Upload) (*Upload, error) {
+	if err := repo.Db.WithContext(ctx).Create(upload).Error; err != nil {
+		return nil, err
+	}
+	return upload, nil
+}
+
+func (repo *Repository) GetByUUID(ctx context.Context, uuid string) (*Upload, error) {
+	var upload Upload
+	if err := repo.Db.WithContext(ctx).First(&upload, "uuid = ?", uuid).Error; err != nil {
+		return nil, err
+	}
+	return &upload, nil
+}
+
+// UpdateOffset moves the offset forward only if nobody else has moved it in the meantime
+func (repo *Repository) UpdateOffset(ctx context.Context, upload *Upload, newOffset int64) error {
+	result := repo.Db.WithContext(ctx).Model(&Upload{}).
+		Where("id = ? AND upload_offset = ?", upload.ID, upload.Offset).
+		Update("upload_offset", newOffset)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrOffsetMismatch
+	}
+	upload.Offset = newOffset
+	return nil
+}
+
+func (repo *Repository) Update(ctx context.Context, upload *Upload, updates map[string]interface{}) error {
+	return repo.Db.WithContext(ctx).Model(upload).Updates(updates).Error
+}
+
+func (repo *Repository) Delete(ctx context.Context, upload *Upload) error {
+	return repo.Db.WithContext(ctx).Delete(&Upload{}, upload.ID).Error
+}
+
+// ListExpired returns pending uploads that expired before now, the oldest first
+func (repo *Repository) ListExpired(ctx context.Context, now time.Time, limit int) ([]Upload, error) {
+	var uploads []Upload
+	err := repo.Db.WithContext(ctx).
+		Where("status = ? AND expires_at < ?", Pending, now).
+		Order("expires_at").
+		Limit(limit).
+		Find(&uploads).Error
+	return uploads, err
+}
@@ -0,0 +1,341 @@
+package resumable
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"hash"
+	"io"
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/serhiirubets/rubeticket/internal/app/file"
+	"github.com/serhiirubets/rubeticket/internal/app/fileuploader"
+	"github.com/serhiirubets/rubeticket/internal/pkg/filestorage"
+	"github.com/serhiirubets/rubeticket/internal/pkg/log"
+	"github.com/serhiirubets/rubeticket/internal/pkg/metrics"
+	"github.com/serhiirubets/rubeticket/internal/pkg/tracing"
+)
+
+var checksumAlgorithms = map[string]func() hash.Hash{
+	"md5":    md5.New,
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+}
+
+type ServiceDeps struct {
+	Logger       log.ILogger
+	Storage      filestorage.Storage
+	Repository   IUploadRepository
+	FileUploader *fileuploader.FileUploader
+	AllowedTypes []string
+	MaxSizeMB    int64
+	ChunkSizeMB  int64
+	Expiration   time.Duration
+}
+
+type Service struct {
+	Logger       log.ILogger
+	Storage      filestorage.Storage
+	Repository   IUploadRepository
+	FileUploader *fileuploader.FileUploader
+	AllowedTypes []string
+	MaxSizeMB    int64
+	ChunkSizeMB  int64
+	Expiration   time.Duration
+	locks        uploadLocks
+}
+
+func NewService(deps *ServiceDeps) *Service {
+	return &Service{
+		Logger:       deps.Logger,
+		Storage:      deps.Storage,
+		Repository:   deps.Repository,
+		FileUploader: deps.FileUploader,
+		AllowedTypes: deps.AllowedTypes,
+		MaxSizeMB:    deps.MaxSizeMB,
+		ChunkSizeMB:  deps.ChunkSizeMB,
+		Expiration:   deps.Expiration,
+		locks:        uploadLocks{held: make(map[string]*uploadLock)},
+	}
+}
+
+func (s *Service) Create(ctx context.Context, userID uint, input *CreateUploadInput) (*Upload, error) {
+	ctx, span := tracing.Start(ctx, "Service.Create")
+	defer span.End()
+
+	if input.Length <= 0 {
+		return nil, ErrInvalidUploadInput
+	}
+	if input.Length > s.MaxSizeMB<<20 {
+		return nil, ErrTooLarge
+	}
+
+	allowed := false
+	for _, allowedType := range s.AllowedTypes {
+		if strings.HasPrefix(input.ContentType, allowedType) {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		s.Logger.WithContext(ctx).Warn("Invalid file type attempted", "type", input.ContentType)
+		return nil, ErrInvalidType
+	}
+
+	if input.ChecksumAlgorithm != "" {
+		if _, ok := checksumAlgorithms[input.ChecksumAlgorithm]; !ok {
+			return nil, ErrChecksumAlgorithm
+		}
+	}
+
+	upload := &Upload{
+		UUID:              uuid.New().String(),
+		UserID:            userID,
+		FileName:          filepath.Base(input.FileName),
+		ContentType:       input.ContentType,
+		Purpose:           input.Purpose,
+		Length:            input.Length,
+		ChecksumAlgorithm: input.ChecksumAlgorithm,
+		Checksum:          input.Checksum,
+		Status:            Pending,
+		ExpiresAt:         time.Now().Add(s.Expiration),
+	}
+
+	return s.Repository.Create(ctx, upload)
+}
+
+func (s *Service) Get(ctx context.Context, userID uint, uploadUUID string) (*Upload, error) {
+	ctx, span := tracing.Start(ctx, "Service.Get")
+	defer span.End()
+
+	upload, err := s.Repository.GetByUUID(ctx, uploadUUID)
+	if err != nil || upload.UserID != userID {
+		return nil, ErrUploadNotFound
+	}
+	return upload, nil
+}
+
+// WriteChunk appends chunk to the upload at offset and finalizes the upload
+// once the last byte has been received
+func (s *Service) WriteChunk(ctx context.Context, userID uint, uploadUUID string, offset int64, chunk io.Reader) (*Upload, error) {
+	ctx, span := tracing.Start(ctx, "Service.WriteChunk")
+	defer span.End()
+
+	unlock := s.lock(uploadUUID)
+	defer unlock()
+
+	upload, err := s.Get(ctx, userID, uploadUUID)
+	if err != nil {
+		return nil, err
+	}
+	if upload.Status != Pending {
+		return nil, ErrUploadCompleted
+	}
+	if time.Now().After(upload.ExpiresAt) {
+		return nil, ErrUploadExpired
+	}
+	if offset != upload.Offset {
+		return nil, ErrOffsetMismatch
+	}
+
+	limit := min(upload.Length-upload.Offset, s.ChunkSizeMB<<20)
+	written, writeErr := s.Storage.WriteChunk(upload.PartName(), upload.Offset, io.LimitReader(chunk, limit))
+	if writeErr == nil {
+		// Anything left in the body means the chunk was bigger than allowed
+		if n, _ := io.ReadFull(chunk, make([]byte, 1)); n > 0 {
+			writeErr = ErrChunkTooLarge
+		}
+	}
+
+	// Keep whatever was written so that the client can resume from there
+	if written > 0 {
+		if err := s.Repository.UpdateOffset(ctx, upload, upload.Offset+written); err != nil {
+			return nil, err
+		}
+	}
+	if writeErr != nil {
+		s.Logger.WithContext(ctx).Error("Failed to write upload chunk", "uuid", upload.UUID, "error", writeErr.Error())
+		return upload, writeErr
+	}
+
+	if upload.Offset == upload.Length {
+		return s.complete(ctx, upload)
+	}
+
+	return upload, nil
+}
+
+func (s *Service) Terminate(ctx context.Context, userID uint, uploadUUID string) error {
+	ctx, span := tracing.Start(ctx, "Service.Terminate")
+	defer span.End()
+
+	unlock := s.lock(uploadUUID)
+	defer unlock()
+
+	upload, err := s.Get(ctx, userID, uploadUUID)
+	if err != nil {
+		return err
+	}
+	if upload.Status == Completed {
+		return ErrUploadCompleted
+	}
+
+	if err := s.Storage.Remove(upload.PartName()); err != nil {
+		s.Logger.WithContext(ctx).Warn("Failed to remove upload part", "uuid", upload.UUID, "error", err.Error())
+	}
+
+	return s.Repository.Delete(ctx, upload)
+}
+
+func (s *Service) complete(ctx context.Context, upload *Upload) (*Upload, error) {
+	if upload.ChecksumAlgorithm != "" {
+		if err := s.verifyChecksum(ctx, upload); err != nil {
+			s.Storage.Remove(upload.PartName())
+			s.Repository.Update(ctx, upload, map[string]interface{}{"status": Failed})
+			return nil, err
+		}
+	}
+
+	fileName := upload.UUID + filepath.Ext(upload.FileName)
+	if err := s.Storage.Rename(upload.PartName(), fileName); err != nil {
+		s.Logger.WithContext(ctx).Error("Failed to finalize upload", "uuid", upload.UUID, "error", err.Error())
+		return nil, err
+	}
+	metrics.ObserveUpload(metrics.UploadResumable, upload.Length)
+
+	// Quarantined files are still linked to the upload so that admins can review them
+	createdFile, registerErr := s.FileUploader.Register(ctx, &file.File{
+		UUID:     upload.UUID,
+		UserID:   upload.UserID,
+		FilePath: fileName,
+		Purpose:  upload.Purpose,
+	})
+	if createdFile == nil {
+		// The finished part is gone, so the upload cannot be completed again
+		if err := s.Repository.Update(ctx, upload, map[string]interface{}{"status": Failed}); err != nil {
+			s.Logger.WithContext(ctx).Error("Failed to mark upload as failed", "uuid", upload.UUID, "error", err.Error())
+		}
+		return nil, registerErr
+	}
+
+	err := s.Repository.Update(ctx, upload, map[string]interface{}{
+		"status":  Completed,
+		"file_id": createdFile.ID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	upload.Status = Completed
+	upload.FileID = &createdFile.ID
+
+	return upload, registerErr
+}
+
+func (s *Service) verifyChecksum(ctx context.Context, upload *Upload) error {
+	newHash, ok := checksumAlgorithms[upload.ChecksumAlgorithm]
+	if !ok {
+		return ErrChecksumAlgorithm
+	}
+
+	part, err := s.Storage.Open(upload.PartName())
+	if err != nil {
+		return err
+	}
+	defer part.Close()
+
+	h := newHash()
+	if _, err := io.Copy(h, part); err != nil {
+		return err
+	}
+
+	if base64.StdEncoding.EncodeToString(h.Sum(nil)) != upload.Checksum {
+		s.Logger.WithContext(ctx).Warn("Upload checksum mismatch", "uuid", upload.UUID)
+		return ErrChecksumMismatch
+	}
+
+	return nil
+}
+
+// reapBatch is how many expired uploads are removed per query
+const reapBatch = 100
+
+// ReapExpired removes the parts and records of pending uploads whose expiration has passed
+func (s *Service) ReapExpired(ctx context.Context) error {
+	ctx, span := tracing.Start(ctx, "Service.ReapExpired")
+	defer span.End()
+
+	for {
+		expired, err := s.Repository.ListExpired(ctx, time.Now(), reapBatch)
+		if err != nil {
+			return err
+		}
+		for _, upload := range expired {
+			if err := s.reap(ctx, upload.UUID); err != nil {
+				return err
+			}
+		}
+		if len(expired) < reapBatch {
+			return nil
+		}
+	}
+}
+
+func (s *Service) reap(ctx context.Context, uploadUUID string) error {
+	unlock := s.lock(uploadUUID)
+	defer unlock()
+
+	// A chunk may have completed the upload while waiting for the lock
+	upload, err := s.Repository.GetByUUID(ctx, uploadUUID)
+	if err != nil || upload.Status != Pending || time.Now().Before(upload.ExpiresAt) {
+		return nil
+	}
+
+	if err := s.Storage.Remove(upload.PartName()); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		s.Logger.WithContext(ctx).Warn("Failed to remove expired upload part", "uuid", upload.UUID, "error", err.Error())
+	}
+	s.Logger.WithContext(ctx).Info("Removed expired upload", "uuid", upload.UUID)
+	return s.Repository.Delete(ctx, upload)
+}
+
+// uploadLocks serializes requests to the same upload within this process. Requests to other
+// instances are caught by UpdateOffset, which moves the offset only from the value it was read at.
+// A lock is dropped once nobody holds or waits for it, so the map does not grow with every upload
+type uploadLocks struct {
+	mu   sync.Mutex
+	held map[string]*uploadLock
+}
+
+type uploadLock struct {
+	sync.Mutex
+	users int
+}
+
+func (s *Service) lock(uploadUUID string) func() {
+	s.locks.mu.Lock()
+	lock, ok := s.locks.held[uploadUUID]
+	if !ok {
+		lock = &uploadLock{}
+		s.locks.held[uploadUUID] = lock
+	}
+	lock.users++
+	s.locks.mu.Unlock()
+
+	lock.Lock()
+	return func() {
+		lock.Unlock()
+		s.locks.mu.Lock()
+		lock.users--
+		if lock.users == 0 {
+			delete(s.locks.held, uploadUUID)
+		}
+		s.locks.mu.Unlock()
+	}
+}
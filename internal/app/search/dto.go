@@ -0,0 +1,52 @@
+package search
+
+import "time"
+
+// @Description Search response
+type SearchResponse struct {
+	Query    string       `json:"query"`
+	Concerts []ConcertHit `json:"concerts"`
+	Bands    []BandHit    `json:"bands"`
+	Venues   []VenueHit   `json:"venues"`
+	Facets   Facets       `json:"facets"`
+}
+
+// @Description Concert found by search
+type ConcertHit struct {
+	ID        uint      `json:"id"`
+	Title     string    `json:"title"`
+	Date      time.Time `json:"date"`
+	VenueID   uint      `json:"venueId"`
+	VenueName string    `json:"venueName"`
+	Rank      float64   `json:"rank"`
+}
+
+// @Description Band found by search
+type BandHit struct {
+	ID    uint    `json:"id"`
+	Name  string  `json:"name"`
+	Genre string  `json:"genre"`
+	Rank  float64 `json:"rank"`
+}
+
+// @Description Venue found by search
+type VenueHit struct {
+	ID      uint    `json:"id"`
+	Name    string  `json:"name"`
+	Address string  `json:"address"`
+	Rank    float64 `json:"rank"`
+}
+
+// @Description Number of matching concerts per facet value
+type Facets struct {
+	Genre []FacetCount `json:"genre"`
+	Venue []FacetCount `json:"venue"`
+	Month []FacetCount `json:"month"`
+}
+
+// @Description Facet value, for venues value is the venue id and label its name, for genres the slug and name
+type FacetCount struct {
+	Value string `json:"value"`
+	Label string `json:"label,omitempty"`
+	Count int64  `json:"count"`
+}
@@ -0,0 +1,9 @@
+package search
+
+import "github.com/serhiirubets/rubeticket/internal/pkg/apperr"
+
+var (
+	ErrEmptyQuery     = apperr.New(apperr.Invalid, "empty_query", "search query is empty")
+	ErrInvalidVenueID = apperr.New(apperr.Invalid, "invalid_venue_id", "invalid venue ID")
+	ErrInvalidMonth   = apperr.New(apperr.Invalid, "invalid_month", "invalid month, expected YYYY-MM")
+)
@@ -0,0 +1,89 @@
+package search
+
+import (
+	"net/http"
+	"regexp"
+	"strconv"
+
+	"github.com/serhiirubets/rubeticket/config"
+	"github.com/serhiirubets/rubeticket/internal/pkg/apperr"
+	"github.com/serhiirubets/rubeticket/internal/pkg/log"
+	"github.com/serhiirubets/rubeticket/internal/pkg/res"
+)
+
+var yearMonthPattern = regexp.MustCompile(`^\d{4}-(0[1-9]|1[0-2])$`)
+
+type HandlerDeps struct {
+	Logger  log.ILogger
+	Config  *config.Config
+	Service *Service
+}
+
+type Handler struct {
+	Logger  log.ILogger
+	Config  *config.Config
+	Service *Service
+}
+
+func NewSearchHandler(router *http.ServeMux, deps *HandlerDeps) {
+	handler := &Handler{
+		Logger:  deps.Logger,
+		Config:  deps.Config,
+		Service: deps.Service,
+	}
+
+	router.HandleFunc("GET /search", handler.Search())
+}
+
+// Search godoc
+// @Summary Search concerts, bands and venues
+// @Description Full-text search with typo tolerance, e.g. "metal Kyiv March". Month names filter concerts by month. Facets count matching concerts by genre, venue and month
+// @Tags Search
+// @Security ApiKeyAuth
+// @Produce json
+// @Param q query string true "Search text"
+// @Param limit query int false "Results per section (default: 10, max: 50)"
+// @Param genre query string false "Genre facet filter, a genre slug"
+// @Param venueId query int false "Venue facet filter"
+// @Param month query string false "Month facet filter, YYYY-MM"
+// @Success 200 {object} SearchResponse
+// @Failure 400 {object} res.Problem "Bad request"
+// @Failure 401 {object} res.Problem "Unauthorized"
+// @Router /api/v1/search [get]
+func (handler *Handler) Search() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		params := r.URL.Query()
+		limit, _ := strconv.Atoi(params.Get("limit"))
+
+		q := ParseQuery(params.Get("q"), limit)
+		q.Genre = params.Get("genre")
+
+		if venueID := params.Get("venueId"); venueID != "" {
+			id, err := strconv.ParseUint(venueID, 10, 32)
+			if err != nil {
+				res.Error(w, r, ErrInvalidVenueID)
+				return
+			}
+			q.VenueID = uint(id)
+		}
+
+		if month := params.Get("month"); month != "" {
+			if !yearMonthPattern.MatchString(month) {
+				res.Error(w, r, ErrInvalidMonth)
+				return
+			}
+			q.YearMonth = month
+		}
+
+		result, err := handler.Service.Search(r.Context(), params.Get("q"), q)
+		if err != nil {
+			if apperr.IsInternal(err) {
+				handler.Logger.WithContext(r.Context()).Error("Search failed", "error", err.Error())
+			}
+			res.Error(w, r, err)
+			return
+		}
+
+		res.Json(w, result, http.StatusOK)
+	}
+}
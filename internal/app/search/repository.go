@@ -0,0 +1,230 @@
+package search
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/serhiirubets/rubeticket/internal/pkg/db"
+)
+
+// Query is a parsed search request. Every term must match a concert, bands and venues need any of them
+type Query struct {
+	Terms []string
+	// Month is a month of any year taken from the text, 0 when not given
+	Month time.Month
+	Limit int
+
+	// Facet filters picked by the user, Genre is a genre slug
+	Genre   string
+	VenueID uint
+	// YearMonth is "2025-03"
+	YearMonth string
+}
+
+type IRepository interface {
+	Concerts(ctx context.Context, q *Query) ([]ConcertHit, error)
+	Facets(ctx context.Context, q *Query) (*Facets, error)
+	Bands(ctx context.Context, q *Query) ([]BandHit, error)
+	Venues(ctx context.Context, q *Query) ([]VenueHit, error)
+}
+
+type Repository struct {
+	Db db.IDb
+}
+
+func NewRepository(Db db.IDb) IRepository {
+	return &Repository{Db: Db}
+}
+
+// concertMatches selects live concerts with their venue, conditions refer to concerts as c and venues as v
+const concertMatches = `
+WITH matched AS (
+	SELECT c.id, c.title, c.date, c.venue_id, v.name AS venue_name, v.address AS venue_address,
+		c.search_vector || v.search_vector AS vector
+	FROM concerts c
+	JOIN venues v ON v.id = c.venue_id AND v.deleted_at IS NULL
+	WHERE c.deleted_at IS NULL AND %s
+)
+`
+
+// concertTerm collects concerts matching a term by lexeme or trigram word similarity of the
+// concert, its venue, bands or band genres. Every branch filters one table by its own
+// indexed columns, so the term is looked up in the indexes before concerts are joined
+const concertTerm = `c.id IN (
+	SELECT id FROM concerts WHERE search_vector @@ plainto_tsquery('simple', ?) OR ? <% title
+	UNION
+	SELECT concerts.id FROM concerts JOIN venues ON venues.id = concerts.venue_id
+	WHERE venues.search_vector @@ plainto_tsquery('simple', ?) OR ? <% venues.name OR ? <% venues.address
+	UNION
+	SELECT cb.concert_id FROM concert_bands cb JOIN bands b ON b.id = cb.band_id AND b.deleted_at IS NULL
+	WHERE b.search_vector @@ plainto_tsquery('simple', ?) OR ? <% b.name
+	UNION
+	SELECT cb.concert_id FROM genres g
+	JOIN band_genres bg ON bg.genre_id = g.id
+	JOIN bands b ON b.id = bg.band_id AND b.deleted_at IS NULL
+	JOIN concert_bands cb ON cb.band_id = b.id
+	WHERE g.deleted_at IS NULL AND ? <% g.name
+)`
+
+// termConditions matches each term by lexeme or by trigram word similarity for typos
+func termConditions(terms []string, vector string, texts []string, joiner string) (string, []interface{}) {
+	conditions := make([]string, len(terms))
+	var args []interface{}
+	for i, term := range terms {
+		parts := []string{vector + " @@ plainto_tsquery('simple', ?)"}
+		args = append(args, term)
+		for _, text := range texts {
+			parts = append(parts, "? <% "+text)
+			args = append(args, term)
+		}
+		conditions[i] = "(" + strings.Join(parts, " OR ") + ")"
+	}
+	return "(" + strings.Join(conditions, joiner) + ")", args
+}
+
+func (r *Repository) concertFilter(q *Query) (string, []interface{}) {
+	conditions := []string{"true"}
+	var args []interface{}
+
+	for _, term := range q.Terms {
+		conditions = append(conditions, concertTerm)
+		for i := strings.Count(concertTerm, "?"); i > 0; i-- {
+			args = append(args, term)
+		}
+	}
+	if q.Month != 0 {
+		conditions = append(conditions, "EXTRACT(MONTH FROM c.date) = ?")
+		args = append(args, int(q.Month))
+	}
+	if q.YearMonth != "" {
+		conditions = append(conditions, "to_char(c.date, 'YYYY-MM') = ?")
+		args = append(args, q.YearMonth)
+	}
+	if q.VenueID != 0 {
+		conditions = append(conditions, "c.venue_id = ?")
+		args = append(args, q.VenueID)
+	}
+	if q.Genre != "" {
+		conditions = append(conditions, `EXISTS (
+			SELECT 1 FROM concert_bands cb
+			JOIN bands b ON b.id = cb.band_id AND b.deleted_at IS NULL
+			JOIN band_genres bg ON bg.band_id = b.id
+			JOIN genres g ON g.id = bg.genre_id AND g.deleted_at IS NULL
+			WHERE cb.concert_id = c.id AND g.slug = ?)`)
+		args = append(args, q.Genre)
+	}
+
+	return strings.Replace(concertMatches, "%s", strings.Join(conditions, " AND "), 1), args
+}
+
+func (r *Repository) Concerts(ctx context.Context, q *Query) ([]ConcertHit, error) {
+	cte, args := r.concertFilter(q)
+	text := strings.Join(q.Terms, " ")
+
+	hits := []ConcertHit{}
+	// ranking only looks at the matched concerts, their best similar column counts
+	err := r.Db.WithContext(ctx).Raw(cte+`
+		SELECT m.id, m.title, m.date, m.venue_id, m.venue_name,
+			ts_rank(m.vector || coalesce(bv.vector, ''::tsvector), plainto_tsquery('simple', ?)) +
+			greatest(word_similarity(?, m.title), word_similarity(?, m.venue_name),
+				word_similarity(?, m.venue_address), coalesce(bv.similarity, 0)) AS rank
+		FROM matched m
+		LEFT JOIN LATERAL (
+			SELECT tsvector_agg(b.search_vector) AS vector, max(word_similarity(?, b.name)) AS similarity
+			FROM concert_bands cb
+			JOIN bands b ON b.id = cb.band_id AND b.deleted_at IS NULL
+			WHERE cb.concert_id = m.id
+		) bv ON true
+		ORDER BY rank DESC, m.date
+		LIMIT ?`, append(args, text, text, text, text, text, q.Limit)...).Scan(&hits).Error
+	if err != nil {
+		return nil, err
+	}
+	return hits, nil
+}
+
+// Facets counts all matching concerts, not only the returned page
+func (r *Repository) Facets(ctx context.Context, q *Query) (*Facets, error) {
+	cte, args := r.concertFilter(q)
+
+	var rows []struct {
+		Facet string
+		FacetCount
+	}
+	err := r.Db.WithContext(ctx).Raw(cte+`
+		SELECT 'genre' AS facet, g.slug AS value, g.name AS label, count(DISTINCT m.id) AS count
+		FROM matched m
+		JOIN concert_bands cb ON cb.concert_id = m.id
+		JOIN bands b ON b.id = cb.band_id AND b.deleted_at IS NULL
+		JOIN band_genres bg ON bg.band_id = b.id
+		JOIN genres g ON g.id = bg.genre_id AND g.deleted_at IS NULL
+		GROUP BY g.slug, g.name
+		UNION ALL
+		SELECT 'venue', m.venue_id::text, m.venue_name, count(*)
+		FROM matched m
+		GROUP BY m.venue_id, m.venue_name
+		UNION ALL
+		SELECT 'month', to_char(m.date, 'YYYY-MM'), '', count(*)
+		FROM matched m
+		GROUP BY to_char(m.date, 'YYYY-MM')
+		ORDER BY facet, count DESC, value`, args...).Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	facets := &Facets{Genre: []FacetCount{}, Venue: []FacetCount{}, Month: []FacetCount{}}
+	for _, row := range rows {
+		switch row.Facet {
+		case "genre":
+			facets.Genre = append(facets.Genre, row.FacetCount)
+		case "venue":
+			facets.Venue = append(facets.Venue, row.FacetCount)
+		case "month":
+			facets.Month = append(facets.Month, row.FacetCount)
+		}
+	}
+	return facets, nil
+}
+
+func (r *Repository) Bands(ctx context.Context, q *Query) ([]BandHit, error) {
+	hits := []BandHit{}
+	if len(q.Terms) == 0 {
+		return hits, nil
+	}
+
+	condition, args := termConditions(q.Terms, "search_vector", []string{"name", "genre"}, " OR ")
+	text := strings.Join(q.Terms, " ")
+	err := r.Db.WithContext(ctx).Raw(`
+		SELECT id, name, genre,
+			ts_rank(search_vector, plainto_tsquery('simple', ?)) + word_similarity(?, concat_ws(' ', name, genre)) AS rank
+		FROM bands
+		WHERE deleted_at IS NULL AND `+condition+`
+		ORDER BY rank DESC, name
+		LIMIT ?`, append(append([]interface{}{text, text}, args...), q.Limit)...).Scan(&hits).Error
+	if err != nil {
+		return nil, err
+	}
+	return hits, nil
+}
+
+func (r *Repository) Venues(ctx context.Context, q *Query) ([]VenueHit, error) {
+	hits := []VenueHit{}
+	if len(q.Terms) == 0 {
+		return hits, nil
+	}
+
+	condition, args := termConditions(q.Terms, "search_vector", []string{"name", "address"}, " OR ")
+	text := strings.Join(q.Terms, " ")
+	err := r.Db.WithContext(ctx).Raw(`
+		SELECT id, name, address,
+			ts_rank(search_vector, plainto_tsquery('simple', ?)) + word_similarity(?, concat_ws(' ', name, address)) AS rank
+		FROM venues
+		WHERE deleted_at IS NULL AND `+condition+`
+		ORDER BY rank DESC, name
+		LIMIT ?`, append(append([]interface{}{text, text}, args...), q.Limit)...).Scan(&hits).Error
+	if err != nil {
+		return nil, err
+	}
+	return hits, nil
+}
@@ -0,0 +1,83 @@
+package search
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/serhiirubets/rubeticket/internal/pkg/tracing"
+)
+
+const (
+	defaultLimit = 10
+	maxLimit     = 50
+)
+
+var months = func() map[string]time.Month {
+	byName := make(map[string]time.Month, 24)
+	for m := time.January; m <= time.December; m++ {
+		name := strings.ToLower(m.String())
+		byName[name] = m
+		byName[name[:3]] = m
+	}
+	return byName
+}()
+
+type Service struct {
+	repository IRepository
+}
+
+func NewService(repository IRepository) *Service {
+	return &Service{repository: repository}
+}
+
+// ParseQuery splits free text into terms, a month name like "March" becomes a month filter
+func ParseQuery(text string, limit int) *Query {
+	q := &Query{Limit: limit}
+	if q.Limit < 1 || q.Limit > maxLimit {
+		q.Limit = defaultLimit
+	}
+
+	for _, term := range strings.Fields(strings.ToLower(text)) {
+		if month, ok := months[term]; ok && q.Month == 0 {
+			q.Month = month
+			continue
+		}
+		q.Terms = append(q.Terms, term)
+	}
+	return q
+}
+
+func (s *Service) Search(ctx context.Context, text string, q *Query) (*SearchResponse, error) {
+	ctx, span := tracing.Start(ctx, "Service.Search")
+	defer span.End()
+
+	if len(q.Terms) == 0 && q.Month == 0 && q.Genre == "" && q.VenueID == 0 && q.YearMonth == "" {
+		return nil, ErrEmptyQuery
+	}
+
+	concerts, err := s.repository.Concerts(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	facets, err := s.repository.Facets(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	bands, err := s.repository.Bands(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	venues, err := s.repository.Venues(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SearchResponse{
+		Query:    text,
+		Concerts: concerts,
+		Bands:    bands,
+		Venues:   venues,
+		Facets:   *facets,
+	}, nil
+}
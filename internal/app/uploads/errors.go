@@ -0,0 +1,10 @@
+package uploads
+
+import "github.com/serhiirubets/rubeticket/internal/pkg/apperr"
+
+var (
+	ErrInvalidPath   = apperr.New(apperr.Invalid, "invalid_file_path", "invalid file path")
+	ErrFileForbidden = apperr.New(apperr.Forbidden, "file_forbidden", "forbidden or file not found")
+	ErrFileNotFound  = apperr.New(apperr.NotFound, "file_not_found", "file not found")
+	ErrLinkUsed      = apperr.New(apperr.Gone, "link_used", "link already used")
+)
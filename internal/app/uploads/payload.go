@@ -0,0 +1,16 @@
+package uploads
+
+import "time"
+
+type CreateSignedURLRequest struct {
+	FileName string `json:"fileName" validate:"required"`
+	// ExpiresIn is a link lifetime in seconds, 1 hour by default
+	ExpiresIn int  `json:"expiresIn" validate:"omitempty,min=60,max=604800"`
+	OneTime   bool `json:"oneTime"`
+}
+
+type CreateSignedURLResponse struct {
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expiresAt"`
+	OneTime   bool      `json:"oneTime"`
+}
@@ -1,25 +1,47 @@
 package uploads
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net"
 	"net/http"
+	"os"
+	"path/filepath"
+	"time"
 
 	"github.com/serhiirubets/rubeticket/config"
 	"github.com/serhiirubets/rubeticket/internal/app/file"
 	"github.com/serhiirubets/rubeticket/internal/app/fileuploader"
 	"github.com/serhiirubets/rubeticket/internal/pkg/log"
 	"github.com/serhiirubets/rubeticket/internal/pkg/middleware"
+	"github.com/serhiirubets/rubeticket/internal/pkg/req"
+	"github.com/serhiirubets/rubeticket/internal/pkg/res"
+	"github.com/serhiirubets/rubeticket/internal/pkg/signedurl"
+)
+
+const (
+	defaultSignedURLLifetime = time.Hour
+	// MaxSignedURLLifetime matches the limit of CreateSignedURLRequest.ExpiresIn,
+	// uses of one-time links older than that can be forgotten
+	MaxSignedURLLifetime = 7 * 24 * time.Hour
+	// A one-time link keeps working for a while after its first GET for range requests of the same
+	// client, so that a player can fetch the rest of a video. HEAD requests do not use the link
+	oneTimeReuseWindow = 10 * time.Minute
 )
 
 type HandlerDeps struct {
 	Logger       log.ILogger
 	Config       *config.Config
 	FileUploader *fileuploader.FileUploader
+	Signer       *signedurl.Signer
 }
 
 type Handler struct {
 	Logger       log.ILogger
 	Config       *config.Config
 	FileUploader *fileuploader.FileUploader
+	Signer       *signedurl.Signer
 }
 
 func NewUploadsHandler(router *http.ServeMux, deps *HandlerDeps) {
@@ -27,8 +49,11 @@ func NewUploadsHandler(router *http.ServeMux, deps *HandlerDeps) {
 		Logger:       deps.Logger,
 		Config:       deps.Config,
 		FileUploader: deps.FileUploader,
+		Signer:       deps.Signer,
 	}
 	router.HandleFunc("GET /uploads/{fileName}", handler.GetPhoto())
+	router.HandleFunc("POST /signed-urls", handler.CreateSignedURL())
+	router.HandleFunc("GET /signed-files/{fileName}", handler.GetSignedFile())
 }
 
 // GetPhoto godoc
@@ -39,22 +64,22 @@ func NewUploadsHandler(router *http.ServeMux, deps *HandlerDeps) {
 // @Produce application/octet-stream
 // @Param fileName path string true "File path (e.g., b60b4dd7-6dda-49fc-830f-020fa5fe4817.png)"
 // @Success 200 {file} file "File content"
-// @Failure 400 {object} string "Invalid file path"
-// @Failure 401 {object} string "Not authorized"
-// @Failure 403 {object} string "Forbidden or file not found"
-// @Failure 500 {object} string "Internal server error"
+// @Failure 400 {object} res.Problem "Invalid file path"
+// @Failure 401 {object} res.Problem "Not authorized"
+// @Failure 403 {object} res.Problem "Forbidden or file not found"
+// @Failure 500 {object} res.Problem "Internal server error"
 // @Router /uploads/{fileName} [get]
 func (handler *Handler) GetPhoto() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		authData, err := middleware.GetAuthData(r)
 		if err != nil {
-			handler.Logger.Error("Error getting auth data", "error", err.Error())
+			handler.Logger.WithContext(r.Context()).Error("Error getting auth data", "error", err.Error())
 			return
 		}
 
 		fileName := r.PathValue("fileName")
 		if fileName == "" {
-			http.Error(w, "Invalid file path", http.StatusBadRequest)
+			res.Error(w, r, ErrInvalidPath)
 			return
 		}
 
@@ -64,25 +89,179 @@ func (handler *Handler) GetPhoto() http.HandlerFunc {
 		if authData.UserID != 0 {
 			userID = authData.UserID
 		}
-		query := handler.FileUploader.DB.Where("file_path = ?", fileName)
+		query := handler.FileUploader.DB.WithContext(r.Context()).Where("file_path = ? AND status = ?", fileName, file.Available)
 		if userID != 0 {
 			query = query.Where("user_id = ?", userID)
 		}
 		if err := query.First(&fileModel).Error; err != nil {
-			handler.Logger.Warn("File not found in database", "file_path", fileName, "user_id", userID, "error", err.Error())
-			http.Error(w, "Forbidden or file not found", http.StatusForbidden)
+			handler.Logger.WithContext(r.Context()).Warn("File not found in database", "file_path", fileName, "user_id", userID, "error", err.Error())
+			res.Error(w, r, ErrFileForbidden)
+			return
+		}
+
+		handler.serveFile(w, r, &fileModel)
+	}
+}
+
+// CreateSignedURL godoc
+// @Summary Create a signed link to a file
+// @Description Create a link that gives access to a single file of the current user without authentication until it expires
+// @Tags Account
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param request body CreateSignedURLRequest true "File and link options"
+// @Success 201 {object} CreateSignedURLResponse
+// @Failure 400 {object} res.Problem "Bad request"
+// @Failure 401 {object} res.Problem "Not authorized"
+// @Failure 403 {object} res.Problem "Forbidden or file not found"
+// @Failure 500 {object} res.Problem "Internal server error"
+// @Router /api/v1/signed-urls [post]
+func (handler *Handler) CreateSignedURL() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		authData, err := middleware.GetAuthData(r)
+		if err != nil {
+			res.Error(w, r, err)
+			return
+		}
+
+		body, err := req.HandleBody[CreateSignedURLRequest](&w, r)
+		if err != nil {
+			return
+		}
+
+		var fileModel file.File
+		err = handler.FileUploader.DB.WithContext(r.Context()).
+			Where("file_path = ? AND user_id = ? AND status = ?", body.FileName, authData.UserID, file.Available).
+			First(&fileModel).Error
+		if err != nil {
+			res.Error(w, r, ErrFileForbidden)
+			return
+		}
+
+		lifetime := defaultSignedURLLifetime
+		if body.ExpiresIn > 0 {
+			lifetime = time.Duration(body.ExpiresIn) * time.Second
+		}
+		expiresAt := time.Now().Add(lifetime)
+
+		query, err := handler.Signer.Sign(fileModel.FilePath, expiresAt, body.OneTime)
+		if err != nil {
+			handler.Logger.WithContext(r.Context()).Error("Failed to sign url", "error", err.Error())
+			res.Error(w, r, err)
 			return
 		}
 
-		filePath := "uploads/" + fileModel.FilePath
+		res.Json(w, &CreateSignedURLResponse{
+			URL:       handler.Config.App.PublicURL + "/api/v1/signed-files/" + fileModel.FilePath + "?" + query.Encode(),
+			ExpiresAt: expiresAt,
+			OneTime:   body.OneTime,
+		}, http.StatusCreated)
+	}
+}
+
+// GetSignedFile godoc
+// @Summary Get a file by signed link
+// @Description Retrieve a file using a signed link, no authentication required.
+// @Description A one-time link can be reused for 10 minutes after its first GET only for range requests of the same client, HEAD does not use it
+// @Tags Account
+// @Produce application/octet-stream
+// @Param fileName path string true "File path"
+// @Param exp query int true "Expiry as unix timestamp"
+// @Param nonce query string false "Nonce of one-time links"
+// @Param sig query string true "Signature"
+// @Success 200 {file} file "File content"
+// @Failure 403 {object} res.Problem "Invalid signature"
+// @Failure 404 {object} res.Problem "File not found"
+// @Failure 410 {object} res.Problem "Link expired or already used"
+// @Router /api/v1/signed-files/{fileName} [get]
+// @Router /api/v1/signed-files/{fileName} [head]
+func (handler *Handler) GetSignedFile() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		fileName := r.PathValue("fileName")
+
+		nonce, err := handler.Signer.Verify(fileName, r.URL.Query())
+		if err != nil {
+			if !errors.Is(err, signedurl.ErrExpired) {
+				handler.Logger.WithContext(r.Context()).Warn("Invalid signed url", "file_path", fileName)
+			}
+			res.Error(w, r, err)
+			return
+		}
 
-		// Check if file exists on disk
-		if _, err := http.Dir(".").Open(filePath); err != nil {
-			handler.Logger.Warn("File not found on disk", "file_path", filePath, "error", err.Error())
-			http.Error(w, "File not found", http.StatusNotFound)
+		fileModel, err := handler.FileUploader.FileRepository.GetAvailableByPath(r.Context(), fileName)
+		if err != nil {
+			res.Error(w, r, ErrFileNotFound)
 			return
 		}
 
-		http.ServeFile(w, r, filePath)
+		if nonce != "" {
+			if err := handler.useOneTimeLink(r, nonce, fileModel.ID); err != nil {
+				res.Error(w, r, err)
+				return
+			}
+		}
+
+		// Signed links may be shared, so intermediaries must not keep a copy
+		w.Header().Set("Cache-Control", "private, no-store")
+		handler.serveFile(w, r, fileModel)
+	}
+}
+
+// useOneTimeLink lets the first GET of a one-time link through. Later requests only continue
+// that download: range requests of the same client within oneTimeReuseWindow
+func (handler *Handler) useOneTimeLink(r *http.Request, nonce string, fileID uint) error {
+	repository := handler.FileUploader.FileRepository
+	client := linkClient(r)
+
+	var use *file.SignedURLUse
+	if r.Method == http.MethodHead {
+		used, err := repository.GetSignedURLUse(r.Context(), nonce)
+		if err != nil {
+			handler.Logger.WithContext(r.Context()).Error("Failed to check signed url use", "error", err.Error())
+			return err
+		}
+		if used == nil {
+			return nil
+		}
+		use = used
+	} else {
+		used, first, err := repository.UseSignedURL(r.Context(), nonce, fileID, client)
+		if err != nil {
+			handler.Logger.WithContext(r.Context()).Error("Failed to mark signed url as used", "error", err.Error())
+			return err
+		}
+		if first {
+			return nil
+		}
+		use = used
+	}
+
+	if r.Header.Get("Range") == "" || use.Client != client || time.Since(use.UsedAt) > oneTimeReuseWindow {
+		return ErrLinkUsed
+	}
+	return nil
+}
+
+// linkClient identifies the client of r by its address and user agent, forwarding headers are not trusted
+func linkClient(r *http.Request) string {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+	sum := sha256.Sum256([]byte(ip + "\x00" + r.UserAgent()))
+	return hex.EncodeToString(sum[:])
+}
+
+func (handler *Handler) serveFile(w http.ResponseWriter, r *http.Request, fileModel *file.File) {
+	filePath := filepath.Join(handler.Config.Uploads.Dir, fileModel.FilePath)
+
+	// Check if file exists on disk
+	if _, err := os.Stat(filePath); err != nil {
+		handler.Logger.WithContext(r.Context()).Warn("File not found on disk", "file_path", filePath, "error", err.Error())
+		res.Error(w, r, ErrFileNotFound)
+		return
 	}
-}
\ No newline at end of file
+
+	http.ServeFile(w, r, filePath)
+}
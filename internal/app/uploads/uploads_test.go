@@ -0,0 +1,157 @@
+package uploads_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/serhiirubets/rubeticket/config"
+	"github.com/serhiirubets/rubeticket/internal/app/file"
+	"github.com/serhiirubets/rubeticket/internal/app/fileuploader"
+	"github.com/serhiirubets/rubeticket/internal/app/uploads"
+	"github.com/serhiirubets/rubeticket/internal/pkg/log"
+	"github.com/serhiirubets/rubeticket/internal/pkg/res"
+	"github.com/serhiirubets/rubeticket/internal/pkg/signedurl"
+	"gorm.io/gorm"
+)
+
+const fileName = "photo.png"
+
+// fileRepository keeps signed link uses in memory, methods the handler does not call are not implemented
+type fileRepository struct {
+	file.IFileRepository
+	uses map[string]*file.SignedURLUse
+}
+
+func (r *fileRepository) GetAvailableByPath(ctx context.Context, filePath string) (*file.File, error) {
+	return &file.File{Model: &gorm.Model{ID: 1}, FilePath: filePath, Status: file.Available}, nil
+}
+
+func (r *fileRepository) UseSignedURL(ctx context.Context, nonce string, fileID uint, client string) (*file.SignedURLUse, bool, error) {
+	if use, ok := r.uses[nonce]; ok {
+		return use, false, nil
+	}
+	use := &file.SignedURLUse{Nonce: nonce, FileID: fileID, UsedAt: time.Now(), Client: client}
+	r.uses[nonce] = use
+	return use, true, nil
+}
+
+func (r *fileRepository) GetSignedURLUse(ctx context.Context, nonce string) (*file.SignedURLUse, error) {
+	return r.uses[nonce], nil
+}
+
+type request struct {
+	method    string
+	rangeFrom bool
+	addr      string
+	userAgent string
+}
+
+func TestOneTimeLink(t *testing.T) {
+	first := request{method: http.MethodGet, addr: "192.0.2.1:1234", userAgent: "player"}
+
+	tests := []struct {
+		name       string
+		requests   []request
+		wantStatus int
+	}{
+		{
+			name:       "first GET",
+			requests:   []request{first},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "second full GET",
+			requests:   []request{first, first},
+			wantStatus: http.StatusGone,
+		},
+		{
+			name:       "range continuation",
+			requests:   []request{first, {method: http.MethodGet, rangeFrom: true, addr: "192.0.2.1:5678", userAgent: "player"}},
+			wantStatus: http.StatusPartialContent,
+		},
+		{
+			name:       "range from another address",
+			requests:   []request{first, {method: http.MethodGet, rangeFrom: true, addr: "198.51.100.7:1234", userAgent: "player"}},
+			wantStatus: http.StatusGone,
+		},
+		{
+			name:       "range from another user agent",
+			requests:   []request{first, {method: http.MethodGet, rangeFrom: true, addr: "192.0.2.1:1234", userAgent: "curl"}},
+			wantStatus: http.StatusGone,
+		},
+		{
+			name:       "HEAD before the first GET",
+			requests:   []request{{method: http.MethodHead, addr: "198.51.100.7:1234"}, first},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "HEAD after the first GET",
+			requests:   []request{first, {method: http.MethodHead, addr: "192.0.2.1:1234", userAgent: "player"}},
+			wantStatus: http.StatusGone,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router, link := newRouter(t)
+
+			var w *httptest.ResponseRecorder
+			for _, request := range tt.requests {
+				r := httptest.NewRequest(request.method, link, nil)
+				r.RemoteAddr = request.addr
+				r.Header.Set("User-Agent", request.userAgent)
+				if request.rangeFrom {
+					r.Header.Set("Range", "bytes=2-")
+				}
+				w = httptest.NewRecorder()
+				router.ServeHTTP(w, r)
+			}
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if tt.wantStatus == http.StatusGone {
+				var problem res.Problem
+				if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil {
+					t.Fatal(err)
+				}
+				if problem.Code != uploads.ErrLinkUsed.Code {
+					t.Errorf("code = %q, want %q", problem.Code, uploads.ErrLinkUsed.Code)
+				}
+			}
+		})
+	}
+}
+
+// newRouter serves a stored file and returns a one-time link to it
+func newRouter(t *testing.T) (http.Handler, string) {
+	t.Helper()
+	conf := config.Default()
+	conf.Uploads.Dir = t.TempDir()
+	if err := os.WriteFile(filepath.Join(conf.Uploads.Dir, fileName), []byte("content"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	signer := signedurl.NewSigner("secret")
+	router := http.NewServeMux()
+	uploads.NewUploadsHandler(router, &uploads.HandlerDeps{
+		Logger: log.NewLogrusLogger("error"),
+		Config: conf,
+		FileUploader: fileuploader.NewFileUploader(&fileuploader.Deps{
+			FileRepository: &fileRepository{uses: map[string]*file.SignedURLUse{}},
+		}),
+		Signer: signer,
+	})
+
+	query, err := signer.Sign(fileName, time.Now().Add(time.Hour), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return router, "/signed-files/" + fileName + "?" + query.Encode()
+}
@@ -0,0 +1,5 @@
+package users
+
+import "github.com/serhiirubets/rubeticket/internal/pkg/apperr"
+
+var ErrUserNotFound = apperr.New(apperr.NotFound, "user_not_found", "user not found")
@@ -33,18 +33,18 @@ func NewUserHandler(router *http.ServeMux, deps *UserHandlerDeps) {
 // @Produce json
 // @Param id path string true "User ID"
 // @Success 200 {object} GetUserResponse
-// @Failure 401 {string} string "Unauthorized"
-// @Failure 404 {string} string "Not Found"
-// @Failure 500 {string} string "Internal server error"
+// @Failure 401 {object} res.Problem "Unauthorized"
+// @Failure 404 {object} res.Problem "Not Found"
+// @Failure 500 {object} res.Problem "Internal server error"
 // @Router /api/v1/users/{id} [get]
 func (handler *UserHandler) GetById() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		id := r.PathValue("id")
 
-		user, userErr := handler.UserRepository.GetById(id)
+		user, userErr := handler.UserRepository.GetById(r.Context(), id)
 
 		if userErr != nil {
-			res.Json(w, nil, http.StatusNotFound)
+			res.Error(w, r, ErrUserNotFound)
 			return
 		}
 
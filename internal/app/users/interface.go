@@ -1,7 +1,9 @@
 package users
 
+import "context"
+
 type IUserRepository interface {
-	Create(user *User) (*User, error)
-	GetByEmail(email string) (*User, error)
-	Update(user *User, updates map[string]interface{}) error
+	Create(ctx context.Context, user *User) (*User, error)
+	GetByEmail(ctx context.Context, email string) (*User, error)
+	Update(ctx context.Context, user *User, updates map[string]interface{}) error
 }
@@ -1,6 +1,8 @@
 package users
 
 import (
+	"context"
+
 	"github.com/serhiirubets/rubeticket/internal/pkg/db"
 )
 
@@ -14,8 +16,13 @@ func NewUserRepository(db db.IDb) *UserRepository {
 	}
 }
 
-func (repo *UserRepository) Create(user *User) (*User, error) {
-	createdUser := repo.DB.Create(user)
+// WithTx returns a copy of the repository that runs queries in tx
+func (repo *UserRepository) WithTx(tx db.IDb) *UserRepository {
+	return &UserRepository{DB: tx}
+}
+
+func (repo *UserRepository) Create(ctx context.Context, user *User) (*User, error) {
+	createdUser := repo.DB.WithContext(ctx).Create(user)
 
 	if createdUser.Error != nil {
 		return nil, createdUser.Error
@@ -24,18 +31,18 @@ func (repo *UserRepository) Create(user *User) (*User, error) {
 	return user, nil
 }
 
-func (repo *UserRepository) GetByEmail(email string) (*User, error) {
+func (repo *UserRepository) GetByEmail(ctx context.Context, email string) (*User, error) {
 	var user User
-	result := repo.DB.First(&user, "email = ?", email)
+	result := repo.DB.WithContext(ctx).First(&user, "email = ?", email)
 	if result.Error != nil {
 		return nil, result.Error
 	}
 	return &user, nil
 }
 
-func (repo *UserRepository) GetById(id string) (*User, error) {
+func (repo *UserRepository) GetById(ctx context.Context, id string) (*User, error) {
 	var user User
-	result := repo.DB.First(&user, id)
+	result := repo.DB.WithContext(ctx).First(&user, id)
 
 	if result.Error != nil {
 		return nil, result.Error
@@ -44,6 +51,10 @@ func (repo *UserRepository) GetById(id string) (*User, error) {
 	return &user, nil
 }
 
-func (repo *UserRepository) Update(user *User, updates map[string]interface{}) error {
-	return repo.DB.Model(user).Updates(updates).Error
+func (repo *UserRepository) Update(ctx context.Context, user *User, updates map[string]interface{}) error {
+	return repo.DB.WithContext(ctx).Model(user).Updates(updates).Error
+}
+
+func (repo *UserRepository) Save(ctx context.Context, user *User) error {
+	return repo.DB.WithContext(ctx).Save(user).Error
 }
@@ -0,0 +1,98 @@
+// Package apperr holds the typed errors services return. A handler passes them to res.Error,
+// which maps the kind to a status and writes the code, so clients never parse messages
+package apperr
+
+import "errors"
+
+// Kind says what went wrong, each kind is answered with its own HTTP status
+type Kind int
+
+const (
+	Internal Kind = iota
+	Invalid
+	Unauthorized
+	Forbidden
+	NotFound
+	Conflict
+	Gone
+	PreconditionFailed
+	TooLarge
+	UnsupportedMediaType
+	Unprocessable
+	PreconditionRequired
+)
+
+// FieldError points to one field of a request that failed, by its json path
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+type Error struct {
+	Kind Kind
+	// Code is stable and snake_case, e.g. "concert_not_found", messages may change
+	Code    string
+	Message string
+	Fields  []FieldError
+	cause   error
+}
+
+func New(kind Kind, code string, message string) *Error {
+	return &Error{Kind: kind, Code: code, Message: message}
+}
+
+// Error includes the cause for logs, clients only get Message
+func (e *Error) Error() string {
+	if e.cause != nil {
+		return e.Message + ": " + e.cause.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.cause
+}
+
+// Is matches by code, so copies made by Wrap or WithFields still match the declared error
+func (e *Error) Is(target error) bool {
+	var other *Error
+	return errors.As(target, &other) && other.Code == e.Code
+}
+
+// Wrap returns a copy of e caused by err. The cause is kept for logs and never sent to clients
+func (e *Error) Wrap(err error) *Error {
+	wrapped := *e
+	wrapped.cause = err
+	return &wrapped
+}
+
+// WithFields returns a copy of e that points to the fields which caused it
+func (e *Error) WithFields(fields ...FieldError) *Error {
+	withFields := *e
+	withFields.Fields = fields
+	return &withFields
+}
+
+// WithMessage returns a copy of e with another message and the same code
+func (e *Error) WithMessage(message string) *Error {
+	withMessage := *e
+	withMessage.Message = message
+	return &withMessage
+}
+
+// As returns the *Error in the chain of err, or nil when err is not one
+func As(err error) *Error {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+	return nil
+}
+
+// IsInternal reports whether err is unexpected, i.e. not a typed error or one of kind Internal.
+// Handlers log those, typed errors are answered as they are
+func IsInternal(err error) bool {
+	appErr := As(err)
+	return appErr == nil || appErr.Kind == Internal
+}
@@ -0,0 +1,88 @@
+package background
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/serhiirubets/rubeticket/internal/pkg/log"
+)
+
+var ErrStopped = errors.New("background runner is stopped")
+
+// Runner owns the work that outlives a request, so that shutdown can cancel it and wait for it
+// before the database is closed
+type Runner struct {
+	logger  log.ILogger
+	ctx     context.Context
+	cancel  context.CancelFunc
+	mu      sync.Mutex
+	stopped bool
+	wg      sync.WaitGroup
+}
+
+func NewRunner(logger log.ILogger) *Runner {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Runner{logger: logger, ctx: ctx, cancel: cancel}
+}
+
+// Go runs fn in its own goroutine. ctx of fn is canceled by Stop, fn is expected to return soon after
+func (r *Runner) Go(name string, fn func(ctx context.Context)) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.stopped {
+		return ErrStopped
+	}
+
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				r.logger.Error("Background task panicked", "task", name, "panic", recovered)
+			}
+		}()
+		fn(r.ctx)
+	}()
+	return nil
+}
+
+// Every runs fn each interval until Stop, a failed run is logged and retried on the next tick
+func (r *Runner) Every(name string, interval time.Duration, fn func(ctx context.Context) error) error {
+	return r.Go(name, func(ctx context.Context) {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := fn(ctx); err != nil && ctx.Err() == nil {
+					r.logger.Error("Background task failed", "task", name, "error", err.Error())
+				}
+			}
+		}
+	})
+}
+
+// Stop cancels all tasks and waits until they return or ctx is done. No task can be started afterwards
+func (r *Runner) Stop(ctx context.Context) error {
+	r.mu.Lock()
+	r.stopped = true
+	r.mu.Unlock()
+	r.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		r.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -10,3 +10,20 @@ func StringToInt(s string, def int) int {
 
 	return i
 }
+
+func StringToFloat(s string, def float64) float64 {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		f = def
+	}
+
+	return f
+}
+
+// OptionalString returns nil for an empty string, so it is stored as NULL
+func OptionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
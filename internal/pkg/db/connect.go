@@ -0,0 +1,41 @@
+package db
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"github.com/serhiirubets/rubeticket/internal/pkg/log"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+const (
+	connectFirstDelay = 500 * time.Millisecond
+	connectMaxDelay   = 10 * time.Second
+)
+
+// connect opens the database and retries with exponential backoff and jitter until it answers
+// or timeout has passed. A zero timeout tries once
+func connect(dsn string, gormConfig *gorm.Config, timeout time.Duration, logger log.ILogger) (*gorm.DB, error) {
+	deadline := time.Now().Add(timeout)
+	delay := connectFirstDelay
+
+	for attempt := 1; ; attempt++ {
+		db, err := gorm.Open(postgres.Open(dsn), gormConfig)
+		if err == nil {
+			return db, nil
+		}
+
+		// Up to a half of the delay is random, so that replicas started together do not retry together
+		wait := delay/2 + rand.N(delay/2)
+		if time.Now().Add(wait).After(deadline) {
+			return nil, fmt.Errorf("database is not available after %d attempts: %w", attempt, err)
+		}
+		logger.Warn("Database is not available, retrying", "attempt", attempt, "in", wait.Round(time.Millisecond).String(), "error", err.Error())
+		time.Sleep(wait)
+
+		delay = min(delay*2, connectMaxDelay)
+	}
+}
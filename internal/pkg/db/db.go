@@ -1,56 +1,69 @@
 package db
 
 import (
-	"log"
+	"database/sql"
+	stdlog "log"
 	"os"
 	"time"
 
 	configs "github.com/serhiirubets/rubeticket/config"
-	"gorm.io/driver/postgres"
+	"github.com/serhiirubets/rubeticket/internal/pkg/log"
 	"gorm.io/gorm"
-	"gorm.io/gorm/logger"
+	gormlogger "gorm.io/gorm/logger"
 )
 
 type Db struct {
 	*gorm.DB
+	txOptions TxOptions
+	inTx      bool
 }
 
-func NewDb(conf *configs.Config) IDb {
-	var gormLogger logger.Interface
+// NewDb connects to the database, retrying with backoff for up to Db.ConnectTimeoutSeconds,
+// so that the app can start before the database is up. Retries are logged to logger
+func NewDb(conf *configs.Config, logger log.ILogger) (IDb, error) {
+	var gormLogger gormlogger.Interface
 
 	if conf.Env == "dev" {
-		gormLogger = logger.New(
-			log.New(os.Stdout, "\r\n", log.LstdFlags),
-			logger.Config{
+		gormLogger = gormlogger.New(
+			stdlog.New(os.Stdout, "\r\n", stdlog.LstdFlags),
+			gormlogger.Config{
 				SlowThreshold:             time.Second, // For slow request
-				LogLevel:                  logger.Info,
+				LogLevel:                  gormlogger.Info,
 				IgnoreRecordNotFoundError: true,
 				Colorful:                  true,
 			},
 		)
 	} else {
 		// For other env log only errors
-		gormLogger = logger.New(
-			log.New(os.Stdout, "\r\n", log.LstdFlags),
-			logger.Config{
+		gormLogger = gormlogger.New(
+			stdlog.New(os.Stdout, "\r\n", stdlog.LstdFlags),
+			gormlogger.Config{
 				SlowThreshold:             time.Second,
-				LogLevel:                  logger.Error,
+				LogLevel:                  gormlogger.Error,
 				IgnoreRecordNotFoundError: true,
 				Colorful:                  false,
 			},
 		)
 	}
-	db, err := gorm.Open(postgres.Open(conf.Db.Dsn), &gorm.Config{
-		Logger: gormLogger,
-	})
-
+	db, err := connect(conf.Db.Dsn, &gorm.Config{Logger: gormLogger}, time.Duration(conf.Db.ConnectTimeoutSeconds)*time.Second, logger)
 	if err != nil {
-		panic(err)
+		return nil, err
+	}
+
+	if err := registerQueryTimeout(db, time.Duration(conf.Db.QueryTimeoutSeconds)*time.Second); err != nil {
+		return nil, err
+	}
+
+	// Tracing callbacks go after the timeout ones, they are nested inside them
+	if conf.Tracing.Enabled() {
+		if err := registerTracing(db); err != nil {
+			return nil, err
+		}
 	}
 
 	pgDb, err := db.DB()
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 	pgDb.SetMaxOpenConns(conf.Db.MaxOpenConnections)
 
@@ -58,5 +71,24 @@ func NewDb(conf *configs.Config) IDb {
 
 	pgDb.SetConnMaxLifetime(time.Duration(conf.Db.MaxLifetimeConnectionsInMinutes) * time.Minute)
 
-	return &Db{db}
+	return &Db{
+		DB: db,
+		txOptions: TxOptions{
+			Isolation:  ParseIsolationLevel(conf.Db.TxIsolationLevel),
+			MaxRetries: conf.Db.TxMaxRetries,
+		},
+	}, nil
+}
+
+func (d *Db) SqlDB() (*sql.DB, error) {
+	return d.DB.DB()
+}
+
+// Close closes the connection pool, call it after the server has stopped
+func (d *Db) Close() error {
+	pgDb, err := d.DB.DB()
+	if err != nil {
+		return err
+	}
+	return pgDb.Close()
 }
@@ -1,6 +1,9 @@
 package db
 
 import (
+	"context"
+	"database/sql"
+
 	"gorm.io/gorm"
 )
 
@@ -12,4 +15,10 @@ type IDb interface {
 	Model(value interface{}) *gorm.DB
 	Offset(offset int) *gorm.DB
 	Delete(value interface{}, conds ...interface{}) *gorm.DB
+	WithContext(ctx context.Context) *gorm.DB
+	WithTx(ctx context.Context, fn func(tx IDb) error) error
+	WithTxOptions(ctx context.Context, opts TxOptions, fn func(tx IDb) error) error
+	// SqlDB is the connection pool behind the gorm DB
+	SqlDB() (*sql.DB, error)
+	Close() error
 }
@@ -0,0 +1,60 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const queryCancelKey = "rubeticket:query_timeout"
+
+type queryTimeout struct {
+	parent context.Context
+	cancel context.CancelFunc
+}
+
+// registerQueryTimeout limits every statement by timeout on top of the request context,
+// so a slow query is canceled even if the client is still waiting
+func registerQueryTimeout(db *gorm.DB, timeout time.Duration) error {
+	if timeout <= 0 {
+		return nil
+	}
+
+	before := func(tx *gorm.DB) {
+		parent := tx.Statement.Context
+		if parent == nil {
+			parent = context.Background()
+		}
+		ctx, cancel := context.WithTimeout(parent, timeout)
+		tx.Statement.Context = ctx
+		tx.InstanceSet(queryCancelKey, &queryTimeout{parent: parent, cancel: cancel})
+	}
+	after := func(tx *gorm.DB) {
+		if value, ok := tx.InstanceGet(queryCancelKey); ok {
+			qt := value.(*queryTimeout)
+			qt.cancel()
+			// Statements chained on the same session must not inherit the canceled context
+			tx.Statement.Context = qt.parent
+		}
+	}
+
+	// The timeout wraps the whole processor chain, so that associations
+	// and preloads that run after the main statement share it
+	callbacks := db.Callback()
+	errs := []error{
+		callbacks.Create().Before("gorm:begin_transaction").Register("timeout:before_create", before),
+		callbacks.Create().After("gorm:commit_or_rollback_transaction").Register("timeout:after_create", after),
+		callbacks.Query().Before("gorm:query").Register("timeout:before_query", before),
+		callbacks.Query().After("gorm:after_query").Register("timeout:after_query", after),
+		callbacks.Update().Before("gorm:begin_transaction").Register("timeout:before_update", before),
+		callbacks.Update().After("gorm:commit_or_rollback_transaction").Register("timeout:after_update", after),
+		callbacks.Delete().Before("gorm:begin_transaction").Register("timeout:before_delete", before),
+		callbacks.Delete().After("gorm:commit_or_rollback_transaction").Register("timeout:after_delete", after),
+		callbacks.Raw().Before("gorm:raw").Register("timeout:before_raw", before),
+		callbacks.Raw().After("gorm:raw").Register("timeout:after_raw", after),
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,82 @@
+package db
+
+import (
+	"context"
+	"errors"
+
+	"github.com/serhiirubets/rubeticket/internal/pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+const querySpanKey = "rubeticket:query_span"
+
+type querySpan struct {
+	parent    context.Context
+	span      trace.Span
+	operation string
+}
+
+// registerTracing wraps every statement in a client span with its SQL, table and affected rows.
+// Bound values are not recorded, only the placeholders. Spans are started inside the query timeout
+// and ended before it, so each statement restores the context it got
+func registerTracing(db *gorm.DB) error {
+	before := func(operation string) func(tx *gorm.DB) {
+		return func(tx *gorm.DB) {
+			parent := tx.Statement.Context
+			if parent == nil {
+				parent = context.Background()
+			}
+			ctx, span := tracing.Start(parent, "db."+operation,
+				trace.WithSpanKind(trace.SpanKindClient),
+				trace.WithAttributes(semconv.DBSystemPostgreSQL, semconv.DBOperationName(operation)),
+			)
+			tx.Statement.Context = ctx
+			tx.InstanceSet(querySpanKey, &querySpan{parent: parent, span: span, operation: operation})
+		}
+	}
+	after := func(tx *gorm.DB) {
+		value, ok := tx.InstanceGet(querySpanKey)
+		if !ok {
+			return
+		}
+		qs := value.(*querySpan)
+		defer qs.span.End()
+		// Statements chained on the same session must not become children of this span
+		tx.Statement.Context = qs.parent
+
+		if table := tx.Statement.Table; table != "" {
+			qs.span.SetName("db." + qs.operation + " " + table)
+			qs.span.SetAttributes(semconv.DBCollectionName(table))
+		}
+		qs.span.SetAttributes(
+			semconv.DBQueryText(tx.Statement.SQL.String()),
+			attribute.Int64("db.rows_affected", tx.Statement.RowsAffected),
+		)
+		if tx.Error != nil && !errors.Is(tx.Error, gorm.ErrRecordNotFound) {
+			qs.span.RecordError(tx.Error)
+			qs.span.SetStatus(codes.Error, tx.Error.Error())
+		}
+	}
+
+	callbacks := db.Callback()
+	errs := []error{
+		callbacks.Create().Before("gorm:begin_transaction").Register("tracing:before_create", before("insert")),
+		callbacks.Create().After("gorm:commit_or_rollback_transaction").Before("timeout:after_create").Register("tracing:after_create", after),
+		callbacks.Query().Before("gorm:query").Register("tracing:before_query", before("select")),
+		callbacks.Query().After("gorm:after_query").Before("timeout:after_query").Register("tracing:after_query", after),
+		callbacks.Update().Before("gorm:begin_transaction").Register("tracing:before_update", before("update")),
+		callbacks.Update().After("gorm:commit_or_rollback_transaction").Before("timeout:after_update").Register("tracing:after_update", after),
+		callbacks.Delete().Before("gorm:begin_transaction").Register("tracing:before_delete", before("delete")),
+		callbacks.Delete().After("gorm:commit_or_rollback_transaction").Before("timeout:after_delete").Register("tracing:after_delete", after),
+		callbacks.Row().Before("gorm:row").Register("tracing:before_row", before("select")),
+		callbacks.Row().After("gorm:row").Register("tracing:after_row", after),
+		callbacks.Raw().Before("gorm:raw").Register("tracing:before_raw", before("raw")),
+		callbacks.Raw().After("gorm:raw").Before("timeout:after_raw").Register("tracing:after_raw", after),
+	}
+
+	return errors.Join(errs...)
+}
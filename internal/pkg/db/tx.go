@@ -0,0 +1,84 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+)
+
+const (
+	serializationFailure = "40001"
+	deadlockDetected     = "40P01"
+	retryBaseDelay       = 20 * time.Millisecond
+)
+
+type TxOptions struct {
+	Isolation sql.IsolationLevel
+	ReadOnly  bool
+	// MaxRetries is how many times the transaction is run again after a serialization failure or deadlock
+	MaxRetries int
+}
+
+// WithTx runs fn in a transaction with the default options from config.
+// Repositories take part in it by using tx instead of their own connection, e.g. repo.WithTx(tx)
+func (d *Db) WithTx(ctx context.Context, fn func(tx IDb) error) error {
+	return d.WithTxOptions(ctx, d.txOptions, fn)
+}
+
+// WithTxOptions runs fn in a transaction and commits it if fn returns nil.
+// Called inside another transaction it creates a savepoint and is not retried,
+// because the outer transaction has to be run again as a whole
+func (d *Db) WithTxOptions(ctx context.Context, opts TxOptions, fn func(tx IDb) error) error {
+	run := func() error {
+		return d.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			return fn(&Db{DB: tx, txOptions: d.txOptions, inTx: true})
+		}, &sql.TxOptions{Isolation: opts.Isolation, ReadOnly: opts.ReadOnly})
+	}
+
+	if d.inTx {
+		return run()
+	}
+
+	for attempt := 0; ; attempt++ {
+		err := run()
+		if err == nil || attempt >= opts.MaxRetries || !IsRetryable(err) {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(retryBaseDelay << attempt):
+		}
+	}
+}
+
+// IsRetryable reports whether err is a serialization failure or deadlock, after which the whole transaction can be run again
+func IsRetryable(err error) bool {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code == serializationFailure || pgErr.Code == deadlockDetected
+	}
+	return false
+}
+
+// ParseIsolationLevel converts config values like "repeatable read" to sql.IsolationLevel
+func ParseIsolationLevel(level string) sql.IsolationLevel {
+	switch strings.ToLower(strings.ReplaceAll(strings.TrimSpace(level), "_", " ")) {
+	case "read uncommitted":
+		return sql.LevelReadUncommitted
+	case "repeatable read":
+		return sql.LevelRepeatableRead
+	case "serializable":
+		return sql.LevelSerializable
+	case "read committed":
+		return sql.LevelReadCommitted
+	default:
+		return sql.LevelDefault
+	}
+}
@@ -0,0 +1,25 @@
+package db
+
+import (
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SaveVersioned saves all fields of model except associations, only if the row still has the
+// version it was read with, and bumps the version. version points at the Version field of model.
+// It reports false when the row was changed or deleted since it was read
+func SaveVersioned(conn *gorm.DB, model interface{}, version *uint) (bool, error) {
+	expected := *version
+	*version = expected + 1
+
+	result := conn.Model(model).
+		Select("*").
+		Omit(clause.Associations, "id", "created_at").
+		Where("version = ?", expected).
+		Updates(model)
+	if result.Error != nil || result.RowsAffected == 0 {
+		*version = expected
+		return false, result.Error
+	}
+	return true, nil
+}
@@ -0,0 +1,53 @@
+// Package etag maps row versions to ETag and If-Match headers for optimistic locking
+package etag
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/serhiirubets/rubeticket/internal/pkg/apperr"
+)
+
+var (
+	ErrMissingIfMatch = apperr.New(apperr.PreconditionRequired, "if_match_required", "If-Match header with the ETag of the resource is required")
+	ErrNoMatch        = apperr.New(apperr.PreconditionFailed, "etag_mismatch", "resource was changed, reload it and retry")
+)
+
+// Any is the version returned for "If-Match: *", it matches every version
+const Any uint = 0
+
+// Set writes the ETag header for a resource version
+func Set(w http.ResponseWriter, version uint) {
+	w.Header().Set("ETag", Format(version))
+}
+
+func Format(version uint) string {
+	return `"` + strconv.FormatUint(uint64(version), 10) + `"`
+}
+
+// IfMatch reads the expected version from the If-Match header. A missing header is ErrMissingIfMatch,
+// weak, malformed or several ETags cannot match a single version and are ErrNoMatch
+func IfMatch(r *http.Request) (uint, error) {
+	value := strings.TrimSpace(r.Header.Get("If-Match"))
+	if value == "" {
+		return 0, ErrMissingIfMatch
+	}
+	if value == "*" {
+		return Any, nil
+	}
+
+	if len(value) < 3 || !strings.HasPrefix(value, `"`) || !strings.HasSuffix(value, `"`) {
+		return 0, ErrNoMatch
+	}
+	version, err := strconv.ParseUint(value[1:len(value)-1], 10, 32)
+	if err != nil || version == 0 {
+		return 0, ErrNoMatch
+	}
+	return uint(version), nil
+}
+
+// Matches reports whether a version satisfies the expected one from IfMatch
+func Matches(expected, version uint) bool {
+	return expected == Any || expected == version
+}
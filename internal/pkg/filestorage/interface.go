@@ -8,4 +8,10 @@ import (
 type Storage interface {
 	SaveFile(file multipart.File, header *multipart.FileHeader, uuid string) (string, error)
 	GetFile(filePath string) (io.ReadCloser, error)
+	// WriteChunk writes chunk into fileName starting at offset and returns the number of bytes written
+	WriteChunk(fileName string, offset int64, chunk io.Reader) (int64, error)
+	Open(fileName string) (io.ReadCloser, error)
+	Rename(oldName, newName string) error
+	Remove(fileName string) error
+	Size(fileName string) (int64, error)
 }
@@ -40,3 +40,57 @@ func (s *LocalStorage) SaveFile(file multipart.File, header *multipart.FileHeade
 func (s *LocalStorage) GetFile(filePath string) (io.ReadCloser, error) {
 	return os.Open(filePath)
 }
+
+func (s *LocalStorage) WriteChunk(fileName string, offset int64, chunk io.Reader) (int64, error) {
+	filePath := filepath.Join(s.BaseDir, fileName)
+
+	if err := os.MkdirAll(filepath.Dir(filePath), os.ModePerm); err != nil {
+		return 0, err
+	}
+
+	dst, err := os.OpenFile(filePath, os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return 0, err
+	}
+	defer dst.Close()
+
+	if _, err := dst.Seek(offset, io.SeekStart); err != nil {
+		return 0, err
+	}
+
+	return io.Copy(dst, chunk)
+}
+
+func (s *LocalStorage) Open(fileName string) (io.ReadCloser, error) {
+	return os.Open(filepath.Join(s.BaseDir, fileName))
+}
+
+func (s *LocalStorage) Rename(oldName, newName string) error {
+	return os.Rename(filepath.Join(s.BaseDir, oldName), filepath.Join(s.BaseDir, newName))
+}
+
+func (s *LocalStorage) Remove(fileName string) error {
+	return os.Remove(filepath.Join(s.BaseDir, fileName))
+}
+
+func (s *LocalStorage) Size(fileName string) (int64, error) {
+	info, err := os.Stat(filepath.Join(s.BaseDir, fileName))
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+// CheckWritable creates and removes a file in BaseDir, so that a full or read-only disk
+// is noticed before uploads fail
+func (s *LocalStorage) CheckWritable() error {
+	if err := os.MkdirAll(s.BaseDir, os.ModePerm); err != nil {
+		return err
+	}
+	probe, err := os.CreateTemp(s.BaseDir, ".writable-*")
+	if err != nil {
+		return err
+	}
+	probe.Close()
+	return os.Remove(probe.Name())
+}
@@ -0,0 +1,151 @@
+// Package health answers liveness and readiness probes of an orchestrator.
+// Liveness only fails when the process is stuck and has to be restarted, readiness
+// also fails while a dependency is down or the server is shutting down
+package health
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/serhiirubets/rubeticket/internal/pkg/res"
+)
+
+// checkTimeout limits each check, a probe must answer before the orchestrator gives up on it
+const checkTimeout = 2 * time.Second
+
+var ErrShuttingDown = errors.New("shutting down")
+
+type Check func(ctx context.Context) error
+
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+type Checker struct {
+	mu        sync.RWMutex
+	liveness  []namedCheck
+	readiness []namedCheck
+	ready     atomic.Bool
+}
+
+// NewChecker returns a checker that is ready, turn readiness off with SetReady on shutdown
+func NewChecker() *Checker {
+	checker := &Checker{}
+	checker.ready.Store(true)
+	return checker
+}
+
+// AddLiveness adds a check of /healthz and /readyz, it should only fail when a restart helps
+func (c *Checker) AddLiveness(name string, check Check) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.liveness = append(c.liveness, namedCheck{name: name, check: check})
+}
+
+// AddReadiness adds a check of /readyz, e.g. of a dependency that can come back by itself
+func (c *Checker) AddReadiness(name string, check Check) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.readiness = append(c.readiness, namedCheck{name: name, check: check})
+}
+
+func (c *Checker) SetReady(ready bool) {
+	c.ready.Store(ready)
+}
+
+// @Description Result of a probe. Checks map each check to "ok" or its error
+type Response struct {
+	Status string            `json:"status" example:"ok"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+// Liveness godoc
+// @Summary Liveness probe
+// @Description Fails when background workers stopped sending heartbeats and the process should be restarted
+// @Tags Health
+// @Produce json
+// @Success 200 {object} Response
+// @Failure 503 {object} Response
+// @Router /healthz [get]
+func (c *Checker) Liveness() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		c.mu.RLock()
+		checks := c.liveness
+		c.mu.RUnlock()
+
+		c.write(w, r, checks, nil)
+	}
+}
+
+// Readiness godoc
+// @Summary Readiness probe
+// @Description Fails while the database or storage is unavailable, a worker is stuck or the server is shutting down
+// @Tags Health
+// @Produce json
+// @Success 200 {object} Response
+// @Failure 503 {object} Response
+// @Router /readyz [get]
+func (c *Checker) Readiness() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		c.mu.RLock()
+		checks := append(append([]namedCheck{}, c.liveness...), c.readiness...)
+		c.mu.RUnlock()
+
+		var shutdownErr error
+		if !c.ready.Load() {
+			shutdownErr = ErrShuttingDown
+		}
+		c.write(w, r, checks, shutdownErr)
+	}
+}
+
+func (c *Checker) write(w http.ResponseWriter, r *http.Request, checks []namedCheck, shutdownErr error) {
+	results := run(r.Context(), checks)
+
+	response := &Response{Status: "ok", Checks: make(map[string]string, len(results)+1)}
+	status := http.StatusOK
+	for name, err := range results {
+		response.Checks[name] = "ok"
+		if err != nil {
+			response.Checks[name] = err.Error()
+			response.Status = "unavailable"
+			status = http.StatusServiceUnavailable
+		}
+	}
+	if shutdownErr != nil {
+		response.Checks["server"] = shutdownErr.Error()
+		response.Status = "unavailable"
+		status = http.StatusServiceUnavailable
+	}
+
+	// Probes must see the current state, never a cached one
+	w.Header().Set("Cache-Control", "no-store")
+	res.Json(w, response, status)
+}
+
+// run runs the checks at the same time, so a slow one does not delay the others
+func run(ctx context.Context, checks []namedCheck) map[string]error {
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	results := make(map[string]error, len(checks))
+	for _, nc := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := nc.check(ctx)
+			mu.Lock()
+			results[nc.name] = err
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	return results
+}
@@ -0,0 +1,55 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Heartbeats tracks running background work. A worker beats while it makes progress and is done
+// when it stops, the check fails when a worker has not beaten for longer than maxSilence
+type Heartbeats struct {
+	mu         sync.Mutex
+	maxSilence time.Duration
+	beats      map[string]time.Time
+}
+
+func NewHeartbeats(maxSilence time.Duration) *Heartbeats {
+	return &Heartbeats{maxSilence: maxSilence, beats: make(map[string]time.Time)}
+}
+
+// Beat and Done do nothing on nil heartbeats, so workers do not have to be given any
+func (h *Heartbeats) Beat(worker string) {
+	if h == nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.beats[worker] = time.Now()
+}
+
+func (h *Heartbeats) Done(worker string) {
+	if h == nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.beats, worker)
+}
+
+// Check reports a worker that went silent
+func (h *Heartbeats) Check(ctx context.Context) error {
+	if h == nil {
+		return nil
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for worker, last := range h.beats {
+		if silence := time.Since(last); silence > h.maxSilence {
+			return fmt.Errorf("%s has not reported progress for %s", worker, silence.Round(time.Second))
+		}
+	}
+	return nil
+}
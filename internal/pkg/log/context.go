@@ -0,0 +1,23 @@
+package log
+
+import "context"
+
+type contextKey struct{}
+
+// NewContext returns a context whose loggers add fields to every line,
+// fields of the parent context are kept
+func NewContext(ctx context.Context, fields WithFields) context.Context {
+	merged := make(WithFields, len(fields))
+	for key, value := range FieldsFromContext(ctx) {
+		merged[key] = value
+	}
+	for key, value := range fields {
+		merged[key] = value
+	}
+	return context.WithValue(ctx, contextKey{}, merged)
+}
+
+func FieldsFromContext(ctx context.Context) WithFields {
+	fields, _ := ctx.Value(contextKey{}).(WithFields)
+	return fields
+}
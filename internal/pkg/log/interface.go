@@ -1,5 +1,7 @@
 package log
 
+import "context"
+
 type WithFields map[string]interface{}
 
 type ILogger interface {
@@ -8,4 +10,6 @@ type ILogger interface {
 	Error(args ...interface{})
 	Debug(args ...interface{})
 	WithFields(fields WithFields) ILogger
+	// WithContext adds the fields stored in ctx by NewContext, e.g. the request ID
+	WithContext(ctx context.Context) ILogger
 }
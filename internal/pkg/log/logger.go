@@ -1,32 +1,80 @@
 package log
 
 import (
-	"github.com/sirupsen/logrus"
+	"context"
+	"fmt"
 	"os"
+
+	"github.com/sirupsen/logrus"
 )
 
 type LogrusLogger struct {
-	logger *logrus.Logger
+	entry *logrus.Entry
 }
 
 func (l *LogrusLogger) Info(args ...interface{}) {
-	l.logger.Info(args...)
+	l.withArgs(args).Info(message(args))
 }
 
 func (l *LogrusLogger) Warn(args ...interface{}) {
-	l.logger.Warn(args...)
+	l.withArgs(args).Warn(message(args))
 }
 
 func (l *LogrusLogger) Error(args ...interface{}) {
-	l.logger.Error(args...)
+	l.withArgs(args).Error(message(args))
 }
 
 func (l *LogrusLogger) Debug(args ...interface{}) {
-	l.logger.Debug(args...)
+	l.withArgs(args).Debug(message(args))
 }
 
 func (l *LogrusLogger) WithFields(fields WithFields) ILogger {
-	return &LogrusLogger{logger: l.logger.WithFields(logrus.Fields(fields)).Logger}
+	return &LogrusLogger{entry: l.entry.WithFields(logrus.Fields(fields))}
+}
+
+func (l *LogrusLogger) WithContext(ctx context.Context) ILogger {
+	fields := FieldsFromContext(ctx)
+	if len(fields) == 0 {
+		return l
+	}
+	return l.WithFields(fields)
+}
+
+// withArgs turns key-value pairs after the message into fields, as in
+// Error("Failed to save", "id", id, "error", err.Error())
+func (l *LogrusLogger) withArgs(args []interface{}) *logrus.Entry {
+	if !isStructured(args) {
+		return l.entry
+	}
+	fields := make(logrus.Fields, (len(args)-1)/2)
+	for i := 1; i < len(args); i += 2 {
+		fields[args[i].(string)] = args[i+1]
+	}
+	return l.entry.WithFields(fields)
+}
+
+func message(args []interface{}) string {
+	if isStructured(args) {
+		return args[0].(string)
+	}
+	return fmt.Sprint(args...)
+}
+
+// isStructured reports whether args are a message followed by pairs with string keys,
+// anything else is logged as one message the way logrus does
+func isStructured(args []interface{}) bool {
+	if len(args) < 3 || len(args)%2 == 0 {
+		return false
+	}
+	if _, ok := args[0].(string); !ok {
+		return false
+	}
+	for i := 1; i < len(args); i += 2 {
+		if _, ok := args[i].(string); !ok {
+			return false
+		}
+	}
+	return true
 }
 
 func NewLogrusLogger(logLevel string) ILogger {
@@ -53,5 +101,5 @@ func NewLogrusLogger(logLevel string) ILogger {
 
 	logrusLogger.SetLevel(level)
 
-	return &LogrusLogger{logger: logrusLogger}
+	return &LogrusLogger{entry: logrus.NewEntry(logrusLogger)}
 }
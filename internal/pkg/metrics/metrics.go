@@ -0,0 +1,99 @@
+// Package metrics holds the Prometheus metrics of the app, they are served on /metrics of the metrics port
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "rubeticket"
+
+// unmatchedRoute labels requests no route matched, their paths are not used as labels
+// so that random URLs cannot create new series
+const unmatchedRoute = "unmatched"
+
+// otherMethod labels requests with methods outside of knownMethods, for the same reason
+const otherMethod = "other"
+
+var knownMethods = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodPost:    true,
+	http.MethodPut:     true,
+	http.MethodPatch:   true,
+	http.MethodDelete:  true,
+	http.MethodOptions: true,
+}
+
+var (
+	httpRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by method, route pattern and status",
+	}, []string{"method", "route", "status"})
+
+	httpDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by method and route pattern",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	uploadSize = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "upload_size_bytes",
+		Help:      "Size of uploaded files by the way they were uploaded, form or resumable",
+		// 64 KB to 4 GB
+		Buckets: prometheus.ExponentialBuckets(64<<10, 4, 9),
+	}, []string{"via"})
+
+	logins = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "logins_total",
+		Help:      "Login attempts by result",
+	}, []string{"result"})
+)
+
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+func ObserveRequest(method string, route string, status int, duration time.Duration) {
+	if route == "" {
+		route = unmatchedRoute
+	}
+	if !knownMethods[method] {
+		method = otherMethod
+	}
+	httpRequests.WithLabelValues(method, route, strconv.Itoa(status)).Inc()
+	httpDuration.WithLabelValues(method, route).Observe(duration.Seconds())
+}
+
+const (
+	UploadForm      = "form"
+	UploadResumable = "resumable"
+)
+
+func ObserveUpload(via string, size int64) {
+	uploadSize.WithLabelValues(via).Observe(float64(size))
+}
+
+func Login(success bool) {
+	result := "failure"
+	if success {
+		result = "success"
+	}
+	logins.WithLabelValues(result).Inc()
+}
+
+// RegisterDB exports the connection pool stats of sqlDB
+func RegisterDB(sqlDB *sql.DB) error {
+	return prometheus.Register(collectors.NewDBStatsCollector(sqlDB, "postgres"))
+}
@@ -0,0 +1,122 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/serhiirubets/rubeticket/internal/pkg/log"
+)
+
+type accessLogKey struct{}
+
+// accessInfo is filled in by handlers deeper in the chain, which get their own copies of the request,
+// so that the access log can report the matched route and the user
+type accessInfo struct {
+	route  string
+	userID uint
+}
+
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += n
+	return n, err
+}
+
+// statusCode is the status sent, 200 when the handler wrote nothing
+func (w *statusWriter) statusCode() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+	return w.status
+}
+
+// Unwrap lets http.ResponseController reach the flusher of the wrapped writer
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// AccessLog logs one line per request with its method, route pattern, status, latency and user.
+// It goes after RequestID, so the line carries the request ID
+func AccessLog(logger log.ILogger) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			r, info := withAccessInfo(r)
+			writer := &statusWriter{ResponseWriter: w}
+
+			next.ServeHTTP(writer, r)
+
+			status := writer.statusCode()
+			fields := log.WithFields{
+				"method":    r.Method,
+				"path":      r.URL.Path,
+				"route":     info.route,
+				"status":    status,
+				"bytes":     writer.bytes,
+				"latencyMs": float64(time.Since(start).Microseconds()) / 1000,
+			}
+			if info.userID != 0 {
+				fields["userId"] = info.userID
+			}
+
+			entry := logger.WithContext(r.Context()).WithFields(fields)
+			if status >= http.StatusInternalServerError {
+				entry.Error("Request failed")
+			} else {
+				entry.Info("Request")
+			}
+		})
+	}
+}
+
+// Route records the pattern matched by router for the access log. Routers below http.StripPrefix
+// only see the rest of the path, so prefix is put back in front of it
+func Route(prefix string, router http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// ServeMux sets the pattern on the request it is given
+		router.ServeHTTP(w, r)
+
+		// the innermost router has the most specific pattern and returns first
+		info := accessInfoFrom(r.Context())
+		if info == nil || info.route != "" || r.Pattern == "" {
+			return
+		}
+		if method, path, ok := strings.Cut(r.Pattern, " "); ok {
+			info.route = method + " " + prefix + path
+		} else {
+			info.route = prefix + r.Pattern
+		}
+	})
+}
+
+// withAccessInfo adds accessInfo to the request unless a middleware before did
+func withAccessInfo(r *http.Request) (*http.Request, *accessInfo) {
+	if info := accessInfoFrom(r.Context()); info != nil {
+		return r, info
+	}
+	info := &accessInfo{}
+	return r.WithContext(context.WithValue(r.Context(), accessLogKey{}, info)), info
+}
+
+func accessInfoFrom(ctx context.Context) *accessInfo {
+	info, _ := ctx.Value(accessLogKey{}).(*accessInfo)
+	return info
+}
@@ -2,15 +2,18 @@ package middleware
 
 import (
 	"context"
-	"fmt"
+	"errors"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/serhiirubets/rubeticket/config"
 	"github.com/serhiirubets/rubeticket/internal/app/users"
+	"github.com/serhiirubets/rubeticket/internal/pkg/apperr"
 	"github.com/serhiirubets/rubeticket/internal/pkg/jwt"
 	"github.com/serhiirubets/rubeticket/internal/pkg/log"
 	"github.com/serhiirubets/rubeticket/internal/pkg/res"
+	"gorm.io/gorm"
 )
 
 type contextKey string
@@ -23,14 +26,20 @@ type AuthContextData struct {
 
 const AuthKey contextKey = "authData"
 
+var (
+	ErrUnauthorized = apperr.New(apperr.Unauthorized, "unauthorized", "not authorized")
+	ErrAdminOnly    = apperr.New(apperr.Forbidden, "admin_only", "admin access required")
+)
+
 type AuthMiddleware struct {
 	conf       *config.Config
 	logger     log.ILogger
+	users      *users.UserRepository
 	openRoutes map[string]struct{}
 	apiPrefix  string // Example: "/api/v1"
 }
 
-func NewAuthMiddleware(conf *config.Config, logger log.ILogger, openRoutes []string, apiPrefix string) *AuthMiddleware {
+func NewAuthMiddleware(conf *config.Config, logger log.ILogger, userRepository *users.UserRepository, openRoutes []string, apiPrefix string) *AuthMiddleware {
 	openRoutesMap := make(map[string]struct{})
 	for _, route := range openRoutes {
 		normalizedRoute := "/" + strings.Trim(route, "/")
@@ -39,6 +48,7 @@ func NewAuthMiddleware(conf *config.Config, logger log.ILogger, openRoutes []str
 	return &AuthMiddleware{
 		conf:       conf,
 		logger:     logger,
+		users:      userRepository,
 		openRoutes: openRoutesMap,
 		apiPrefix:  apiPrefix,
 	}
@@ -76,32 +86,36 @@ func (m *AuthMiddleware) Auth(next http.Handler) http.Handler {
 		// Check token for closed routes
 		cookie, err := r.Cookie("token")
 		if err != nil {
-			m.logger.Debug("No token cookie found", "error", err.Error())
-			writeUnathed(w)
+			m.logger.WithContext(r.Context()).Debug("No token cookie found", "error", err.Error())
+			res.Error(w, r, ErrUnauthorized)
 			return
 		}
 
 		token := cookie.Value
 		if token == "" {
-			m.logger.Debug("Token is empty")
-			writeUnathed(w)
+			m.logger.WithContext(r.Context()).Debug("Token is empty")
+			res.Error(w, r, ErrUnauthorized)
 			return
 		}
 
 		data, parseErr := jwt.NewJWT(m.conf.Auth.Secret).Parse(token)
 		if parseErr != nil {
-			m.logger.Error("Token parse failed", "error", parseErr.Error())
-			writeUnathed(w)
+			m.logger.WithContext(r.Context()).Error("Token parse failed", "error", parseErr.Error())
+			res.Error(w, r, ErrUnauthorized)
 			return
 		}
 
 		authData := AuthContextData{
 			Email:  data.Email,
 			UserID: data.Id,
-			Role:   users.UserRole,
+			Role:   data.Role,
 		}
 
 		ctx := context.WithValue(r.Context(), AuthKey, authData)
+		ctx = log.NewContext(ctx, log.WithFields{"userId": authData.UserID})
+		if info := accessInfoFrom(ctx); info != nil {
+			info.userID = authData.UserID
+		}
 		req := r.WithContext(ctx)
 		next.ServeHTTP(w, req)
 	})
@@ -111,28 +125,38 @@ func (m *AuthMiddleware) AdminOnly(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		authData, err := GetAuthData(r)
 		if err != nil {
-			m.logger.Error("Admin auth failed", "error", err.Error())
-			res.Json(w, "Unauthorized", http.StatusUnauthorized)
+			m.logger.WithContext(r.Context()).Error("Admin auth failed", "error", err.Error())
+			res.Error(w, r, err)
 			return
 		}
-		if authData.Role != users.AdminRole {
-			m.logger.Error("Admin access required", "role", authData.Role)
-			res.Json(w, "Forbidden: Admin access required", http.StatusForbidden)
+
+		// The role in the token may be outdated, a user whose admin role was taken away keeps it until
+		// the token expires. The stored role is checked instead and passed on to the handlers
+		user, err := m.users.GetById(r.Context(), strconv.FormatUint(uint64(authData.UserID), 10))
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				res.Error(w, r, ErrUnauthorized)
+				return
+			}
+			m.logger.WithContext(r.Context()).Error("Failed to load user role", "error", err.Error())
+			res.Error(w, r, err)
+			return
+		}
+		if user.Role != users.AdminRole {
+			m.logger.WithContext(r.Context()).Error("Admin access required", "role", user.Role)
+			res.Error(w, r, ErrAdminOnly)
 			return
 		}
-		next.ServeHTTP(w, r)
-	})
-}
 
-func writeUnathed(w http.ResponseWriter) {
-	w.WriteHeader(http.StatusUnauthorized)
-	w.Write([]byte(http.StatusText(http.StatusUnauthorized)))
+		authData.Role = user.Role
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), AuthKey, authData)))
+	})
 }
 
 func GetAuthData(r *http.Request) (AuthContextData, error) {
 	authData, ok := r.Context().Value(AuthKey).(AuthContextData)
 	if !ok {
-		return AuthContextData{}, fmt.Errorf("not authorized")
+		return AuthContextData{}, ErrUnauthorized
 	}
 	return authData, nil
 }
@@ -1,40 +1,157 @@
 package middleware
 
-import "net/http"
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
 
-func CORS(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		origin := r.Header.Get("Origin")
+	"github.com/serhiirubets/rubeticket/config"
+)
 
-		if origin == "" {
-			next.ServeHTTP(w, r)
-			return
+// CORS answers preflight requests and sets the CORS headers of the policy whose route prefix
+// is the longest match of the request path, conf.Default applies when none matches
+func CORS(conf config.CORSConfig) Middleware {
+	defaultPolicy := newCORSPolicy(conf.Default)
+	routes := make([]corsRoute, 0, len(conf.Routes))
+	for prefix, policy := range conf.Routes {
+		routes = append(routes, corsRoute{prefix: prefix, policy: newCORSPolicy(policy)})
+	}
+	sort.Slice(routes, func(i, j int) bool {
+		return len(routes[i].prefix) > len(routes[j].prefix)
+	})
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			policy := defaultPolicy
+			for _, route := range routes {
+				if strings.HasPrefix(r.URL.Path, route.prefix) {
+					policy = route.policy
+					break
+				}
+			}
+			policy.serve(w, r, next)
+		})
+	}
+}
+
+type corsRoute struct {
+	prefix string
+	policy *corsPolicy
+}
+
+type corsPolicy struct {
+	anyOrigin   bool
+	origins     map[string]bool
+	patterns    []originPattern
+	methods     string
+	headers     string
+	exposed     string
+	credentials bool
+	maxAge      string
+}
+
+// originPattern matches "https://*.example.com" as a prefix and a suffix around the subdomain
+type originPattern struct {
+	prefix string
+	suffix string
+}
+
+func newCORSPolicy(conf config.CORSPolicy) *corsPolicy {
+	policy := &corsPolicy{
+		origins:     make(map[string]bool),
+		methods:     strings.Join(conf.AllowedMethods, ", "),
+		headers:     strings.Join(conf.AllowedHeaders, ","),
+		exposed:     strings.Join(conf.ExposedHeaders, ","),
+		credentials: conf.AllowCredentials,
+	}
+	if conf.MaxAgeSeconds > 0 {
+		policy.maxAge = strconv.Itoa(conf.MaxAgeSeconds)
+	}
+
+	for _, origin := range conf.AllowedOrigins {
+		origin = strings.ToLower(origin)
+		switch {
+		case origin == "*":
+			policy.anyOrigin = true
+		case strings.Contains(origin, "://*."):
+			scheme, host, _ := strings.Cut(origin, "://*")
+			policy.patterns = append(policy.patterns, originPattern{prefix: scheme + "://", suffix: host})
+		default:
+			policy.origins[origin] = true
 		}
+	}
+	return policy
+}
 
-		allowedOrigins := map[string]bool{
-			"http://localhost":      true,
-			"http://localhost:8080": true,
-			"http://localhost:4200": true,
-			"http://127.0.0.1":      true,
-			"http://127.0.0.1:8080": true,
-			"http://127.0.0.1:4200": true,
+func (p *corsPolicy) allows(origin string) bool {
+	origin = strings.ToLower(origin)
+	if p.anyOrigin || p.origins[origin] {
+		return true
+	}
+	for _, pattern := range p.patterns {
+		if pattern.match(origin) {
+			return true
 		}
+	}
+	return false
+}
+
+func (p originPattern) match(origin string) bool {
+	if len(origin) <= len(p.prefix)+len(p.suffix) ||
+		!strings.HasPrefix(origin, p.prefix) || !strings.HasSuffix(origin, p.suffix) {
+		return false
+	}
+	// only subdomain labels may stand for the star, not a port, credentials or a path
+	subdomain := origin[len(p.prefix) : len(origin)-len(p.suffix)]
+	return !strings.ContainsAny(subdomain, ":/@?#")
+}
 
-		header := w.Header()
+func (p *corsPolicy) serve(w http.ResponseWriter, r *http.Request, next http.Handler) {
+	header := w.Header()
+
+	// The allowed origin is echoed back, so caches must keep a response per origin,
+	// requests without an Origin included
+	if !p.anyOrigin {
+		header.Add("Vary", "Origin")
+	}
+
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		next.ServeHTTP(w, r)
+		return
+	}
 
-		if allowedOrigins[origin] {
+	allowed := p.allows(origin)
+	preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+
+	if allowed {
+		if p.anyOrigin {
+			// credentials are never allowed for any origin
+			header.Set("Access-Control-Allow-Origin", "*")
+		} else {
 			header.Set("Access-Control-Allow-Origin", origin)
-			header.Set("Access-Control-Allow-Credentials", "true")
+			if p.credentials {
+				header.Set("Access-Control-Allow-Credentials", "true")
+			}
 		}
+		if !preflight && p.exposed != "" {
+			header.Set("Access-Control-Expose-Headers", p.exposed)
+		}
+	}
 
-		if r.Method == http.MethodOptions {
-			header.Set("Access-Control-Allow-Methods", "POST, GET, DELETE, HEAD, PATCH, PUT")
-			header.Set("Access-Control-Allow-Headers", "authorization,content-type,content-length")
-			header.Set("Access-Control-Max-Age", "86400")
-			w.WriteHeader(http.StatusNoContent)
-			return
-
+	if preflight {
+		if allowed {
+			header.Set("Access-Control-Allow-Methods", p.methods)
+			if p.headers != "" {
+				header.Set("Access-Control-Allow-Headers", p.headers)
+			}
+			if p.maxAge != "" {
+				header.Set("Access-Control-Max-Age", p.maxAge)
+			}
 		}
-		next.ServeHTTP(w, r)
-	})
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	next.ServeHTTP(w, r)
 }
@@ -0,0 +1,209 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/serhiirubets/rubeticket/config"
+	"github.com/serhiirubets/rubeticket/internal/pkg/middleware"
+)
+
+var testCORS = config.CORSConfig{
+	Default: config.CORSPolicy{
+		AllowedOrigins:   []string{"https://app.example.com", "http://localhost:8080", "https://*.example.com"},
+		AllowedMethods:   []string{"GET", "POST"},
+		AllowedHeaders:   []string{"authorization", "content-type"},
+		ExposedHeaders:   []string{"location"},
+		AllowCredentials: true,
+		MaxAgeSeconds:    600,
+	},
+	Routes: map[string]config.CORSPolicy{
+		"/public/": {
+			AllowedOrigins: []string{"*"},
+			AllowedMethods: []string{"GET"},
+		},
+	},
+}
+
+// serveCORS sends a request with origin through the CORS middleware, a preflight one when preflight is set.
+// It also returns if the request reached the next handler
+func serveCORS(t *testing.T, path string, origin string, preflight bool) (*httptest.ResponseRecorder, bool) {
+	t.Helper()
+	var reached bool
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reached = true
+	})
+
+	method := http.MethodGet
+	if preflight {
+		method = http.MethodOptions
+	}
+	r := httptest.NewRequest(method, path, nil)
+	if origin != "" {
+		r.Header.Set("Origin", origin)
+	}
+	if preflight {
+		r.Header.Set("Access-Control-Request-Method", http.MethodPost)
+	}
+
+	w := httptest.NewRecorder()
+	middleware.CORS(testCORS)(next).ServeHTTP(w, r)
+	return w, reached
+}
+
+func TestCORSOrigins(t *testing.T) {
+	tests := []struct {
+		name    string
+		origin  string
+		allowed bool
+	}{
+		{name: "exact origin", origin: "https://app.example.com", allowed: true},
+		{name: "exact origin with port", origin: "http://localhost:8080", allowed: true},
+		{name: "other port", origin: "http://localhost:9090"},
+		{name: "no port for an origin with port", origin: "http://localhost"},
+		{name: "origin in other case", origin: "HTTPS://App.Example.COM", allowed: true},
+		{name: "subdomain", origin: "https://api.example.com", allowed: true},
+		{name: "nested subdomain", origin: "https://a.b.example.com", allowed: true},
+		{name: "subdomain in other case", origin: "https://API.Example.com", allowed: true},
+		{name: "subdomain with port", origin: "https://api.example.com:8443"},
+		{name: "bare domain for a subdomain pattern", origin: "https://example.com"},
+		{name: "domain with the same suffix", origin: "https://aexample.com"},
+		{name: "other scheme", origin: "http://api.example.com"},
+		{name: "host with a port before the suffix", origin: "https://evil.com:x.example.com"},
+		{name: "credentials before the suffix", origin: "https://evil.com@x.example.com"},
+		{name: "path before the suffix", origin: "https://evil.com/x.example.com"},
+		{name: "suffix as a subdomain of another domain", origin: "https://api.example.com.evil.com"},
+		{name: "null origin", origin: "null"},
+		{name: "malformed origin", origin: "not an origin"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, reached := serveCORS(t, "/api/v1/concerts", tt.origin, false)
+			if !reached {
+				t.Fatal("simple request did not reach the handler")
+			}
+
+			got := w.Header().Get("Access-Control-Allow-Origin")
+			want := ""
+			if tt.allowed {
+				want = tt.origin
+			}
+			if got != want {
+				t.Errorf("Access-Control-Allow-Origin = %q, want %q", got, want)
+			}
+			if credentials := w.Header().Get("Access-Control-Allow-Credentials"); (credentials == "true") != tt.allowed {
+				t.Errorf("Access-Control-Allow-Credentials = %q, allowed %v", credentials, tt.allowed)
+			}
+		})
+	}
+}
+
+func TestCORSRequests(t *testing.T) {
+	tests := []struct {
+		name        string
+		path        string
+		origin      string
+		preflight   bool
+		wantReached bool
+		wantStatus  int
+		wantHeaders map[string]string
+	}{
+		{
+			name:        "simple request",
+			path:        "/api/v1/concerts",
+			origin:      "https://app.example.com",
+			wantReached: true,
+			wantStatus:  http.StatusOK,
+			wantHeaders: map[string]string{
+				"Access-Control-Allow-Origin":      "https://app.example.com",
+				"Access-Control-Allow-Credentials": "true",
+				"Access-Control-Expose-Headers":    "location",
+				"Access-Control-Allow-Methods":     "",
+				"Access-Control-Max-Age":           "",
+				"Vary":                             "Origin",
+			},
+		},
+		{
+			name:       "preflight",
+			path:       "/api/v1/concerts",
+			origin:     "https://app.example.com",
+			preflight:  true,
+			wantStatus: http.StatusNoContent,
+			wantHeaders: map[string]string{
+				"Access-Control-Allow-Origin":      "https://app.example.com",
+				"Access-Control-Allow-Credentials": "true",
+				"Access-Control-Allow-Methods":     "GET, POST",
+				"Access-Control-Allow-Headers":     "authorization,content-type",
+				"Access-Control-Max-Age":           "600",
+				"Access-Control-Expose-Headers":    "",
+				"Vary":                             "Origin",
+			},
+		},
+		{
+			name:       "preflight of a disallowed origin",
+			path:       "/api/v1/concerts",
+			origin:     "https://evil.com",
+			preflight:  true,
+			wantStatus: http.StatusNoContent,
+			wantHeaders: map[string]string{
+				"Access-Control-Allow-Origin":  "",
+				"Access-Control-Allow-Methods": "",
+				"Access-Control-Max-Age":       "",
+				"Vary":                         "Origin",
+			},
+		},
+		{
+			name:        "simple request of a disallowed origin",
+			path:        "/api/v1/concerts",
+			origin:      "https://evil.com",
+			wantReached: true,
+			wantStatus:  http.StatusOK,
+			wantHeaders: map[string]string{
+				"Access-Control-Allow-Origin":   "",
+				"Access-Control-Expose-Headers": "",
+				"Vary":                          "Origin",
+			},
+		},
+		{
+			name:        "request without origin",
+			path:        "/api/v1/concerts",
+			wantReached: true,
+			wantStatus:  http.StatusOK,
+			wantHeaders: map[string]string{
+				"Access-Control-Allow-Origin": "",
+				"Vary":                        "Origin",
+			},
+		},
+		{
+			name:        "any origin route",
+			path:        "/public/logo.png",
+			origin:      "https://evil.com",
+			wantReached: true,
+			wantStatus:  http.StatusOK,
+			wantHeaders: map[string]string{
+				"Access-Control-Allow-Origin":      "*",
+				"Access-Control-Allow-Credentials": "",
+				"Vary":                             "",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, reached := serveCORS(t, tt.path, tt.origin, tt.preflight)
+			if reached != tt.wantReached {
+				t.Errorf("reached handler = %v, want %v", reached, tt.wantReached)
+			}
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			for name, want := range tt.wantHeaders {
+				if got := w.Header().Get(name); got != want {
+					t.Errorf("%s = %q, want %q", name, got, want)
+				}
+			}
+		})
+	}
+}
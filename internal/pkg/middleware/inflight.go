@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"sync"
+)
+
+// InFlight counts running requests, so that shutdown can wait until all of them are finished
+type InFlight struct {
+	wg sync.WaitGroup
+}
+
+func NewInFlight() *InFlight {
+	return &InFlight{}
+}
+
+func (t *InFlight) Track(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.wg.Add(1)
+		defer t.wg.Done()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Wait blocks until all tracked requests are finished or ctx is done
+func (t *InFlight) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		t.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,22 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/serhiirubets/rubeticket/internal/pkg/metrics"
+)
+
+// Metrics counts requests and their latency by route pattern and status,
+// routers must be wrapped in Route for the pattern to be known
+func Metrics(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		r, info := withAccessInfo(r)
+		writer := &statusWriter{ResponseWriter: w}
+
+		next.ServeHTTP(writer, r)
+
+		metrics.ObserveRequest(r.Method, info.route, writer.statusCode(), time.Since(start))
+	})
+}
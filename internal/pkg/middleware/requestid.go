@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/serhiirubets/rubeticket/internal/pkg/log"
+	"github.com/serhiirubets/rubeticket/internal/pkg/requestid"
+)
+
+// RequestID keeps the X-Request-ID of the request or generates one, and sends it back in the response.
+// Loggers given the request context add the ID to every line
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(requestid.Header)
+		if !requestid.Valid(id) {
+			id = requestid.New()
+		}
+
+		w.Header().Set(requestid.Header, id)
+		ctx := requestid.NewContext(r.Context(), id)
+		ctx = log.NewContext(ctx, log.WithFields{"requestId": id})
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
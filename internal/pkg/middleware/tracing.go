@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/serhiirubets/rubeticket/internal/pkg/log"
+	"github.com/serhiirubets/rubeticket/internal/pkg/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Tracing starts a server span for each request, continuing the trace of the caller
+// from the traceparent header. The span is named after the route pattern, so routers
+// must be wrapped in Route. The trace ID is sent back in X-Trace-ID and added to logs
+func Tracing(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx, span := tracing.Start(ctx, r.Method,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(r.Method),
+				semconv.URLPath(r.URL.Path),
+			),
+		)
+		defer span.End()
+
+		if traceID := tracing.TraceID(ctx); traceID != "" {
+			w.Header().Set(tracing.Header, traceID)
+			ctx = log.NewContext(ctx, log.WithFields{"traceId": traceID})
+		}
+
+		r, info := withAccessInfo(r.WithContext(ctx))
+		writer := &statusWriter{ResponseWriter: w}
+
+		next.ServeHTTP(writer, r)
+
+		status := writer.statusCode()
+		if info.route != "" {
+			span.SetName(info.route)
+			// http.route is the path template only, routes are recorded with their method
+			_, route, found := strings.Cut(info.route, " ")
+			if !found {
+				route = info.route
+			}
+			span.SetAttributes(semconv.HTTPRoute(route))
+		}
+		span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+		if info.userID != 0 {
+			span.SetAttributes(semconv.EnduserID(strconv.FormatUint(uint64(info.userID), 10)))
+		}
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+	})
+}
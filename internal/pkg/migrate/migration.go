@@ -0,0 +1,113 @@
+package migrate
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// fileNamePattern matches "<version>_<name>.up.sql" and "<version>_<name>.down.sql"
+var fileNamePattern = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+	// Checksum covers both files, so editing either of them after it was applied is noticed
+	Checksum string
+}
+
+// Load reads migrations from the root of fsys ordered by version
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		matches := fileNamePattern.FindStringSubmatch(entry.Name())
+		if matches == nil {
+			continue
+		}
+
+		version, _ := strconv.ParseInt(matches[1], 10, 64)
+		content, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: matches[2]}
+			byVersion[version] = migration
+		} else if migration.Name != matches[2] {
+			return nil, fmt.Errorf("migration %d has different names: %s and %s", version, migration.Name, matches[2])
+		}
+
+		if matches[3] == "up" {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up file", migration.Version, migration.Name)
+		}
+		migration.Checksum = checksum(migration.Up, migration.Down)
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// Create writes empty up and down files for a new migration into dir and returns their paths
+func Create(dir, name string) ([]string, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	name = regexp.MustCompile(`[^a-z0-9]+`).ReplaceAllString(name, "_")
+	name = strings.Trim(name, "_")
+	if name == "" {
+		return nil, fmt.Errorf("migration name is required")
+	}
+
+	version := time.Now().UTC().Format("20060102150405")
+	paths := []string{
+		filepath.Join(dir, version+"_"+name+".up.sql"),
+		filepath.Join(dir, version+"_"+name+".down.sql"),
+	}
+
+	for _, path := range paths {
+		if err := os.WriteFile(path, []byte("-- "+filepath.Base(path)+"\n"), 0644); err != nil {
+			return nil, err
+		}
+	}
+
+	return paths, nil
+}
+
+func checksum(up, down string) string {
+	h := sha256.New()
+	h.Write([]byte(up))
+	// the separator keeps moving text from one file to the other from giving the same checksum
+	h.Write([]byte{0})
+	h.Write([]byte(down))
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,213 @@
+package migrate
+
+import (
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/serhiirubets/rubeticket/migrations"
+)
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name         string
+		files        fstest.MapFS
+		wantVersions []int64
+		wantErr      bool
+	}{
+		{
+			name: "ordered by version",
+			files: fstest.MapFS{
+				"20250201000000_second.up.sql":   {Data: []byte("SELECT 2;")},
+				"20250201000000_second.down.sql": {Data: []byte("SELECT -2;")},
+				"20250101000000_first.up.sql":    {Data: []byte("SELECT 1;")},
+			},
+			wantVersions: []int64{20250101000000, 20250201000000},
+		},
+		{
+			name: "other files are ignored",
+			files: fstest.MapFS{
+				"20250101000000_first.up.sql": {Data: []byte("SELECT 1;")},
+				"migrations.go":               {Data: []byte("package migrations")},
+				"README.md":                   {Data: []byte("notes")},
+				"1_Bad-Name.up.sql":           {Data: []byte("SELECT 0;")},
+			},
+			wantVersions: []int64{20250101000000},
+		},
+		{
+			name: "down file without up file",
+			files: fstest.MapFS{
+				"20250101000000_first.down.sql": {Data: []byte("SELECT 1;")},
+			},
+			wantErr: true,
+		},
+		{
+			name: "same version with different names",
+			files: fstest.MapFS{
+				"20250101000000_first.up.sql":   {Data: []byte("SELECT 1;")},
+				"20250101000000_other.down.sql": {Data: []byte("SELECT 1;")},
+			},
+			wantErr: true,
+		},
+		{
+			name:         "empty dir",
+			files:        fstest.MapFS{},
+			wantVersions: []int64{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loaded, err := Load(tt.files)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(loaded) != len(tt.wantVersions) {
+				t.Fatalf("Load() returned %d migrations, want %d", len(loaded), len(tt.wantVersions))
+			}
+			for i, migration := range loaded {
+				if migration.Version != tt.wantVersions[i] {
+					t.Errorf("migration %d has version %d, want %d", i, migration.Version, tt.wantVersions[i])
+				}
+				if migration.Checksum == "" {
+					t.Errorf("migration %d has no checksum", migration.Version)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadReadsBothFiles(t *testing.T) {
+	loaded, err := Load(fstest.MapFS{
+		"20250101000000_first.up.sql":   {Data: []byte("CREATE TABLE a ();")},
+		"20250101000000_first.down.sql": {Data: []byte("DROP TABLE a;")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	migration := loaded[0]
+	if migration.Name != "first" || migration.Up != "CREATE TABLE a ();" || migration.Down != "DROP TABLE a;" {
+		t.Errorf("Load() = %+v", migration)
+	}
+}
+
+func TestChecksum(t *testing.T) {
+	base := checksum("CREATE TABLE a ();", "DROP TABLE a;")
+
+	tests := []struct {
+		name     string
+		up       string
+		down     string
+		wantSame bool
+	}{
+		{name: "same files", up: "CREATE TABLE a ();", down: "DROP TABLE a;", wantSame: true},
+		{name: "up changed", up: "CREATE TABLE b ();", down: "DROP TABLE a;"},
+		{name: "down changed", up: "CREATE TABLE a ();", down: "DROP TABLE b;"},
+		{name: "down removed", up: "CREATE TABLE a ();", down: ""},
+		{name: "text moved between files", up: "CREATE TABLE a ();DROP TABLE a;", down: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if same := checksum(tt.up, tt.down) == base; same != tt.wantSame {
+				t.Errorf("checksum equal = %v, want %v", same, tt.wantSame)
+			}
+		})
+	}
+}
+
+func TestDrift(t *testing.T) {
+	files := fstest.MapFS{
+		"20250101000000_first.up.sql":   {Data: []byte("CREATE TABLE a ();")},
+		"20250101000000_first.down.sql": {Data: []byte("DROP TABLE a;")},
+		"20250201000000_second.up.sql":  {Data: []byte("CREATE TABLE b ();")},
+	}
+	loaded, err := Load(files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	appliedAt := time.Now()
+
+	tests := []struct {
+		name        string
+		applied     []appliedMigration
+		wantDrifted map[int64]bool
+		wantMissing map[int64]bool
+		wantApplied map[int64]bool
+		wantErr     bool
+	}{
+		{
+			name:        "nothing applied",
+			wantApplied: map[int64]bool{},
+		},
+		{
+			name: "applied unchanged",
+			applied: []appliedMigration{
+				{Version: 20250101000000, Name: "first", Checksum: loaded[0].Checksum, AppliedAt: appliedAt},
+			},
+			wantApplied: map[int64]bool{20250101000000: true},
+		},
+		{
+			name: "applied file changed",
+			applied: []appliedMigration{
+				{Version: 20250101000000, Name: "first", Checksum: checksum("CREATE TABLE a ();", "DROP TABLE old;"), AppliedAt: appliedAt},
+			},
+			wantApplied: map[int64]bool{20250101000000: true},
+			wantDrifted: map[int64]bool{20250101000000: true},
+			wantErr:     true,
+		},
+		{
+			name: "applied file removed",
+			applied: []appliedMigration{
+				{Version: 20241201000000, Name: "gone", Checksum: "x", AppliedAt: appliedAt},
+			},
+			wantApplied: map[int64]bool{20241201000000: true},
+			wantMissing: map[int64]bool{20241201000000: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			applied := make(map[int64]appliedMigration)
+			for _, a := range tt.applied {
+				applied[a.Version] = a
+			}
+
+			statuses := mergeStatuses(loaded, applied)
+			for i := 1; i < len(statuses); i++ {
+				if statuses[i-1].Version > statuses[i].Version {
+					t.Errorf("statuses are not ordered by version")
+				}
+			}
+			for _, status := range statuses {
+				if status.Applied != tt.wantApplied[status.Version] {
+					t.Errorf("%d applied = %v", status.Version, status.Applied)
+				}
+				if status.Drifted != tt.wantDrifted[status.Version] {
+					t.Errorf("%d drifted = %v", status.Version, status.Drifted)
+				}
+				if status.Missing != tt.wantMissing[status.Version] {
+					t.Errorf("%d missing = %v", status.Version, status.Missing)
+				}
+			}
+			if err := checkDrift(statuses); (err != nil) != tt.wantErr {
+				t.Errorf("checkDrift() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadEmbeddedMigrations(t *testing.T) {
+	loaded, err := Load(migrations.FS)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	for _, migration := range loaded {
+		if migration.Down == "" {
+			t.Errorf("migration %d_%s has no down file", migration.Version, migration.Name)
+		}
+	}
+}
@@ -0,0 +1,237 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"sort"
+	"time"
+)
+
+// lockKey is an arbitrary key of the postgres advisory lock that guards schema changes
+const lockKey = 7_230_561_902
+
+const createTableQuery = `CREATE TABLE IF NOT EXISTS schema_migrations (
+	version BIGINT PRIMARY KEY,
+	name TEXT NOT NULL,
+	checksum TEXT NOT NULL,
+	applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+)`
+
+type MigrationStatus struct {
+	Migration
+	Applied   bool
+	AppliedAt *time.Time
+	// Drifted is set when an applied migration file was changed afterwards
+	Drifted bool
+	// Missing is set when a migration is applied in the database, but its file is gone
+	Missing bool
+}
+
+type appliedMigration struct {
+	Version   int64
+	Name      string
+	Checksum  string
+	AppliedAt time.Time
+}
+
+type Migrator struct {
+	db   *sql.DB
+	fsys fs.FS
+}
+
+func NewMigrator(db *sql.DB, fsys fs.FS) *Migrator {
+	return &Migrator{db: db, fsys: fsys}
+}
+
+// Up applies at most steps pending migrations, all of them when steps <= 0
+func (m *Migrator) Up(ctx context.Context, steps int) ([]Migration, error) {
+	var applied []Migration
+
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		statuses, err := m.status(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if err := checkDrift(statuses); err != nil {
+			return err
+		}
+
+		for _, status := range statuses {
+			if status.Applied || status.Missing {
+				continue
+			}
+			if steps > 0 && len(applied) == steps {
+				break
+			}
+
+			err := runInTx(ctx, conn, status.Up,
+				`INSERT INTO schema_migrations (version, name, checksum) VALUES ($1, $2, $3)`,
+				status.Version, status.Name, status.Checksum)
+			if err != nil {
+				return fmt.Errorf("migration %d_%s failed: %w", status.Version, status.Name, err)
+			}
+			applied = append(applied, status.Migration)
+		}
+		return nil
+	})
+
+	return applied, err
+}
+
+// Down rolls back at most steps applied migrations starting from the latest one
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	var reverted []Migration
+
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		statuses, err := m.status(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if err := checkDrift(statuses); err != nil {
+			return err
+		}
+
+		for i := len(statuses) - 1; i >= 0 && len(reverted) < steps; i-- {
+			status := statuses[i]
+			if !status.Applied {
+				continue
+			}
+			if status.Missing {
+				return fmt.Errorf("migration %d_%s is applied, but its files are missing", status.Version, status.Name)
+			}
+			if status.Down == "" {
+				return fmt.Errorf("migration %d_%s has no down file", status.Version, status.Name)
+			}
+
+			err := runInTx(ctx, conn, status.Down,
+				`DELETE FROM schema_migrations WHERE version = $1`, status.Version)
+			if err != nil {
+				return fmt.Errorf("rollback of %d_%s failed: %w", status.Version, status.Name, err)
+			}
+			reverted = append(reverted, status.Migration)
+		}
+		return nil
+	})
+
+	return reverted, err
+}
+
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, createTableQuery); err != nil {
+		return nil, err
+	}
+
+	return m.status(ctx, conn)
+}
+
+func (m *Migrator) status(ctx context.Context, conn *sql.Conn) ([]MigrationStatus, error) {
+	migrations, err := Load(m.fsys)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := conn.QueryContext(ctx, `SELECT version, name, checksum, applied_at FROM schema_migrations ORDER BY version`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int64]appliedMigration)
+	for rows.Next() {
+		var a appliedMigration
+		if err := rows.Scan(&a.Version, &a.Name, &a.Checksum, &a.AppliedAt); err != nil {
+			return nil, err
+		}
+		applied[a.Version] = a
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return mergeStatuses(migrations, applied), nil
+}
+
+// mergeStatuses pairs the migration files with the migrations applied in the database
+func mergeStatuses(migrations []Migration, applied map[int64]appliedMigration) []MigrationStatus {
+	statuses := make([]MigrationStatus, 0, len(migrations))
+	for _, migration := range migrations {
+		status := MigrationStatus{Migration: migration}
+		if a, ok := applied[migration.Version]; ok {
+			status.Applied = true
+			status.AppliedAt = &a.AppliedAt
+			status.Drifted = a.Checksum != migration.Checksum
+			delete(applied, migration.Version)
+		}
+		statuses = append(statuses, status)
+	}
+
+	for _, a := range applied {
+		appliedAt := a.AppliedAt
+		statuses = append(statuses, MigrationStatus{
+			Migration: Migration{Version: a.Version, Name: a.Name, Checksum: a.Checksum},
+			Applied:   true,
+			AppliedAt: &appliedAt,
+			Missing:   true,
+		})
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Version < statuses[j].Version
+	})
+
+	return statuses
+}
+
+// withLock runs fn on a single connection that holds the advisory lock,
+// so that concurrent deploys apply migrations one after another
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, lockKey); err != nil {
+		return fmt.Errorf("acquiring migration lock failed: %w", err)
+	}
+	defer conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, lockKey)
+
+	if _, err := conn.ExecContext(ctx, createTableQuery); err != nil {
+		return err
+	}
+
+	return fn(conn)
+}
+
+func runInTx(ctx context.Context, conn *sql.Conn, migrationSQL, bookkeepingSQL string, args ...any) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, migrationSQL); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, bookkeepingSQL, args...); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func checkDrift(statuses []MigrationStatus) error {
+	for _, status := range statuses {
+		if status.Drifted {
+			return fmt.Errorf("migration %d_%s was changed after it had been applied", status.Version, status.Name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,198 @@
+package query
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql/driver"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// cursor is the position after the last row of a page, bound to the sort, filters and search it was made for.
+// A nil value stands for NULL
+type cursor struct {
+	Sort    string    `json:"s"`
+	Filters string    `json:"f"`
+	Values  []*string `json:"v"`
+}
+
+// keyset is the sort with id appended as a tie-breaker, so rows are totally ordered
+func (spec *Spec) keyset() []SortField {
+	for _, sort := range spec.Sort {
+		if sort.Column == "id" {
+			return spec.Sort
+		}
+	}
+	return append(append([]SortField{}, spec.Sort...), SortField{Column: "id"})
+}
+
+func (spec *Spec) sortSignature() string {
+	columns := make([]string, 0, len(spec.Sort)+1)
+	for _, sort := range spec.keyset() {
+		if sort.Desc {
+			columns = append(columns, "-"+sort.Column)
+		} else {
+			columns = append(columns, sort.Column)
+		}
+	}
+	return strings.Join(columns, ",")
+}
+
+// filterSignature hashes the filters and the search term, a cursor of one result set
+// does not point into another
+func (spec *Spec) filterSignature() string {
+	filters := make([]string, 0, len(spec.Filters))
+	for _, filter := range spec.Filters {
+		filters = append(filters, strings.Join([]string{filter.Column, filter.Condition, string(filter.Op), filter.Value}, "\x00"))
+	}
+	sort.Strings(filters)
+
+	hash := sha256.New()
+	for _, filter := range filters {
+		hash.Write([]byte(filter))
+		hash.Write([]byte{0x1e})
+	}
+	hash.Write([]byte(spec.Search))
+	return base64.RawURLEncoding.EncodeToString(hash.Sum(nil)[:12])
+}
+
+func (spec *Spec) decodeCursor(encoded string) error {
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return paramError(ErrInvalidCursor, "cursor", ErrInvalidCursor.Message)
+	}
+
+	var c cursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return paramError(ErrInvalidCursor, "cursor", ErrInvalidCursor.Message)
+	}
+	if c.Sort != spec.sortSignature() || c.Filters != spec.filterSignature() || len(c.Values) != len(spec.keyset()) {
+		return paramError(ErrInvalidCursor, "cursor", ErrInvalidCursor.Message)
+	}
+
+	spec.Cursor = c.Values
+	return nil
+}
+
+// nextCursor encodes the sort values of last, a pointer to a model
+func (spec *Spec) nextCursor(db *gorm.DB, last interface{}) (string, error) {
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(last); err != nil {
+		return "", err
+	}
+
+	keys := spec.keyset()
+	values := make([]*string, len(keys))
+	row := reflect.ValueOf(last)
+	for i, key := range keys {
+		field := stmt.Schema.LookUpField(key.Column)
+		if field == nil {
+			return "", fmt.Errorf("cursor column %q is not in %s", key.Column, stmt.Schema.Name)
+		}
+
+		value, _ := field.ValueOf(context.Background(), row)
+		encoded, err := encodeCursorValue(value)
+		if err != nil {
+			return "", fmt.Errorf("cursor column %q: %w", key.Column, err)
+		}
+		values[i] = encoded
+	}
+
+	data, err := json.Marshal(cursor{Sort: spec.sortSignature(), Filters: spec.filterSignature(), Values: values})
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// encodeCursorValue turns a sort value into text the database compares like the column.
+// Pointers, sql.Null* types and gorm.DeletedAt are unwrapped, NULL is nil
+func encodeCursorValue(value interface{}) (*string, error) {
+	rv := reflect.ValueOf(value)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return nil, nil
+		}
+		rv = rv.Elem()
+	}
+	if !rv.IsValid() {
+		return nil, nil
+	}
+
+	value = rv.Interface()
+	if valuer, ok := value.(driver.Valuer); ok {
+		var err error
+		if value, err = valuer.Value(); err != nil {
+			return nil, err
+		}
+	}
+
+	var encoded string
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case time.Time:
+		encoded = v.Format(time.RFC3339Nano)
+	default:
+		encoded = fmt.Sprint(v)
+	}
+	return &encoded, nil
+}
+
+// afterCursor keeps rows that sort after the cursor: (a > ?) OR (a = ? AND b > ?) OR ...
+// Postgres sorts NULL last in ascending and first in descending order, the comparisons follow it
+func (spec *Spec) afterCursor(db *gorm.DB) *gorm.DB {
+	keys := spec.keyset()
+	clauses := make([]string, 0, len(keys))
+	var args []interface{}
+
+	for i, key := range keys {
+		after, afterArgs := sortsAfter(key, spec.Cursor[i])
+		if after == "" {
+			continue
+		}
+
+		parts := make([]string, 0, i+1)
+		for j := 0; j < i; j++ {
+			if spec.Cursor[j] == nil {
+				parts = append(parts, keys[j].Column+" IS NULL")
+			} else {
+				parts = append(parts, keys[j].Column+" = ?")
+				args = append(args, *spec.Cursor[j])
+			}
+		}
+		parts = append(parts, after)
+		args = append(args, afterArgs...)
+
+		clauses = append(clauses, "("+strings.Join(parts, " AND ")+")")
+	}
+
+	if len(clauses) == 0 {
+		return db.Where("FALSE")
+	}
+	return db.Where("("+strings.Join(clauses, " OR ")+")", args...)
+}
+
+// sortsAfter is the condition of key values sorting after value, empty when none does
+func sortsAfter(key SortField, value *string) (string, []interface{}) {
+	switch {
+	case value == nil && key.Desc:
+		return key.Column + " IS NOT NULL", nil
+	case value == nil:
+		return "", nil
+	case key.Desc:
+		return key.Column + " < ?", []interface{}{*value}
+	case key.Column == "id":
+		// the primary key is never NULL
+		return key.Column + " > ?", []interface{}{*value}
+	default:
+		return "(" + key.Column + " > ? OR " + key.Column + " IS NULL)", []interface{}{*value}
+	}
+}
@@ -0,0 +1,217 @@
+package query_test
+
+import (
+	"errors"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/serhiirubets/rubeticket/internal/pkg/query"
+	"gorm.io/gorm"
+)
+
+type testConcert struct {
+	ID        uint
+	Title     string
+	Date      time.Time
+	Capacity  *int
+	DeletedAt gorm.DeletedAt
+}
+
+var (
+	capacity     = 300
+	rockNight    = testConcert{ID: 4, Title: "Rock night", Date: time.Date(2025, 3, 1, 20, 0, 0, 0, time.UTC), Capacity: &capacity}
+	jazzNight    = testConcert{ID: 9, Title: "Jazz night", Date: time.Date(2025, 2, 1, 20, 0, 0, 0, time.UTC)}
+	deletedNight = testConcert{
+		ID:        7,
+		Title:     "Folk night",
+		DeletedAt: gorm.DeletedAt{Time: time.Date(2025, 4, 1, 12, 30, 0, 0, time.UTC), Valid: true},
+	}
+)
+
+func ptr(value string) *string {
+	return &value
+}
+
+// nextCursor returns the cursor of a page of one row, last, listed with params
+func nextCursor(t *testing.T, params string, last testConcert) string {
+	t.Helper()
+	values, err := url.ParseQuery(params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	spec, err := query.ParseSpec(values, testSchema)
+	if err != nil {
+		t.Fatalf("ParseSpec() error = %v", err)
+	}
+	spec.PageSize = 1
+
+	items := []testConcert{last, {ID: 100}}
+	_, meta, err := query.NewPage(dryRunDB(t), spec, items, 2)
+	if err != nil {
+		t.Fatalf("NewPage() error = %v", err)
+	}
+	if !meta.HasNext || meta.NextCursor == "" {
+		t.Fatalf("NewPage() meta = %+v, want a next cursor", meta)
+	}
+	return meta.NextCursor
+}
+
+func TestCursor(t *testing.T) {
+	tests := []struct {
+		name       string
+		params     string
+		nextParams string
+		last       testConcert
+		wantCursor []*string
+		wantErr    bool
+	}{
+		{
+			name:       "same sort",
+			params:     "",
+			nextParams: "",
+			wantCursor: []*string{ptr("2025-03-01T20:00:00Z"), ptr("4")},
+		},
+		{
+			name:       "same filters in another order",
+			params:     "venueId=1&title[like]=night&q=rock",
+			nextParams: "q=rock&title[like]=night&venueId=1",
+			wantCursor: []*string{ptr("2025-03-01T20:00:00Z"), ptr("4")},
+		},
+		{
+			name:       "explicit sort by title",
+			params:     "sort=title",
+			nextParams: "sort=title",
+			wantCursor: []*string{ptr("Rock night"), ptr("4")},
+		},
+		{
+			name:       "pointer column",
+			params:     "sort=capacity",
+			nextParams: "sort=capacity",
+			wantCursor: []*string{ptr("300"), ptr("4")},
+		},
+		{
+			name:       "null pointer column",
+			params:     "sort=capacity",
+			nextParams: "sort=capacity",
+			last:       jazzNight,
+			wantCursor: []*string{nil, ptr("9")},
+		},
+		{
+			name:       "deleted at column",
+			params:     "sort=-deletedAt",
+			nextParams: "sort=-deletedAt",
+			last:       deletedNight,
+			wantCursor: []*string{ptr("2025-04-01T12:30:00Z"), ptr("7")},
+		},
+		{name: "other sort", params: "", nextParams: "sort=title", wantErr: true},
+		{name: "other sort direction", params: "sort=title", nextParams: "sort=-title", wantErr: true},
+		{name: "filter added", params: "", nextParams: "venueId=1", wantErr: true},
+		{name: "filter removed", params: "venueId=1", nextParams: "", wantErr: true},
+		{name: "filter value changed", params: "venueId=1", nextParams: "venueId=2", wantErr: true},
+		{name: "filter operator changed", params: "date[gte]=2025-01-01", nextParams: "date[lt]=2025-01-01", wantErr: true},
+		{name: "search changed", params: "q=rock", nextParams: "q=jazz", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			last := tt.last
+			if last.ID == 0 {
+				last = rockNight
+			}
+			values, err := url.ParseQuery(tt.nextParams)
+			if err != nil {
+				t.Fatal(err)
+			}
+			values.Set("cursor", nextCursor(t, tt.params, last))
+
+			spec, err := query.ParseSpec(values, testSchema)
+			if tt.wantErr {
+				if !errors.Is(err, query.ErrInvalidCursor) {
+					t.Fatalf("ParseSpec() error = %v, want %v", err, query.ErrInvalidCursor)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseSpec() error = %v", err)
+			}
+			if !reflect.DeepEqual(spec.Cursor, tt.wantCursor) {
+				t.Errorf("cursor = %v, want %v", spec.Cursor, tt.wantCursor)
+			}
+			if spec.Page != 0 || spec.Offset() != 0 {
+				t.Errorf("page = %d, offset = %d, want both 0 for cursor pages", spec.Page, spec.Offset())
+			}
+		})
+	}
+}
+
+func TestMalformedCursor(t *testing.T) {
+	for _, encoded := range []string{"not base64!", "bm90IGpzb24", "e30"} {
+		t.Run(encoded, func(t *testing.T) {
+			_, err := query.ParseSpec(url.Values{"cursor": {encoded}}, testSchema)
+			if !errors.Is(err, query.ErrInvalidCursor) {
+				t.Errorf("ParseSpec() error = %v, want %v", err, query.ErrInvalidCursor)
+			}
+		})
+	}
+}
+
+func TestAfterCursor(t *testing.T) {
+	tests := []struct {
+		name     string
+		sort     string
+		last     testConcert
+		wantSQL  string
+		wantVars []interface{}
+	}{
+		{
+			name:     "ascending",
+			sort:     "title",
+			last:     rockNight,
+			wantSQL:  `SELECT * FROM "concerts" WHERE (((title > $1 OR title IS NULL)) OR (title = $2 AND id > $3)) ORDER BY title,id LIMIT $4`,
+			wantVars: []interface{}{"Rock night", "Rock night", "4", 11},
+		},
+		{
+			name:     "ascending after null",
+			sort:     "capacity",
+			last:     jazzNight,
+			wantSQL:  `SELECT * FROM "concerts" WHERE ((capacity IS NULL AND id > $1)) ORDER BY capacity,id LIMIT $2`,
+			wantVars: []interface{}{"9", 11},
+		},
+		{
+			name:     "descending after null",
+			sort:     "-capacity",
+			last:     jazzNight,
+			wantSQL:  `SELECT * FROM "concerts" WHERE ((capacity IS NOT NULL) OR (capacity IS NULL AND id > $1)) ORDER BY capacity DESC,id LIMIT $2`,
+			wantVars: []interface{}{"9", 11},
+		},
+		{
+			name:     "descending by deleted at",
+			sort:     "-deletedAt",
+			last:     deletedNight,
+			wantSQL:  `SELECT * FROM "concerts" WHERE ((deleted_at < $1) OR (deleted_at = $2 AND id > $3)) ORDER BY deleted_at DESC,id LIMIT $4`,
+			wantVars: []interface{}{"2025-04-01T12:30:00Z", "2025-04-01T12:30:00Z", "7", 11},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values := url.Values{"sort": {tt.sort}}
+			values.Set("cursor", nextCursor(t, "sort="+tt.sort, tt.last))
+			spec, err := query.ParseSpec(values, testSchema)
+			if err != nil {
+				t.Fatalf("ParseSpec() error = %v", err)
+			}
+
+			var rows []map[string]interface{}
+			stmt := spec.Paginate(spec.Where(dryRunDB(t).Table("concerts"), testSchema)).Find(&rows).Statement
+			if got := stmt.SQL.String(); got != tt.wantSQL {
+				t.Errorf("SQL = %s\nwant  %s", got, tt.wantSQL)
+			}
+			if !reflect.DeepEqual(stmt.Vars, tt.wantVars) {
+				t.Errorf("vars = %#v, want %#v", stmt.Vars, tt.wantVars)
+			}
+		})
+	}
+}
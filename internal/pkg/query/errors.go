@@ -0,0 +1,16 @@
+package query
+
+import "github.com/serhiirubets/rubeticket/internal/pkg/apperr"
+
+var (
+	ErrInvalidCursor      = apperr.New(apperr.Invalid, "invalid_cursor", "invalid cursor")
+	ErrUnsupportedSort    = apperr.New(apperr.Invalid, "unsupported_sort", "sorting is not supported")
+	ErrUnsupportedInclude = apperr.New(apperr.Invalid, "unsupported_include", "including is not supported")
+	ErrUnsupportedFilter  = apperr.New(apperr.Invalid, "unsupported_filter", "filter is not supported")
+	ErrInvalidFilterValue = apperr.New(apperr.Invalid, "invalid_filter_value", "invalid filter value")
+)
+
+// paramError points err to the query param that caused it
+func paramError(err *apperr.Error, param string, message string) *apperr.Error {
+	return err.WithMessage(message).WithFields(apperr.FieldError{Field: param, Code: err.Code, Message: message})
+}
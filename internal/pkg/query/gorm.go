@@ -0,0 +1,67 @@
+package query
+
+import (
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// Trashed selects only soft deleted rows
+func Trashed(db *gorm.DB) *gorm.DB {
+	return db.Unscoped().Where("deleted_at IS NOT NULL")
+}
+
+// Where applies filters and search. Columns come from the schema allow-list, values are always bound
+func (spec *Spec) Where(db *gorm.DB, schema Schema) *gorm.DB {
+	for _, filter := range spec.Filters {
+		switch {
+		case filter.Condition != "":
+			db = db.Where(filter.Condition, filter.Value)
+		case filter.Op == In:
+			db = db.Where(filter.Column+" IN ?", strings.Split(filter.Value, ","))
+		case filter.Op == Like:
+			db = db.Where(filter.Column+" ILIKE ?", "%"+likeEscaper.Replace(filter.Value)+"%")
+		default:
+			db = db.Where(filter.Column+" "+sqlOperators[filter.Op]+" ?", filter.Value)
+		}
+	}
+
+	if spec.Search != "" && len(schema.Search) > 0 {
+		conditions := make([]string, len(schema.Search))
+		args := make([]interface{}, len(schema.Search))
+		term := "%" + likeEscaper.Replace(spec.Search) + "%"
+		for i, column := range schema.Search {
+			conditions[i] = column + " ILIKE ?"
+			args[i] = term
+		}
+		db = db.Where("("+strings.Join(conditions, " OR ")+")", args...)
+	}
+
+	return db
+}
+
+// Paginate applies sorting, cursor or offset and limit. Call it after counting, postgres does not allow ORDER BY in count queries.
+// One extra row is fetched so NewPage knows whether there is a next page
+func (spec *Spec) Paginate(db *gorm.DB) *gorm.DB {
+	if spec.Cursor != nil {
+		db = spec.afterCursor(db)
+	}
+	for _, sort := range spec.keyset() {
+		if sort.Desc {
+			db = db.Order(sort.Column + " DESC")
+		} else {
+			db = db.Order(sort.Column)
+		}
+	}
+	return db.Offset(spec.Offset()).Limit(spec.PageSize + 1)
+}
+
+// Preload loads included associations, gorm runs one IN query per association for the whole page
+func (spec *Spec) Preload(db *gorm.DB) *gorm.DB {
+	for _, association := range spec.Include {
+		db = db.Preload(association)
+	}
+	return db
+}
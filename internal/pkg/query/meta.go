@@ -0,0 +1,33 @@
+package query
+
+import "gorm.io/gorm"
+
+// PageMeta is embedded into list responses. Page is 0 when the list was requested by cursor
+type PageMeta struct {
+	Total      int64  `json:"total"`
+	Page       int    `json:"page"`
+	PageSize   int    `json:"pageSize"`
+	HasNext    bool   `json:"hasNext"`
+	NextCursor string `json:"nextCursor,omitempty"`
+}
+
+// NewPage trims the extra row fetched by Paginate and builds the page meta with a cursor to the next page
+func NewPage[T any](db *gorm.DB, spec *Spec, items []T, total int64) ([]T, PageMeta, error) {
+	meta := PageMeta{
+		Total:    total,
+		Page:     spec.Page,
+		PageSize: spec.PageSize,
+	}
+
+	if len(items) > spec.PageSize {
+		items = items[:spec.PageSize]
+		nextCursor, err := spec.nextCursor(db, &items[len(items)-1])
+		if err != nil {
+			return nil, PageMeta{}, err
+		}
+		meta.HasNext = true
+		meta.NextCursor = nextCursor
+	}
+
+	return items, meta, nil
+}
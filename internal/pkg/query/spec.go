@@ -0,0 +1,254 @@
+package query
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultPageSize = 10
+	MaxPageSize     = 100
+)
+
+type Operator string
+
+const (
+	Eq   Operator = "eq"
+	Ne   Operator = "ne"
+	Gt   Operator = "gt"
+	Gte  Operator = "gte"
+	Lt   Operator = "lt"
+	Lte  Operator = "lte"
+	Like Operator = "like"
+	In   Operator = "in"
+)
+
+var sqlOperators = map[Operator]string{
+	Eq:   "=",
+	Ne:   "<>",
+	Gt:   ">",
+	Gte:  ">=",
+	Lt:   "<",
+	Lte:  "<=",
+	Like: "ILIKE",
+	In:   "IN",
+}
+
+// reservedParams are list params that are not filters
+var reservedParams = map[string]bool{
+	"page":     true,
+	"pageSize": true,
+	"sort":     true,
+	"q":        true,
+	"cursor":   true,
+	"include":  true,
+}
+
+// filterKeyPattern matches "field" and "field[op]"
+var filterKeyPattern = regexp.MustCompile(`^(\w+)(?:\[(\w+)\])?$`)
+
+// FieldType is what filter values must parse as, so that bad input is a 400 and not a DB error
+type FieldType int
+
+const (
+	String FieldType = iota
+	Int
+	// Time accepts RFC 3339 timestamps and dates like 2025-03-01
+	Time
+)
+
+// Field describes a filterable column and operators allowed for it, only Eq when Ops is empty.
+// Condition replaces the column comparison with SQL taking the value as its only argument, Eq only
+type Field struct {
+	Column    string
+	Ops       []Operator
+	Condition string
+	Type      FieldType
+}
+
+// Schema is an allow-list of what a list endpoint can be filtered, sorted and searched by.
+// Keys are API names, values are DB columns
+type Schema struct {
+	Filters map[string]Field
+	Sort    map[string]string
+	Search  []string
+	// DefaultSort is a column, prefixed with - for descending
+	DefaultSort string
+	// Includes maps ?include names to associations that can be preloaded
+	Includes map[string]string
+}
+
+// WithSort returns a copy of the schema that can also be sorted by name
+func (s Schema) WithSort(name, column string) Schema {
+	sort := make(map[string]string, len(s.Sort)+1)
+	for key, value := range s.Sort {
+		sort[key] = value
+	}
+	sort[name] = column
+	s.Sort = sort
+	return s
+}
+
+type Filter struct {
+	Column    string
+	Op        Operator
+	Value     string
+	Condition string
+}
+
+type SortField struct {
+	Column string
+	Desc   bool
+}
+
+type Spec struct {
+	Page     int
+	PageSize int
+	Filters  []Filter
+	Sort     []SortField
+	Search   string
+	// Cursor holds keyset values from the previous page, nil for NULL. Offset is ignored when it is set
+	Cursor []*string
+	// Include holds associations to preload
+	Include []string
+}
+
+// ParseSpec reads list params from the query string:
+// ?page=2&pageSize=20&sort=-date,title&q=metal&venueId=3&date[gte]=2025-03-01
+// Pass ?cursor=<nextCursor> instead of page to continue from the previous page without offsets,
+// ?include=venue,bands preloads relations listed in the schema
+func ParseSpec(values url.Values, schema Schema) (*Spec, error) {
+	spec := &Spec{
+		Page:     1,
+		PageSize: DefaultPageSize,
+		Search:   strings.TrimSpace(values.Get("q")),
+	}
+
+	if page, err := strconv.Atoi(values.Get("page")); err == nil && page > 0 {
+		spec.Page = page
+	}
+	if pageSize, err := strconv.Atoi(values.Get("pageSize")); err == nil && pageSize > 0 && pageSize <= MaxPageSize {
+		spec.PageSize = pageSize
+	}
+
+	if sortParam := values.Get("sort"); sortParam != "" {
+		for _, name := range strings.Split(sortParam, ",") {
+			name = strings.TrimSpace(name)
+			desc := strings.HasPrefix(name, "-")
+			name = strings.TrimPrefix(name, "-")
+
+			column, ok := schema.Sort[name]
+			if !ok {
+				return nil, paramError(ErrUnsupportedSort, "sort", fmt.Sprintf("sorting by %q is not supported", name))
+			}
+			spec.Sort = append(spec.Sort, SortField{Column: column, Desc: desc})
+		}
+	}
+
+	if includeParam := values.Get("include"); includeParam != "" {
+		for _, name := range strings.Split(includeParam, ",") {
+			name = strings.TrimSpace(name)
+			association, ok := schema.Includes[name]
+			if !ok {
+				return nil, paramError(ErrUnsupportedInclude, "include", fmt.Sprintf("including %q is not supported", name))
+			}
+			spec.Include = append(spec.Include, association)
+		}
+	}
+
+	for key, vals := range values {
+		if reservedParams[key] || len(vals) == 0 {
+			continue
+		}
+
+		matches := filterKeyPattern.FindStringSubmatch(key)
+		if matches == nil {
+			return nil, paramError(ErrUnsupportedFilter, key, fmt.Sprintf("invalid filter %q", key))
+		}
+
+		field, ok := schema.Filters[matches[1]]
+		if !ok {
+			return nil, paramError(ErrUnsupportedFilter, key, fmt.Sprintf("filtering by %q is not supported", matches[1]))
+		}
+
+		op := Eq
+		if matches[2] != "" {
+			op = Operator(matches[2])
+		}
+		if !field.allows(op) {
+			return nil, paramError(ErrUnsupportedFilter, key, fmt.Sprintf("operator %q is not supported for %q", op, matches[1]))
+		}
+		if err := field.check(op, vals[0]); err != nil {
+			return nil, paramError(ErrInvalidFilterValue, key, fmt.Sprintf("%q %s", matches[1], err.Error()))
+		}
+
+		spec.Filters = append(spec.Filters, Filter{Column: field.Column, Op: op, Value: vals[0], Condition: field.Condition})
+	}
+
+	if len(spec.Sort) == 0 && schema.DefaultSort != "" {
+		spec.Sort = []SortField{{
+			Column: strings.TrimPrefix(schema.DefaultSort, "-"),
+			Desc:   strings.HasPrefix(schema.DefaultSort, "-"),
+		}}
+	}
+
+	if encoded := values.Get("cursor"); encoded != "" {
+		if err := spec.decodeCursor(encoded); err != nil {
+			return nil, err
+		}
+		spec.Page = 0
+	}
+
+	return spec, nil
+}
+
+func (spec *Spec) Offset() int {
+	if spec.Cursor != nil {
+		return 0
+	}
+	return (spec.Page - 1) * spec.PageSize
+}
+
+func (f Field) allows(op Operator) bool {
+	if _, ok := sqlOperators[op]; !ok {
+		return false
+	}
+	if len(f.Ops) == 0 {
+		return op == Eq
+	}
+	for _, allowed := range f.Ops {
+		if allowed == op {
+			return true
+		}
+	}
+	return false
+}
+
+// check parses every value of the filter as the field type
+func (f Field) check(op Operator, value string) error {
+	values := []string{value}
+	if op == In {
+		values = strings.Split(value, ",")
+	}
+
+	for _, v := range values {
+		switch f.Type {
+		case Int:
+			if _, err := strconv.ParseInt(v, 10, 64); err != nil {
+				return fmt.Errorf("must be a whole number, got %q", v)
+			}
+		case Time:
+			if _, err := time.Parse(time.RFC3339, v); err == nil {
+				continue
+			}
+			if _, err := time.Parse(time.DateOnly, v); err != nil {
+				return fmt.Errorf("must be a date like 2025-03-01 or an RFC 3339 time, got %q", v)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,231 @@
+package query_test
+
+import (
+	"errors"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/serhiirubets/rubeticket/internal/pkg/apperr"
+	"github.com/serhiirubets/rubeticket/internal/pkg/query"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+var testSchema = query.Schema{
+	Filters: map[string]query.Field{
+		"title":   {Column: "title", Ops: []query.Operator{query.Eq, query.Like}},
+		"venueId": {Column: "venue_id", Ops: []query.Operator{query.Eq, query.In}, Type: query.Int},
+		"date":    {Column: "date", Ops: []query.Operator{query.Gte, query.Lt}, Type: query.Time},
+		"genreId": {Condition: "id IN (SELECT band_id FROM band_genres WHERE genre_id = ?)", Type: query.Int},
+	},
+	Sort: map[string]string{
+		"id":        "id",
+		"title":     "title",
+		"date":      "date",
+		"capacity":  "capacity",
+		"deletedAt": "deleted_at",
+	},
+	Search:      []string{"title", "description"},
+	DefaultSort: "-date",
+	Includes:    map[string]string{"venue": "Venue"},
+}
+
+func TestParseSpec(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		want    *query.Spec
+		wantErr error
+	}{
+		{
+			name:  "defaults",
+			query: "",
+			want: &query.Spec{
+				Page: 1, PageSize: query.DefaultPageSize,
+				Sort: []query.SortField{{Column: "date", Desc: true}},
+			},
+		},
+		{
+			name:  "page, size, sort, search and include",
+			query: "page=3&pageSize=20&sort=-title,id&q=+metal+&include=venue",
+			want: &query.Spec{
+				Page: 3, PageSize: 20, Search: "metal",
+				Sort:    []query.SortField{{Column: "title", Desc: true}, {Column: "id"}},
+				Include: []string{"Venue"},
+			},
+		},
+		{
+			name:  "invalid page and too big page size fall back to defaults",
+			query: "page=-1&pageSize=1000",
+			want: &query.Spec{
+				Page: 1, PageSize: query.DefaultPageSize,
+				Sort: []query.SortField{{Column: "date", Desc: true}},
+			},
+		},
+		{
+			name:  "filters with operators",
+			query: "title[like]=rock&venueId[in]=1,2&date[gte]=2025-03-01",
+			want: &query.Spec{
+				Page: 1, PageSize: query.DefaultPageSize,
+				Sort: []query.SortField{{Column: "date", Desc: true}},
+				Filters: []query.Filter{
+					{Column: "date", Op: query.Gte, Value: "2025-03-01"},
+					{Column: "title", Op: query.Like, Value: "rock"},
+					{Column: "venue_id", Op: query.In, Value: "1,2"},
+				},
+			},
+		},
+		{
+			name:  "condition filter",
+			query: "genreId=7",
+			want: &query.Spec{
+				Page: 1, PageSize: query.DefaultPageSize,
+				Sort: []query.SortField{{Column: "date", Desc: true}},
+				Filters: []query.Filter{
+					{Op: query.Eq, Value: "7", Condition: "id IN (SELECT band_id FROM band_genres WHERE genre_id = ?)"},
+				},
+			},
+		},
+		{
+			name:  "RFC 3339 time",
+			query: "date[lt]=2025-03-01T20:00:00Z",
+			want: &query.Spec{
+				Page: 1, PageSize: query.DefaultPageSize,
+				Sort:    []query.SortField{{Column: "date", Desc: true}},
+				Filters: []query.Filter{{Column: "date", Op: query.Lt, Value: "2025-03-01T20:00:00Z"}},
+			},
+		},
+		{name: "unsupported sort", query: "sort=description", wantErr: query.ErrUnsupportedSort},
+		{name: "unsupported include", query: "include=bands", wantErr: query.ErrUnsupportedInclude},
+		{name: "unknown filter", query: "description=x", wantErr: query.ErrUnsupportedFilter},
+		{name: "malformed filter key", query: "title[like=x", wantErr: query.ErrUnsupportedFilter},
+		{name: "operator not allowed for field", query: "title[gt]=x", wantErr: query.ErrUnsupportedFilter},
+		{name: "unknown operator", query: "title[regex]=x", wantErr: query.ErrUnsupportedFilter},
+		{name: "text for int", query: "venueId=abc", wantErr: query.ErrInvalidFilterValue},
+		{name: "text in int list", query: "venueId[in]=1,x", wantErr: query.ErrInvalidFilterValue},
+		{name: "text for time", query: "date[gte]=abc", wantErr: query.ErrInvalidFilterValue},
+		{name: "text for condition", query: "genreId=x", wantErr: query.ErrInvalidFilterValue},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values, err := url.ParseQuery(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			spec, err := query.ParseSpec(values, testSchema)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("ParseSpec() error = %v, want %v", err, tt.wantErr)
+				}
+				if appErr := apperr.As(err); appErr == nil || appErr.Kind != apperr.Invalid {
+					t.Errorf("ParseSpec() error = %v, want a validation error", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseSpec() error = %v", err)
+			}
+
+			// map iteration order is random, filters are compared by column
+			sortFilters(spec.Filters)
+			if !reflect.DeepEqual(spec, tt.want) {
+				t.Errorf("ParseSpec() = %+v, want %+v", spec, tt.want)
+			}
+		})
+	}
+}
+
+func TestWhereAndPaginate(t *testing.T) {
+	conn := dryRunDB(t)
+
+	tests := []struct {
+		name     string
+		query    string
+		wantSQL  string
+		wantVars []interface{}
+	}{
+		{
+			name:     "no filters",
+			query:    "",
+			wantSQL:  `SELECT * FROM "concerts" ORDER BY date DESC,id LIMIT $1`,
+			wantVars: []interface{}{11},
+		},
+		{
+			name:     "comparison and in",
+			query:    "venueId[in]=1,2&date[gte]=2025-03-01",
+			wantSQL:  `SELECT * FROM "concerts" WHERE date >= $1 AND venue_id IN ($2,$3) ORDER BY date DESC,id LIMIT $4`,
+			wantVars: []interface{}{"2025-03-01", "1", "2", 11},
+		},
+		{
+			name:     "like escapes wildcards",
+			query:    "title[like]=50%25_off",
+			wantSQL:  `SELECT * FROM "concerts" WHERE title ILIKE $1 ORDER BY date DESC,id LIMIT $2`,
+			wantVars: []interface{}{`%50\%\_off%`, 11},
+		},
+		{
+			name:     "condition",
+			query:    "genreId=7",
+			wantSQL:  `SELECT * FROM "concerts" WHERE id IN (SELECT band_id FROM band_genres WHERE genre_id = $1) ORDER BY date DESC,id LIMIT $2`,
+			wantVars: []interface{}{"7", 11},
+		},
+		{
+			name:     "search over all search columns",
+			query:    "q=rock&sort=title",
+			wantSQL:  `SELECT * FROM "concerts" WHERE (title ILIKE $1 OR description ILIKE $2) ORDER BY title,id LIMIT $3`,
+			wantVars: []interface{}{"%rock%", "%rock%", 11},
+		},
+		{
+			name:     "offset of later pages",
+			query:    "page=3&pageSize=5&sort=id",
+			wantSQL:  `SELECT * FROM "concerts" ORDER BY id LIMIT $1 OFFSET $2`,
+			wantVars: []interface{}{6, 10},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values, err := url.ParseQuery(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			spec, err := query.ParseSpec(values, testSchema)
+			if err != nil {
+				t.Fatalf("ParseSpec() error = %v", err)
+			}
+			sortFilters(spec.Filters)
+
+			var rows []map[string]interface{}
+			stmt := spec.Paginate(spec.Where(conn.Table("concerts"), testSchema)).Find(&rows).Statement
+			if got := stmt.SQL.String(); got != tt.wantSQL {
+				t.Errorf("SQL = %s\nwant  %s", got, tt.wantSQL)
+			}
+			if !reflect.DeepEqual(stmt.Vars, tt.wantVars) {
+				t.Errorf("vars = %#v, want %#v", stmt.Vars, tt.wantVars)
+			}
+		})
+	}
+}
+
+// dryRunDB builds SQL without connecting to a database
+func dryRunDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	conn, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}), &gorm.Config{
+		DryRun:               true,
+		DisableAutomaticPing: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return conn
+}
+
+func sortFilters(filters []query.Filter) {
+	for i := 1; i < len(filters); i++ {
+		for j := i; j > 0 && filters[j].Column < filters[j-1].Column; j-- {
+			filters[j], filters[j-1] = filters[j-1], filters[j]
+		}
+	}
+}
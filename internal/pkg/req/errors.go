@@ -0,0 +1,9 @@
+package req
+
+import "github.com/serhiirubets/rubeticket/internal/pkg/apperr"
+
+var (
+	ErrInvalidBody  = apperr.New(apperr.Invalid, "invalid_body", "request body is not valid JSON")
+	ErrValidation   = apperr.New(apperr.Invalid, "validation_failed", "request has invalid fields")
+	ErrInvalidParam = apperr.New(apperr.Invalid, "invalid_param", "request has an invalid param")
+)
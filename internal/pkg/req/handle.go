@@ -1,23 +1,56 @@
 package req
 
 import (
-	"github.com/serhiirubets/rubeticket/internal/pkg/res"
+	"encoding/json"
+	"errors"
 	"net/http"
+	"reflect"
+
+	"github.com/serhiirubets/rubeticket/internal/pkg/apperr"
+	"github.com/serhiirubets/rubeticket/internal/pkg/res"
 )
 
+// HandleBody decodes and validates the body. On failure it writes the problem itself,
+// listing the fields that failed, and the handler only has to return
 func HandleBody[T any](w *http.ResponseWriter, r *http.Request) (*T, error) {
 	body, err := Decode[T](r.Body)
 	if err != nil {
-		res.Json(*w, err.Error(), http.StatusBadRequest)
-		return nil, err
+		problem := ErrInvalidBody.Wrap(err)
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) && typeErr.Field != "" {
+			problem = problem.WithFields(apperr.FieldError{
+				Field:   typeErr.Field,
+				Code:    "type",
+				Message: "must be " + jsonType(typeErr.Type),
+			})
+		}
+		res.Error(*w, r, problem)
+		return nil, problem
 	}
 
 	err = IsValid(body)
-
 	if err != nil {
-		res.Json(*w, err.Error(), http.StatusBadRequest)
-		return nil, err
+		problem := ErrValidation.Wrap(err).WithFields(ValidationFields(err)...)
+		res.Error(*w, r, problem)
+		return nil, problem
 	}
 
 	return &body, nil
 }
+
+func jsonType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.String:
+		return "a string"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	case reflect.Map, reflect.Struct:
+		return "an object"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	default:
+		return "a whole number"
+	}
+}
@@ -0,0 +1,26 @@
+package req
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/serhiirubets/rubeticket/internal/pkg/apperr"
+)
+
+// BoolParam reads a boolean query param, a missing param is false
+func BoolParam(r *http.Request, name string) (bool, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return false, nil
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, ErrInvalidParam.WithMessage(fmt.Sprintf("invalid %s: %q", name, value)).WithFields(apperr.FieldError{
+			Field:   name,
+			Code:    "type",
+			Message: "must be true or false",
+		})
+	}
+	return parsed, nil
+}
@@ -1,10 +1,34 @@
 package req
 
 import (
-	"github.com/go-playground/validator/v10"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
 	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/serhiirubets/rubeticket/internal/pkg/apperr"
 )
 
+// validate reports fields by their json names, so clients can match errors to what they sent
+var validate = newValidator()
+
+func newValidator() *validator.Validate {
+	v := validator.New()
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
+	if err := v.RegisterValidation("date_iso8601", validateDateISO8601); err != nil {
+		panic(err)
+	}
+	return v
+}
+
 func validateDateISO8601(fl validator.FieldLevel) bool {
 	dateStr := fl.Field().String()
 
@@ -14,11 +38,80 @@ func validateDateISO8601(fl validator.FieldLevel) bool {
 }
 
 func IsValid[T any](payload T) error {
-	validate := validator.New()
-	err := validate.RegisterValidation("date_iso8601", validateDateISO8601)
-	if err != nil {
-		return err
+	return validate.Struct(payload)
+}
+
+// ValidationFields turns validator errors into field errors, it returns nil for other errors
+func ValidationFields(err error) []apperr.FieldError {
+	var fieldErrors validator.ValidationErrors
+	if !errors.As(err, &fieldErrors) {
+		return nil
+	}
+
+	fields := make([]apperr.FieldError, len(fieldErrors))
+	for i, fieldError := range fieldErrors {
+		fields[i] = apperr.FieldError{
+			Field:   fieldPath(fieldError.Namespace()),
+			Code:    fieldError.Tag(),
+			Message: validationMessage(fieldError),
+		}
+	}
+	return fields
+}
+
+// fieldPath turns "CreateConcertRequest.lineup[0].bandId" into "lineup[0].bandId". Structs and
+// embedded structs have no json name and keep their Go names, which start upper case
+func fieldPath(namespace string) string {
+	var parts []string
+	for _, part := range strings.Split(namespace, ".") {
+		if part != "" && strings.ToUpper(part[:1]) == part[:1] {
+			continue
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, ".")
+}
+
+func validationMessage(fieldError validator.FieldError) string {
+	param := fieldError.Param()
+	isLength := false
+	switch fieldError.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		isLength = true
+	}
+
+	switch fieldError.Tag() {
+	case "required", "required_with", "required_without":
+		return "is required"
+	case "email":
+		return "must be an email address"
+	case "url":
+		return "must be a URL"
+	case "alphaunicode":
+		return "must contain letters only"
+	case "iso3166_1_alpha2":
+		return "must be a two letter country code"
+	case "iso4217":
+		return "must be a currency code"
+	case "timezone":
+		return "must be an IANA time zone"
+	case "date_iso8601":
+		return "must be a date in YYYY-MM-DD format"
+	case "oneof":
+		return "must be one of " + strings.ReplaceAll(param, " ", ", ")
+	case "min":
+		if isLength {
+			return fmt.Sprintf("must have at least %s characters or items", param)
+		}
+		return "must be at least " + param
+	case "max":
+		if isLength {
+			return fmt.Sprintf("must have at most %s characters or items", param)
+		}
+		return "must be at most " + param
+	}
+	if param != "" {
+		return fmt.Sprintf("must satisfy %s=%s", fieldError.Tag(), param)
 	}
-	err = validate.Struct(payload)
-	return err
+	return "must satisfy " + fieldError.Tag()
 }
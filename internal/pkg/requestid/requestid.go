@@ -0,0 +1,50 @@
+// Package requestid keeps the ID of the current request in its context,
+// so errors and logs can be matched to the request a client reports
+package requestid
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// Header carries the request ID in both directions, a valid ID sent by a client or proxy is kept
+const Header = "X-Request-ID"
+
+const maxLength = 128
+
+type contextKey struct{}
+
+func New() string {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(id)
+}
+
+// Valid accepts IDs of up to 128 letters, digits, dashes, dots and underscores,
+// anything else could be used to forge log lines
+func Valid(id string) bool {
+	if id == "" || len(id) > maxLength {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '-', c == '.', c == '_':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the request ID, or "" outside of a request
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
@@ -0,0 +1,86 @@
+package res
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/serhiirubets/rubeticket/internal/pkg/apperr"
+	"github.com/serhiirubets/rubeticket/internal/pkg/requestid"
+)
+
+const problemContentType = "application/problem+json"
+
+// Problem is an RFC 7807 error body. Code is stable and meant for clients to branch on,
+// Errors lists the fields that failed validation
+type Problem struct {
+	Type      string              `json:"type"`
+	Title     string              `json:"title"`
+	Status    int                 `json:"status"`
+	Detail    string              `json:"detail,omitempty"`
+	Instance  string              `json:"instance,omitempty"`
+	Code      string              `json:"code"`
+	Errors    []apperr.FieldError `json:"errors,omitempty"`
+	RequestID string              `json:"requestId,omitempty"`
+}
+
+var statuses = map[apperr.Kind]int{
+	apperr.Internal:             http.StatusInternalServerError,
+	apperr.Invalid:              http.StatusBadRequest,
+	apperr.Unauthorized:         http.StatusUnauthorized,
+	apperr.Forbidden:            http.StatusForbidden,
+	apperr.NotFound:             http.StatusNotFound,
+	apperr.Conflict:             http.StatusConflict,
+	apperr.Gone:                 http.StatusGone,
+	apperr.PreconditionFailed:   http.StatusPreconditionFailed,
+	apperr.TooLarge:             http.StatusRequestEntityTooLarge,
+	apperr.UnsupportedMediaType: http.StatusUnsupportedMediaType,
+	apperr.Unprocessable:        http.StatusUnprocessableEntity,
+	apperr.PreconditionRequired: http.StatusPreconditionRequired,
+}
+
+var (
+	errInternal = apperr.New(apperr.Internal, "internal_error", "internal server error")
+	errTooLarge = apperr.New(apperr.TooLarge, "request_too_large", "request body is too large")
+)
+
+// Error writes err as a problem. Errors other than *apperr.Error are answered with a generic 500,
+// so their messages never reach clients and should be logged by the caller
+func Error(w http.ResponseWriter, r *http.Request, err error) {
+	WriteProblem(w, r, ToProblem(err))
+}
+
+// WriteProblem writes a problem built by ToProblem, for handlers that have to adjust it, e.g. the status
+func WriteProblem(w http.ResponseWriter, r *http.Request, problem *Problem) {
+	problem.Instance = r.URL.Path
+	problem.RequestID = requestid.FromContext(r.Context())
+
+	w.Header().Set("Content-Type", problemContentType)
+	w.WriteHeader(problem.Status)
+	json.NewEncoder(w).Encode(problem)
+}
+
+func ToProblem(err error) *Problem {
+	appErr := apperr.As(err)
+	if appErr == nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			appErr = errTooLarge
+		} else {
+			appErr = errInternal
+		}
+	}
+
+	status, ok := statuses[appErr.Kind]
+	if !ok {
+		status = http.StatusInternalServerError
+	}
+	return &Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: appErr.Message,
+		Code:   appErr.Code,
+		Errors: appErr.Fields,
+	}
+}
@@ -0,0 +1,142 @@
+package scanner
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+const clamavChunkSize = 64 * 1024
+
+// ClamAVScanner streams files to a clamd daemon using the INSTREAM command
+type ClamAVScanner struct {
+	Network string // "tcp" or "unix"
+	Address string
+	// IdleTimeout limits every single read and write, so a big file may take as long as it streams
+	IdleTimeout time.Duration
+}
+
+// NewClamAVScanner accepts "tcp://host:port", "unix:///path/to/clamd.sock" or plain "host:port"
+func NewClamAVScanner(address string, idleTimeout time.Duration) *ClamAVScanner {
+	network := "tcp"
+	if strings.HasPrefix(address, "unix://") {
+		network = "unix"
+		address = strings.TrimPrefix(address, "unix://")
+	}
+	address = strings.TrimPrefix(address, "tcp://")
+
+	return &ClamAVScanner{Network: network, Address: address, IdleTimeout: idleTimeout}
+}
+
+func (s *ClamAVScanner) Scan(ctx context.Context, r io.Reader) (*Result, error) {
+	dialer := net.Dialer{Timeout: s.IdleTimeout}
+	rawConn, err := dialer.DialContext(ctx, s.Network, s.Address)
+	if err != nil {
+		return nil, fmt.Errorf("clamav connection failed: %w", err)
+	}
+	defer rawConn.Close()
+	conn := &idleConn{Conn: rawConn, timeout: s.IdleTimeout}
+
+	// Unblock reads and writes when the request is canceled
+	stop := context.AfterFunc(ctx, func() {
+		conn.cancel()
+	})
+	defer stop()
+
+	if _, err := conn.Write([]byte("zINSTREAM\x00")); err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, clamavChunkSize)
+	size := make([]byte, 4)
+	for {
+		n, readErr := r.Read(buf)
+		if n > 0 {
+			binary.BigEndian.PutUint32(size, uint32(n))
+			if _, err := conn.Write(size); err != nil {
+				return nil, err
+			}
+			if _, err := conn.Write(buf[:n]); err != nil {
+				return nil, err
+			}
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			return nil, readErr
+		}
+	}
+
+	// Zero length chunk ends the stream
+	binary.BigEndian.PutUint32(size, 0)
+	if _, err := conn.Write(size); err != nil {
+		return nil, err
+	}
+
+	reply, err := bufio.NewReader(conn).ReadString('\x00')
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	return parseClamAVReply(strings.TrimRight(reply, "\x00\n"))
+}
+
+// idleConn moves the deadline forward before every read and write
+type idleConn struct {
+	net.Conn
+	timeout  time.Duration
+	mu       sync.Mutex
+	canceled bool
+}
+
+func (c *idleConn) Read(p []byte) (int, error) {
+	c.extend()
+	return c.Conn.Read(p)
+}
+
+func (c *idleConn) Write(p []byte) (int, error) {
+	c.extend()
+	return c.Conn.Write(p)
+}
+
+func (c *idleConn) extend() {
+	if c.timeout <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.canceled {
+		c.Conn.SetDeadline(time.Now().Add(c.timeout))
+	}
+}
+
+func (c *idleConn) cancel() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.canceled = true
+	c.Conn.SetDeadline(time.Now())
+}
+
+// parseClamAVReply parses replies like "stream: OK" or "stream: Eicar-Test-Signature FOUND"
+func parseClamAVReply(reply string) (*Result, error) {
+	reply = strings.TrimPrefix(reply, "stream: ")
+
+	switch {
+	case reply == "OK":
+		return &Result{Clean: true}, nil
+	case strings.HasSuffix(reply, " FOUND"):
+		return &Result{Clean: false, Signature: strings.TrimSuffix(reply, " FOUND")}, nil
+	case strings.HasSuffix(reply, " ERROR"):
+		return nil, errors.New("clamav error: " + strings.TrimSuffix(reply, " ERROR"))
+	default:
+		return nil, errors.New("unexpected clamav reply: " + reply)
+	}
+}
@@ -0,0 +1,15 @@
+package scanner
+
+import (
+	"context"
+	"io"
+)
+
+type Result struct {
+	Clean     bool
+	Signature string
+}
+
+type Scanner interface {
+	Scan(ctx context.Context, r io.Reader) (*Result, error)
+}
@@ -0,0 +1,72 @@
+package signedurl
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/serhiirubets/rubeticket/internal/pkg/apperr"
+)
+
+var (
+	ErrInvalidSignature = apperr.New(apperr.Forbidden, "invalid_signature", "invalid signature")
+	ErrExpired          = apperr.New(apperr.Gone, "link_expired", "link expired")
+)
+
+type Signer struct {
+	Secret string
+}
+
+func NewSigner(secret string) *Signer {
+	return &Signer{Secret: secret}
+}
+
+// Sign returns query params for resource that are valid until expiresAt.
+// When oneTime is set a random nonce is added, so that the link can be tracked and used only once
+func (s *Signer) Sign(resource string, expiresAt time.Time, oneTime bool) (url.Values, error) {
+	query := url.Values{}
+	query.Set("exp", strconv.FormatInt(expiresAt.Unix(), 10))
+
+	if oneTime {
+		nonce := make([]byte, 16)
+		if _, err := rand.Read(nonce); err != nil {
+			return nil, err
+		}
+		query.Set("nonce", hex.EncodeToString(nonce))
+	}
+
+	query.Set("sig", s.signature(resource, query.Get("exp"), query.Get("nonce")))
+	return query, nil
+}
+
+// Verify checks the signature and expiry and returns the nonce of one-time links
+func (s *Signer) Verify(resource string, query url.Values) (string, error) {
+	exp := query.Get("exp")
+	nonce := query.Get("nonce")
+
+	expected := s.signature(resource, exp, nonce)
+	if !hmac.Equal([]byte(expected), []byte(query.Get("sig"))) {
+		return "", ErrInvalidSignature
+	}
+
+	expUnix, err := strconv.ParseInt(exp, 10, 64)
+	if err != nil {
+		return "", ErrInvalidSignature
+	}
+	if time.Now().Unix() > expUnix {
+		return "", ErrExpired
+	}
+
+	return nonce, nil
+}
+
+func (s *Signer) signature(resource, exp, nonce string) string {
+	mac := hmac.New(sha256.New, []byte(s.Secret))
+	mac.Write([]byte(resource + "\n" + exp + "\n" + nonce))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
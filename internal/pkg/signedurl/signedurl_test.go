@@ -0,0 +1,136 @@
+package signedurl_test
+
+import (
+	"errors"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/serhiirubets/rubeticket/internal/pkg/signedurl"
+)
+
+func TestSignAndVerify(t *testing.T) {
+	signer := signedurl.NewSigner("url-secret")
+
+	tests := []struct {
+		name      string
+		resource  string
+		expiresAt time.Time
+		oneTime   bool
+		tamper    func(query url.Values) url.Values
+		verifyAs  string
+		signer    *signedurl.Signer
+		wantErr   error
+		wantNonce bool
+	}{
+		{
+			name:      "valid link",
+			resource:  "a.png",
+			expiresAt: time.Now().Add(time.Hour),
+		},
+		{
+			name:      "valid one-time link has a nonce",
+			resource:  "a.png",
+			expiresAt: time.Now().Add(time.Hour),
+			oneTime:   true,
+			wantNonce: true,
+		},
+		{
+			name:      "expired link",
+			resource:  "a.png",
+			expiresAt: time.Now().Add(-time.Minute),
+			wantErr:   signedurl.ErrExpired,
+		},
+		{
+			name:      "other resource",
+			resource:  "a.png",
+			expiresAt: time.Now().Add(time.Hour),
+			verifyAs:  "b.png",
+			wantErr:   signedurl.ErrInvalidSignature,
+		},
+		{
+			name:      "other secret",
+			resource:  "a.png",
+			expiresAt: time.Now().Add(time.Hour),
+			signer:    signedurl.NewSigner("other-secret"),
+			wantErr:   signedurl.ErrInvalidSignature,
+		},
+		{
+			name:      "extended expiry",
+			resource:  "a.png",
+			expiresAt: time.Now().Add(time.Hour),
+			tamper: func(query url.Values) url.Values {
+				query.Set("exp", strconv.FormatInt(time.Now().Add(24*time.Hour).Unix(), 10))
+				return query
+			},
+			wantErr: signedurl.ErrInvalidSignature,
+		},
+		{
+			name:      "nonce removed from a one-time link",
+			resource:  "a.png",
+			expiresAt: time.Now().Add(time.Hour),
+			oneTime:   true,
+			tamper: func(query url.Values) url.Values {
+				query.Del("nonce")
+				return query
+			},
+			wantErr: signedurl.ErrInvalidSignature,
+		},
+		{
+			name:      "missing signature",
+			resource:  "a.png",
+			expiresAt: time.Now().Add(time.Hour),
+			tamper: func(query url.Values) url.Values {
+				query.Del("sig")
+				return query
+			},
+			wantErr: signedurl.ErrInvalidSignature,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, err := signer.Sign(tt.resource, tt.expiresAt, tt.oneTime)
+			if err != nil {
+				t.Fatalf("Sign() error = %v", err)
+			}
+			if tt.tamper != nil {
+				query = tt.tamper(query)
+			}
+			verifier := signer
+			if tt.signer != nil {
+				verifier = tt.signer
+			}
+			resource := tt.resource
+			if tt.verifyAs != "" {
+				resource = tt.verifyAs
+			}
+
+			nonce, err := verifier.Verify(resource, query)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Verify() error = %v, want %v", err, tt.wantErr)
+			}
+			if (nonce != "") != tt.wantNonce {
+				t.Errorf("Verify() nonce = %q, want nonce %v", nonce, tt.wantNonce)
+			}
+		})
+	}
+}
+
+func TestSignOneTimeNoncesDiffer(t *testing.T) {
+	signer := signedurl.NewSigner("url-secret")
+	expiresAt := time.Now().Add(time.Hour)
+
+	first, err := signer.Sign("a.png", expiresAt, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := signer.Sign("a.png", expiresAt, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Get("nonce") == second.Get("nonce") {
+		t.Errorf("two one-time links got the same nonce %q", first.Get("nonce"))
+	}
+}
@@ -0,0 +1,91 @@
+// Package tracing sets up OpenTelemetry and starts spans. While tracing is off the no-op
+// provider of otel is kept, spans are not recorded and cost next to nothing
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/serhiirubets/rubeticket/config"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/serhiirubets/rubeticket"
+
+// Header sends the trace ID back to the client, so a slow request can be found in the traces
+const Header = "X-Trace-ID"
+
+var ErrUnknownExporter = errors.New("unknown tracing exporter")
+
+// Setup installs the tracer provider and the W3C trace context propagator.
+// The returned shutdown flushes spans that were not exported yet, call it before exit
+func Setup(ctx context.Context, conf config.TracingConfig) (func(context.Context) error, error) {
+	if !conf.Enabled() {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := newExporter(ctx, conf)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := resource.New(ctx,
+		resource.WithAttributes(semconv.ServiceName(conf.ServiceName)),
+		resource.WithTelemetrySDK(),
+		resource.WithHost(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(conf.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	return provider.Shutdown, nil
+}
+
+func newExporter(ctx context.Context, conf config.TracingConfig) (sdktrace.SpanExporter, error) {
+	switch conf.Exporter {
+	case "otlp":
+		var opts []otlptracehttp.Option
+		if conf.OTLPEndpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpoint(conf.OTLPEndpoint))
+		}
+		if conf.OTLPInsecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		return otlptracehttp.New(ctx, opts...)
+	case "stdout":
+		return stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownExporter, conf.Exporter)
+	}
+}
+
+// Start starts a span as a child of the span in ctx
+func Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, opts...)
+}
+
+// TraceID returns the ID of the trace ctx is part of, "" when there is none
+func TraceID(ctx context.Context) string {
+	spanContext := trace.SpanContextFromContext(ctx)
+	if !spanContext.HasTraceID() {
+		return ""
+	}
+	return spanContext.TraceID().String()
+}
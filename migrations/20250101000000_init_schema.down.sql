@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS concert_bands;
+DROP TABLE IF EXISTS concerts;
+DROP TABLE IF EXISTS bands;
+DROP TABLE IF EXISTS venues;
+DROP TABLE IF EXISTS uploads;
+DROP TABLE IF EXISTS signed_url_uses;
+DROP TABLE IF EXISTS files;
+DROP TABLE IF EXISTS users;
@@ -0,0 +1,119 @@
+-- IF NOT EXISTS lets databases created by the former AutoMigrate adopt this migration
+CREATE TABLE IF NOT EXISTS users (
+    id            BIGSERIAL PRIMARY KEY,
+    created_at    TIMESTAMPTZ,
+    updated_at    TIMESTAMPTZ,
+    deleted_at    TIMESTAMPTZ,
+    email         VARCHAR(255) NOT NULL,
+    first_name    TEXT         NOT NULL,
+    last_name     TEXT         NOT NULL,
+    password_hash TEXT         NOT NULL,
+    birthday      TIMESTAMPTZ  NOT NULL,
+    gender        VARCHAR(6)   NOT NULL,
+    activated_at  TIMESTAMPTZ,
+    status        VARCHAR(20) DEFAULT 'pending',
+    role          VARCHAR(20) DEFAULT 'user'
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email ON users (email);
+CREATE INDEX IF NOT EXISTS idx_users_deleted_at ON users (deleted_at);
+
+CREATE TABLE IF NOT EXISTS files (
+    id          BIGSERIAL PRIMARY KEY,
+    created_at  TIMESTAMPTZ,
+    updated_at  TIMESTAMPTZ,
+    deleted_at  TIMESTAMPTZ,
+    uuid        TEXT NOT NULL UNIQUE,
+    user_id     BIGINT NOT NULL,
+    file_path   TEXT NOT NULL,
+    purpose     TEXT,
+    status      VARCHAR(20) DEFAULT 'available',
+    scan_result TEXT,
+    scanned_at  TIMESTAMPTZ
+);
+-- Columns added after the first release, CREATE TABLE IF NOT EXISTS skips them on an existing table
+ALTER TABLE files ADD COLUMN IF NOT EXISTS status VARCHAR(20) DEFAULT 'available';
+ALTER TABLE files ADD COLUMN IF NOT EXISTS scan_result TEXT;
+ALTER TABLE files ADD COLUMN IF NOT EXISTS scanned_at TIMESTAMPTZ;
+CREATE INDEX IF NOT EXISTS idx_files_deleted_at ON files (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_file_status ON files (status);
+
+CREATE TABLE IF NOT EXISTS signed_url_uses (
+    nonce   VARCHAR(64) PRIMARY KEY,
+    file_id BIGINT      NOT NULL,
+    used_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS uploads (
+    id                 BIGSERIAL PRIMARY KEY,
+    created_at         TIMESTAMPTZ,
+    updated_at         TIMESTAMPTZ,
+    deleted_at         TIMESTAMPTZ,
+    uuid               TEXT         NOT NULL UNIQUE,
+    user_id            BIGINT       NOT NULL,
+    file_name          VARCHAR(255),
+    content_type       VARCHAR(100) NOT NULL,
+    purpose            VARCHAR(50),
+    length             BIGINT       NOT NULL,
+    upload_offset      BIGINT       NOT NULL DEFAULT 0,
+    checksum_algorithm VARCHAR(10),
+    checksum           VARCHAR(128),
+    status             VARCHAR(20) DEFAULT 'pending',
+    file_id            BIGINT,
+    expires_at         TIMESTAMPTZ  NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_uploads_deleted_at ON uploads (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_upload_user_id ON uploads (user_id);
+CREATE INDEX IF NOT EXISTS idx_uploads_file_id ON uploads (file_id);
+
+CREATE TABLE IF NOT EXISTS venues (
+    id          BIGSERIAL PRIMARY KEY,
+    created_at  TIMESTAMPTZ,
+    updated_at  TIMESTAMPTZ,
+    deleted_at  TIMESTAMPTZ,
+    name        VARCHAR(100) NOT NULL,
+    description VARCHAR(300),
+    address     VARCHAR(50)  NOT NULL,
+    phone       VARCHAR(20),
+    email       VARCHAR(50)
+);
+CREATE INDEX IF NOT EXISTS idx_venues_deleted_at ON venues (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_venue_name ON venues (name);
+CREATE INDEX IF NOT EXISTS idx_venue_address ON venues (address);
+
+CREATE TABLE IF NOT EXISTS bands (
+    id          BIGSERIAL PRIMARY KEY,
+    created_at  TIMESTAMPTZ,
+    updated_at  TIMESTAMPTZ,
+    deleted_at  TIMESTAMPTZ,
+    name        VARCHAR(255) NOT NULL,
+    description TEXT,
+    genre       VARCHAR(100)
+);
+CREATE INDEX IF NOT EXISTS idx_bands_deleted_at ON bands (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_band_name ON bands (name);
+CREATE INDEX IF NOT EXISTS idx_band_genre ON bands (genre);
+
+CREATE TABLE IF NOT EXISTS concerts (
+    id          BIGSERIAL PRIMARY KEY,
+    created_at  TIMESTAMPTZ,
+    updated_at  TIMESTAMPTZ,
+    deleted_at  TIMESTAMPTZ,
+    title       VARCHAR(100) NOT NULL,
+    description VARCHAR(300),
+    poster_url  VARCHAR(100),
+    date        TIMESTAMPTZ  NOT NULL,
+    venue_id    BIGINT       NOT NULL,
+    CONSTRAINT fk_concerts_venue FOREIGN KEY (venue_id) REFERENCES venues (id)
+);
+CREATE INDEX IF NOT EXISTS idx_concerts_deleted_at ON concerts (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_concert_date ON concerts (date);
+CREATE INDEX IF NOT EXISTS idx_concert_venue_id ON concerts (venue_id);
+
+CREATE TABLE IF NOT EXISTS concert_bands (
+    concert_id BIGINT NOT NULL,
+    band_id    BIGINT NOT NULL,
+    PRIMARY KEY (concert_id, band_id),
+    CONSTRAINT fk_concert_bands_concert FOREIGN KEY (concert_id) REFERENCES concerts (id),
+    CONSTRAINT fk_concert_bands_band FOREIGN KEY (band_id) REFERENCES bands (id)
+);
+CREATE INDEX IF NOT EXISTS idx_concert_bands ON concert_bands (concert_id, band_id);
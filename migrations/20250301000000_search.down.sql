@@ -0,0 +1,17 @@
+DROP AGGREGATE IF EXISTS tsvector_agg (tsvector);
+
+DROP INDEX IF EXISTS idx_venues_address_trgm;
+DROP INDEX IF EXISTS idx_venues_name_trgm;
+DROP INDEX IF EXISTS idx_bands_genre_trgm;
+DROP INDEX IF EXISTS idx_bands_name_trgm;
+DROP INDEX IF EXISTS idx_concerts_title_trgm;
+
+DROP INDEX IF EXISTS idx_venues_search;
+DROP INDEX IF EXISTS idx_bands_search;
+DROP INDEX IF EXISTS idx_concerts_search;
+
+ALTER TABLE venues DROP COLUMN IF EXISTS search_vector;
+ALTER TABLE bands DROP COLUMN IF EXISTS search_vector;
+ALTER TABLE concerts DROP COLUMN IF EXISTS search_vector;
+
+-- pg_trgm is left installed, other objects in the database may use it
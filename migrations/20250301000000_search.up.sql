@@ -0,0 +1,37 @@
+-- Full-text search. The 'simple' config is used because names and cities are not English words
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+ALTER TABLE concerts ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('simple', coalesce(title, '')), 'A') ||
+    setweight(to_tsvector('simple', coalesce(description, '')), 'D')
+) STORED;
+
+ALTER TABLE bands ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('simple', coalesce(name, '')), 'A') ||
+    setweight(to_tsvector('simple', coalesce(genre, '')), 'B') ||
+    setweight(to_tsvector('simple', coalesce(description, '')), 'D')
+) STORED;
+
+ALTER TABLE venues ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('simple', coalesce(name, '')), 'A') ||
+    setweight(to_tsvector('simple', coalesce(address, '')), 'B') ||
+    setweight(to_tsvector('simple', coalesce(description, '')), 'D')
+) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_concerts_search ON concerts USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS idx_bands_search ON bands USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS idx_venues_search ON venues USING GIN (search_vector);
+
+-- Trigram indexes for typo tolerant matching
+CREATE INDEX IF NOT EXISTS idx_concerts_title_trgm ON concerts USING GIN (title gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_bands_name_trgm ON bands USING GIN (name gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_bands_genre_trgm ON bands USING GIN (genre gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_venues_name_trgm ON venues USING GIN (name gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_venues_address_trgm ON venues USING GIN (address gin_trgm_ops);
+
+-- Merges band vectors of a concert keeping their weights
+CREATE OR REPLACE AGGREGATE tsvector_agg (tsvector) (
+    SFUNC = tsvector_concat,
+    STYPE = tsvector,
+    INITCOND = ''
+);
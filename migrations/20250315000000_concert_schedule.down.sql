@@ -0,0 +1,10 @@
+ALTER TABLE concert_bands DROP COLUMN IF EXISTS set_end;
+ALTER TABLE concert_bands DROP COLUMN IF EXISTS set_start;
+ALTER TABLE concert_bands DROP COLUMN IF EXISTS role;
+ALTER TABLE concert_bands DROP COLUMN IF EXISTS position;
+
+DROP INDEX IF EXISTS idx_concert_venue_date;
+ALTER TABLE concerts DROP COLUMN IF EXISTS curfew_at;
+ALTER TABLE concerts DROP COLUMN IF EXISTS doors_at;
+
+ALTER TABLE venues DROP COLUMN IF EXISTS time_zone;
@@ -0,0 +1,19 @@
+ALTER TABLE venues ADD COLUMN IF NOT EXISTS time_zone VARCHAR(64) NOT NULL DEFAULT 'UTC';
+
+-- date stays the show time
+ALTER TABLE concerts ADD COLUMN IF NOT EXISTS doors_at TIMESTAMPTZ;
+ALTER TABLE concerts ADD COLUMN IF NOT EXISTS curfew_at TIMESTAMPTZ;
+CREATE INDEX IF NOT EXISTS idx_concert_venue_date ON concerts (venue_id, date);
+
+ALTER TABLE concert_bands ADD COLUMN IF NOT EXISTS position INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE concert_bands ADD COLUMN IF NOT EXISTS role VARCHAR(20) NOT NULL DEFAULT 'support';
+ALTER TABLE concert_bands ADD COLUMN IF NOT EXISTS set_start TIMESTAMPTZ;
+ALTER TABLE concert_bands ADD COLUMN IF NOT EXISTS set_end TIMESTAMPTZ;
+
+-- existing lineups get the first band as headliner
+UPDATE concert_bands cb SET position = ranked.position, role = CASE WHEN ranked.position = 1 THEN 'headliner' ELSE 'support' END
+FROM (
+    SELECT concert_id, band_id, row_number() OVER (PARTITION BY concert_id ORDER BY band_id) AS position
+    FROM concert_bands
+) ranked
+WHERE cb.concert_id = ranked.concert_id AND cb.band_id = ranked.band_id;
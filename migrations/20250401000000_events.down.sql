@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS pass_concerts;
+DROP TABLE IF EXISTS passes;
+
+DROP INDEX IF EXISTS idx_concert_event_id;
+ALTER TABLE concerts DROP CONSTRAINT IF EXISTS fk_concerts_event;
+ALTER TABLE concerts DROP COLUMN IF EXISTS event_id;
+
+DROP TABLE IF EXISTS events;
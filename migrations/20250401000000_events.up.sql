@@ -0,0 +1,40 @@
+CREATE TABLE IF NOT EXISTS events (
+    id          BIGSERIAL PRIMARY KEY,
+    created_at  TIMESTAMPTZ,
+    updated_at  TIMESTAMPTZ,
+    deleted_at  TIMESTAMPTZ,
+    kind        VARCHAR(20)  NOT NULL,
+    name        VARCHAR(100) NOT NULL,
+    description VARCHAR(1000),
+    poster_url  VARCHAR(100)
+);
+CREATE INDEX IF NOT EXISTS idx_events_deleted_at ON events (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_event_kind ON events (kind);
+
+ALTER TABLE concerts ADD COLUMN IF NOT EXISTS event_id BIGINT;
+ALTER TABLE concerts ADD CONSTRAINT fk_concerts_event FOREIGN KEY (event_id) REFERENCES events (id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS idx_concert_event_id ON concerts (event_id);
+
+CREATE TABLE IF NOT EXISTS passes (
+    id          BIGSERIAL PRIMARY KEY,
+    created_at  TIMESTAMPTZ,
+    updated_at  TIMESTAMPTZ,
+    deleted_at  TIMESTAMPTZ,
+    event_id    BIGINT       NOT NULL,
+    name        VARCHAR(100) NOT NULL,
+    description VARCHAR(300),
+    price       BIGINT       NOT NULL,
+    currency    VARCHAR(3)   NOT NULL,
+    CONSTRAINT fk_passes_event FOREIGN KEY (event_id) REFERENCES events (id)
+);
+CREATE INDEX IF NOT EXISTS idx_passes_deleted_at ON passes (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_pass_event_id ON passes (event_id);
+
+CREATE TABLE IF NOT EXISTS pass_concerts (
+    pass_id    BIGINT NOT NULL,
+    concert_id BIGINT NOT NULL,
+    PRIMARY KEY (pass_id, concert_id),
+    CONSTRAINT fk_pass_concerts_pass FOREIGN KEY (pass_id) REFERENCES passes (id) ON DELETE CASCADE,
+    CONSTRAINT fk_pass_concerts_concert FOREIGN KEY (concert_id) REFERENCES concerts (id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_pass_concerts_concert ON pass_concerts (concert_id);
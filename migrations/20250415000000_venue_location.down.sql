@@ -0,0 +1,22 @@
+DROP INDEX IF EXISTS idx_venues_search;
+ALTER TABLE venues DROP COLUMN IF EXISTS search_vector;
+
+DROP INDEX IF EXISTS idx_venue_location;
+DROP INDEX IF EXISTS idx_venue_country;
+DROP INDEX IF EXISTS idx_venue_city;
+
+ALTER TABLE venues DROP COLUMN IF EXISTS longitude;
+ALTER TABLE venues DROP COLUMN IF EXISTS latitude;
+ALTER TABLE venues DROP COLUMN IF EXISTS capacity;
+ALTER TABLE venues DROP COLUMN IF EXISTS country;
+ALTER TABLE venues DROP COLUMN IF EXISTS postal_code;
+ALTER TABLE venues DROP COLUMN IF EXISTS region;
+ALTER TABLE venues DROP COLUMN IF EXISTS city;
+ALTER TABLE venues ALTER COLUMN address TYPE VARCHAR(50) USING left(address, 50);
+
+ALTER TABLE venues ADD COLUMN search_vector tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('simple', coalesce(name, '')), 'A') ||
+    setweight(to_tsvector('simple', coalesce(address, '')), 'B') ||
+    setweight(to_tsvector('simple', coalesce(description, '')), 'D')
+) STORED;
+CREATE INDEX IF NOT EXISTS idx_venues_search ON venues USING GIN (search_vector);
@@ -0,0 +1,24 @@
+-- search_vector depends on address, it has to be dropped to change the column type
+DROP INDEX IF EXISTS idx_venues_search;
+ALTER TABLE venues DROP COLUMN IF EXISTS search_vector;
+
+ALTER TABLE venues ALTER COLUMN address TYPE VARCHAR(255);
+ALTER TABLE venues ADD COLUMN IF NOT EXISTS city VARCHAR(100);
+ALTER TABLE venues ADD COLUMN IF NOT EXISTS region VARCHAR(100);
+ALTER TABLE venues ADD COLUMN IF NOT EXISTS postal_code VARCHAR(20);
+ALTER TABLE venues ADD COLUMN IF NOT EXISTS country VARCHAR(2);
+ALTER TABLE venues ADD COLUMN IF NOT EXISTS capacity INTEGER CHECK (capacity > 0);
+ALTER TABLE venues ADD COLUMN IF NOT EXISTS latitude DOUBLE PRECISION CHECK (latitude BETWEEN -90 AND 90);
+ALTER TABLE venues ADD COLUMN IF NOT EXISTS longitude DOUBLE PRECISION CHECK (longitude BETWEEN -180 AND 180);
+
+CREATE INDEX IF NOT EXISTS idx_venue_city ON venues (city);
+CREATE INDEX IF NOT EXISTS idx_venue_country ON venues (country);
+-- bounding box prefilter for near me queries
+CREATE INDEX IF NOT EXISTS idx_venue_location ON venues (latitude, longitude);
+
+ALTER TABLE venues ADD COLUMN search_vector tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('simple', coalesce(name, '')), 'A') ||
+    setweight(to_tsvector('simple', coalesce(address, '') || ' ' || coalesce(city, '')), 'B') ||
+    setweight(to_tsvector('simple', coalesce(description, '')), 'D')
+) STORED;
+CREATE INDEX IF NOT EXISTS idx_venues_search ON venues USING GIN (search_vector);
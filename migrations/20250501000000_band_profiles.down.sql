@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_band_country;
+ALTER TABLE bands DROP COLUMN IF EXISTS country;
+
+DROP TABLE IF EXISTS band_links;
+DROP TABLE IF EXISTS band_members;
+DROP TABLE IF EXISTS band_genres;
+DROP TABLE IF EXISTS genres;
@@ -0,0 +1,64 @@
+CREATE TABLE IF NOT EXISTS genres (
+    id         BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    name       VARCHAR(100) NOT NULL,
+    slug       VARCHAR(100) NOT NULL,
+    parent_id  BIGINT,
+    CONSTRAINT fk_genres_parent FOREIGN KEY (parent_id) REFERENCES genres (id) ON DELETE SET NULL
+);
+CREATE INDEX IF NOT EXISTS idx_genres_deleted_at ON genres (deleted_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_genre_slug ON genres (slug) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_genre_parent_id ON genres (parent_id);
+
+CREATE TABLE IF NOT EXISTS band_genres (
+    band_id  BIGINT NOT NULL,
+    genre_id BIGINT NOT NULL,
+    PRIMARY KEY (band_id, genre_id),
+    CONSTRAINT fk_band_genres_band FOREIGN KEY (band_id) REFERENCES bands (id) ON DELETE CASCADE,
+    CONSTRAINT fk_band_genres_genre FOREIGN KEY (genre_id) REFERENCES genres (id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_band_genres_genre ON band_genres (genre_id);
+
+CREATE TABLE IF NOT EXISTS band_members (
+    id          BIGSERIAL PRIMARY KEY,
+    band_id     BIGINT       NOT NULL,
+    name        VARCHAR(100) NOT NULL,
+    role        VARCHAR(100),
+    joined_year INTEGER,
+    left_year   INTEGER,
+    position    INTEGER      NOT NULL DEFAULT 0,
+    CONSTRAINT fk_band_members_band FOREIGN KEY (band_id) REFERENCES bands (id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_band_member_band_id ON band_members (band_id);
+
+CREATE TABLE IF NOT EXISTS band_links (
+    id      BIGSERIAL PRIMARY KEY,
+    band_id BIGINT       NOT NULL,
+    kind    VARCHAR(20)  NOT NULL,
+    url     VARCHAR(255) NOT NULL,
+    CONSTRAINT fk_band_links_band FOREIGN KEY (band_id) REFERENCES bands (id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_band_link_band_id ON band_links (band_id);
+
+ALTER TABLE bands ADD COLUMN IF NOT EXISTS country VARCHAR(2);
+CREATE INDEX IF NOT EXISTS idx_band_country ON bands (country);
+
+-- free text genres become taxonomy entries, bands.genre stays as the primary genre name
+INSERT INTO genres (created_at, updated_at, name, slug)
+SELECT DISTINCT ON (slug) now(), now(), name, slug
+FROM (
+    SELECT trim(genre) AS name, trim(BOTH '-' FROM regexp_replace(lower(trim(genre)), '[^[:alnum:]]+', '-', 'g')) AS slug
+    FROM bands
+    WHERE deleted_at IS NULL AND coalesce(trim(genre), '') <> ''
+) free_text
+WHERE slug <> ''
+ORDER BY slug, name;
+
+INSERT INTO band_genres (band_id, genre_id)
+SELECT b.id, g.id
+FROM bands b
+JOIN genres g ON g.slug = trim(BOTH '-' FROM regexp_replace(lower(trim(b.genre)), '[^[:alnum:]]+', '-', 'g'))
+WHERE b.deleted_at IS NULL
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,31 @@
+CREATE TABLE IF NOT EXISTS audit_log (
+    id          BIGSERIAL PRIMARY KEY,
+    created_at  TIMESTAMPTZ  NOT NULL DEFAULT now(),
+    actor_id    BIGINT,
+    actor_email VARCHAR(255),
+    actor_role  VARCHAR(20),
+    action      VARCHAR(20)  NOT NULL,
+    entity      VARCHAR(30)  NOT NULL,
+    entity_id   BIGINT,
+    changes     JSONB,
+    ip          VARCHAR(45),
+    user_agent  VARCHAR(255)
+);
+CREATE INDEX IF NOT EXISTS idx_audit_entity ON audit_log (entity, entity_id);
+CREATE INDEX IF NOT EXISTS idx_audit_actor_id ON audit_log (actor_id);
+CREATE INDEX IF NOT EXISTS idx_audit_created_at ON audit_log (created_at);
+
+-- the log is append-only, rows cannot be changed or removed through the application role
+CREATE OR REPLACE FUNCTION audit_log_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS audit_log_no_change ON audit_log;
+CREATE TRIGGER audit_log_no_change BEFORE UPDATE OR DELETE ON audit_log
+    FOR EACH ROW EXECUTE FUNCTION audit_log_append_only();
+
+DROP TRIGGER IF EXISTS audit_log_no_truncate ON audit_log;
+CREATE TRIGGER audit_log_no_truncate BEFORE TRUNCATE ON audit_log
+    FOR EACH STATEMENT EXECUTE FUNCTION audit_log_append_only();
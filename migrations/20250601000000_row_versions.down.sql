@@ -0,0 +1,3 @@
+ALTER TABLE bands DROP COLUMN IF EXISTS version;
+ALTER TABLE venues DROP COLUMN IF EXISTS version;
+ALTER TABLE concerts DROP COLUMN IF EXISTS version;
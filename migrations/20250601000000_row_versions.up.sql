@@ -0,0 +1,4 @@
+-- version is bumped on every write, updates and deletes only apply when it matches the ETag sent by the client
+ALTER TABLE concerts ADD COLUMN IF NOT EXISTS version BIGINT NOT NULL DEFAULT 1;
+ALTER TABLE venues ADD COLUMN IF NOT EXISTS version BIGINT NOT NULL DEFAULT 1;
+ALTER TABLE bands ADD COLUMN IF NOT EXISTS version BIGINT NOT NULL DEFAULT 1;
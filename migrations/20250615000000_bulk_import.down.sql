@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS import_jobs;
+
+DROP INDEX IF EXISTS idx_concert_external_id;
+ALTER TABLE concerts DROP COLUMN IF EXISTS external_id;
+DROP INDEX IF EXISTS idx_band_external_id;
+ALTER TABLE bands DROP COLUMN IF EXISTS external_id;
+DROP INDEX IF EXISTS idx_venue_external_id;
+ALTER TABLE venues DROP COLUMN IF EXISTS external_id;
@@ -0,0 +1,26 @@
+-- external_id is the key of the row in the system it was imported from, imports upsert by it
+ALTER TABLE venues ADD COLUMN IF NOT EXISTS external_id VARCHAR(100);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_venue_external_id ON venues (external_id);
+ALTER TABLE bands ADD COLUMN IF NOT EXISTS external_id VARCHAR(100);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_band_external_id ON bands (external_id);
+ALTER TABLE concerts ADD COLUMN IF NOT EXISTS external_id VARCHAR(100);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_concert_external_id ON concerts (external_id);
+
+CREATE TABLE IF NOT EXISTS import_jobs (
+    id          BIGSERIAL PRIMARY KEY,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    finished_at TIMESTAMPTZ,
+    entity      VARCHAR(20) NOT NULL,
+    format      VARCHAR(10) NOT NULL,
+    dry_run     BOOLEAN     NOT NULL DEFAULT FALSE,
+    status      VARCHAR(20) NOT NULL,
+    total       INTEGER     NOT NULL DEFAULT 0,
+    created     INTEGER     NOT NULL DEFAULT 0,
+    updated     INTEGER     NOT NULL DEFAULT 0,
+    failed      INTEGER     NOT NULL DEFAULT 0,
+    errors      JSONB,
+    error       TEXT,
+    created_by  BIGINT
+);
+CREATE INDEX IF NOT EXISTS idx_import_job_created_by ON import_jobs (created_by);
@@ -0,0 +1,2 @@
+-- Lets the reaper find expired pending uploads without scanning the table
+CREATE INDEX IF NOT EXISTS idx_uploads_status_expires_at ON uploads (status, expires_at);
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_signed_url_uses_used_at;
@@ -0,0 +1,2 @@
+-- Lets the pruner delete old uses of one-time links without scanning the table
+CREATE INDEX IF NOT EXISTS idx_signed_url_uses_used_at ON signed_url_uses (used_at);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_genres_name_trgm;
+DROP INDEX IF EXISTS idx_concert_bands_band_id;
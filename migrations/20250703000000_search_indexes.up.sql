@@ -0,0 +1,3 @@
+-- Search matches concerts through their bands and genre names
+CREATE INDEX IF NOT EXISTS idx_concert_bands_band_id ON concert_bands (band_id);
+CREATE INDEX IF NOT EXISTS idx_genres_name_trgm ON genres USING GIN (name gin_trgm_ops);
@@ -0,0 +1 @@
+ALTER TABLE signed_url_uses DROP COLUMN IF EXISTS client;
@@ -0,0 +1,2 @@
+-- One-time links can be continued only by the client that used them first
+ALTER TABLE signed_url_uses ADD COLUMN IF NOT EXISTS client VARCHAR(64) NOT NULL DEFAULT '';
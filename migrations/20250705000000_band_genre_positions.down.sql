@@ -0,0 +1 @@
+ALTER TABLE band_genres DROP COLUMN IF EXISTS position;
@@ -0,0 +1,12 @@
+-- Band genres keep the order they were given in, the first one is the primary genre
+ALTER TABLE band_genres ADD COLUMN IF NOT EXISTS position INTEGER NOT NULL DEFAULT 0;
+
+UPDATE band_genres bg SET position = ordered.position
+FROM (
+    SELECT bg.band_id, bg.genre_id,
+        row_number() OVER (PARTITION BY bg.band_id ORDER BY g.name = b.genre DESC, g.name) - 1 AS position
+    FROM band_genres bg
+    JOIN genres g ON g.id = bg.genre_id
+    JOIN bands b ON b.id = bg.band_id
+) ordered
+WHERE bg.band_id = ordered.band_id AND bg.genre_id = ordered.genre_id;
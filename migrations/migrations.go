@@ -0,0 +1,8 @@
+// Package migrations contains versioned SQL migrations applied by cmd/migrate.
+// Files are named "<version>_<name>.up.sql" and "<version>_<name>.down.sql"
+package migrations
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS
@@ -2,6 +2,8 @@ package tests
 
 import (
 	"github.com/serhiirubets/rubeticket/app"
+	"github.com/serhiirubets/rubeticket/internal/pkg/background"
+	"github.com/serhiirubets/rubeticket/internal/pkg/health"
 	"net/http/httptest"
 	"os"
 	"testing"
@@ -11,7 +13,7 @@ func TestMain(m *testing.M) {
 	os.Setenv("ENV", "test")
 	env := SetupTestEnv()
 
-	router, err := app.InitApp(env.Conf, env.Logger)
+	router, err := app.InitApp(env.Conf, env.Logger, env.DB, health.NewChecker(), background.NewRunner(env.Logger))
 	if err != nil {
 		env.Logger.Error("Failed to initialize app", "error", err.Error())
 		os.Exit(1)
@@ -13,9 +13,15 @@ type TestEnv struct {
 }
 
 func SetupTestEnv() *TestEnv {
-	conf := config.LoadConfig()
-	dbInstance := db.NewDb(conf)
+	conf, err := config.LoadConfig(nil)
+	if err != nil {
+		panic(err)
+	}
 	logger := log.NewLogrusLogger(conf.LogLevel)
+	dbInstance, err := db.NewDb(conf, logger)
+	if err != nil {
+		panic(err)
+	}
 
 	return &TestEnv{
 		DB:     dbInstance,